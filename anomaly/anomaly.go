@@ -0,0 +1,203 @@
+// Package anomaly flags hops whose RTT is easy to misread: sharp jumps, later hops answering
+// faster than earlier ones (routers deprioritizing ICMP) and RTTs too low for the distance
+// between the hop and an earlier one (wrong geolocation).
+package anomaly
+
+import (
+	"math"
+	"time"
+
+	"github.com/nxtrace/NTrace-core/i18n"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+// Kind is the kind of an annotation.
+type Kind string
+
+// Annotation kinds.
+const (
+	// Jump: the RTT rises sharply over the previous responding hop.
+	Jump Kind = "rtt_jump"
+	// Inversion: the hop answers faster than an earlier one, which therefore only looks slow
+	// because it deprioritizes ICMP replies.
+	Inversion Kind = "rtt_inversion"
+	// Impossible: the RTT is lower than light in fiber needs for the distance from an earlier
+	// hop, so the geolocation of the hop is wrong.
+	Impossible Kind = "impossible_rtt"
+)
+
+const (
+	defaultJumpMs      = 30
+	defaultInversionMs = 10
+	// fiberKmPerMs 光在光纤中每毫秒传播的距离，约为真空光速的 2/3
+	fiberKmPerMs  = 200
+	earthRadiusKm = 6371
+)
+
+// Annotation explains the RTT of one hop. RTTs are in milliseconds, TTLs start at 1.
+type Annotation struct {
+	Kind Kind `json:"kind"`
+	// RTTMs is the lowest RTT of the hop.
+	RTTMs float64 `json:"rtt_ms"`
+	// RefTTL is the earlier hop the hop is compared with.
+	RefTTL int `json:"ref_ttl"`
+	// DeltaMs is the RTT difference to RefTTL (Jump and Inversion).
+	DeltaMs float64 `json:"delta_ms,omitempty"`
+	// DistanceKm is the great-circle distance to RefTTL (Impossible).
+	DistanceKm float64 `json:"distance_km,omitempty"`
+	// MinRTTMs is the lowest RTT possible over DistanceKm (Impossible).
+	MinRTTMs float64 `json:"min_rtt_ms,omitempty"`
+}
+
+// Text explains a in the current UI language.
+func (a Annotation) Text() string {
+	switch a.Kind {
+	case Jump:
+		return i18n.T(i18n.AnomalyJump, a.DeltaMs, a.RefTTL)
+	case Inversion:
+		return i18n.T(i18n.AnomalyInversion, a.RefTTL, a.DeltaMs, a.RefTTL)
+	case Impossible:
+		return i18n.T(i18n.AnomalyImpossible, a.RefTTL, a.DistanceKm, a.MinRTTMs, a.RTTMs)
+	}
+	return string(a.Kind)
+}
+
+// Short is a short form of Text for tables.
+func (a Annotation) Short() string {
+	switch a.Kind {
+	case Jump:
+		return i18n.T(i18n.AnomalyJumpShort, a.DeltaMs)
+	case Inversion:
+		return i18n.T(i18n.AnomalyInversionShort, a.RefTTL)
+	case Impossible:
+		return i18n.T(i18n.AnomalyImpossibleShort)
+	}
+	return string(a.Kind)
+}
+
+// Options are the thresholds of Analyze; zero values use the defaults.
+type Options struct {
+	// JumpMs is the RTT rise over the previous responding hop flagged as a jump. Default: 30.
+	JumpMs float64
+	// InversionMs is how much faster than an earlier hop a hop must answer to flag the earlier
+	// one as a slow ICMP responder. Default: 10.
+	InversionMs float64
+}
+
+func (o Options) withDefaults() Options {
+	if o.JumpMs <= 0 {
+		o.JumpMs = defaultJumpMs
+	}
+	if o.InversionMs <= 0 {
+		o.InversionMs = defaultInversionMs
+	}
+	return o
+}
+
+// Analyze annotates hops, the attempts of every TTL as in trace.Result.Hops; the result is
+// indexed like hops. The annotations of a hop depend only on the hops before it, so analyzing
+// a running trace gives the same annotations as the finished one.
+func Analyze(hops [][]trace.Hop, opts Options) [][]Annotation {
+	opts = opts.withDefaults()
+	out := make([][]Annotation, len(hops))
+	// prev 为上一个有响应的跳，slowest 为此前延迟最高且尚未报告倒挂的跳，anchor 为首个有坐标的跳
+	prev, slowest, anchor := -1, -1, -1
+	rtts := make([]float64, len(hops))
+	for i, attempts := range hops {
+		rtt, ok := minRTT(attempts)
+		if !ok {
+			continue
+		}
+		rtts[i] = rtt
+		if prev >= 0 && rtt-rtts[prev] >= opts.JumpMs {
+			out[i] = append(out[i], Annotation{Kind: Jump, RTTMs: rtt, RefTTL: prev + 1, DeltaMs: rtt - rtts[prev]})
+		}
+		// 每个慢响应的跳只报告一次，否则其后的每一跳都会被标记
+		if slowest >= 0 && rtts[slowest]-rtt >= opts.InversionMs {
+			out[i] = append(out[i], Annotation{Kind: Inversion, RTTMs: rtt, RefTTL: slowest + 1, DeltaMs: rtts[slowest] - rtt})
+			slowest = -1
+		}
+		if a, ok := impossible(hops, anchor, i, rtts); ok {
+			out[i] = append(out[i], a)
+		}
+		if slowest < 0 || rtt > rtts[slowest] {
+			slowest = i
+		}
+		if anchor < 0 {
+			if _, _, ok := located(attempts); ok {
+				anchor = i
+			}
+		}
+		prev = i
+	}
+	return out
+}
+
+// At returns the annotations of hops[ttl] (0-based), e.g. for a printer showing one hop at a time.
+func At(hops [][]trace.Hop, ttl int, opts Options) []Annotation {
+	if ttl < 0 || ttl >= len(hops) {
+		return nil
+	}
+	return Analyze(hops[:ttl+1], opts)[ttl]
+}
+
+// impossible 检查第 i 跳与锚点 anchor 之间的距离。锚点距源不超过其延迟对应的距离，
+// 因此第 i 跳的往返延迟至少为 2d/v 减去锚点的延迟
+func impossible(hops [][]trace.Hop, anchor, i int, rtts []float64) (Annotation, bool) {
+	if anchor < 0 {
+		return Annotation{}, false
+	}
+	lat, lng, _ := located(hops[anchor])
+	var worst Annotation
+	for _, h := range hops[i] {
+		if !responded(h) || h.Geo == nil || (h.Geo.Lat == 0 && h.Geo.Lng == 0) {
+			continue
+		}
+		rtt := ms(h.RTT)
+		d := distance(lat, lng, h.Geo.Lat, h.Geo.Lng)
+		least := 2*d/fiberKmPerMs - rtts[anchor]
+		if rtt < least && least-rtt > worst.MinRTTMs-worst.RTTMs {
+			worst = Annotation{Kind: Impossible, RTTMs: rtt, RefTTL: anchor + 1, DistanceKm: d, MinRTTMs: least}
+		}
+	}
+	return worst, worst.Kind != ""
+}
+
+func responded(h trace.Hop) bool {
+	return h.Success && h.Address != nil && h.RTT > 0
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func minRTT(attempts []trace.Hop) (float64, bool) {
+	best, ok := 0.0, false
+	for _, h := range attempts {
+		if !responded(h) {
+			continue
+		}
+		if rtt := ms(h.RTT); !ok || rtt < best {
+			best, ok = rtt, true
+		}
+	}
+	return best, ok
+}
+
+// located 返回首个带坐标的响应的位置
+func located(attempts []trace.Hop) (lat, lng float64, ok bool) {
+	for _, h := range attempts {
+		if responded(h) && h.Geo != nil && (h.Geo.Lat != 0 || h.Geo.Lng != 0) {
+			return h.Geo.Lat, h.Geo.Lng, true
+		}
+	}
+	return 0, 0, false
+}
+
+// distance 为两点间的大圆距离（km）
+func distance(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180
+	dLat, dLng := (lat2-lat1)*rad, (lng2-lng1)*rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
@@ -0,0 +1,87 @@
+package anomaly
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/i18n"
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+func hop(rtt float64, lat, lng float64) trace.Hop {
+	return trace.Hop{
+		Success: true,
+		Address: &net.IPAddr{IP: net.IPv4(192, 0, 2, 1)},
+		RTT:     time.Duration(rtt * float64(time.Millisecond)),
+		Geo:     &ipgeo.IPGeoData{Lat: lat, Lng: lng},
+	}
+}
+
+func TestJumpAndInversion(t *testing.T) {
+	hops := [][]trace.Hop{
+		{hop(1, 0, 0)},
+		{hop(3, 0, 0), hop(2, 0, 0)},
+		{hop(80, 0, 0)}, // 降低 ICMP 优先级的路由器
+		{{TTL: 4}},
+		{hop(6, 0, 0)},
+		{hop(7, 0, 0)},
+		{hop(60, 0, 0)}, // 真实的长距离链路
+		{hop(61, 0, 0)},
+	}
+	got := Analyze(hops, Options{})
+	require.Len(t, got, len(hops))
+
+	assert.Empty(t, got[1])
+	assert.Equal(t, []Annotation{{Kind: Jump, RTTMs: 80, RefTTL: 2, DeltaMs: 78}}, got[2])
+	assert.Empty(t, got[3])
+	assert.Equal(t, []Annotation{{Kind: Inversion, RTTMs: 6, RefTTL: 3, DeltaMs: 74}}, got[4])
+	// 倒挂只在第一个更快的跳上报告
+	assert.Empty(t, got[5])
+	assert.Equal(t, Jump, got[6][0].Kind)
+	assert.Equal(t, 6, got[6][0].RefTTL)
+	assert.Empty(t, got[7])
+
+	// 运行中的结果与完成后的结果一致
+	assert.Equal(t, got[:5], Analyze(hops[:5], Options{}))
+	assert.Equal(t, got[4], At(hops, 4, Options{}))
+	assert.Nil(t, At(hops, len(hops), Options{}))
+	assert.Empty(t, Analyze(hops, Options{JumpMs: 100, InversionMs: 100})[2])
+}
+
+func TestImpossible(t *testing.T) {
+	hops := [][]trace.Hop{
+		{hop(1, 0, 0)},
+		{hop(2, 39.9, 116.4)},  // 北京
+		{hop(12, 31.2, 121.5)}, // 上海，约 1070 km，至少约 8.7 ms
+		{hop(4, 40.7, -74.0)},  // 纽约
+		{hop(190, 40.7, -74.0)},
+	}
+	got := Analyze(hops, Options{})
+	assert.Empty(t, got[1])
+	assert.Empty(t, got[2])
+	require.Len(t, got[3], 1)
+	a := got[3][0]
+	assert.Equal(t, Impossible, a.Kind)
+	assert.Equal(t, 2, a.RefTTL)
+	assert.InDelta(t, 11000, a.DistanceKm, 100)
+	assert.InDelta(t, 2*a.DistanceKm/fiberKmPerMs-2, a.MinRTTMs, 0.001)
+	assert.Equal(t, Jump, got[4][0].Kind)
+	assert.Len(t, got[4], 1)
+	assert.InDelta(t, 1067, distance(39.9, 116.4, 31.2, 121.5), 10)
+}
+
+func TestText(t *testing.T) {
+	old := i18n.Current()
+	defer func() { require.NoError(t, i18n.Set(old)) }()
+	require.NoError(t, i18n.Set(i18n.English))
+
+	a := Annotation{Kind: Inversion, RTTMs: 6, RefTTL: 3, DeltaMs: 74}
+	assert.Contains(t, a.Text(), "hop 3 deprioritizes ICMP")
+	assert.Equal(t, "hop 3: ICMP slow path", a.Short())
+	assert.Equal(t, "RTT jump +78 ms", Annotation{Kind: Jump, DeltaMs: 78.4}.Short())
+}
@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
 	"os/signal"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,12 +21,17 @@ import (
 
 	"github.com/nxtrace/NTrace-core/assets/windivert"
 	"github.com/nxtrace/NTrace-core/config"
+	"github.com/nxtrace/NTrace-core/export"
 	fastTrace "github.com/nxtrace/NTrace-core/fast_trace"
+	"github.com/nxtrace/NTrace-core/i18n"
 	"github.com/nxtrace/NTrace-core/ipgeo"
 	"github.com/nxtrace/NTrace-core/printer"
+	"github.com/nxtrace/NTrace-core/redact"
 	"github.com/nxtrace/NTrace-core/reporter"
+	"github.com/nxtrace/NTrace-core/schema"
 	"github.com/nxtrace/NTrace-core/server"
 	"github.com/nxtrace/NTrace-core/trace"
+	"github.com/nxtrace/NTrace-core/tracediff"
 	"github.com/nxtrace/NTrace-core/tracelog"
 	"github.com/nxtrace/NTrace-core/tracemap"
 	"github.com/nxtrace/NTrace-core/util"
@@ -95,6 +102,17 @@ func buildListenInfo(addr string) listenInfo {
 	return info
 }
 
+// withScheme 替换 Binding 与 Access 中的 URL 协议
+func (l listenInfo) withScheme(scheme string) listenInfo {
+	swap := func(u string) string {
+		if rest, ok := strings.CutPrefix(u, "http://"); ok {
+			return scheme + "://" + rest
+		}
+		return u
+	}
+	return listenInfo{Binding: swap(l.Binding), Access: swap(l.Access)}
+}
+
 func isDigitsOnly(s string) bool {
 	if s == "" {
 		return false
@@ -182,16 +200,42 @@ func Execute() {
 		Help: "Choose PoW Provider [api.nxtrace.org, sakura] For China mainland users, please use sakura"})
 	norDNS := parser.Flag("n", "no-rdns", &argparse.Options{Help: "Do not resolve IP addresses to their domain names"})
 	alwaysrDNS := parser.Flag("a", "always-rdns", &argparse.Options{Help: "Always resolve IP addresses to their domain names"})
-	routePath := parser.Flag("P", "route-path", &argparse.Options{Help: "Print traceroute hop path by ASN and location"})
+	routePath := parser.Flag("P", "route-path", &argparse.Options{Help: "Print traceroute hop path by ASN and location, with border crossings, IXPs and detours; with --json, add it to the document as route_path"})
 	report := parser.Flag("r", "report", &argparse.Options{Help: "output using report mode"})
 	dn42 := parser.Flag("", "dn42", &argparse.Options{Help: "DN42 Mode"})
-	output := parser.Flag("o", "output", &argparse.Options{Help: "Write trace result to file (RealTimePrinter ONLY)"})
+	output := parser.Flag("o", "output", &argparse.Options{Help: "Also append the trace to a log file, by default nexttrace-<user>.log in the temporary directory"})
+	outputPath := parser.String("", "output-path", &argparse.Options{Help: "Log file used by --output (implies it); the path may contain {target}, {ip}, {date}, {time}, {user} and {pid}"})
+	logFormat := parser.Selector("", "log-format", tracelog.Formats, &argparse.Options{Default: tracelog.FormatText, Help: "Format of the --output log: text, or jsonl with the full hop data and a metadata header per trace"})
+	logMaxSize := parser.Int("", "log-max-size", &argparse.Options{Help: "Rotate the --output log when it would grow past this many MB (0: never)"})
+	logMaxAge := parser.Int("", "log-max-age", &argparse.Options{Help: "Rotate the --output log once it is older than this many days and delete rotated logs older than that (0: keep)"})
+	logMaxBackups := parser.Int("", "log-max-backups", &argparse.Options{Help: "Keep at most this many rotated --output logs (0: keep all)"})
 	tablePrint := parser.Flag("t", "table", &argparse.Options{Help: "Output trace results as table"})
 	rawPrint := parser.Flag("", "raw", &argparse.Options{Help: "An Output Easy to Parse"})
-	jsonPrint := parser.Flag("j", "json", &argparse.Options{Help: "Output trace results as JSON"})
+	jsonPrint := parser.Flag("j", "json", &argparse.Options{Help: "Output trace results as JSON (see --json-schema)"})
+	ndjsonPrint := parser.Flag("", "ndjson", &argparse.Options{Help: "Stream trace results as newline-delimited JSON: a header, one record per hop as it completes (plus updates for late geo/rDNS data) and a summary"})
+	csvPrint := parser.Flag("", "csv", &argparse.Options{Help: "Output trace results as CSV, one row per probe; also converts a saved trace or MTR result given with --load"})
+	tsvPrint := parser.Flag("", "tsv", &argparse.Options{Help: "Same as --csv but tab-separated"})
+	geojsonPrint := parser.Flag("", "geojson", &argparse.Options{Help: "Output the geolocated path as GeoJSON (a LineString plus one Point per hop); works with --load"})
+	kmlPrint := parser.Flag("", "kml", &argparse.Options{Help: "Output the geolocated path as KML; works with --load"})
+	geoInterpolate := parser.Flag("", "geo-interpolate", &argparse.Options{Help: "With --geojson/--kml, place hops without coordinates between their neighbours instead of skipping them"})
+	formatTemplate := parser.String("", "format-template", &argparse.Options{Help: "Render results with a Go text/template file: an optional \"hop\" template runs for every hop as it completes, an optional \"header\" template before the trace, and the top-level template once with the whole trace; works with --load"})
+	compatFormat := parser.Selector("", "compat", export.CompatFormats, &argparse.Options{Help: "Output in the format of another tool: Linux traceroute text, mtr --json or a RIPE Atlas traceroute result; works with --load, which also reads Atlas and mtr --json results"})
+	outputFormat := parser.String("", "output-format", &argparse.Options{Help: "Comma-separated output formats to use at once, e.g. realtime,log,ndjson; see --list-formats. Added to the formats selected by the other output flags"})
+	listFormats := parser.Flag("", "list-formats", &argparse.Options{Help: "List the formats accepted by --output-format and exit"})
+	dotGraph := parser.Flag("", "dot", &argparse.Options{Help: "Output the route topology as a Graphviz DOT graph; traces from --file or --fast-trace are merged into one graph"})
+	mermaidGraph := parser.Flag("", "mermaid", &argparse.Options{Help: "Same as --dot but as a Mermaid flowchart"})
+	htmlReport := parser.String("", "html", &argparse.Options{Help: "Also write a self-contained offline HTML report (hop table, AS path, RTT chart, map) to the given file; works with --load"})
+	jsonSchema := parser.Flag("", "json-schema", &argparse.Options{Help: "Print the JSON Schema of the --json output and exit"})
 	classicPrint := parser.Flag("c", "classic", &argparse.Options{Help: "Classic Output trace results like BestTrace"})
 	beginHop := parser.Int("f", "first", &argparse.Options{Default: 1, Help: "Start from the first_ttl hop (instead of 1)"})
 	disableMaptrace := parser.Flag("M", "map", &argparse.Options{Help: "Disable Print Trace Map"})
+	uploadMap := parser.Flag("", "upload-map", &argparse.Options{Help: "Upload a result given with --load to the tracemap service and print the map link; loaded results are not uploaded otherwise"})
+	mapFile := parser.String("", "map-file", &argparse.Options{Help: "Render the trace map locally to the given .svg or .png file instead of uploading the result to the tracemap service; works with --load"})
+	redactDst := parser.Flag("", "redact-dst", &argparse.Options{Help: "Hide the destination: show only its /16 (IPv4) or /32 (IPv6) prefix and drop its hostname in every output, export, log and tracemap upload"})
+	redactHops := parser.Int("", "redact-hops", &argparse.Options{Help: "Hide the address, hostname and location of the first N hops, e.g. your home network"})
+	redactSelf := parser.String("", "redact-self", &argparse.Options{Help: "Comma-separated addresses or prefixes of your own, shown as \"self\"; \"auto\" stands for the source address"})
+	redactHostnames := parser.String("", "redact-hostnames", &argparse.Options{Help: "Comma-separated hostname patterns to drop, e.g. \"*.home.arpa,*.corp.example\""})
+	redactKey := parser.String("", "redact-key", &argparse.Options{Help: "Replace the remaining addresses with pseudonyms keyed by this secret, so traces shared with the same key stay comparable (or NEXTTRACE_REDACT_KEY)"})
 	disableMPLS := parser.Flag("e", "disable-mpls", &argparse.Options{Help: "Disable MPLS"})
 	ver := parser.Flag("V", "version", &argparse.Options{Help: "Print version info and exit"})
 	srcAddr := parser.String("s", "source", &argparse.Options{Help: "Use source address src_addr for outgoing packets"})
@@ -199,6 +243,16 @@ func Execute() {
 	srcDev := parser.String("D", "dev", &argparse.Options{Help: "Use the following Network Devices as the source address in outgoing packets"})
 	deployListen := parser.String("", "listen", &argparse.Options{Help: "Set listen address for web console (e.g. 127.0.0.1:30080)"})
 	deploy := parser.Flag("", "deploy", &argparse.Options{Help: "Start the Gin powered web console"})
+	deployConfig := parser.String("", "deploy-config", &argparse.Options{Help: "Load web console settings (authentication, TLS, access control) from the given YAML config"})
+	historyList := parser.Flag("", "history", &argparse.Options{Help: "List saved trace history (filter by the target argument, --since and --until; combine with --json for full records)"})
+	historySince := parser.String("", "since", &argparse.Options{Help: "History start time: RFC 3339, YYYY-MM-DD[ HH:MM] or a duration ago such as 24h"})
+	historyUntil := parser.String("", "until", &argparse.Options{Help: "History end time, same formats as --since"})
+	load := parser.String("", "load", &argparse.Options{Help: "Render a result saved with --json (or a RIPE Atlas or mtr --json result) using the selected printer, without sending any probes"})
+	enrichPath := parser.String("", "enrich", &argparse.Options{Help: "Re-run geo lookups for a saved trace or MTR JSON offline and print the updated JSON"})
+	enrichProviders := parser.String("", "enrich-providers", &argparse.Options{Help: "Comma-separated data providers for --enrich in priority order; later ones fill fields left empty (default: --data-provider)"})
+	enrichRDNS := parser.Flag("", "enrich-rdns", &argparse.Options{Help: "Also redo reverse DNS lookups with --enrich"})
+	compare := parser.String("", "compare", &argparse.Options{Help: "Compare with a saved trace (JSON file or history:<id>): the target argument is either a second saved trace or a host to trace live"})
+	monitor := parser.String("", "monitor", &argparse.Options{Help: "Run as a monitoring daemon with targets, rules and webhooks from the given YAML config"})
 	//router := parser.Flag("R", "route", &argparse.Options{Help: "Show Routing Table [Provided By BGP.Tools]"})
 	packetInterval := parser.Int("z", "send-time", &argparse.Options{Default: 50, Help: "Set how many [milliseconds] between sending each packet. Useful when some routers use rate-limit for ICMP messages"})
 	ttlInterval := parser.Int("i", "ttl-time", &argparse.Options{Default: 50, Help: "Set how many [milliseconds] between sending packets groups by TTL. Useful when some routers use rate-limit for ICMP messages"})
@@ -208,7 +262,9 @@ func Execute() {
 	dot := parser.Selector("", "dot-server", []string{"dnssb", "aliyun", "dnspod", "google", "cloudflare"}, &argparse.Options{
 		Help: "Use DoT Server for DNS Parse [dnssb, aliyun, dnspod, google, cloudflare]"})
 	lang := parser.Selector("g", "language", []string{"en", "cn"}, &argparse.Options{Default: "cn",
-		Help: "Choose the language for displaying [en, cn]"})
+		Help: "Choose the language of the geo data [en, cn]"})
+	uiLang := parser.Selector("", "ui-language", append([]string{"auto"}, i18n.Languages...), &argparse.Options{Default: "auto",
+		Help: "Choose the language of messages [auto, en, zh, ja, ru]; auto follows NEXTTRACE_LANG, LANGUAGE, LC_ALL, LC_MESSAGES and LANG"})
 	file := parser.String("", "file", &argparse.Options{Help: "Read IP Address or domain name from file"})
 	noColor := parser.Flag("C", "no-color", &argparse.Options{Help: "Disable Colorful Output"})
 	from := parser.String("", "from", &argparse.Options{Help: "Run traceroute via Globalping (https://globalping.io/network) from a specified location. The location field accepts continents, countries, regions, cities, ASNs, ISPs, or cloud regions."})
@@ -221,13 +277,102 @@ func Execute() {
 		return
 	}
 
+	if *uiLang != "auto" {
+		_ = i18n.Set(*uiLang)
+	}
+
 	if *noColor {
 		color.NoColor = true
 	} else {
 		color.NoColor = false
 	}
 
-	if !*jsonPrint {
+	if *listFormats {
+		for _, f := range printer.Formats() {
+			fmt.Printf("%-12s %s\n", f.Name, f.Description)
+		}
+		return
+	}
+
+	// 各输出参数对应到输出格式，与 --output-format 中的格式同时生效。它们都写入标准输出，只能选择其一
+	formatFlags := []struct {
+		name     string
+		selected bool
+		format   string
+	}{
+		{"--ndjson", *ndjsonPrint, printer.FormatNDJSON},
+		{"--csv", *csvPrint, printer.FormatCSV},
+		{"--tsv", *tsvPrint, printer.FormatTSV},
+		{"--geojson", *geojsonPrint, printer.FormatGeoJSON},
+		{"--kml", *kmlPrint, printer.FormatKML},
+		{"--dot", *dotGraph, printer.FormatDOT},
+		{"--mermaid", *mermaidGraph, printer.FormatMermaid},
+		{"--compat", *compatFormat != "", *compatFormat},
+		{"--format-template", *formatTemplate != "", printer.FormatTemplate},
+		{"--json", *jsonPrint, printer.FormatJSON},
+		{"--table", *tablePrint, printer.FormatTable},
+		{"--classic", *classicPrint, printer.FormatClassic},
+		{"--raw", *rawPrint, printer.FormatRaw},
+	}
+	formats := printer.ParseFormats(*outputFormat)
+	var selected []string
+	for _, f := range formatFlags {
+		if f.selected {
+			selected = append(selected, f.name)
+			formats = append(formats, f.format)
+		}
+	}
+	if len(selected) > 1 {
+		fmt.Println(i18n.T(i18n.ConflictingOutputs, strings.Join(selected, ", ")))
+		return
+	}
+	if len(formats) == 0 {
+		formats = []string{printer.FormatRealtime}
+	}
+	// 指定 --output-path 即表示要写日志
+	logging := *output || *outputPath != ""
+	if logging {
+		formats = append(formats, printer.FormatLog)
+	}
+	for _, name := range formats {
+		if _, ok := printer.Lookup(name); !ok {
+			fmt.Println(i18n.T(i18n.UnknownOutputFormat, name))
+			return
+		}
+	}
+	printOpts := printer.Options{
+		Lang:     *lang,
+		Template: *formatTemplate,
+		Report:   *report,
+		Geo:      export.GeoOptions{Interpolate: *geoInterpolate},
+		Log: tracelog.Options{
+			Path:       *outputPath,
+			Format:     *logFormat,
+			MaxSize:    int64(*logMaxSize) << 20,
+			MaxAge:     time.Duration(*logMaxAge) * 24 * time.Hour,
+			MaxBackups: *logMaxBackups,
+		},
+		Redact: redact.Policy{
+			HideDestination: *redactDst || util.EnableHidDstIP,
+			HideHops:        *redactHops,
+			Self:            redact.ParseList(*redactSelf),
+			DropHostnames:   redact.ParseList(*redactHostnames),
+			Key:             *redactKey,
+		},
+	}
+	if printOpts.Redact.Key == "" {
+		printOpts.Redact.Key = util.EnvRedactKey
+	}
+	if err := printOpts.Redact.Validate(); err != nil {
+		fmt.Println(err)
+		return
+	}
+	// 结构化的输出格式需要保持标准输出只包含结果本身
+	if printer.Structured(formats) {
+		*jsonPrint = true
+	}
+
+	if !*jsonPrint && *enrichPath == "" && !*jsonSchema {
 		printer.Version()
 	}
 
@@ -249,22 +394,159 @@ func Execute() {
 			listenAddr = defaultLocalListenAddr()
 		}
 
+		configPath := strings.TrimSpace(*deployConfig)
+		if configPath == "" {
+			configPath = strings.TrimSpace(util.EnvDeployConfig)
+		}
+		deployCfg, err := server.LoadConfig(configPath)
+		if err != nil {
+			if util.EnvDevMode {
+				panic(err)
+			}
+			log.Fatal(err)
+		}
+
 		info := buildListenInfo(listenAddr)
+		if deployCfg.TLS.CertFile != "" {
+			info = info.withScheme("https")
+		}
 		// 判断是否同时未通过 CLI 和环境变量指定地址
+		fmt.Println(i18n.T(i18n.WebListening, info.Binding))
 		if !userProvided {
-			fmt.Printf("启动 NextTrace Web 控制台，监听地址: %s\n", info.Binding)
-			fmt.Println("远程访问请显式设置 --listen（例如 --listen 0.0.0.0:1080）。")
-			if info.Access != "" && info.Access != info.Binding {
-				fmt.Printf("如需远程访问，请尝试: %s\n", info.Access)
-			}
+			fmt.Println(i18n.T(i18n.WebListenHint))
+		}
+		if info.Access != "" && info.Access != info.Binding {
+			fmt.Println(i18n.T(i18n.WebRemoteAccess, info.Access))
+		}
+		if deployCfg.AuthEnabled() {
+			fmt.Println(i18n.T(i18n.WebAuthEnabled))
 		} else {
-			fmt.Printf("启动 NextTrace Web 控制台，监听地址: %s\n", info.Binding)
-			if info.Access != "" && info.Access != info.Binding {
-				fmt.Printf("如需远程访问，请尝试: %s\n", info.Access)
+			fmt.Println(i18n.T(i18n.WebAuthDisabled))
+		}
+		if err := server.Run(listenAddr, deployCfg); err != nil {
+			if util.EnvDevMode {
+				panic(err)
+			}
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *historyList {
+		if err := listHistory(os.Stdout, strings.TrimSpace(*str), *historySince, *historyUntil, *jsonPrint); err != nil {
+			if util.EnvDevMode {
+				panic(err)
+			}
+			log.Fatal(err)
+		}
+		return
+	}
+
+	var compareBase tracediff.Path
+	if *compare != "" {
+		var err error
+		if compareBase, err = loadCompareSource(*compare); err != nil {
+			fmt.Println(err)
+			return
+		}
+		if isCompareSource(*str) {
+			other, err := loadCompareSource(*str)
+			if err == nil {
+				err = printCompare(os.Stdout, compareBase, other, *jsonPrint)
+			}
+			if err != nil {
+				fmt.Println(err)
+			}
+			return
+		}
+	}
+
+	if *enrichPath != "" {
+		providers := *enrichProviders
+		if providers == "" {
+			providers = *dataOrigin
+		}
+		if err := enrichFile(os.Stdout, *enrichPath, providers, *lang, *enrichRDNS, *parallelRequests); err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+
+	// CSV / TSV 另外支持转换 MTR 结果与多条追踪
+	if *load != "" && len(formats) == 1 && (formats[0] == printer.FormatCSV || formats[0] == printer.FormatTSV) {
+		comma := export.CSV
+		if formats[0] == printer.FormatTSV {
+			comma = export.TSV
+		}
+		loaded, err := export.LoadFile(*load)
+		if err == nil {
+			err = loaded.Write(os.Stdout, comma)
+		}
+		if err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+
+	if *load != "" {
+		doc, err := schema.LoadDocument(*load)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		res, err := doc.Result()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		// 脱敏后的结果用于全部输出与上传
+		redactor := printOpts.Redact.For(doc.Metadata)
+		res = redactor.Result(res)
+		*doc = redactor.Document(*doc)
+		if *routePath && *jsonPrint {
+			dst := doc.Metadata.DstIP
+			if dst == "" {
+				dst = destinationIP(res)
+			}
+			path := reporter.Analyze(res, dst)
+			doc.RoutePath = &path
+		}
+		if *htmlReport != "" {
+			if err := writeHTMLReport(*htmlReport, *doc, *jsonPrint); err != nil {
+				fmt.Println(err)
+				return
+			}
+		}
+		if *mapFile != "" {
+			if err := writeMapFile(*mapFile, *doc, *jsonPrint); err != nil {
+				fmt.Println(err)
+				return
 			}
 		}
-		fmt.Println("注意：Web 控制台的安全性有限，请在确保安全的前提下使用，如有必要请使用ACL等方式加强安全性")
-		if err := server.Run(listenAddr); err != nil {
+		printOpts.Metadata = doc.Metadata
+		p, err := printer.NewPrinter(formats, printOpts)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer p.Close()
+		opts := loadedRender{doc: doc, printer: p, routePath: *routePath && !*jsonPrint}
+		// 已保存的地图链接直接输出；加载的结果可能来自他人，只在 --upload-map 时上传
+		opts.showMap = !*disableMaptrace && !*jsonPrint && *mapFile == "" && (res.TraceMapUrl != "" || *uploadMap && hasGeoData(res))
+		if err := renderLoadedResult(res, opts); err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+
+	if *jsonSchema {
+		fmt.Println(string(schema.JSONSchema))
+		return
+	}
+
+	if *monitor != "" {
+		capabilitiesCheck()
+		if err := server.RunMonitor(*monitor); err != nil {
 			if util.EnvDevMode {
 				panic(err)
 			}
@@ -288,7 +570,7 @@ func Execute() {
 			}
 			log.Fatal(err)
 		}
-		fmt.Println("WinDivert runtime is ready.")
+		fmt.Println(i18n.T(i18n.WinDivertReady))
 		return
 	}
 
@@ -302,12 +584,12 @@ func Execute() {
 
 	if !*tcp {
 		if *numMeasurements > 255 {
-			fmt.Println("Query 最大值为 255，已自动调整为 255")
+			fmt.Println(i18n.T(i18n.QueriesClamped))
 			*numMeasurements = 255
 		}
 
 		if *maxAttempts > 255 {
-			fmt.Println("MaxAttempt 最大值为 255，已自动调整为 255")
+			fmt.Println(i18n.T(i18n.MaxAttemptsClamped))
 			*maxAttempts = 255
 		}
 	}
@@ -341,11 +623,43 @@ func Execute() {
 			Timeout:        time.Duration(*timeout) * time.Millisecond,
 			File:           *file,
 			Dot:            *dot,
+			Redact:         printOpts.Redact,
+		}
+		var graph *export.Graph
+		var graphExport func(*export.Graph, io.Writer) error
+		switch {
+		case slices.Contains(formats, printer.FormatDOT):
+			graphExport = (*export.Graph).WriteDOT
+		case slices.Contains(formats, printer.FormatMermaid):
+			graphExport = (*export.Graph).WriteMermaid
+		}
+		if graphExport != nil {
+			// 逐个目标的结果合并为一张图，全部完成后输出
+			graph = export.NewGraph()
+			paramsFastTrace.Quiet = true
+			paramsFastTrace.OnResult = graph.Add
 		}
 
-		fastTrace.FastTest(m, *output, paramsFastTrace)
-		if *output {
-			fmt.Println("您的追踪日志已经存放在 /tmp/trace.log 中")
+		// logged 为实际写入的日志文件；路径中的 {target}、{ip} 等按每个目标展开
+		var logged []string
+		if logging {
+			// 快速测试逐个追踪目标，回调不会并发执行
+			printOpts.Log.Opened = func(path string) {
+				if !slices.Contains(logged, path) {
+					logged = append(logged, path)
+				}
+			}
+			paramsFastTrace.Log = &printOpts.Log
+		}
+
+		fastTrace.FastTest(m, paramsFastTrace)
+		if graph != nil {
+			if err := graphExport(graph, os.Stdout); err != nil {
+				fmt.Println(err)
+			}
+		}
+		for _, path := range logged {
+			fmt.Println(i18n.T(i18n.TraceLogSaved, path))
 		}
 
 		os.Exit(0)
@@ -361,7 +675,7 @@ func Execute() {
 		domain = "n" + domain
 		parts := strings.Split(domain, "/")
 		if len(parts) < 3 {
-			fmt.Println("Invalid input")
+			fmt.Println(i18n.T(i18n.InvalidInput))
 			return
 		}
 		domain = parts[2]
@@ -437,11 +751,6 @@ func Execute() {
 
 				DisableMaptrace: *disableMaptrace,
 				DataOrigin:      *dataOrigin,
-
-				TablePrint:   *tablePrint,
-				ClassicPrint: *classicPrint,
-				RawPrint:     *rawPrint,
-				JSONPrint:    *jsonPrint,
 			},
 			&trace.Config{
 				OSType:          OSType,
@@ -453,6 +762,8 @@ func Execute() {
 				IPGeoSource:     ipgeo.GetSource(*dataOrigin),
 				Timeout:         time.Duration(*timeout) * time.Millisecond,
 			},
+			formats,
+			printOpts,
 		)
 		return
 	}
@@ -495,16 +806,16 @@ func Execute() {
 
 	// 仅在使用 UDPv6 探测时，确保 UDP 负载长度 ≥ 2
 	if *udp && util.IsIPv6(ip) && *packetSize < 2 {
-		fmt.Println("UDPv6 模式下，数据包长度不能小于 2，已自动调整为 2")
+		fmt.Println(i18n.T(i18n.UDPv6PacketSize))
 		*packetSize = 2
 	}
 
 	if !*jsonPrint {
-		printer.PrintTraceRouteNav(ip, domain, *dataOrigin, *maxHops, *packetSize, *srcAddr, string(m))
+		redactor := printOpts.Redact.For(schema.Metadata{DstIP: ip.String(), SrcIP: *srcAddr})
+		printer.PrintTraceRouteNav(ip, domain, *dataOrigin, *maxHops, *packetSize, *srcAddr, string(m), redactor)
 	}
 
 	util.SrcPort = *srcPort
-	util.DstIP = ip.String()
 	var conf = trace.Config{
 		OSType:           OSType,
 		ICMPMode:         *icmpMode,
@@ -528,36 +839,21 @@ func Execute() {
 		PktSize:          *packetSize,
 	}
 
-	// 暂时弃用
-	router := new(bool)
-	*router = false
-	if !*tablePrint {
-		if *classicPrint {
-			conf.RealtimePrinter = printer.ClassicPrinter
-		} else if *rawPrint {
-			conf.RealtimePrinter = printer.EasyPrinter
-		} else {
-			if *output {
-				conf.RealtimePrinter = tracelog.RealtimePrinter
-			} else if *router {
-				conf.RealtimePrinter = printer.RealtimePrinterWithRouter
-				fmt.Println("路由表数据源由 BGP.Tools 提供，在此特表感谢")
-			} else {
-				conf.RealtimePrinter = printer.RealtimePrinter
-			}
-		}
-	} else {
-		if !*report {
-			conf.AsyncPrinter = printer.TracerouteTablePrinter
-		}
+	// --compare 的输出代替结果文档的 JSON
+	if *compare != "" {
+		formats = slices.DeleteFunc(formats, func(name string) bool { return name == printer.FormatJSON })
 	}
-
-	if *jsonPrint {
-		conf.RealtimePrinter = nil
-		conf.AsyncPrinter = nil
+	traceStart := time.Now()
+	printOpts.Metadata = schema.NewMetadata(domain, m, *dataOrigin, conf, traceStart, traceStart)
+	p, err := printer.NewPrinter(formats, printOpts)
+	if err != nil {
+		fmt.Println(err)
+		return
 	}
+	defer p.Close()
+	printer.Attach(p, &conf)
 
-	if util.Uninterrupted && *rawPrint {
+	if util.Uninterrupted && slices.Contains(formats, printer.FormatRaw) {
 		for {
 			_, err := trace.Traceroute(m, conf)
 			if err != nil {
@@ -571,8 +867,9 @@ func Execute() {
 	}
 
 	res, err := trace.Traceroute(m, conf)
+	traceDuration := time.Since(traceStart)
 	if err != nil {
-		if !errors.Is(err, context.Canceled) {
+		if !printer.ReportError(p, err) && !errors.Is(err, context.Canceled) {
 			// 用户主动中断：跳过后续的正常收尾
 			// os.Exit(130)
 			fmt.Println(err)
@@ -580,39 +877,62 @@ func Execute() {
 		return
 	}
 
-	if *tablePrint {
-		printer.TracerouteTablePrinter(res)
-	}
-
-	if *routePath {
-		r := reporter.New(res, ip.String())
-		r.Print()
-	}
-
-	r, err := json.Marshal(res)
+	doc := schema.NewDocument(res, schema.NewMetadata(domain, m, *dataOrigin, conf, traceStart, traceStart.Add(traceDuration)))
+	// 历史记录保存原始结果，其余对外的输出与上传均使用脱敏后的副本
+	redactor := printOpts.Redact.For(doc.Metadata)
+	shared := redactor.Result(res)
+	r, err := json.Marshal(shared)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	if !*disableMaptrace &&
+	// 指定本地地图文件时不再上传结果
+	if !*disableMaptrace && *mapFile == "" &&
 		(util.StringInSlice(strings.ToUpper(*dataOrigin), []string{"LEOMOEAPI", "IPINFO", "IP-API.COM", "IPAPI.COM"})) {
 		url, err := tracemap.GetMapUrl(string(r))
 		if err != nil {
-			fmt.Println(err)
+			if !printer.ReportError(p, err) {
+				fmt.Println(err)
+			}
 			return
 		}
 		res.TraceMapUrl = url
-		if !*jsonPrint {
-			tracemap.PrintMapUrl(url)
-		}
+		shared.TraceMapUrl = url
+		doc.TraceMapURL = url
 	}
-	r, err = json.Marshal(res)
-	if err != nil {
-		fmt.Println(err)
+	// 路由路径分析只加入输出的文档，历史记录仍保存原始结果
+	completed := doc
+	if *routePath && *jsonPrint {
+		path := reporter.Analyze(shared, redactor.IP(ip.String()))
+		completed.RoutePath = &path
+	}
+	if err := p.OnComplete(res, completed); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		return
 	}
-	if *jsonPrint {
-		fmt.Println(string(r))
+	if *routePath && !*jsonPrint {
+		reporter.New(shared, redactor.IP(ip.String())).Print()
+	}
+	if res.TraceMapUrl != "" && !*jsonPrint {
+		tracemap.PrintMapUrl(res.TraceMapUrl)
+	}
+	if *htmlReport != "" {
+		if err := writeHTMLReport(*htmlReport, redactor.Document(doc), *jsonPrint); err != nil {
+			fmt.Println(err)
+		}
+	}
+	if *mapFile != "" {
+		if err := writeMapFile(*mapFile, redactor.Document(doc), *jsonPrint); err != nil {
+			fmt.Println(err)
+		}
+	}
+	if util.EnvHistory {
+		saveHistory(doc)
+	}
+	if *compare != "" {
+		if err := printCompare(os.Stdout, compareBase, tracediff.FromResult(domain, res), *jsonPrint); err != nil {
+			fmt.Println(err)
+		}
 	}
 }
 
@@ -663,19 +983,36 @@ func capabilitiesCheck() {
 		return
 	} else {
 		// 没权限啦
-		fmt.Println("您正在以普通用户权限运行 NextTrace，但 NextTrace 未被赋予监听网络套接字的ICMP消息包、修改IP头信息（TTL）等路由跟踪所需的权限")
-		fmt.Println("请使用管理员用户执行 `sudo setcap cap_net_raw,cap_net_admin+eip ${your_nexttrace_path}/nexttrace` 命令，赋予相关权限后再运行~")
-		fmt.Println("什么？为什么 ping 普通用户执行不要 root 权限？因为这些工具在管理员安装时就已经被赋予了一些必要的权限，具体请使用 `getcap /usr/bin/ping` 查看")
+		fmt.Println(i18n.T(i18n.NoCapabilities))
 	}
 }
 
-func executeGlobalpingTraceroute(opts *trace.GlobalpingOptions, config *trace.Config) {
+func executeGlobalpingTraceroute(opts *trace.GlobalpingOptions, config *trace.Config, formats []string, printOpts printer.Options) {
+	started := time.Now()
 	res, measurement, err := trace.GlobalpingTraceroute(opts, config)
+	finished := time.Now()
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
+	method := trace.ICMPTrace
+	if opts.TCP {
+		method = trace.TCPTrace
+	} else if opts.UDP {
+		method = trace.UDPTrace
+	}
+	conf := *config
+	conf.MaxHops = opts.MaxHops
+	conf.NumMeasurements = opts.Packets
+	conf.DstPort = opts.Port
+	if measurement != nil && len(measurement.Results) > 0 {
+		conf.DstIP = net.ParseIP(measurement.Results[0].Result.ResolvedAddress)
+	}
+	doc := schema.NewDocument(res, schema.NewMetadata(opts.Target, method, opts.DataOrigin, conf, started, finished))
+	// 上传地图前脱敏；打印器自行对 doc 脱敏
+	res = printOpts.Redact.For(doc.Metadata).Result(res)
+
 	if !opts.DisableMaptrace &&
 		(util.StringInSlice(strings.ToUpper(opts.DataOrigin), []string{"LEOMOEAPI", "IPINFO", "IP-API.COM", "IPAPI.COM"})) {
 		r, err := json.Marshal(res)
@@ -689,40 +1026,31 @@ func executeGlobalpingTraceroute(opts *trace.GlobalpingOptions, config *trace.Co
 			return
 		}
 		res.TraceMapUrl = url
+		doc.TraceMapURL = url
 	}
 
-	if opts.JSONPrint {
-		r, err := json.Marshal(res)
-		if err != nil {
-			fmt.Println(err)
+	structured := printer.Structured(formats)
+	if !structured {
+		if measurement == nil || len(measurement.Results) == 0 {
+			fmt.Println(i18n.T(i18n.GlobalpingNoResult))
 			return
 		}
-		fmt.Println(string(r))
-		return
+		fmt.Fprintln(color.Output, color.New(color.FgGreen, color.Bold).Sprintf("> %s", trace.GlobalpingFormatLocation(&measurement.Results[0])))
 	}
 
-	if measurement == nil || len(measurement.Results) == 0 {
-		fmt.Println("Globalping 未返回可用的探测结果，已跳过输出。")
+	printOpts.Metadata = doc.Metadata
+	p, err := printer.NewPrinter(formats, printOpts)
+	if err != nil {
+		fmt.Println(err)
 		return
 	}
-
-	fmt.Fprintln(color.Output, color.New(color.FgGreen, color.Bold).Sprintf("> %s", trace.GlobalpingFormatLocation(&measurement.Results[0])))
-
-	if opts.TablePrint {
-		printer.TracerouteTablePrinter(res)
-	} else {
-		for i := range res.Hops {
-			if opts.ClassicPrint {
-				printer.ClassicPrinter(res, i)
-			} else if opts.RawPrint {
-				printer.EasyPrinter(res, i)
-			} else {
-				printer.RealtimePrinter(res, i)
-			}
-		}
+	defer p.Close()
+	if err := printer.Replay(p, res, doc); err != nil {
+		fmt.Println(err)
+		return
 	}
 
-	if res.TraceMapUrl != "" {
+	if res.TraceMapUrl != "" && !structured {
 		tracemap.PrintMapUrl(res.TraceMapUrl)
 	}
 }
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nxtrace/NTrace-core/history"
+	"github.com/nxtrace/NTrace-core/tracediff"
+)
+
+const historySourcePrefix = "history:"
+
+// isCompareSource 判断参数是否为可离线比较的来源（历史记录 ID 或已存在的文件），否则视为实时追踪的目标
+func isCompareSource(arg string) bool {
+	if strings.HasPrefix(arg, historySourcePrefix) {
+		return true
+	}
+	info, err := os.Stat(arg)
+	return err == nil && !info.IsDir()
+}
+
+// loadCompareSource 读取 JSON 文件或 history:<id> 指定的历史记录
+func loadCompareSource(arg string) (tracediff.Path, error) {
+	id, ok := strings.CutPrefix(arg, historySourcePrefix)
+	if !ok {
+		return tracediff.LoadFile(arg)
+	}
+	store, err := history.Open("", 0)
+	if err != nil {
+		return tracediff.Path{}, err
+	}
+	rec, err := store.Get(id)
+	if err != nil {
+		return tracediff.Path{}, fmt.Errorf("history record %s: %w", id, err)
+	}
+	label := fmt.Sprintf("%s@%s", rec.Target, rec.Timestamp.Local().Format("2006-01-02 15:04:05"))
+	return tracediff.Parse(label, rec.Data)
+}
+
+// printCompare 输出比较结果，jsonOut 时输出与 POST /api/diff 相同的 JSON
+func printCompare(w io.Writer, a, b tracediff.Path, jsonOut bool) error {
+	report := tracediff.Compare(a, b)
+	if jsonOut {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	report.Print(w)
+	return nil
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nxtrace/NTrace-core/enrich"
+	"github.com/nxtrace/NTrace-core/i18n"
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/wshandle"
+)
+
+// parseGeoProviders 解析逗号分隔的数据源名称，未知名称直接报错而不是回落到 LeoMoeAPI
+func parseGeoProviders(list string) ([]ipgeo.Source, []string, error) {
+	var (
+		sources []ipgeo.Source
+		names   []string
+	)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		src := ipgeo.GetSource(name)
+		canonical := ipgeo.SourceName(src)
+		if canonical == "LeoMoeAPI" && !strings.EqualFold(name, "LeoMoeAPI") {
+			return nil, nil, fmt.Errorf("unknown data provider %q", name)
+		}
+		sources = append(sources, src)
+		names = append(names, canonical)
+	}
+	if len(sources) == 0 {
+		return nil, nil, fmt.Errorf("no data provider given")
+	}
+	return sources, names, nil
+}
+
+// enrichFile 使用指定数据源重新标注已保存的结果并将 JSON 写入 w，查询失败的跳会在 stderr 中提示
+func enrichFile(w io.Writer, path, providers, lang string, rdns bool, parallel int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sources, names, err := parseGeoProviders(providers)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == "LeoMoeAPI" {
+			if conn := wshandle.New(); conn != nil {
+				defer func() {
+					if conn.Conn != nil {
+						_ = conn.Conn.Close()
+					}
+				}()
+			}
+			break
+		}
+	}
+
+	out, err := enrich.Document(data, enrich.Options{
+		Sources:  sources,
+		Provider: strings.Join(names, ","),
+		Lang:     lang,
+		RDNS:     rdns,
+		Parallel: parallel,
+	})
+	if out == nil {
+		return err
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T(i18n.EnrichLookupFailed, err))
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
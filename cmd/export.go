@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/nxtrace/NTrace-core/export"
+	"github.com/nxtrace/NTrace-core/geomap"
+	"github.com/nxtrace/NTrace-core/i18n"
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+// writeHTMLReport 写出离线 HTML 报告；quiet 时不提示保存位置，保持标准输出只包含结果
+func writeHTMLReport(path string, doc schema.Document, quiet bool) error {
+	if err := export.WriteHTMLFile(path, doc); err != nil {
+		return fmt.Errorf("write HTML report: %w", err)
+	}
+	if !quiet {
+		fmt.Println(i18n.T(i18n.HTMLReportSaved, path))
+	}
+	return nil
+}
+
+// writeMapFile 在本地渲染追踪地图，格式由扩展名决定（.png 或 .svg）
+func writeMapFile(path string, doc schema.Document, quiet bool) error {
+	if err := geomap.WriteFile(path, geomap.Points(doc), geomap.Options{Fit: true}); err != nil {
+		return fmt.Errorf("write map: %w", err)
+	}
+	if !quiet {
+		fmt.Println(i18n.T(i18n.MapSaved, path))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/nxtrace/NTrace-core/history"
+	"github.com/nxtrace/NTrace-core/i18n"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/server"
+)
+
+// listHistory 打印本地历史存储中符合条件的记录；jsonOut 时输出包含逐跳数据的完整记录
+func listHistory(w io.Writer, target, since, until string, jsonOut bool) error {
+	now := time.Now()
+	sinceTime, err := history.ParseTime(since, now)
+	if err != nil {
+		return err
+	}
+	untilTime, err := history.ParseTime(until, now)
+	if err != nil {
+		return err
+	}
+
+	store, err := history.Open("", 0)
+	if err != nil {
+		return err
+	}
+	records, err := store.Query(history.Query{Target: target, Since: sinceTime, Until: untilTime})
+	if err != nil {
+		return err
+	}
+
+	if jsonOut {
+		if records == nil {
+			records = []history.Record{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+
+	if len(records) == 0 {
+		_, err := fmt.Fprintln(w, i18n.T(i18n.HistoryEmpty, store.Dir()))
+		return err
+	}
+	for _, rec := range records {
+		_, err := fmt.Fprintf(w, "%s  %-5s  %-7s  %s  %-15s  %-4s  %-12s  hops=%-3d  id=%s\n",
+			rec.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			rec.Kind,
+			rec.Source,
+			color.New(color.FgHiCyan, color.Bold).Sprint(rec.Target),
+			rec.ResolvedIP,
+			rec.Protocol,
+			rec.DataProvider,
+			rec.HopCount,
+			rec.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveHistory 将命令行追踪结果写入本地历史存储，失败不影响追踪本身
+func saveHistory(doc schema.Document) {
+	rec, err := server.TraceHistoryRecord("cli", doc)
+	if err != nil {
+		fmt.Println(i18n.T(i18n.HistorySaveFailed, err))
+		return
+	}
+	store, err := history.Open("", history.DefaultRetention)
+	if err == nil {
+		err = store.Append(rec)
+	}
+	if err != nil {
+		fmt.Println(i18n.T(i18n.HistorySaveFailed, err))
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net"
+
+	"github.com/nxtrace/NTrace-core/printer"
+	"github.com/nxtrace/NTrace-core/reporter"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/trace"
+	"github.com/nxtrace/NTrace-core/tracemap"
+	"github.com/nxtrace/NTrace-core/util"
+)
+
+// loadedRender 描述离线渲染已保存结果时使用的输出方式
+type loadedRender struct {
+	doc       *schema.Document
+	printer   printer.Printer
+	routePath bool
+	showMap   bool
+}
+
+// renderLoadedResult 使用与实时追踪相同的打印器输出已保存的结果，不发送任何探测包
+func renderLoadedResult(res *trace.Result, opts loadedRender) error {
+	if err := printer.Replay(opts.printer, res, *opts.doc); err != nil {
+		return err
+	}
+	if opts.routePath {
+		reporter.New(res, destinationIP(res)).Print()
+	}
+
+	if !opts.showMap {
+		return nil
+	}
+	if res.TraceMapUrl == "" {
+		payload, err := json.Marshal(res)
+		if err != nil {
+			return err
+		}
+		url, err := tracemap.GetMapUrl(string(payload))
+		if err != nil {
+			return err
+		}
+		res.TraceMapUrl = url
+	}
+	tracemap.PrintMapUrl(res.TraceMapUrl)
+	return nil
+}
+
+// destinationIP 取最后一跳的响应地址作为目标地址
+func destinationIP(res *trace.Result) string {
+	for i := len(res.Hops) - 1; i >= 0; i-- {
+		for _, hop := range res.Hops[i] {
+			if ip := util.AddrIP(hop.Address); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	return net.IPv4zero.String()
+}
+
+// hasGeoData 判断结果中是否含有可供绘制地图的地理信息
+func hasGeoData(res *trace.Result) bool {
+	for _, attempts := range res.Hops {
+		for _, hop := range attempts {
+			if hop.Geo != nil && (hop.Geo.Lat != 0 || hop.Geo.Lng != 0 || hop.Geo.Country != "") {
+				return true
+			}
+		}
+	}
+	return false
+}
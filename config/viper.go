@@ -8,6 +8,8 @@ import (
 	"runtime"
 
 	"github.com/spf13/viper"
+
+	"github.com/nxtrace/NTrace-core/i18n"
 )
 
 func InitConfig() {
@@ -66,18 +68,18 @@ func InitConfig() {
 	if err := viper.ReadInConfig(); err != nil {
 		var notFound viper.ConfigFileNotFoundError
 		if errors.As(err, &notFound) {
-			fmt.Println("未能找到配置文件，我们将在您的运行目录为您创建 nt_config.yaml 默认配置")
+			fmt.Println(i18n.T(i18n.ConfigCreating))
 			if err := viper.SafeWriteConfigAs("./nt_config.yaml"); err != nil {
-				fmt.Println("创建默认配置文件失败:", err)
+				fmt.Println(i18n.T(i18n.ConfigCreateFailed, err))
 				return
 			}
 			if err := viper.ReadInConfig(); err != nil {
-				fmt.Println("加载默认配置失败:", err)
+				fmt.Println(i18n.T(i18n.ConfigDefaultLoadFailed, err))
 			}
 			return
 		}
 
-		fmt.Println("加载配置文件失败:", err)
+		fmt.Println(i18n.T(i18n.ConfigLoadFailed, err))
 		return
 	}
 }
@@ -0,0 +1,256 @@
+// Package enrich re-annotates saved trace and MTR results with geo data and hostnames, offline.
+package enrich
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/nxtrace/NTrace-core/anomaly"
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/reporter"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/trace"
+	"github.com/nxtrace/NTrace-core/util"
+)
+
+// Options controls an enrichment run.
+type Options struct {
+	// Sources 按优先级排列，后面的数据源只补齐前面留空的字段
+	Sources []ipgeo.Source
+	// Provider 写回结果中 data_provider 字段的名称，为空时不修改
+	Provider string
+	Lang     string
+	RDNS     bool
+	Parallel int
+}
+
+func (o Options) config() trace.Config {
+	return trace.Config{
+		Lang:             o.Lang,
+		RDNS:             o.RDNS,
+		AlwaysWaitRDNS:   o.RDNS,
+		NumMeasurements:  3,
+		ParallelRequests: o.Parallel,
+	}
+}
+
+// Document enriches a JSON document and returns the updated JSON. It accepts `nexttrace --json`
+// output, web console trace responses, MTR snapshots and history records wrapping either of them.
+// RIPE Atlas and `mtr --json` results are converted to a `nexttrace --json` document first.
+// Lookup failures are reported in the returned error alongside the updated document.
+func Document(data []byte, opts Options) ([]byte, error) {
+	if len(opts.Sources) == 0 {
+		return nil, errors.New("no geo source given")
+	}
+	if imported, ok, err := schema.Import(data); ok {
+		if err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(imported); err != nil {
+			return nil, err
+		}
+	}
+	doc, err := decodeObject(data)
+	if err != nil {
+		return nil, err
+	}
+	var lookupErr error
+	switch {
+	case doc["Hops"] != nil:
+		// 区分大小写：命令行 JSON 为 "Hops"，控制台响应为 "hops"
+		return enrichResult(data, opts)
+	case doc["kind"] != nil && doc["data"] != nil:
+		// 历史记录：递归处理其中的结果
+		inner, err := json.Marshal(doc["data"])
+		if err != nil {
+			return nil, err
+		}
+		updated, err := Document(inner, opts)
+		if updated == nil {
+			return nil, err
+		}
+		lookupErr = err
+		doc["data"] = json.RawMessage(updated)
+	case doc["stats"] != nil:
+		lookupErr = run(collectRows(doc["stats"], "host"), opts)
+	case doc["hops"] != nil:
+		var err error
+		if lookupErr, err = enrichHops(doc, data, opts); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("unrecognised result format")
+	}
+	if opts.Provider != "" {
+		if _, ok := doc["data_provider"]; ok {
+			doc["data_provider"] = opts.Provider
+		}
+		if meta, ok := doc["metadata"].(map[string]any); ok {
+			meta["data_provider"] = opts.Provider
+		}
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return out, lookupErr
+}
+
+func enrichResult(data []byte, opts Options) ([]byte, error) {
+	var res trace.Result
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("decode result: %w", err)
+	}
+	lookupErr := trace.EnrichResult(&res, opts.config(), opts.Sources...)
+	// 地理信息已变化，原有的地图链接不再对应
+	res.TraceMapUrl = ""
+	out, err := json.Marshal(&res)
+	if err != nil {
+		return nil, err
+	}
+	return out, lookupErr
+}
+
+// enrichHops 处理 schema 文档与控制台响应：地理信息按与正常导出相同的方式转换并本地化，
+// 随后在新数据上重新计算注释与路由路径。只改写 geo / hostname 等派生字段，保留原有 RTT 的精度
+func enrichHops(doc map[string]any, data []byte, opts Options) (lookupErr, err error) {
+	var parsed schema.Document
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("decode result: %w", err)
+	}
+	res, err := parsed.Result()
+	if err != nil {
+		return nil, err
+	}
+	lang := parsed.Metadata.Language
+	if opts.Lang != "" {
+		// 查询语言即展示语言，与正常导出的 metadata.language 保持一致
+		lang = opts.Lang
+		if meta, ok := doc["metadata"].(map[string]any); ok {
+			meta["language"] = lang
+		}
+		if _, ok := doc["language"]; ok {
+			doc["language"] = lang
+		}
+	}
+	lookupErr = trace.EnrichResult(res, opts.config(), opts.Sources...)
+
+	annotations := anomaly.Analyze(res.Hops, anomaly.Options{})
+	for i, item := range asSlice(doc["hops"]) {
+		obj, ok := item.(map[string]any)
+		if !ok || i >= len(parsed.Hops) {
+			continue
+		}
+		ttl := parsed.Hops[i].TTL
+		attempts := res.Hops[ttl-1]
+		for j, item := range asSlice(obj["attempts"]) {
+			attempt, ok := item.(map[string]any)
+			if !ok || j >= len(attempts) || util.AddrIP(attempts[j].Address) == nil {
+				continue
+			}
+			attempt["geo"] = schema.NewGeo(schema.LocalizeGeo(attempts[j].Geo, lang))
+			if attempts[j].Hostname != "" {
+				attempt["hostname"] = attempts[j].Hostname
+			}
+		}
+		if notes := annotations[ttl-1]; len(notes) > 0 {
+			obj["annotations"] = notes
+		} else {
+			delete(obj, "annotations")
+		}
+	}
+	if _, ok := doc["route_path"]; ok {
+		path := reporter.Analyze(res, parsed.Metadata.DstIP)
+		doc["route_path"] = &path
+	}
+	// 地理信息已变化，原有的地图链接不再对应
+	delete(doc, "trace_map_url")
+	return lookupErr, nil
+}
+
+// row 为 MTR 统计行，hostKey 为其中主机名的字段名
+type row struct {
+	obj     map[string]any
+	hostKey string
+}
+
+func collectRows(list any, hostKey string) []row {
+	var rows []row
+	for _, item := range asSlice(list) {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		if ip, _ := obj["ip"].(string); ip != "" {
+			rows = append(rows, row{obj: obj, hostKey: hostKey})
+		}
+	}
+	return rows
+}
+
+func run(rows []row, opts Options) error {
+	cfg := opts.config()
+	workers := opts.Parallel
+	if workers <= 0 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	hops := make([]trace.Hop, len(rows))
+	errs := make([]error, len(rows))
+
+	var wg sync.WaitGroup
+	for i, r := range rows {
+		ipStr, _ := r.obj["ip"].(string)
+		ip := net.ParseIP(strings.TrimSpace(ipStr))
+		if ip == nil {
+			// 脱敏后的标签没有可查询的地址，保持原样
+			continue
+		}
+		host, _ := r.obj[r.hostKey].(string)
+		hops[i] = trace.Hop{Address: &net.IPAddr{IP: ip}, Hostname: host}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = trace.EnrichHop(&hops[i], cfg, opts.Sources...)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, r := range rows {
+		if hops[i].Address == nil {
+			continue
+		}
+		r.obj["geo"] = hops[i].Geo
+		if hops[i].Hostname != "" {
+			r.obj[r.hostKey] = hops[i].Hostname
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func decodeObject(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// 保留原始数字精度，避免 RTT 等字段被改写
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode result: %w", err)
+	}
+	if doc == nil {
+		return nil, errors.New("unrecognised result format")
+	}
+	return doc, nil
+}
+
+func asSlice(v any) []any {
+	list, _ := v.([]any)
+	return list
+}
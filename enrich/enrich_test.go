@@ -0,0 +1,139 @@
+package enrich
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+func primarySource(ip string, _ time.Duration, _ string, _ bool) (*ipgeo.IPGeoData, error) {
+	if ip == "9.9.9.9" {
+		return nil, errors.New("lookup failed")
+	}
+	return &ipgeo.IPGeoData{IP: ip, Asnumber: "13335", Country: "美国"}, nil
+}
+
+func fallbackSource(ip string, _ time.Duration, _ string, _ bool) (*ipgeo.IPGeoData, error) {
+	return &ipgeo.IPGeoData{IP: ip, Asnumber: "64500", Country: "Fallback", City: "Sydney", Owner: "Example"}, nil
+}
+
+func testOptions() Options {
+	return Options{Sources: []ipgeo.Source{primarySource, fallbackSource}, Provider: "primary,fallback", Lang: "en", Parallel: 2}
+}
+
+func TestDocumentLegacyResult(t *testing.T) {
+	doc := `{"Hops":[[{"Success":false,"TTL":1,"Error":{}}],[{"Success":true,"Address":{"IP":"1.1.1.1","Zone":""},"TTL":2,"RTT":5000000,"Geo":{"asnumber":"0","country":"stale"}}]],"TraceMapUrl":"https://example.com/old"}`
+
+	out, err := Document([]byte(doc), testOptions())
+	require.NoError(t, err)
+	var res trace.Result
+	require.NoError(t, json.Unmarshal(out, &res))
+
+	assert.Nil(t, res.Hops[0][0].Geo)
+	geo := res.Hops[1][0].Geo
+	require.NotNil(t, geo)
+	assert.Equal(t, "13335", geo.Asnumber, "primary source wins")
+	assert.Equal(t, "Sydney", geo.City, "fallback fills empty fields")
+	assert.Equal(t, 5*time.Millisecond, res.Hops[1][0].RTT)
+	assert.Empty(t, res.TraceMapUrl)
+}
+
+func TestDocumentMTRAndHistory(t *testing.T) {
+	mtr := `{"iteration":4,"stats":[{"ttl":1,"ip":"9.9.9.9","host":"dns9","avg_ms":1.25,"geo":null},{"ttl":2,"ip":"","sent":4}]}`
+	record := `{"id":"abc","kind":"mtr","data_provider":"disable-geoip","data":` + mtr + `}`
+
+	out, err := Document([]byte(record), testOptions())
+	require.NoError(t, err)
+
+	var got struct {
+		DataProvider string `json:"data_provider"`
+		Data         struct {
+			Stats []struct {
+				Host  string           `json:"host"`
+				Avg   json.Number      `json:"avg_ms"`
+				Geo   *ipgeo.IPGeoData `json:"geo"`
+				Sent  int              `json:"sent"`
+				IP    string           `json:"ip"`
+				Extra any              `json:"extra"`
+			} `json:"stats"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(out, &got))
+	assert.Equal(t, "primary,fallback", got.DataProvider)
+	require.Len(t, got.Data.Stats, 2)
+	assert.Equal(t, "dns9", got.Data.Stats[0].Host)
+	assert.Equal(t, "1.25", got.Data.Stats[0].Avg.String())
+	require.NotNil(t, got.Data.Stats[0].Geo)
+	assert.Equal(t, "64500", got.Data.Stats[0].Geo.Asnumber, "failed primary falls back")
+	assert.Nil(t, got.Data.Stats[1].Geo)
+	assert.Equal(t, 4, got.Data.Stats[1].Sent)
+}
+
+func TestDocumentConsoleResponse(t *testing.T) {
+	doc := `{"target":"1.1.1.1","data_provider":"disable-geoip","hops":[{"ttl":1,"attempts":[{"success":true,"ip":"1.1.1.1","rtt_ms":2}]}]}`
+
+	out, err := Document([]byte(doc), Options{Sources: []ipgeo.Source{primarySource}})
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"asnumber":"13335"`)
+	assert.Contains(t, string(out), `"data_provider":"disable-geoip"`, "provider is kept when not given")
+
+	atlas := `{"type":"traceroute","dst_addr":"1.1.1.1","result":[{"hop":1,"result":[{"from":"1.1.1.1","rtt":2}]}]}`
+	out, err = Document([]byte(atlas), Options{Sources: []ipgeo.Source{primarySource}})
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"schema_version"`, "atlas results become documents")
+	assert.Contains(t, string(out), `"asnumber":"13335"`)
+
+	_, err = Document([]byte(`{"foo":1}`), testOptions())
+	assert.Error(t, err)
+	_, err = Document([]byte(doc), Options{})
+	assert.Error(t, err)
+}
+
+func localizedSource(ip string, _ time.Duration, _ string, _ bool) (*ipgeo.IPGeoData, error) {
+	return &ipgeo.IPGeoData{IP: ip, Asnumber: "13335", Country: "美国", CountryEn: "United States", Isp: "Cloudflare"}, nil
+}
+
+func TestDocumentRebuildsSchemaFields(t *testing.T) {
+	doc := `{"schema_version":"1.0","metadata":{"language":"cn","dst_ip":"1.1.1.1"},` +
+		`"hops":[{"ttl":1,"attempts":[{"success":true,"ip":"1.1.1.1","hostname":"one.one","rtt_ms":1.234567}]}],` +
+		`"route_path":{"segments":[]},"trace_map_url":"https://example.com/old"}`
+
+	out, err := Document([]byte(doc), Options{Sources: []ipgeo.Source{localizedSource}, Lang: "en"})
+	require.NoError(t, err)
+
+	var got struct {
+		Metadata struct {
+			Language string `json:"language"`
+		} `json:"metadata"`
+		Hops []struct {
+			Attempts []struct {
+				Hostname string          `json:"hostname"`
+				RTT      json.Number     `json:"rtt_ms"`
+				Geo      json.RawMessage `json:"geo"`
+			} `json:"attempts"`
+		} `json:"hops"`
+		RoutePath struct {
+			Segments []struct {
+				ASN string `json:"asn"`
+			} `json:"segments"`
+		} `json:"route_path"`
+		TraceMapURL string `json:"trace_map_url"`
+	}
+	require.NoError(t, json.Unmarshal(out, &got))
+	assert.Equal(t, "en", got.Metadata.Language)
+	attempt := got.Hops[0].Attempts[0]
+	assert.JSONEq(t, `{"asnumber":"13335","country":"United States","country_en":"United States","isp":"Cloudflare"}`, string(attempt.Geo),
+		"geo uses the schema shape and the lookup language")
+	assert.Equal(t, "1.234567", attempt.RTT.String())
+	assert.Equal(t, "one.one", attempt.Hostname)
+	require.Len(t, got.RoutePath.Segments, 1)
+	assert.Equal(t, "13335", got.RoutePath.Segments[0].ASN)
+	assert.Empty(t, got.TraceMapURL)
+}
@@ -0,0 +1,318 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+// Names of the formats of other tools accepted by WriteCompat.
+const (
+	CompatTraceroute = "traceroute"
+	CompatMTRJSON    = "mtr-json"
+	CompatAtlas      = "atlas"
+)
+
+// CompatFormats lists the names accepted by WriteCompat.
+var CompatFormats = []string{CompatTraceroute, CompatMTRJSON, CompatAtlas}
+
+// WriteCompat writes doc in the output format of another tool, so parsers written for that
+// tool can read NextTrace results. See WriteTraceroute, WriteMTRJSON and WriteAtlas.
+func WriteCompat(w io.Writer, doc schema.Document, format string) error {
+	switch format {
+	case CompatTraceroute:
+		return WriteTraceroute(w, doc)
+	case CompatMTRJSON:
+		return WriteMTRJSON(w, doc)
+	case CompatAtlas:
+		return WriteAtlas(w, doc)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// WriteTraceroute writes doc as the text output of the Linux traceroute(8) command.
+// Addresses are printed as "hostname (ip)" when rDNS was enabled or a hostname is known.
+func WriteTraceroute(w io.Writer, doc schema.Document) error {
+	meta := doc.Metadata
+	maxHops := meta.Options.MaxHops
+	if maxHops <= 0 {
+		maxHops = 30
+	}
+	size := meta.Options.PacketSize
+	if size <= 0 {
+		size = 60
+	}
+	target := meta.Target
+	if target == "" {
+		target = meta.DstIP
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "traceroute to %s (%s), %d hops max, %d byte packets\n", target, meta.DstIP, maxHops, size)
+	for _, hop := range doc.Hops {
+		fmt.Fprintf(&b, "%2d ", hop.TTL)
+		// 与 traceroute 相同：地址与本行上一个响应不同时才重复打印
+		last := ""
+		for _, a := range hop.Attempts {
+			if !a.Success || a.IP == "" {
+				b.WriteString(" *")
+				continue
+			}
+			if a.IP != last {
+				switch {
+				case a.Hostname != "" && a.Hostname != a.IP:
+					fmt.Fprintf(&b, " %s (%s)", a.Hostname, a.IP)
+				case meta.Options.RDNS:
+					fmt.Fprintf(&b, " %s (%s)", a.IP, a.IP)
+				default:
+					b.WriteString(" " + a.IP)
+				}
+				last = a.IP
+			}
+			fmt.Fprintf(&b, "  %.3f ms", a.RTT)
+		}
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mtrJSON 为 `mtr --json` 的输出结构，字段顺序与 mtr 一致
+type mtrJSON struct {
+	Report struct {
+		MTR  mtrJSONInfo  `json:"mtr"`
+		Hubs []mtrJSONHub `json:"hubs"`
+	} `json:"report"`
+}
+
+type mtrJSONInfo struct {
+	Src        string `json:"src"`
+	Dst        string `json:"dst"`
+	Tos        int    `json:"tos"`
+	Tests      int    `json:"tests"`
+	PSize      string `json:"psize"`
+	BitPattern string `json:"bitpattern"`
+}
+
+type mtrJSONHub struct {
+	Count int     `json:"count"`
+	Host  string  `json:"host"`
+	ASN   string  `json:"ASN,omitempty"`
+	Loss  float64 `json:"Loss%"`
+	Snt   int     `json:"Snt"`
+	Last  float64 `json:"Last"`
+	Avg   float64 `json:"Avg"`
+	Best  float64 `json:"Best"`
+	Wrst  float64 `json:"Wrst"`
+	StDev float64 `json:"StDev"`
+}
+
+// WriteMTRJSON writes doc in the schema of `mtr --json`. Each TTL becomes one hub named after
+// its most frequent responder, as "hostname (ip)" (like `mtr -b`) when a hostname is known;
+// silent TTLs are "???". The ASN field is present when the result carries geo data, as with `mtr -z`.
+func WriteMTRJSON(w io.Writer, doc schema.Document) error {
+	var out mtrJSON
+	meta := doc.Metadata
+	out.Report.MTR = mtrJSONInfo{
+		Src:        meta.SrcIP,
+		Dst:        meta.Target,
+		Tests:      meta.Options.Queries,
+		PSize:      strconv.Itoa(meta.Options.PacketSize),
+		BitPattern: "0x00",
+	}
+	if out.Report.MTR.Dst == "" {
+		out.Report.MTR.Dst = meta.DstIP
+	}
+	withASN := false
+	for _, hop := range doc.Hops {
+		for _, a := range hop.Attempts {
+			withASN = withASN || (a.Geo != nil && a.Geo.ASN != "")
+		}
+	}
+
+	prev := 0
+	for _, hop := range doc.Hops {
+		// mtr 为每个 TTL 输出一行，文档中缺失的 TTL 视为无响应
+		for ttl := prev + 1; ttl < hop.TTL; ttl++ {
+			out.Report.Hubs = append(out.Report.Hubs, mtrJSONHub{Count: ttl, Host: "???", Loss: 100})
+		}
+		prev = hop.TTL
+		hub := mtrJSONHub{Count: hop.TTL, Host: "???", Snt: len(hop.Attempts)}
+		counts := make(map[string]int)
+		var rtts []float64
+		var best *schema.Attempt
+		for i := range hop.Attempts {
+			a := &hop.Attempts[i]
+			if !a.Success || a.IP == "" {
+				continue
+			}
+			rtts = append(rtts, a.RTT)
+			counts[a.IP]++
+			if best == nil || counts[a.IP] > counts[best.IP] {
+				best = a
+			}
+		}
+		if best != nil {
+			hub.Host = best.IP
+			if best.Hostname != "" && best.Hostname != best.IP {
+				hub.Host = best.Hostname + " (" + best.IP + ")"
+			}
+			hub.Last = roundTo(rtts[len(rtts)-1], 3)
+			hub.Best, hub.Wrst, hub.Avg, hub.StDev = rttStats(rtts)
+		}
+		if withASN {
+			hub.ASN = "AS???"
+			if best != nil && best.Geo != nil && best.Geo.ASN != "" {
+				hub.ASN = "AS" + strings.TrimPrefix(strings.ToUpper(best.Geo.ASN), "AS")
+			}
+		}
+		if hub.Snt > 0 {
+			hub.Loss = roundTo(float64(hub.Snt-len(rtts))*100/float64(hub.Snt), 1)
+		}
+		out.Report.Hubs = append(out.Report.Hubs, hub)
+	}
+	if out.Report.MTR.Tests == 0 && len(out.Report.Hubs) > 0 {
+		out.Report.MTR.Tests = out.Report.Hubs[0].Snt
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(out)
+}
+
+// rttStats 返回最小值、最大值、平均值与样本标准差（与 mtr 相同，n-1 为分母）
+func rttStats(rtts []float64) (best, worst, avg, stdev float64) {
+	best = math.Inf(1)
+	for _, r := range rtts {
+		best, worst, avg = math.Min(best, r), math.Max(worst, r), avg+r
+	}
+	avg /= float64(len(rtts))
+	if len(rtts) > 1 {
+		for _, r := range rtts {
+			stdev += (r - avg) * (r - avg)
+		}
+		stdev = math.Sqrt(stdev / float64(len(rtts)-1))
+	}
+	return roundTo(best, 3), roundTo(worst, 3), roundTo(avg, 3), roundTo(stdev, 3)
+}
+
+func roundTo(v float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	return math.Round(v*p) / p
+}
+
+// atlasJSON 为 RIPE Atlas traceroute 结果（fw 5080 格式）
+type atlasJSON struct {
+	Af        int        `json:"af"`
+	DstAddr   string     `json:"dst_addr"`
+	DstName   string     `json:"dst_name"`
+	EndTime   int64      `json:"endtime"`
+	From      string     `json:"from"`
+	Fw        int        `json:"fw"`
+	MsmID     int        `json:"msm_id"`
+	MsmName   string     `json:"msm_name"`
+	ParisID   int        `json:"paris_id"`
+	PrbID     int        `json:"prb_id"`
+	Proto     string     `json:"proto"`
+	Result    []atlasHop `json:"result"`
+	Size      int        `json:"size"`
+	SrcAddr   string     `json:"src_addr"`
+	Timestamp int64      `json:"timestamp"`
+	Type      string     `json:"type"`
+}
+
+type atlasHop struct {
+	Hop    int          `json:"hop"`
+	Result []atlasReply `json:"result"`
+}
+
+type atlasReply struct {
+	X       string        `json:"x,omitempty"`
+	From    string        `json:"from,omitempty"`
+	RTT     *float64      `json:"rtt,omitempty"`
+	ICMPExt *atlasICMPExt `json:"icmpext,omitempty"`
+}
+
+type atlasICMPExt struct {
+	Version int               `json:"version"`
+	RFC4884 int               `json:"rfc4884"`
+	Obj     []atlasICMPExtObj `json:"obj"`
+}
+
+type atlasICMPExtObj struct {
+	Class int         `json:"class"`
+	Type  int         `json:"type"`
+	MPLS  []atlasMPLS `json:"mpls"`
+}
+
+type atlasMPLS struct {
+	Exp   int `json:"exp"`
+	Label int `json:"label"`
+	S     int `json:"s"`
+	TTL   int `json:"ttl"`
+}
+
+// WriteAtlas writes doc as a RIPE Atlas traceroute result on a single line. Probe and
+// measurement IDs are 0, and the firmware version is set to the result format the fields follow.
+func WriteAtlas(w io.Writer, doc schema.Document) error {
+	meta := doc.Metadata
+	out := atlasJSON{
+		Af:      4,
+		DstAddr: meta.DstIP,
+		DstName: meta.Target,
+		From:    meta.SrcIP,
+		// Atlas 解析库根据 fw 判断字段格式，5080 对应当前格式
+		Fw:      5080,
+		MsmName: "Traceroute",
+		Proto:   strings.ToUpper(meta.Method),
+		Result:  []atlasHop{},
+		Size:    meta.Options.PacketSize,
+		SrcAddr: meta.SrcIP,
+		Type:    "traceroute",
+	}
+	if ip := net.ParseIP(meta.DstIP); ip != nil && ip.To4() == nil {
+		out.Af = 6
+	}
+	if out.DstName == "" {
+		out.DstName = meta.DstIP
+	}
+	if out.Proto == "" {
+		out.Proto = "ICMP"
+	}
+	if !meta.StartedAt.IsZero() {
+		out.Timestamp = meta.StartedAt.Unix()
+		out.EndTime = out.Timestamp
+	}
+	if !meta.FinishedAt.IsZero() {
+		out.EndTime = meta.FinishedAt.Unix()
+	}
+	for _, hop := range doc.Hops {
+		h := atlasHop{Hop: hop.TTL, Result: []atlasReply{}}
+		for _, a := range hop.Attempts {
+			if !a.Success || a.IP == "" {
+				h.Result = append(h.Result, atlasReply{X: "*"})
+				continue
+			}
+			rtt := roundTo(a.RTT, 3)
+			r := atlasReply{From: a.IP, RTT: &rtt}
+			var stack []atlasMPLS
+			for _, m := range a.MPLS {
+				if label, tc, s, ttl, ok := schema.ParseMPLSLabel(m); ok {
+					stack = append(stack, atlasMPLS{Exp: tc, Label: label, S: s, TTL: ttl})
+				}
+			}
+			if len(stack) > 0 {
+				r.ICMPExt = &atlasICMPExt{Version: 2, Obj: []atlasICMPExtObj{{Class: 1, Type: 1, MPLS: stack}}}
+			}
+			h.Result = append(h.Result, r)
+		}
+		out.Result = append(out.Result, h)
+	}
+	return json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+func testCompatDocument() schema.Document {
+	started := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+	return schema.Document{
+		SchemaVersion: schema.Version,
+		Metadata: schema.Metadata{
+			Method: "icmp", Target: "one.one.one.one", DstIP: "1.1.1.1", SrcIP: "192.0.2.10",
+			StartedAt: started, FinishedAt: started.Add(3 * time.Second),
+			Options: schema.Options{MaxHops: 30, Queries: 3, PacketSize: 52, RDNS: true},
+		},
+		Hops: []schema.Hop{
+			{TTL: 1, Attempts: []schema.Attempt{
+				{Success: true, IP: "192.168.1.1", Hostname: "_gateway", RTT: 0.5},
+				{Success: true, IP: "192.168.1.1", Hostname: "_gateway", RTT: 0.7},
+				{Success: true, IP: "192.168.1.1", Hostname: "_gateway", RTT: 0.9},
+			}},
+			{TTL: 2, Attempts: []schema.Attempt{{ErrorCode: schema.ErrorCodeTimeout}, {ErrorCode: schema.ErrorCodeTimeout}, {ErrorCode: schema.ErrorCodeTimeout}}},
+			{TTL: 3, Attempts: []schema.Attempt{
+				{Success: true, IP: "10.0.0.1", RTT: 5, MPLS: []string{"[MPLS: Lbl 24001, TC 0, S 1, TTL 1]"}},
+				{ErrorCode: schema.ErrorCodeTimeout},
+				{Success: true, IP: "10.0.0.2", RTT: 6},
+			}},
+			{TTL: 4, Attempts: []schema.Attempt{
+				{Success: true, IP: "1.1.1.1", Hostname: "one.one.one.one", RTT: 10, Geo: &schema.Geo{ASN: "13335"}},
+			}},
+		},
+	}
+}
+
+func TestWriteTraceroute(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteTraceroute(&buf, testCompatDocument()))
+	assert.Equal(t, `traceroute to one.one.one.one (1.1.1.1), 30 hops max, 52 byte packets
+ 1  _gateway (192.168.1.1)  0.500 ms  0.700 ms  0.900 ms
+ 2  * * *
+ 3  10.0.0.1 (10.0.0.1)  5.000 ms * 10.0.0.2 (10.0.0.2)  6.000 ms
+ 4  one.one.one.one (1.1.1.1)  10.000 ms
+`, buf.String())
+}
+
+func TestWriteMTRJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteMTRJSON(&buf, testCompatDocument()))
+
+	var out mtrJSON
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.Equal(t, mtrJSONInfo{Src: "192.0.2.10", Dst: "one.one.one.one", Tests: 3, PSize: "52", BitPattern: "0x00"}, out.Report.MTR)
+	require.Len(t, out.Report.Hubs, 4)
+	assert.Equal(t, mtrJSONHub{Count: 1, Host: "_gateway (192.168.1.1)", ASN: "AS???", Snt: 3, Last: 0.9, Avg: 0.7, Best: 0.5, Wrst: 0.9, StDev: 0.2}, out.Report.Hubs[0])
+	assert.Equal(t, mtrJSONHub{Count: 2, Host: "???", ASN: "AS???", Loss: 100, Snt: 3}, out.Report.Hubs[1])
+	assert.Equal(t, 33.3, out.Report.Hubs[2].Loss)
+	assert.Equal(t, "AS13335", out.Report.Hubs[3].ASN)
+	assert.Contains(t, buf.String(), "\n    \"report\"")
+}
+
+func TestWriteAtlas(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteAtlas(&buf, testCompatDocument()))
+	assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte("\n")))
+
+	var out atlasJSON
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.Equal(t, 4, out.Af)
+	assert.Equal(t, "ICMP", out.Proto)
+	assert.Equal(t, "traceroute", out.Type)
+	assert.EqualValues(t, 3, out.EndTime-out.Timestamp)
+	require.Len(t, out.Result, 4)
+	assert.Equal(t, "*", out.Result[1].Result[0].X)
+	assert.Equal(t, 24001, out.Result[2].Result[0].ICMPExt.Obj[0].MPLS[0].Label)
+
+	// 写出的结果能被导入器读回
+	doc, err := schema.ParseDocument(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "one.one.one.one", doc.Metadata.Target)
+	assert.Equal(t, testCompatDocument().Hops[2].Attempts[0].MPLS, doc.Hops[2].Attempts[0].MPLS)
+}
+
+func TestWriteMTRJSONRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteMTRJSON(&buf, testCompatDocument()))
+	doc, err := schema.ParseDocument(buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, doc.Hops, 4)
+	assert.Equal(t, "1.1.1.1", doc.Metadata.DstIP)
+	assert.Len(t, doc.Hops[2].Attempts, 3)
+}
@@ -0,0 +1,187 @@
+// Package export converts trace and MTR results into formats for other tools: CSV/TSV for
+// spreadsheets, GeoJSON/KML for GIS and a self-contained HTML report.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+// Field delimiters accepted by WriteTrace and WriteMTR.
+const (
+	CSV = ','
+	TSV = '\t'
+)
+
+// TraceHeader is the header row of WriteTrace: one row per probe attempt.
+var TraceHeader = []string{"ttl", "index", "ip", "hostname", "rtt_ms", "asn", "country", "prov", "city", "owner", "mpls"}
+
+// MTRHeader is the header row of WriteMTR: one row per responding address of a TTL.
+var MTRHeader = []string{"ttl", "host", "ip", "sent", "received", "loss_percent", "last_ms", "avg_ms", "best_ms", "worst_ms", "asn", "country", "prov", "city", "owner", "mpls"}
+
+// MTRStat is one aggregated row of an MTR snapshot as served by the web console.
+type MTRStat struct {
+	TTL         int         `json:"ttl"`
+	Host        string      `json:"host,omitempty"`
+	IP          string      `json:"ip,omitempty"`
+	Sent        int         `json:"sent"`
+	Received    int         `json:"received"`
+	LossPercent float64     `json:"loss_percent"`
+	Last        float64     `json:"last_ms"`
+	Avg         float64     `json:"avg_ms"`
+	Best        float64     `json:"best_ms"`
+	Worst       float64     `json:"worst_ms"`
+	Geo         *schema.Geo `json:"geo,omitempty"`
+	MPLS        []string    `json:"mpls,omitempty"`
+}
+
+// Result is a loaded trace or MTR result; exactly one of Trace and MTR is set.
+type Result struct {
+	Trace *schema.Document
+	MTR   []MTRStat
+}
+
+// Write writes r with the given delimiter.
+func (r Result) Write(w io.Writer, comma rune) error {
+	if r.Trace != nil {
+		return WriteTrace(w, *r.Trace, comma)
+	}
+	return WriteMTR(w, r.MTR, comma)
+}
+
+// WriteTrace writes one row per probe attempt of doc. Timed-out probes keep their row with an
+// empty ip and rtt_ms so that the index column stays contiguous.
+func WriteTrace(w io.Writer, doc schema.Document, comma rune) error {
+	cw := newWriter(w, comma)
+	if err := cw.Write(TraceHeader); err != nil {
+		return err
+	}
+	for _, hop := range doc.Hops {
+		for i, a := range hop.Attempts {
+			rtt := ""
+			if a.Success && a.RTT > 0 {
+				rtt = formatMs(a.RTT)
+			}
+			row := append([]string{
+				strconv.Itoa(hop.TTL),
+				strconv.Itoa(i + 1),
+				a.IP,
+				a.Hostname,
+				rtt,
+			}, geoColumns(a.Geo)...)
+			row = append(row, strings.Join(a.MPLS, " "))
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteMTR writes one row per MTR statistic.
+func WriteMTR(w io.Writer, stats []MTRStat, comma rune) error {
+	cw := newWriter(w, comma)
+	if err := cw.Write(MTRHeader); err != nil {
+		return err
+	}
+	for _, s := range stats {
+		row := append([]string{
+			strconv.Itoa(s.TTL),
+			s.Host,
+			s.IP,
+			strconv.Itoa(s.Sent),
+			strconv.Itoa(s.Received),
+			strconv.FormatFloat(s.LossPercent, 'f', 1, 64),
+			formatMs(s.Last),
+			formatMs(s.Avg),
+			formatMs(s.Best),
+			formatMs(s.Worst),
+		}, geoColumns(s.Geo)...)
+		row = append(row, strings.Join(s.MPLS, " "))
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// LoadFile reads a result saved as `nexttrace --json` output (current or legacy), a web console
+// trace response, an MTR snapshot, a history record wrapping one of them, or a RIPE Atlas
+// or `mtr --json` result.
+func LoadFile(path string) (Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+	r, err := Parse(data)
+	if err != nil {
+		return Result{}, errors.New(path + ": " + err.Error())
+	}
+	return r, nil
+}
+
+// Parse is LoadFile for data already in memory.
+func Parse(data []byte) (Result, error) {
+	// 其他工具的结果可能是 JSON 数组，先于信封识别
+	if doc, ok, err := schema.Import(data); ok {
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{Trace: doc}, nil
+	}
+	var env struct {
+		Kind  string          `json:"kind"`
+		Data  json.RawMessage `json:"data"`
+		Stats json.RawMessage `json:"stats"`
+	}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return Result{}, err
+	}
+	switch {
+	case env.Kind != "" && len(env.Data) > 0:
+		return Parse(env.Data)
+	case len(env.Stats) > 0:
+		var stats []MTRStat
+		if err := json.Unmarshal(env.Stats, &stats); err != nil {
+			return Result{}, err
+		}
+		return Result{MTR: stats}, nil
+	}
+	doc, err := schema.ParseDocument(data)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Trace: doc}, nil
+}
+
+func newWriter(w io.Writer, comma rune) *csv.Writer {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	return cw
+}
+
+func geoColumns(g *schema.Geo) []string {
+	if g == nil {
+		return []string{"", "", "", "", ""}
+	}
+	// 部分数据源（如 IPInfo）返回的 ASN 已带 AS 前缀，统一去掉便于表格排序
+	asn := strings.TrimPrefix(strings.ToUpper(g.ASN), "AS")
+	owner := g.Owner
+	if owner == "" {
+		owner = g.ISP
+	}
+	return []string{asn, g.Country, g.Prov, g.City, owner}
+}
+
+func formatMs(ms float64) string {
+	return strconv.FormatFloat(ms, 'f', 2, 64)
+}
@@ -0,0 +1,63 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+func TestWriteTrace(t *testing.T) {
+	doc := schema.Document{Hops: []schema.Hop{
+		{TTL: 1, Attempts: []schema.Attempt{
+			{Success: true, IP: "10.0.0.1", RTT: 1.234, MPLS: []string{"Lbl 1", "Lbl 2"}},
+			{Success: false, ErrorCode: schema.ErrorCodeTimeout},
+		}},
+		{TTL: 2, Attempts: []schema.Attempt{
+			{Success: true, IP: "202.97.1.1", Hostname: "core.example", RTT: 20,
+				Geo: &schema.Geo{ASN: "AS4134", Country: "中国", Prov: "广东, 深圳", City: `"特区"`, ISP: "电信"}},
+		}},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteTrace(&buf, doc, CSV))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, TraceHeader, rows[0])
+	assert.Equal(t, []string{"1", "1", "10.0.0.1", "", "1.23", "", "", "", "", "", "Lbl 1 Lbl 2"}, rows[1])
+	assert.Equal(t, []string{"1", "2", "", "", "", "", "", "", "", "", ""}, rows[2])
+	// 含分隔符与引号的中文地名需正确转义
+	assert.Equal(t, []string{"2", "1", "202.97.1.1", "core.example", "20.00", "4134", "中国", "广东, 深圳", `"特区"`, "电信", ""}, rows[3])
+}
+
+func TestParseMTRAndTSV(t *testing.T) {
+	data := []byte(`{"kind":"mtr","data":{"iteration":3,"stats":[
+		{"ttl":1,"ip":"10.0.0.1","sent":3,"received":2,"loss_percent":33.333,"last_ms":1,"avg_ms":1.5,"best_ms":1,"worst_ms":2,
+		 "geo":{"asnumber":"64500","country":"中国","prov":"北京","city":"北京","owner":"Example"}}]}}`)
+	r, err := Parse(data)
+	require.NoError(t, err)
+	require.Nil(t, r.Trace)
+	require.Len(t, r.MTR, 1)
+
+	var buf bytes.Buffer
+	require.NoError(t, r.Write(&buf, TSV))
+	assert.Equal(t, "ttl\thost\tip\tsent\treceived\tloss_percent\tlast_ms\tavg_ms\tbest_ms\tworst_ms\tasn\tcountry\tprov\tcity\towner\tmpls\n"+
+		"1\t\t10.0.0.1\t3\t2\t33.3\t1.00\t1.50\t1.00\t2.00\t64500\t中国\t北京\t北京\tExample\t\n", buf.String())
+}
+
+func TestParseTrace(t *testing.T) {
+	r, err := Parse([]byte(`{"schema_version":"1.0","metadata":{},"hops":[{"ttl":1,"attempts":[{"success":true,"ip":"1.1.1.1","rtt_ms":3}]}]}`))
+	require.NoError(t, err)
+	require.NotNil(t, r.Trace)
+	assert.Nil(t, r.MTR)
+	assert.Equal(t, "1.1.1.1", r.Trace.Hops[0].Attempts[0].IP)
+
+	_, err = Parse([]byte(`{"foo":1}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,182 @@
+package export
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+// GeoOptions controls how hops are placed in GeoJSON and KML output.
+type GeoOptions struct {
+	// Interpolate places responding hops without coordinates between their located neighbours
+	// instead of skipping them; such hops are marked as interpolated.
+	Interpolate bool
+}
+
+// geoHop 为路径上的一个位置；同一位置的连续跳合并为一个
+type geoHop struct {
+	TTLs         []int
+	IPs          []string
+	Hostname     string
+	ASN          string
+	Owner        string
+	Location     string
+	RTT          float64
+	Lat, Lng     float64
+	Interpolated bool
+}
+
+// geoPath 为导出的地理路径；Skipped 记录缺少坐标而被跳过的 TTL
+type geoPath struct {
+	Hops    []geoHop
+	Skipped []int
+}
+
+// buildGeoPath 每个 TTL 取第一个响应地址，超时的跳不参与路径
+func buildGeoPath(doc schema.Document, opts GeoOptions) geoPath {
+	type candidate struct {
+		hop     geoHop
+		located bool
+	}
+	var cands []candidate
+	for _, hop := range doc.Hops {
+		var first *schema.Attempt
+		sum, n := 0.0, 0
+		for i := range hop.Attempts {
+			a := &hop.Attempts[i]
+			if !a.Success || a.IP == "" {
+				continue
+			}
+			if first == nil {
+				first = a
+			}
+			if a.IP == first.IP {
+				sum += a.RTT
+				n++
+			}
+		}
+		if first == nil {
+			continue
+		}
+		h := geoHop{TTLs: []int{hop.TTL}, IPs: []string{first.IP}, Hostname: first.Hostname, RTT: sum / float64(n)}
+		located := false
+		if g := first.Geo; g != nil {
+			h.ASN = strings.TrimPrefix(strings.ToUpper(g.ASN), "AS")
+			h.Owner = g.Owner
+			if h.Owner == "" {
+				h.Owner = g.ISP
+			}
+			h.Location = joinNonEmpty(" ", g.Country, g.Prov, g.City)
+			if g.Lat != 0 || g.Lng != 0 {
+				h.Lat, h.Lng, located = g.Lat, g.Lng, true
+			}
+		}
+		cands = append(cands, candidate{hop: h, located: located})
+	}
+
+	var path geoPath
+	for i, c := range cands {
+		if !c.located && opts.Interpolate {
+			c.located = interpolate(&c.hop, func(j int) (geoHop, bool) {
+				if j < 0 || j >= len(cands) {
+					return geoHop{}, false
+				}
+				return cands[j].hop, cands[j].located
+			}, i, len(cands))
+		}
+		if !c.located {
+			path.Skipped = append(path.Skipped, c.hop.TTLs[0])
+			continue
+		}
+		if n := len(path.Hops); n > 0 && path.Hops[n-1].Lat == c.hop.Lat && path.Hops[n-1].Lng == c.hop.Lng {
+			// 连续位于同一坐标的跳合并为一个点
+			last := &path.Hops[n-1]
+			last.TTLs = append(last.TTLs, c.hop.TTLs...)
+			for _, ip := range c.hop.IPs {
+				if !slices.Contains(last.IPs, ip) {
+					last.IPs = append(last.IPs, ip)
+				}
+			}
+			last.Interpolated = last.Interpolated && c.hop.Interpolated
+			continue
+		}
+		path.Hops = append(path.Hops, c.hop)
+	}
+	return path
+}
+
+// interpolate 按 TTL 在前后最近的已定位跳之间线性插值；任一侧缺失时无法插值
+func interpolate(h *geoHop, at func(int) (geoHop, bool), idx, n int) bool {
+	var prev, next geoHop
+	found := 0
+	for j := idx - 1; j >= 0; j-- {
+		if c, ok := at(j); ok {
+			prev, found = c, found+1
+			break
+		}
+	}
+	for j := idx + 1; j < n; j++ {
+		if c, ok := at(j); ok {
+			next, found = c, found+1
+			break
+		}
+	}
+	if found < 2 {
+		return false
+	}
+	from, to := prev.TTLs[len(prev.TTLs)-1], next.TTLs[0]
+	t := float64(h.TTLs[0]-from) / float64(to-from)
+	// 经度差超过 180° 时沿较短方向插值
+	dLng := next.Lng - prev.Lng
+	if dLng > 180 {
+		dLng -= 360
+	} else if dLng < -180 {
+		dLng += 360
+	}
+	h.Lat = prev.Lat + (next.Lat-prev.Lat)*t
+	h.Lng = normalizeLng(prev.Lng + dLng*t)
+	h.Interpolated = true
+	return true
+}
+
+func normalizeLng(lng float64) float64 {
+	for lng > 180 {
+		lng -= 360
+	}
+	for lng < -180 {
+		lng += 360
+	}
+	return lng
+}
+
+// lines 将路径拆分为不跨越 180° 经线的线段，供 GeoJSON MultiLineString 与 KML 使用
+func (p geoPath) lines() [][][2]float64 {
+	if len(p.Hops) < 2 {
+		return nil
+	}
+	var (
+		lines [][][2]float64
+		cur   [][2]float64
+	)
+	cur = append(cur, [2]float64{p.Hops[0].Lng, p.Hops[0].Lat})
+	for i := 1; i < len(p.Hops); i++ {
+		a, b := p.Hops[i-1], p.Hops[i]
+		if d := b.Lng - a.Lng; math.Abs(d) > 180 {
+			// 在 ±180° 处截断，纬度按展开后的经度线性插值
+			edge := 180.0
+			bLng := b.Lng + 360
+			if d > 0 {
+				edge = -180
+				bLng = b.Lng - 360
+			}
+			lat := a.Lat + (b.Lat-a.Lat)*(edge-a.Lng)/(bLng-a.Lng)
+			cur = append(cur, [2]float64{edge, lat})
+			lines = append(lines, cur)
+			cur = [][2]float64{{-edge, lat}}
+		}
+		cur = append(cur, [2]float64{b.Lng, b.Lat})
+	}
+	return append(lines, cur)
+}
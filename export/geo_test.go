@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+func geoAttempt(ip string, rtt, lat, lng float64) schema.Attempt {
+	return schema.Attempt{Success: true, IP: ip, RTT: rtt, Geo: &schema.Geo{ASN: "AS64500", Owner: "Example", Lat: lat, Lng: lng}}
+}
+
+func testGeoDocument() schema.Document {
+	return schema.Document{
+		Metadata: schema.Metadata{Target: "example.com", DstIP: "198.51.100.9"},
+		Hops: []schema.Hop{
+			{TTL: 1, Attempts: []schema.Attempt{{Success: true, IP: "10.0.0.1", RTT: 1}}},
+			{TTL: 2, Attempts: []schema.Attempt{geoAttempt("192.0.2.1", 5, 31.2, 121.5), geoAttempt("192.0.2.1", 7, 31.2, 121.5)}},
+			{TTL: 3, Attempts: []schema.Attempt{geoAttempt("192.0.2.2", 8, 31.2, 121.5)}},
+			{TTL: 4, Attempts: []schema.Attempt{{Success: false}}},
+			{TTL: 5, Attempts: []schema.Attempt{{Success: true, IP: "192.0.2.3", RTT: 60}}},
+			{TTL: 6, Attempts: []schema.Attempt{geoAttempt("198.51.100.9", 120, 37.4, -122)}},
+		},
+	}
+}
+
+func TestBuildGeoPath(t *testing.T) {
+	path := buildGeoPath(testGeoDocument(), GeoOptions{})
+	require.Len(t, path.Hops, 2)
+	// TTL 2 与 3 位于同一坐标，合并为一个点
+	assert.Equal(t, []int{2, 3}, path.Hops[0].TTLs)
+	assert.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, path.Hops[0].IPs)
+	assert.Equal(t, 6.0, path.Hops[0].RTT)
+	assert.Equal(t, "64500", path.Hops[0].ASN)
+	assert.Equal(t, []int{1, 5}, path.Skipped)
+
+	path = buildGeoPath(testGeoDocument(), GeoOptions{Interpolate: true})
+	require.Len(t, path.Hops, 3)
+	mid := path.Hops[1]
+	assert.Equal(t, []int{5}, mid.TTLs)
+	assert.True(t, mid.Interpolated)
+	// TTL 5 位于 TTL 3 与 6 之间的 2/3 处，经度沿太平洋方向插值
+	assert.InDelta(t, 31.2+(37.4-31.2)*2/3, mid.Lat, 1e-9)
+	assert.InDelta(t, 121.5+(360-122-121.5)*2/3-360, mid.Lng, 1e-9)
+	assert.Equal(t, []int{1}, path.Skipped)
+}
+
+func TestGeoPathLinesSplitAtAntimeridian(t *testing.T) {
+	path := geoPath{Hops: []geoHop{{Lat: 30, Lng: 170}, {Lat: 40, Lng: -170}, {Lat: 40, Lng: -160}}}
+	lines := path.lines()
+	require.Len(t, lines, 2)
+	assert.Equal(t, [][2]float64{{170, 30}, {180, 35}}, lines[0])
+	assert.Equal(t, [][2]float64{{-180, 35}, {-170, 40}, {-160, 40}}, lines[1])
+}
+
+func TestWriteGeoJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteGeoJSON(&buf, testGeoDocument(), GeoOptions{}))
+
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &fc))
+	assert.Equal(t, "FeatureCollection", fc.Type)
+	require.Len(t, fc.Features, 3)
+	// 跨越太平洋的路径拆分为 MultiLineString
+	assert.Equal(t, "MultiLineString", fc.Features[0].Geometry.Type)
+	assert.Equal(t, []any{1.0, 5.0}, fc.Features[0].Properties["skipped_ttls"])
+	assert.Equal(t, "Point", fc.Features[1].Geometry.Type)
+	assert.JSONEq(t, `[121.5,31.2]`, string(fc.Features[1].Geometry.Coordinates))
+	props := fc.Features[2].Properties
+	assert.EqualValues(t, 6, props["ttl"])
+	assert.Equal(t, "198.51.100.9", props["ip"])
+	assert.Equal(t, "64500", props["asn"])
+	assert.Equal(t, "Example", props["owner"])
+	assert.EqualValues(t, 120, props["rtt_ms"])
+	assert.Equal(t, false, props["interpolated"])
+}
+
+func TestWriteKML(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteKML(&buf, testGeoDocument(), GeoOptions{Interpolate: true}))
+
+	var root kmlRoot
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &root))
+	pms := root.Document.Placemarks
+	require.Len(t, pms, 4)
+	require.NotNil(t, pms[0].MultiGeometry)
+	assert.Len(t, pms[0].MultiGeometry.LineStrings, 2)
+	assert.Equal(t, "2,3", pms[1].Name)
+	assert.Equal(t, "121.5,31.2,0", pms[1].Point.Coordinates)
+	assert.Equal(t, "#interpolated", pms[2].StyleURL)
+	assert.Contains(t, pms[3].ExtendedData.Data, kmlData{Name: "asn", Value: "64500"})
+}
@@ -0,0 +1,86 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+type geoJSONCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// WriteGeoJSON writes the geolocated path of doc as a GeoJSON FeatureCollection (RFC 7946): one
+// LineString (a MultiLineString when the path crosses the antimeridian) followed by one Point per location.
+func WriteGeoJSON(w io.Writer, doc schema.Document, opts GeoOptions) error {
+	path := buildGeoPath(doc, opts)
+	fc := geoJSONCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+
+	if lines := path.lines(); len(lines) > 0 {
+		geom := geoJSONGeometry{Type: "LineString", Coordinates: lines[0]}
+		if len(lines) > 1 {
+			geom = geoJSONGeometry{Type: "MultiLineString", Coordinates: lines}
+		}
+		fc.Features = append(fc.Features, geoJSONFeature{
+			Type:     "Feature",
+			Geometry: geom,
+			Properties: map[string]any{
+				"target":       doc.Metadata.Target,
+				"dst_ip":       doc.Metadata.DstIP,
+				"skipped_ttls": nonNil(path.Skipped),
+			},
+		})
+	}
+	for _, h := range path.Hops {
+		fc.Features = append(fc.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "Point", Coordinates: [2]float64{h.Lng, h.Lat}},
+			Properties: h.properties(),
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(fc)
+}
+
+// properties 为 GeoJSON 与 KML 共用的点属性
+func (h geoHop) properties() map[string]any {
+	return map[string]any{
+		"ttl":          h.TTLs[0],
+		"ttls":         h.TTLs,
+		"ip":           h.IPs[0],
+		"ips":          h.IPs,
+		"hostname":     h.Hostname,
+		"asn":          h.ASN,
+		"owner":        h.Owner,
+		"location":     h.Location,
+		"rtt_ms":       roundMs(h.RTT),
+		"interpolated": h.Interpolated,
+	}
+}
+
+func nonNil(ttls []int) []int {
+	if ttls == nil {
+		return []int{}
+	}
+	return ttls
+}
+
+func roundMs(ms float64) float64 {
+	return math.Round(ms*100) / 100
+}
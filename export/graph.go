@@ -0,0 +1,227 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+// sourceID 为所有追踪共用的根节点
+const sourceID = "n0"
+
+// Graph merges one or more traces into a directed graph of interfaces rooted at the source.
+// Nodes are keyed by IP, so hops shared by several traces appear once.
+type Graph struct {
+	nodes     map[string]*graphNode
+	nodeOrder []*graphNode
+	edges     map[[2]string]*graphEdge
+	edgeOrder []*graphEdge
+	sources   []string
+}
+
+type graphNode struct {
+	ID       string
+	IP       string
+	Hostname string
+	ASN      string
+	Targets  []string
+}
+
+// graphEdge 连接两个相邻的响应 TTL；Delta 为各次观测 RTT 差值之和
+type graphEdge struct {
+	From, To string
+	Delta    float64
+	Count    int
+	// Direct 表示至少有一次观测的两端 TTL 相邻，否则中间隔着超时的跳
+	Direct bool
+}
+
+// NewGraph returns an empty graph.
+func NewGraph() *Graph {
+	return &Graph{
+		nodes: make(map[string]*graphNode),
+		edges: make(map[[2]string]*graphEdge),
+	}
+}
+
+// Add merges doc into the graph. Every responding IP of a TTL is a node, and each one is
+// linked to every IP of the previous responding TTL, so ECMP branches become parallel paths.
+func (g *Graph) Add(doc schema.Document) {
+	if src := doc.Metadata.SrcIP; src != "" && !slices.Contains(g.sources, src) {
+		g.sources = append(g.sources, src)
+	}
+	type level struct {
+		ttl int
+		ids []string
+		rtt map[string]float64
+	}
+	prev := level{ttl: 0, ids: []string{sourceID}, rtt: map[string]float64{sourceID: 0}}
+	for _, hop := range doc.Hops {
+		cur := level{ttl: hop.TTL, rtt: make(map[string]float64)}
+		counts := make(map[string]int)
+		for _, a := range hop.Attempts {
+			if !a.Success || a.IP == "" {
+				continue
+			}
+			n := g.node(a)
+			if _, ok := cur.rtt[n.ID]; !ok {
+				cur.ids = append(cur.ids, n.ID)
+			}
+			cur.rtt[n.ID] += a.RTT
+			counts[n.ID]++
+			if a.IP == doc.Metadata.DstIP {
+				target := doc.Metadata.Target
+				if target == "" {
+					target = a.IP
+				}
+				if !slices.Contains(n.Targets, target) {
+					n.Targets = append(n.Targets, target)
+				}
+			}
+		}
+		if len(cur.ids) == 0 {
+			continue
+		}
+		for id, c := range counts {
+			cur.rtt[id] /= float64(c)
+		}
+		for _, from := range prev.ids {
+			for _, to := range cur.ids {
+				g.edge(from, to, cur.rtt[to]-prev.rtt[from], cur.ttl-prev.ttl == 1)
+			}
+		}
+		prev = cur
+	}
+}
+
+func (g *Graph) node(a schema.Attempt) *graphNode {
+	n, ok := g.nodes[a.IP]
+	if !ok {
+		n = &graphNode{ID: "n" + strconv.Itoa(len(g.nodeOrder)+1), IP: a.IP}
+		g.nodes[a.IP] = n
+		g.nodeOrder = append(g.nodeOrder, n)
+	}
+	if n.Hostname == "" {
+		n.Hostname = a.Hostname
+	}
+	if n.ASN == "" && a.Geo != nil {
+		n.ASN = strings.TrimPrefix(strings.ToUpper(a.Geo.ASN), "AS")
+	}
+	return n
+}
+
+func (g *Graph) edge(from, to string, delta float64, direct bool) {
+	key := [2]string{from, to}
+	e, ok := g.edges[key]
+	if !ok {
+		e = &graphEdge{From: from, To: to}
+		g.edges[key] = e
+		g.edgeOrder = append(g.edgeOrder, e)
+	}
+	e.Delta += delta
+	e.Count++
+	e.Direct = e.Direct || direct
+}
+
+// label 为节点显示的各行：IP、反向解析与 ASN，目的地址另附目标名
+func (n *graphNode) label() []string {
+	lines := []string{n.IP}
+	if n.Hostname != "" && n.Hostname != n.IP {
+		lines = append(lines, n.Hostname)
+	}
+	if n.ASN != "" {
+		lines = append(lines, "AS"+n.ASN)
+	}
+	for _, t := range n.Targets {
+		if t != n.IP {
+			lines = append(lines, "→ "+t)
+		}
+	}
+	return lines
+}
+
+// label 为边上显示的平均 RTT 增量，被多条追踪经过时附带次数
+func (e *graphEdge) label() string {
+	s := fmt.Sprintf("%+.2f ms", e.Delta/float64(e.Count))
+	if e.Count > 1 {
+		s += " ×" + strconv.Itoa(e.Count)
+	}
+	return s
+}
+
+func (g *Graph) sourceLabel() []string {
+	return append([]string{"source"}, g.sources...)
+}
+
+// WriteDOT writes the graph in Graphviz DOT. Edges across timed-out hops are dashed and
+// edges seen in several traces are drawn thicker.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph nexttrace {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=rounded, fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+	fmt.Fprintf(&b, "  %s [label=%s, shape=ellipse];\n", sourceID, dotQuote(strings.Join(g.sourceLabel(), "\n")))
+	for _, n := range g.nodeOrder {
+		attrs := "label=" + dotQuote(strings.Join(n.label(), "\n"))
+		if len(n.Targets) > 0 {
+			attrs += ", peripheries=2"
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", n.ID, attrs)
+	}
+	for _, e := range g.edgeOrder {
+		attrs := "label=" + dotQuote(e.label())
+		if !e.Direct {
+			attrs += ", style=dashed"
+		}
+		if e.Count > 1 {
+			attrs += ", penwidth=" + strconv.Itoa(min(e.Count, 5))
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", e.From, e.To, attrs)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart. Edges across timed-out hops are dotted.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	fmt.Fprintf(&b, "  %s([%s])\n", sourceID, mermaidQuote(g.sourceLabel()))
+	for _, n := range g.nodeOrder {
+		if len(n.Targets) > 0 {
+			fmt.Fprintf(&b, "  %s[[%s]]\n", n.ID, mermaidQuote(n.label()))
+		} else {
+			fmt.Fprintf(&b, "  %s[%s]\n", n.ID, mermaidQuote(n.label()))
+		}
+	}
+	for _, e := range g.edgeOrder {
+		arrow := "-->"
+		if !e.Direct {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s|%s| %s\n", e.From, arrow, mermaidQuote([]string{e.label()}), e.To)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// mermaidQuote 以 <br/> 连接多行，并用 Mermaid 实体转义双引号
+func mermaidQuote(lines []string) string {
+	r := strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;")
+	escaped := make([]string, len(lines))
+	for i, l := range lines {
+		escaped[i] = r.Replace(l)
+	}
+	return `"` + strings.Join(escaped, "<br/>") + `"`
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+func graphAttempt(ip, host string, rtt float64) schema.Attempt {
+	return schema.Attempt{Success: true, IP: ip, Hostname: host, RTT: rtt, Geo: &schema.Geo{ASN: "AS64500"}}
+}
+
+func testGraph() *Graph {
+	g := NewGraph()
+	g.Add(schema.Document{
+		Metadata: schema.Metadata{Target: "a.example", DstIP: "198.51.100.1", SrcIP: "192.0.2.100"},
+		Hops: []schema.Hop{
+			{TTL: 1, Attempts: []schema.Attempt{graphAttempt("10.0.0.1", "gw", 1)}},
+			// ECMP：同一 TTL 的两个地址成为并行分支
+			{TTL: 2, Attempts: []schema.Attempt{graphAttempt("10.1.0.1", "", 5), graphAttempt("10.1.0.2", "", 7)}},
+			{TTL: 3, Attempts: []schema.Attempt{{Success: false}}},
+			{TTL: 4, Attempts: []schema.Attempt{graphAttempt("198.51.100.1", "", 20)}},
+		},
+	})
+	g.Add(schema.Document{
+		Metadata: schema.Metadata{Target: "b.example", DstIP: "203.0.113.1", SrcIP: "192.0.2.100"},
+		Hops: []schema.Hop{
+			{TTL: 1, Attempts: []schema.Attempt{graphAttempt("10.0.0.1", "gw", 3)}},
+			{TTL: 2, Attempts: []schema.Attempt{graphAttempt("203.0.113.1", "", 10)}},
+		},
+	})
+	return g
+}
+
+func TestGraphAdd(t *testing.T) {
+	g := testGraph()
+	require.Len(t, g.nodeOrder, 5)
+	assert.Equal(t, []string{"192.0.2.100"}, g.sources)
+	assert.Equal(t, []string{"a.example"}, g.nodes["198.51.100.1"].Targets)
+
+	type edge struct {
+		from, to string
+		delta    float64
+		count    int
+		direct   bool
+	}
+	var got []edge
+	for _, e := range g.edgeOrder {
+		got = append(got, edge{e.From, e.To, e.Delta / float64(e.Count), e.Count, e.Direct})
+	}
+	assert.Equal(t, []edge{
+		{"n0", "n1", 2, 2, true},
+		{"n1", "n2", 4, 1, true},
+		{"n1", "n3", 6, 1, true},
+		{"n2", "n4", 15, 1, false},
+		{"n3", "n4", 13, 1, false},
+		{"n1", "n5", 7, 1, true},
+	}, got)
+}
+
+func TestGraphWriteDOT(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, testGraph().WriteDOT(&b))
+	out := b.String()
+	assert.True(t, strings.HasPrefix(out, "digraph nexttrace {\n"))
+	assert.Contains(t, out, `n0 [label="source\n192.0.2.100", shape=ellipse];`)
+	assert.Contains(t, out, `n1 [label="10.0.0.1\ngw\nAS64500"];`)
+	assert.Contains(t, out, `n4 [label="198.51.100.1\nAS64500\n→ a.example", peripheries=2];`)
+	assert.Contains(t, out, `n0 -> n1 [label="+2.00 ms ×2", penwidth=2];`)
+	assert.Contains(t, out, `n2 -> n4 [label="+15.00 ms", style=dashed];`)
+	assert.True(t, strings.HasSuffix(out, "}\n"))
+}
+
+func TestGraphWriteMermaid(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, testGraph().WriteMermaid(&b))
+	out := b.String()
+	assert.True(t, strings.HasPrefix(out, "flowchart LR\n"))
+	assert.Contains(t, out, `  n0(["source<br/>192.0.2.100"])`)
+	assert.Contains(t, out, `  n2["10.1.0.1<br/>AS64500"]`)
+	assert.Contains(t, out, `  n5[["203.0.113.1<br/>AS64500<br/>→ b.example"]]`)
+	assert.Contains(t, out, `  n1 -->|"+4.00 ms"| n2`)
+	assert.Contains(t, out, `  n3 -.->|"+13.00 ms"| n4`)
+}
+
+func TestMermaidQuote(t *testing.T) {
+	assert.Equal(t, `"a #quot;b#quot;<br/>#lt;c#gt;"`, mermaidQuote([]string{`a "b"`, "<c>"}))
+}
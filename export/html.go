@@ -0,0 +1,244 @@
+package export
+
+import (
+	_ "embed"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nxtrace/NTrace-core/geomap"
+	"github.com/nxtrace/NTrace-core/reporter"
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+//go:embed report.html
+var reportTemplate string
+
+var reportTmpl = template.Must(template.New("report").Parse(reportTemplate))
+
+// reportRow 为跳表中的一行：同一 TTL 下的每个响应地址各占一行
+type reportRow struct {
+	TTL      int
+	First    bool
+	Span     int
+	IP       string
+	Hostname string
+	Loss     string
+	RTTs     string
+	Best     string
+	Avg      string
+	Worst    string
+	ASN      string
+	Location string
+	Owner    string
+	MPLS     []string
+}
+
+type reportData struct {
+	Title     string
+	Meta      schema.Metadata
+	Duration  string
+	Generated string
+	Rows      []reportRow
+	Path      []string
+	Chart     template.HTML
+	Map       template.HTML
+}
+
+// WriteHTML writes doc as a single self-contained HTML page: hop table, AS path summary,
+// RTT chart and an SVG map. Everything is inlined so the file works offline.
+func WriteHTML(w io.Writer, doc schema.Document) error {
+	data := reportData{
+		Title:     doc.Metadata.Target,
+		Meta:      doc.Metadata,
+		Generated: time.Now().UTC().Format(time.RFC3339),
+		Rows:      reportRows(doc),
+		Chart:     template.HTML(rttChart(doc)),
+	}
+	if data.Title == "" {
+		data.Title = doc.Metadata.DstIP
+	}
+	if doc.Metadata.DurationMs > 0 {
+		data.Duration = (time.Duration(doc.Metadata.DurationMs) * time.Millisecond).String()
+	}
+	if res, err := doc.Result(); err == nil {
+		for _, seg := range reporter.Summary(res, doc.Metadata.DstIP) {
+			data.Path = append(data.Path, seg.String())
+		}
+	}
+	var m strings.Builder
+	if err := geomap.SVG(&m, geomap.Points(doc), geomap.Options{Fit: true}); err != nil {
+		return err
+	}
+	data.Map = template.HTML(m.String())
+	return reportTmpl.Execute(w, data)
+}
+
+// WriteHTMLFile writes the report of doc to path.
+func WriteHTMLFile(path string, doc schema.Document) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteHTML(f, doc); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func reportRows(doc schema.Document) []reportRow {
+	var rows []reportRow
+	for _, hop := range doc.Hops {
+		// 按地址分组，保持首次出现的顺序
+		var order []string
+		groups := make(map[string][]schema.Attempt)
+		lost := 0
+		for _, a := range hop.Attempts {
+			if !a.Success || a.IP == "" {
+				lost++
+				continue
+			}
+			if _, ok := groups[a.IP]; !ok {
+				order = append(order, a.IP)
+			}
+			groups[a.IP] = append(groups[a.IP], a)
+		}
+		loss := ""
+		if n := len(hop.Attempts); n > 0 {
+			loss = fmt.Sprintf("%d/%d", lost, n)
+		}
+		if len(order) == 0 {
+			rows = append(rows, reportRow{TTL: hop.TTL, First: true, Span: 1, IP: "*", Loss: loss})
+			continue
+		}
+		for i, ip := range order {
+			attempts := groups[ip]
+			row := reportRow{TTL: hop.TTL, First: i == 0, Span: len(order), IP: ip, Loss: loss}
+			rtts := make([]string, 0, len(attempts))
+			best, worst, sum := math.Inf(1), 0.0, 0.0
+			for _, a := range attempts {
+				if row.Hostname == "" {
+					row.Hostname = a.Hostname
+				}
+				if row.MPLS == nil {
+					row.MPLS = a.MPLS
+				}
+				if a.Geo != nil && row.Location == "" {
+					row.ASN = strings.TrimPrefix(strings.ToUpper(a.Geo.ASN), "AS")
+					row.Location = joinNonEmpty(" ", a.Geo.Country, a.Geo.Prov, a.Geo.City)
+					row.Owner = a.Geo.Owner
+					if row.Owner == "" {
+						row.Owner = a.Geo.ISP
+					}
+				}
+				rtts = append(rtts, formatMs(a.RTT))
+				best, worst, sum = math.Min(best, a.RTT), math.Max(worst, a.RTT), sum+a.RTT
+			}
+			row.RTTs = strings.Join(rtts, " / ")
+			row.Best, row.Worst = formatMs(best), formatMs(worst)
+			row.Avg = formatMs(sum / float64(len(attempts)))
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p != "" && (len(out) == 0 || out[len(out)-1] != p) {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, sep)
+}
+
+// rttChart 绘制每跳 RTT：竖线为最小到最大值，圆点与折线为平均值
+func rttChart(doc schema.Document) string {
+	type sample struct {
+		ttl              int
+		best, avg, worst float64
+	}
+	var samples []sample
+	maxTTL, maxRTT := 0, 0.0
+	for _, hop := range doc.Hops {
+		maxTTL = max(maxTTL, hop.TTL)
+		s := sample{ttl: hop.TTL, best: math.Inf(1)}
+		n := 0
+		for _, a := range hop.Attempts {
+			if !a.Success || a.IP == "" {
+				continue
+			}
+			s.best, s.worst, s.avg = math.Min(s.best, a.RTT), math.Max(s.worst, a.RTT), s.avg+a.RTT
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		s.avg /= float64(n)
+		maxRTT = math.Max(maxRTT, s.worst)
+		samples = append(samples, s)
+	}
+	if len(samples) == 0 {
+		return ""
+	}
+
+	const (
+		width, height = 960.0, 260.0
+		left, right   = 48.0, 16.0
+		top, bottom   = 12.0, 28.0
+	)
+	step := niceStep(maxRTT / 4)
+	yMax := math.Max(step*math.Ceil(maxRTT/step), step)
+	plotW, plotH := width-left-right, height-top-bottom
+	x := func(ttl int) float64 { return left + (float64(ttl)-0.5)/float64(maxTTL)*plotW }
+	y := func(ms float64) float64 { return top + plotH - ms/yMax*plotH }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" class="nt-chart" viewBox="0 0 %.0f %.0f" font-family="sans-serif" font-size="11">`, width, height)
+	for v := 0.0; v <= yMax+step/2; v += step {
+		fmt.Fprintf(&b, `<line x1="%.1f" x2="%.1f" y1="%.1f" y2="%.1f" stroke="#e9ecef"/>`, left, width-right, y(v), y(v))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="end" fill="#868e96">%s</text>`, left-6, y(v)+4, strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" fill="#868e96">ms</text>`, 4.0, top+4)
+	for ttl := 1; ttl <= maxTTL; ttl++ {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle" fill="#868e96">%d</text>`, x(ttl), height-10, ttl)
+	}
+	b.WriteString(`<polyline fill="none" stroke="#1c7ed6" stroke-width="1.5" points="`)
+	for i, s := range samples {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%.1f,%.1f", x(s.ttl), y(s.avg))
+	}
+	b.WriteString(`"/>`)
+	for _, s := range samples {
+		fmt.Fprintf(&b, `<g class="nt-hop" data-ttl="%d"><title>%s</title>`, s.ttl,
+			html.EscapeString(fmt.Sprintf("TTL %d: %s / %s / %s ms", s.ttl, formatMs(s.best), formatMs(s.avg), formatMs(s.worst))))
+		fmt.Fprintf(&b, `<line x1="%.1f" x2="%.1f" y1="%.1f" y2="%.1f" stroke="#74c0fc" stroke-width="3"/>`, x(s.ttl), x(s.ttl), y(s.best), y(s.worst))
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3.5" fill="#1c7ed6"/></g>`, x(s.ttl), y(s.avg))
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// niceStep 将刻度间隔取整为 1、2、5 乘以 10 的幂
+func niceStep(raw float64) float64 {
+	if raw <= 0 {
+		return 1
+	}
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if raw <= m*mag {
+			return m * mag
+		}
+	}
+	return 10 * mag
+}
@@ -0,0 +1,58 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+func testReportDocument() schema.Document {
+	return schema.Document{
+		SchemaVersion: schema.Version,
+		Metadata: schema.Metadata{
+			Tool: "nexttrace", Method: "icmp", Target: "example.com", DstIP: "203.0.113.9",
+			StartedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), DurationMs: 1500,
+		},
+		Hops: []schema.Hop{
+			{TTL: 1, Attempts: []schema.Attempt{{Success: true, IP: "10.0.0.1", RTT: 1.2}, {Success: true, IP: "10.0.0.2", RTT: 1.4}}},
+			{TTL: 2, Attempts: []schema.Attempt{{Success: false, ErrorCode: schema.ErrorCodeTimeout}}},
+			{TTL: 3, Attempts: []schema.Attempt{{Success: true, IP: "202.97.1.1", RTT: 30, MPLS: []string{"Lbl 24001"},
+				Geo: &schema.Geo{ASN: "4134", Country: "中国", City: "上海", ISP: "电信", Lat: 31.2, Lng: 121.5}}}},
+			{TTL: 4, Attempts: []schema.Attempt{{Success: true, IP: "203.0.113.9", Hostname: "<b>dst</b>", RTT: 180,
+				Geo: &schema.Geo{ASN: "AS64500", Country: "United States", City: "Los Angeles", Owner: "Example", Lat: 34.05, Lng: -118.24}}}},
+		},
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteHTML(&buf, testReportDocument()))
+	out := buf.String()
+
+	// 离线报告不得引用任何外部资源
+	assert.NotContains(t, out, "src=\"http")
+	assert.NotContains(t, out, "href=\"http")
+	assert.NotContains(t, out, "<b>dst</b>")
+	assert.Contains(t, out, "&lt;b&gt;dst&lt;/b&gt;")
+
+	assert.Contains(t, out, `<td class="num" rowspan="2">1</td>`)
+	assert.Contains(t, out, `<tr data-ttl="2" class="timeout">`)
+	assert.Contains(t, out, `<span class="mpls">Lbl 24001</span>`)
+	assert.Contains(t, out, "AS4134 电信「中国『上海』」")
+	assert.Contains(t, out, "AS64500 Example「United States『Los Angeles』」")
+	assert.Equal(t, 2, strings.Count(out, `<svg xmlns="http://www.w3.org/2000/svg"`), "chart and map")
+	assert.Contains(t, out, "1.5s")
+}
+
+func TestNiceStep(t *testing.T) {
+	assert.Equal(t, 1.0, niceStep(0))
+	assert.Equal(t, 50.0, niceStep(45))
+	assert.Equal(t, 2.0, niceStep(1.3))
+	assert.Equal(t, 100.0, niceStep(100))
+}
@@ -0,0 +1,168 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+type kmlRoot struct {
+	XMLName  xml.Name    `xml:"kml"`
+	NS       string      `xml:"xmlns,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name       string         `xml:"name"`
+	Styles     []kmlStyle     `xml:"Style"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlStyle struct {
+	ID        string        `xml:"id,attr"`
+	LineStyle *kmlLineStyle `xml:"LineStyle,omitempty"`
+	IconStyle *kmlIconStyle `xml:"IconStyle,omitempty"`
+}
+
+type kmlLineStyle struct {
+	Color string `xml:"color"`
+	Width int    `xml:"width"`
+}
+
+type kmlIconStyle struct {
+	Color string  `xml:"color"`
+	Scale float64 `xml:"scale"`
+}
+
+type kmlPlacemark struct {
+	Name          string            `xml:"name"`
+	Description   string            `xml:"description,omitempty"`
+	StyleURL      string            `xml:"styleUrl,omitempty"`
+	ExtendedData  *kmlExtendedData  `xml:"ExtendedData,omitempty"`
+	Point         *kmlPoint         `xml:"Point,omitempty"`
+	MultiGeometry *kmlMultiGeometry `xml:"MultiGeometry,omitempty"`
+}
+
+type kmlExtendedData struct {
+	Data []kmlData `xml:"Data"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlMultiGeometry struct {
+	LineStrings []kmlLineString `xml:"LineString"`
+}
+
+type kmlLineString struct {
+	Tessellate  int    `xml:"tessellate"`
+	Coordinates string `xml:"coordinates"`
+}
+
+// WriteKML writes the geolocated path of doc as KML: one path placemark followed by one
+// placemark per location, carrying the same attributes as WriteGeoJSON as ExtendedData.
+func WriteKML(w io.Writer, doc schema.Document, opts GeoOptions) error {
+	path := buildGeoPath(doc, opts)
+	name := doc.Metadata.Target
+	if name == "" {
+		name = doc.Metadata.DstIP
+	}
+	root := kmlRoot{
+		NS: "http://www.opengis.net/kml/2.2",
+		Document: kmlDocument{
+			Name: "NextTrace " + name,
+			// KML 颜色格式为 aabbggrr
+			Styles: []kmlStyle{
+				{ID: "path", LineStyle: &kmlLineStyle{Color: "ff0f48d9", Width: 3}},
+				{ID: "hop", IconStyle: &kmlIconStyle{Color: "ffd67e1c", Scale: 0.8}},
+				{ID: "interpolated", IconStyle: &kmlIconStyle{Color: "80d67e1c", Scale: 0.6}},
+			},
+		},
+	}
+
+	if lines := path.lines(); len(lines) > 0 {
+		mg := &kmlMultiGeometry{}
+		for _, line := range lines {
+			coords := make([]string, len(line))
+			for i, c := range line {
+				coords[i] = kmlCoord(c[0], c[1])
+			}
+			mg.LineStrings = append(mg.LineStrings, kmlLineString{Tessellate: 1, Coordinates: strings.Join(coords, " ")})
+		}
+		pm := kmlPlacemark{Name: name, StyleURL: "#path", MultiGeometry: mg}
+		if len(path.Skipped) > 0 {
+			pm.ExtendedData = &kmlExtendedData{Data: []kmlData{{Name: "skipped_ttls", Value: joinInts(path.Skipped)}}}
+		}
+		root.Document.Placemarks = append(root.Document.Placemarks, pm)
+	}
+	for _, h := range path.Hops {
+		style := "#hop"
+		if h.Interpolated {
+			style = "#interpolated"
+		}
+		root.Document.Placemarks = append(root.Document.Placemarks, kmlPlacemark{
+			Name:         joinInts(h.TTLs),
+			Description:  strings.TrimSpace(h.IPs[0] + " " + h.Location),
+			StyleURL:     style,
+			ExtendedData: &kmlExtendedData{Data: kmlProperties(h.properties())},
+			Point:        &kmlPoint{Coordinates: kmlCoord(h.Lng, h.Lat)},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func kmlCoord(lng, lat float64) string {
+	return strconv.FormatFloat(lng, 'f', -1, 64) + "," + strconv.FormatFloat(lat, 'f', -1, 64) + ",0"
+}
+
+// kmlProperties 将属性按名称排序后转为 ExtendedData
+func kmlProperties(props map[string]any) []kmlData {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	data := make([]kmlData, 0, len(names))
+	for _, name := range names {
+		var value string
+		switch v := props[name].(type) {
+		case []int:
+			value = joinInts(v)
+		case []string:
+			value = strings.Join(v, ",")
+		default:
+			value = fmt.Sprint(v)
+		}
+		data = append(data, kmlData{Name: name, Value: value})
+	}
+	return data
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
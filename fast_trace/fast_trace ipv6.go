@@ -6,13 +6,13 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 
+	"github.com/nxtrace/NTrace-core/i18n"
 	"github.com/nxtrace/NTrace-core/ipgeo"
-	"github.com/nxtrace/NTrace-core/printer"
 	"github.com/nxtrace/NTrace-core/trace"
-	"github.com/nxtrace/NTrace-core/tracelog"
 	"github.com/nxtrace/NTrace-core/util"
 	"github.com/nxtrace/NTrace-core/wshandle"
 )
@@ -20,8 +20,9 @@ import (
 //var pFastTracer ParamsFastTrace
 
 func (f *FastTracer) tracert_v6(location string, ispCollection ISPCollection) {
-	fmt.Fprintf(color.Output, "%s\n", color.New(color.FgYellow, color.Bold).Sprintf("『%s %s 』", location, ispCollection.ISPName))
-	fmt.Printf("traceroute to %s, %d hops max, %d byte packets, %s mode\n", ispCollection.IPv6, f.ParamsFastTrace.MaxHops, f.ParamsFastTrace.PktSize, strings.ToUpper(string(f.TracerouteMethod)))
+	out := f.ParamsFastTrace.out()
+	fmt.Fprintf(out, "%s\n", color.New(color.FgYellow, color.Bold).Sprintf("『%s %s 』", location, ispCollection.ISPName))
+	fmt.Fprintf(out, "traceroute to %s, %d hops max, %d byte packets, %s mode\n", ispCollection.IPv6, f.ParamsFastTrace.MaxHops, f.ParamsFastTrace.PktSize, strings.ToUpper(string(f.TracerouteMethod)))
 
 	// ip, err := util.DomainLookUp(ispCollection.IPv6, "6", "", true)
 	ip, err := util.DomainLookUp(ispCollection.IPv6, "6", f.ParamsFastTrace.Dot, true)
@@ -49,33 +50,16 @@ func (f *FastTracer) tracert_v6(location string, ispCollection ISPCollection) {
 		Lang:             f.ParamsFastTrace.Lang,
 	}
 
-	if oe {
-		fp, err := os.OpenFile("/tmp/trace.log", os.O_CREATE|os.O_APPEND|os.O_RDWR, os.ModePerm)
-		if err != nil {
-			return
-		}
-		defer func(fp *os.File) {
-			err := fp.Close()
-			if err != nil {
-				log.Fatal(err)
-			}
-		}(fp)
-		log.SetOutput(fp)
-		log.SetFlags(0)
-		log.Printf("『%s %s 』\n", location, ispCollection.ISPName)
-		log.Printf("traceroute to %s, %d hops max, %d byte packets, %s mode\n", ispCollection.IPv6, f.ParamsFastTrace.MaxHops, f.ParamsFastTrace.PktSize, strings.ToUpper(string(f.TracerouteMethod)))
-		conf.RealtimePrinter = tracelog.RealtimePrinter
-	} else {
-		conf.RealtimePrinter = printer.RealtimePrinter
-	}
-
-	_, err = trace.Traceroute(f.TracerouteMethod, conf)
+	pr := f.ParamsFastTrace.attach(ispCollection.IPv6, f.TracerouteMethod, &conf)
+	started := time.Now()
+	res, err := trace.Traceroute(f.TracerouteMethod, conf)
 
 	if err != nil {
 		log.Fatal(err)
 	}
+	f.ParamsFastTrace.finish(pr, ispCollection.IPv6, f.TracerouteMethod, conf, started, res)
 
-	fmt.Println()
+	fmt.Fprintln(out)
 }
 
 func (f *FastTracer) testAll_v6() {
@@ -140,13 +124,13 @@ func (f *FastTracer) testFastGZ_v6() {
 	f.tracert_v6(TestIPsCollection.Guangzhou.Location, TestIPsCollection.Guangzhou.CM)
 }
 
-func FastTestv6(traceMode trace.Method, outEnable bool, paramsFastTrace ParamsFastTrace) {
+func FastTestv6(traceMode trace.Method, paramsFastTrace ParamsFastTrace) {
 	var c string
 
-	oe = outEnable
+	out := paramsFastTrace.out()
 
-	fmt.Println("您想测试哪些ISP的路由？\n1. 北京三网快速测试\n2. 上海三网快速测试\n3. 广州三网快速测试\n4. 全国电信\n5. 全国联通\n6. 全国移动\n7. 全国教育网\n8. 全国五网")
-	fmt.Print("请选择选项：")
+	fmt.Fprintln(out, i18n.T(i18n.FastTraceChooseISP))
+	fmt.Fprint(out, i18n.T(i18n.ChooseOption))
 	_, err := fmt.Scanln(&c)
 	if err != nil {
 		c = "1"
@@ -154,7 +138,7 @@ func FastTestv6(traceMode trace.Method, outEnable bool, paramsFastTrace ParamsFa
 
 	// 仅在使用 UDPv6 探测时，确保 UDP 负载长度 ≥ 2
 	if traceMode == trace.UDPTrace && paramsFastTrace.PktSize < 2 {
-		fmt.Println("UDPv6 模式下，数据包长度不能小于 2，已自动调整为 2")
+		fmt.Fprintln(out, i18n.T(i18n.UDPv6PacketSize))
 		paramsFastTrace.PktSize = 2
 	}
 
@@ -3,6 +3,7 @@ package fastTrace
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
@@ -12,8 +13,11 @@ import (
 
 	"github.com/fatih/color"
 
+	"github.com/nxtrace/NTrace-core/i18n"
 	"github.com/nxtrace/NTrace-core/ipgeo"
 	"github.com/nxtrace/NTrace-core/printer"
+	"github.com/nxtrace/NTrace-core/redact"
+	"github.com/nxtrace/NTrace-core/schema"
 	"github.com/nxtrace/NTrace-core/trace"
 	"github.com/nxtrace/NTrace-core/tracelog"
 	"github.com/nxtrace/NTrace-core/util"
@@ -41,6 +45,58 @@ type ParamsFastTrace struct {
 	Timeout        time.Duration
 	File           string
 	Dot            string
+	// Quiet 不输出逐跳结果，目标标题与菜单改写到标准错误，供 --dot/--mermaid 等需要干净标准输出的格式使用
+	Quiet bool
+	// OnResult 在每个目标追踪完成后被调用
+	OnResult func(doc schema.Document)
+	// Log 不为空时将每个目标的追踪同时写入日志
+	Log *tracelog.Options
+	// Redact 应用于全部输出，包括交给 OnResult 的文档
+	Redact redact.Policy
+}
+
+// out 为标题与菜单的输出位置
+func (p ParamsFastTrace) out() io.Writer {
+	if p.Quiet {
+		return os.Stderr
+	}
+	return color.Output
+}
+
+// attach 为一个目标安装逐跳输出（Quiet 时省略）与日志，返回的打印器在追踪结束后交给 finish
+func (p ParamsFastTrace) attach(target string, method trace.Method, conf *trace.Config) printer.Printer {
+	var names []string
+	if !p.Quiet {
+		names = append(names, printer.FormatRealtime)
+	}
+	opts := printer.Options{Lang: p.Lang, Redact: p.Redact}
+	if p.Log != nil {
+		names = append(names, printer.FormatLog)
+		opts.Log = *p.Log
+	}
+	now := time.Now()
+	opts.Metadata = schema.NewMetadata(target, method, "LeoMoeAPI", *conf, now, now)
+	pr, err := printer.NewPrinter(names, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	printer.Attach(pr, conf)
+	return pr
+}
+
+// finish 将追踪结果交给打印器与 OnResult
+func (p ParamsFastTrace) finish(pr printer.Printer, target string, method trace.Method, conf trace.Config, started time.Time, res *trace.Result) {
+	defer pr.Close()
+	if res == nil {
+		return
+	}
+	doc := schema.NewDocument(res, schema.NewMetadata(target, method, "LeoMoeAPI", conf, started, time.Now()))
+	if err := pr.OnComplete(res, doc); err != nil {
+		log.Println(err)
+	}
+	if p.OnResult != nil {
+		p.OnResult(p.Redact.For(doc.Metadata).Document(doc))
+	}
 }
 
 type IpListElement struct {
@@ -49,11 +105,10 @@ type IpListElement struct {
 	Version4 bool // true for IPv4, false for IPv6
 }
 
-var oe = false
-
 func (f *FastTracer) tracert(location string, ispCollection ISPCollection) {
-	fmt.Fprintf(color.Output, "%s\n", color.New(color.FgYellow, color.Bold).Sprintf("『%s %s 』", location, ispCollection.ISPName))
-	fmt.Printf("traceroute to %s, %d hops max, %d byte packets, %s mode\n", ispCollection.IP, f.ParamsFastTrace.MaxHops, f.ParamsFastTrace.PktSize, strings.ToUpper(string(f.TracerouteMethod)))
+	out := f.ParamsFastTrace.out()
+	fmt.Fprintf(out, "%s\n", color.New(color.FgYellow, color.Bold).Sprintf("『%s %s 』", location, ispCollection.ISPName))
+	fmt.Fprintf(out, "traceroute to %s, %d hops max, %d byte packets, %s mode\n", ispCollection.IP, f.ParamsFastTrace.MaxHops, f.ParamsFastTrace.PktSize, strings.ToUpper(string(f.TracerouteMethod)))
 
 	// ip, err := util.DomainLookUp(ispCollection.IP, "4", "", true)
 	ip, err := util.DomainLookUp(ispCollection.IP, "4", f.ParamsFastTrace.Dot, true)
@@ -81,48 +136,30 @@ func (f *FastTracer) tracert(location string, ispCollection ISPCollection) {
 		Lang:             f.ParamsFastTrace.Lang,
 	}
 
-	if oe {
-		fp, err := os.OpenFile("/tmp/trace.log", os.O_CREATE|os.O_APPEND|os.O_RDWR, os.ModePerm)
-		if err != nil {
-			return
-		}
-		defer func(fp *os.File) {
-			err := fp.Close()
-			if err != nil {
-				log.Fatal(err)
-			}
-		}(fp)
-
-		log.SetOutput(fp)
-		log.SetFlags(0)
-		log.Printf("『%s %s 』\n", location, ispCollection.ISPName)
-		log.Printf("traceroute to %s, %d hops max, %d byte packets, %s mode\n", ispCollection.IP, f.ParamsFastTrace.MaxHops, f.ParamsFastTrace.PktSize, strings.ToUpper(string(f.TracerouteMethod)))
-		conf.RealtimePrinter = tracelog.RealtimePrinter
-	} else {
-		conf.RealtimePrinter = printer.RealtimePrinter
-	}
-
-	_, err = trace.Traceroute(f.TracerouteMethod, conf)
+	pr := f.ParamsFastTrace.attach(ispCollection.IP, f.TracerouteMethod, &conf)
+	started := time.Now()
+	res, err := trace.Traceroute(f.TracerouteMethod, conf)
 
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println()
+	f.ParamsFastTrace.finish(pr, ispCollection.IP, f.TracerouteMethod, conf, started, res)
+	fmt.Fprintln(out)
 }
 
-func FastTest(traceMode trace.Method, outEnable bool, paramsFastTrace ParamsFastTrace) {
+func FastTest(traceMode trace.Method, paramsFastTrace ParamsFastTrace) {
 	// tm means tcp mode
 	var c string
-	oe = outEnable
 
 	if paramsFastTrace.File != "" {
 		testFile(paramsFastTrace, traceMode)
 		return
 	}
 
-	fmt.Println("Hi，欢迎使用 Fast Trace 功能，请注意 Fast Trace 功能只适合新手使用\n因为国内网络复杂，我们设置的测试目标有限，建议普通用户自测以获得更加精准的路由情况")
-	fmt.Println("请您选择要测试的 IP 类型\n1. IPv4\n2. IPv6")
-	fmt.Print("请选择选项：")
+	out := paramsFastTrace.out()
+	fmt.Fprintln(out, i18n.T(i18n.FastTraceWelcome))
+	fmt.Fprintln(out, i18n.T(i18n.FastTraceChooseFamily))
+	fmt.Fprint(out, i18n.T(i18n.ChooseOption))
 	_, err := fmt.Scanln(&c)
 	if err != nil {
 		c = "1"
@@ -146,7 +183,7 @@ func FastTest(traceMode trace.Method, outEnable bool, paramsFastTrace ParamsFast
 				}
 			}
 		}
-		FastTestv6(traceMode, outEnable, paramsFastTrace)
+		FastTestv6(traceMode, paramsFastTrace)
 		return
 	}
 	if paramsFastTrace.SrcDev != "" {
@@ -168,8 +205,8 @@ func FastTest(traceMode trace.Method, outEnable bool, paramsFastTrace ParamsFast
 		}
 	}
 
-	fmt.Println("您想测试哪些ISP的路由？\n1. 北京三网快速测试\n2. 上海三网快速测试\n3. 广州三网快速测试\n4. 全国电信\n5. 全国联通\n6. 全国移动\n7. 全国教育网\n8. 全国五网")
-	fmt.Print("请选择选项：")
+	fmt.Fprintln(out, i18n.T(i18n.FastTraceChooseISP))
+	fmt.Fprint(out, i18n.T(i18n.ChooseOption))
 	_, err = fmt.Scanln(&c)
 	if err != nil {
 		c = "1"
@@ -240,7 +277,7 @@ func testFile(paramsFastTrace ParamsFastTrace, traceMode trace.Method) {
 	filePath := paramsFastTrace.File
 	file, err := os.Open(filePath)
 	if err != nil {
-		fmt.Println("Error opening file:", err)
+		fmt.Println(i18n.T(i18n.FastTraceOpenFailed, err))
 		return
 	}
 	defer func(file *os.File) {
@@ -263,7 +300,7 @@ func testFile(paramsFastTrace ParamsFastTrace, traceMode trace.Method) {
 			ip = parts[0]
 			desc = ip // Set the description to the IP if no description is provided
 		} else {
-			fmt.Printf("Ignoring invalid line: %s\n", line)
+			fmt.Println(i18n.T(i18n.FastTraceInvalidLine, line))
 			continue
 		}
 
@@ -271,7 +308,7 @@ func testFile(paramsFastTrace ParamsFastTrace, traceMode trace.Method) {
 		if parsedIP == nil {
 			netIp, err := util.DomainLookUp(ip, "all", "", true)
 			if err != nil {
-				fmt.Printf("Ignoring invalid IP: %s\n", ip)
+				fmt.Println(i18n.T(i18n.FastTraceInvalidIP, ip))
 				continue
 			}
 			if len(parts) == 1 {
@@ -290,18 +327,20 @@ func testFile(paramsFastTrace ParamsFastTrace, traceMode trace.Method) {
 	}
 
 	if err := scanner.Err(); err != nil {
-		fmt.Println("Error reading file:", err)
+		fmt.Println(i18n.T(i18n.FastTraceReadFailed, err))
 	}
 
 	for _, ip := range ipList {
-		fmt.Fprintf(color.Output, "%s\n",
-			color.New(color.FgYellow, color.Bold).Sprint("『 "+ip.Desc+"』"),
-		)
-		if !util.EnableHidDstIP {
-			fmt.Printf("traceroute to %s, %d hops max, %d bytes payload, %s mode\n", ip.Ip, paramsFastTrace.MaxHops, paramsFastTrace.PktSize, strings.ToUpper(string(tracerouteMethod)))
-		} else {
-			fmt.Printf("traceroute to %s, %d hops max, %d bytes payload, %s mode\n", util.HideIPPart(ip.Ip), paramsFastTrace.MaxHops, paramsFastTrace.PktSize, strings.ToUpper(string(tracerouteMethod)))
+		out := paramsFastTrace.out()
+		shown := paramsFastTrace.Redact.For(schema.Metadata{DstIP: ip.Ip}).IP(ip.Ip)
+		desc := ip.Desc
+		if desc == ip.Ip {
+			desc = shown
 		}
+		fmt.Fprintf(out, "%s\n",
+			color.New(color.FgYellow, color.Bold).Sprint("『 "+desc+"』"),
+		)
+		fmt.Fprintf(out, "traceroute to %s, %d hops max, %d bytes payload, %s mode\n", shown, paramsFastTrace.MaxHops, paramsFastTrace.PktSize, strings.ToUpper(string(tracerouteMethod)))
 		var srcAddr string
 		if ip.Version4 {
 			if paramsFastTrace.SrcDev != "" {
@@ -363,29 +402,18 @@ func testFile(paramsFastTrace ParamsFastTrace, traceMode trace.Method) {
 			Lang:             paramsFastTrace.Lang,
 		}
 
-		if oe {
-			fp, err := os.OpenFile("/tmp/trace.log", os.O_CREATE|os.O_APPEND|os.O_RDWR, os.ModePerm)
-			if err != nil {
-				return
-			}
-			log.SetOutput(fp)
-			log.SetFlags(0)
-			log.Printf("『%s』\n", ip.Desc)
-			log.Printf("traceroute to %s, %d hops max, %d byte packets, %s mode\n", ip.Ip, paramsFastTrace.MaxHops, paramsFastTrace.PktSize, strings.ToUpper(string(tracerouteMethod)))
-			conf.RealtimePrinter = tracelog.RealtimePrinter
-			err = fp.Close()
-			if err != nil {
-				log.Fatal(err)
-			}
-		} else {
-			conf.RealtimePrinter = printer.RealtimePrinter
+		target := ip.Desc
+		if target == "" {
+			target = ip.Ip
 		}
-
-		_, err := trace.Traceroute(tracerouteMethod, conf)
+		pr := paramsFastTrace.attach(target, tracerouteMethod, &conf)
+		started := time.Now()
+		res, err := trace.Traceroute(tracerouteMethod, conf)
 		if err != nil {
 			log.Fatalln(err)
 		}
-		fmt.Println()
+		paramsFastTrace.finish(pr, target, tracerouteMethod, conf, started, res)
+		fmt.Fprintln(out)
 	}
 
 }
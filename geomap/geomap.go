@@ -0,0 +1,270 @@
+// Package geomap draws trace hops on an offline world map, without any tile server or CDN.
+package geomap
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+// 等距圆柱投影的纬度范围，去掉南极洲以节省空间
+const (
+	maxLat = 85.0
+	minLat = -60.0
+)
+
+// Point is a hop with a known location.
+type Point struct {
+	TTL   int
+	IP    string
+	Label string
+	Lat   float64
+	Lng   float64
+}
+
+// Options controls the rendered map.
+type Options struct {
+	// Width of the SVG in pixels; the height follows from the projection. Defaults to 960.
+	Width int
+	// Fit zooms the map to the hops instead of showing the whole world.
+	Fit bool
+}
+
+// Points returns one point per TTL of doc: the first attempt that has coordinates. Hops
+// without coordinates (private addresses, anycast without location, timeouts) are skipped.
+func Points(doc schema.Document) []Point {
+	var points []Point
+	for _, hop := range doc.Hops {
+		for _, a := range hop.Attempts {
+			if a.Geo == nil || (a.Geo.Lat == 0 && a.Geo.Lng == 0) {
+				continue
+			}
+			points = append(points, Point{
+				TTL:   hop.TTL,
+				IP:    a.IP,
+				Label: label(a.Geo),
+				Lat:   a.Geo.Lat,
+				Lng:   a.Geo.Lng,
+			})
+			break
+		}
+	}
+	return points
+}
+
+func label(g *schema.Geo) string {
+	parts := make([]string, 0, 4)
+	if g.ASN != "" {
+		parts = append(parts, "AS"+strings.TrimPrefix(strings.ToUpper(g.ASN), "AS"))
+	}
+	for _, s := range []string{g.Country, g.Prov, g.City} {
+		if s != "" && (len(parts) == 0 || parts[len(parts)-1] != s) {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// projection 将经纬度映射到像素坐标
+type projection struct {
+	width, height float64
+}
+
+func newProjection(width int) projection {
+	if width <= 0 {
+		width = 960
+	}
+	w := float64(width)
+	return projection{width: w, height: w * (maxLat - minLat) / 360}
+}
+
+func (p projection) xy(lng, lat float64) (float64, float64) {
+	lat = math.Max(minLat, math.Min(maxLat, lat))
+	return (lng + 180) / 360 * p.width, (maxLat - lat) / (maxLat - minLat) * p.height
+}
+
+// unwrap 让跨越 180° 经线的相邻点取较短的一侧，返回的经度可能超出 [-180, 180]
+func unwrap(points []Point) []Point {
+	out := make([]Point, len(points))
+	copy(out, points)
+	for i := 1; i < len(out); i++ {
+		for out[i].Lng-out[i-1].Lng > 180 {
+			out[i].Lng -= 360
+		}
+		for out[i].Lng-out[i-1].Lng < -180 {
+			out[i].Lng += 360
+		}
+	}
+	return out
+}
+
+// viewBox 为地图的可见区域
+type viewBox struct {
+	x, y, w, h float64
+}
+
+func (p projection) fit(points []Point) viewBox {
+	full := viewBox{0, 0, p.width, p.height}
+	if len(points) == 0 {
+		return full
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, pt := range points {
+		x, y := p.xy(pt.Lng, pt.Lat)
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	// 至少保留约 20° 经度的范围，避免单点时过度放大
+	pad := math.Max(math.Max(maxX-minX, maxY-minY)*0.15, p.width/36)
+	vb := viewBox{minX - pad, minY - pad, maxX - minX + 2*pad, maxY - minY + 2*pad}
+	// 保持与整张地图相同的宽高比
+	ratio := p.width / p.height
+	if vb.w/vb.h < ratio {
+		grow := vb.h*ratio - vb.w
+		vb.x -= grow / 2
+		vb.w += grow
+	} else {
+		grow := vb.w/ratio - vb.h
+		vb.y -= grow / 2
+		vb.h += grow
+	}
+	return vb
+}
+
+// layout 计算投影与可见区域，返回经度已展开的点
+func layout(points []Point, opts Options) (projection, viewBox, []Point) {
+	p := newProjection(opts.Width)
+	points = unwrap(points)
+	vb := viewBox{0, 0, p.width, p.height}
+	if opts.Fit {
+		vb = p.fit(points)
+	}
+	return p, vb, points
+}
+
+// landOffsets 返回需要绘制陆地的经度偏移：展开后的经度可能超出 [-180, 180]，需在左右两侧再绘制一份
+func landOffsets(p projection, vb viewBox) []float64 {
+	var offsets []float64
+	for _, offset := range wrapOffsets {
+		ox, _ := p.xy(offset-180, 0)
+		if ox+p.width <= vb.x || ox >= vb.x+vb.w {
+			continue
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets
+}
+
+// wrapOffsets 为绘制时尝试的经度平移量
+var wrapOffsets = []float64{-360, 0, 360}
+
+// shift 将所有点平移 offset 度经度
+func shift(points []Point, offset float64) []Point {
+	out := make([]Point, len(points))
+	for i, pt := range points {
+		pt.Lng += offset
+		out[i] = pt
+	}
+	return out
+}
+
+// visible 判断路径的水平范围是否与可见区域相交
+func (p projection) visible(points []Point, vb viewBox) bool {
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	for _, pt := range points {
+		x, _ := p.xy(pt.Lng, pt.Lat)
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+	}
+	return maxX >= vb.x && minX <= vb.x+vb.w
+}
+
+// SVG writes points as an SVG map: land outlines, the hop path and one numbered marker per hop.
+func SVG(w io.Writer, points []Point, opts Options) error {
+	p, vb, points := layout(points, opts)
+	// 标记与线宽随缩放比例调整，保证在输出尺寸下大小一致
+	scale := vb.w / p.width
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" class="nt-map" width="%.0f" height="%.0f" viewBox="%.2f %.2f %.2f %.2f">`,
+		p.width, p.height, vb.x, vb.y, vb.w, vb.h)
+	fmt.Fprintf(&b, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="#dbe9f6"/>`, vb.x, vb.y, vb.w, vb.h)
+
+	b.WriteString(`<g fill="#f4f1e8" stroke="#b9b4a5" stroke-linejoin="round" stroke-width="` + num(0.6*scale) + `">`)
+	for _, offset := range landOffsets(p, vb) {
+		for _, poly := range land {
+			b.WriteString(`<path d="`)
+			for i, pt := range poly {
+				x, y := p.xy(pt[0]+offset, pt[1])
+				if i == 0 {
+					b.WriteString("M" + num(x) + " " + num(y))
+				} else {
+					b.WriteString("L" + num(x) + " " + num(y))
+				}
+			}
+			b.WriteString(`Z"/>`)
+		}
+	}
+	b.WriteString(`</g>`)
+
+	// 跨越 180° 经线的路径在全图模式下会超出右侧，按可见的经度偏移各绘制一份
+	for _, offset := range wrapOffsets {
+		shifted := shift(points, offset)
+		if len(shifted) > 1 && p.visible(shifted, vb) {
+			b.WriteString(`<polyline fill="none" stroke="#d9480f" stroke-opacity="0.8" stroke-width="` + num(2*scale) + `" points="`)
+			for i, pt := range shifted {
+				x, y := p.xy(pt.Lng, pt.Lat)
+				if i > 0 {
+					b.WriteByte(' ')
+				}
+				b.WriteString(num(x) + "," + num(y))
+			}
+			b.WriteString(`"/>`)
+		}
+		for i, pt := range shifted {
+			x, y := p.xy(pt.Lng, pt.Lat)
+			if x < vb.x || x > vb.x+vb.w {
+				continue
+			}
+			fill := "#1c7ed6"
+			if i == len(shifted)-1 {
+				fill = "#2b8a3e"
+			}
+			fmt.Fprintf(&b, `<g class="nt-hop" data-ttl="%d"><title>%s</title>`, pt.TTL, html.EscapeString(fmt.Sprintf("%d  %s  %s", pt.TTL, pt.IP, pt.Label)))
+			fmt.Fprintf(&b, `<circle cx="%s" cy="%s" r="%s" fill="%s" stroke="#fff" stroke-width="%s"/>`, num(x), num(y), num(4*scale), fill, num(scale))
+			fmt.Fprintf(&b, `<text x="%s" y="%s" font-family="sans-serif" font-size="%s" fill="#212529">%d</text>`, num(x+6*scale), num(y-6*scale), num(11*scale), pt.TTL)
+			b.WriteString(`</g>`)
+		}
+	}
+	b.WriteString(`</svg>`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteFile renders points to path as PNG when the name ends in .png and as SVG otherwise.
+func WriteFile(path string, points []Point, opts Options) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	render := SVG
+	if strings.EqualFold(filepath.Ext(path), ".png") {
+		render = PNG
+	}
+	if err := render(f, points, opts); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,59 @@
+package geomap
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+func TestPoints(t *testing.T) {
+	doc := schema.Document{Hops: []schema.Hop{
+		{TTL: 1, Attempts: []schema.Attempt{{Success: true, IP: "10.0.0.1"}}},
+		{TTL: 2, Attempts: []schema.Attempt{
+			{Success: true, IP: "192.0.2.1", Geo: &schema.Geo{Country: "Anycast"}},
+			{Success: true, IP: "192.0.2.2", Geo: &schema.Geo{ASN: "13335", Country: "日本", City: "东京", Lat: 35.7, Lng: 139.7}},
+		}},
+	}}
+	points := Points(doc)
+	require.Len(t, points, 1)
+	assert.Equal(t, Point{TTL: 2, IP: "192.0.2.2", Label: "AS13335 日本 东京", Lat: 35.7, Lng: 139.7}, points[0])
+}
+
+func TestUnwrapAcrossAntimeridian(t *testing.T) {
+	points := unwrap([]Point{{Lng: 139.7}, {Lng: -122.4}, {Lng: -74}})
+	assert.InDelta(t, 237.6, points[1].Lng, 1e-9)
+	assert.InDelta(t, 286, points[2].Lng, 1e-9)
+}
+
+func TestSVG(t *testing.T) {
+	var b strings.Builder
+	points := []Point{{TTL: 3, IP: "192.0.2.1", Label: "<x>", Lat: 31.2, Lng: 121.5}, {TTL: 9, IP: "198.51.100.1", Lat: 34, Lng: -118}}
+	require.NoError(t, SVG(&b, points, Options{Width: 800, Fit: true}))
+	out := b.String()
+	assert.True(t, strings.HasPrefix(out, `<svg xmlns="http://www.w3.org/2000/svg" class="nt-map" width="800" height="322"`))
+	assert.Contains(t, out, `data-ttl="9"`)
+	assert.Contains(t, out, "&lt;x&gt;")
+	assert.Equal(t, 1, strings.Count(out, "<polyline"))
+}
+
+func TestPNG(t *testing.T) {
+	var buf bytes.Buffer
+	points := []Point{{TTL: 8, Lat: 35.7, Lng: 139.7}, {TTL: 12, Lat: 37.4, Lng: -122}}
+	require.NoError(t, PNG(&buf, points, Options{Width: 480}))
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 480, 193), img.Bounds())
+
+	// 目标点在全图模式下应绘制在美国西海岸，而不是展开后的图外位置
+	c := canvas{p: newProjection(480), vb: viewBox{0, 0, 480, 193.33333333333334}, sx: 1, sy: 1}
+	x, y := c.px(-122, 37.4)
+	r, g, b, _ := img.At(int(x), int(y)).RGBA()
+	assert.Equal(t, [3]uint32{0x2b, 0x8a, 0x3e}, [3]uint32{r >> 8, g >> 8, b >> 8})
+}
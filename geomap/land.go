@@ -0,0 +1,95 @@
+package geomap
+
+// land 为手工简化的陆地轮廓（经度, 纬度），仅用于离线底图示意，精度约为数百公里
+var land = [][][2]float64{
+	// 北美洲
+	{{-168, 66}, {-162, 70}, {-156, 71.3}, {-141, 69.6}, {-128, 70}, {-115, 68}, {-110, 68}, {-95, 68}, {-85, 69},
+		{-82, 66}, {-94, 59}, {-92, 57}, {-82, 55}, {-79, 52}, {-77, 56}, {-78, 62}, {-72, 62}, {-64, 60},
+		{-60, 55}, {-56, 52}, {-60, 47}, {-66, 44}, {-70, 42}, {-74, 40.5}, {-76, 37}, {-76, 35}, {-81, 31},
+		{-80, 25.5}, {-82, 27}, {-84, 30}, {-89, 30}, {-94, 29.5}, {-97, 27}, {-97.5, 22}, {-96, 19},
+		{-91, 18.5}, {-90, 21}, {-87, 21.5}, {-88, 16}, {-83, 15}, {-83.5, 11}, {-81, 9}, {-77.5, 8.5},
+		{-79.5, 7.5}, {-82, 8}, {-86, 12}, {-92, 14.5}, {-96, 15.7}, {-105, 19.5}, {-106, 23}, {-109.5, 23},
+		{-112, 26}, {-115, 30}, {-117, 32.5}, {-120.5, 34.5}, {-124, 40}, {-124, 46}, {-123, 49}, {-128, 51},
+		{-133, 55}, {-138, 59}, {-146, 60.5}, {-152, 59}, {-158, 57}, {-164, 55}, {-158, 58}, {-162, 60},
+		{-165, 62.5}},
+	// 加拿大北极群岛
+	{{-80, 73.5}, {-68, 70}, {-61.5, 66.5}, {-64.5, 63}, {-72, 64.5}, {-78, 64.5}, {-80.5, 70}, {-88, 70.5}},
+	{{-120, 71.5}, {-105, 73}, {-95, 75}, {-80, 76.5}, {-75, 79}, {-62, 82.5}, {-90, 81.5}, {-105, 79},
+		{-120, 76}, {-125, 73}},
+	// 格陵兰、冰岛
+	{{-73, 78}, {-60, 82}, {-30, 83.5}, {-20, 81.5}, {-18, 77}, {-22, 70.5}, {-32, 68}, {-40, 65},
+		{-43.5, 60}, {-48, 61}, {-52, 65}, {-54, 69.5}, {-58, 75.5}, {-68, 76.5}},
+	{{-22.5, 64}, {-21.5, 65.5}, {-18, 66.3}, {-14.5, 66}, {-13.6, 65}, {-18, 63.4}},
+	// 古巴
+	{{-84.9, 21.9}, {-82, 23.1}, {-77, 21.7}, {-74.2, 20.2}, {-77.7, 19.9}, {-80.5, 21.8}},
+	// 南美洲
+	{{-77, 8}, {-72, 12}, {-64, 10.5}, {-60, 8.5}, {-52, 5}, {-50, 0}, {-44, -2.5}, {-35, -5}, {-35, -9},
+		{-39, -14}, {-40, -20}, {-42, -23}, {-48, -26}, {-53, -34}, {-58, -34.5}, {-57, -38}, {-62, -39},
+		{-65, -42}, {-66, -47}, {-69, -51}, {-68.5, -55}, {-72, -54}, {-75, -50}, {-74, -44}, {-73.5, -37},
+		{-71.5, -30}, {-70, -18}, {-76, -14}, {-81, -6}, {-80, -1}, {-79, 2}, {-77.5, 6}},
+	// 非洲
+	{{-17, 21}, {-13, 27.5}, {-9.5, 30}, {-6, 35.8}, {0, 35.8}, {10, 37}, {11, 33}, {20, 30.5}, {25, 31.5},
+		{32, 31.2}, {34, 28}, {35, 24}, {37.5, 18}, {42.5, 13}, {43.5, 11.5}, {51, 12}, {51, 10.5},
+		{47.5, 4.5}, {41, -2}, {39.5, -6}, {40.5, -11}, {40.5, -15}, {35, -20}, {35.5, -24}, {32.5, -28.5},
+		{27.5, -33.5}, {20, -34.8}, {18, -32.5}, {15, -27}, {11.7, -17}, {13.5, -11}, {12, -5}, {9, -1},
+		{9.5, 4}, {4.5, 6.3}, {-2, 4.8}, {-7.5, 4.4}, {-12, 7.5}, {-15, 11}, {-17.5, 14.7}, {-16.3, 19}},
+	// 马达加斯加
+	{{49.3, -12}, {50.5, -15.5}, {47.1, -24.9}, {45.2, -25.6}, {43.3, -22}, {44.4, -16.2}, {47, -15.2}},
+	// 欧亚大陆
+	{{-9, 43}, {-9.5, 39}, {-8.8, 37}, {-5.6, 36}, {-2, 36.7}, {0, 39}, {3, 42}, {4.5, 43.4}, {8, 44},
+		{10.3, 43.9}, {12.3, 41.7}, {16, 38.2}, {17, 39}, {18.5, 40.2}, {16, 41.9}, {13.5, 43.6},
+		{12.3, 45.3}, {13.7, 45.6}, {19.5, 42}, {19.5, 40}, {22.5, 36.5}, {24, 38}, {23, 40}, {26, 40.8},
+		{26.2, 39.5}, {27, 37}, {28.5, 36.7}, {32, 36.1}, {36, 36.8}, {35.9, 34.5}, {34.2, 31.3},
+		{34.9, 29.5}, {35, 28}, {39, 22}, {42.5, 15.5}, {43.3, 12.7}, {45, 12.8}, {52, 15.5}, {55, 17},
+		{57.5, 19}, {59.8, 22.5}, {56.5, 24.5}, {56, 26.3}, {54, 24.2}, {51.5, 24.4}, {50.5, 26.2},
+		{48, 29.7}, {50, 30}, {54, 27}, {56.5, 27}, {61.5, 25.2}, {66.5, 25.4}, {68.5, 23.5}, {70.5, 21},
+		{72.8, 19}, {74, 15}, {76.5, 8.3}, {78.2, 8.9}, {80.2, 13}, {80.3, 15.8}, {82.3, 17}, {86.9, 20.8},
+		{88.5, 21.8}, {91.8, 22.4}, {92.5, 20.5}, {94.3, 16}, {97.5, 16.5}, {98.4, 12}, {98.4, 8},
+		{100.2, 6.4}, {103.5, 1.3}, {104.2, 1.5}, {103.4, 4.5}, {102, 6.2}, {100.1, 13.4}, {102.3, 12.2},
+		{104.7, 10.4}, {106.7, 9.3}, {109.3, 11.5}, {108.8, 15.5}, {106.6, 18}, {106.6, 20.2}, {108, 21.6},
+		{110.4, 21}, {111.6, 21.6}, {114.2, 22.3}, {117, 23.4}, {119.5, 25.5}, {121.5, 28}, {122, 30},
+		{121, 32}, {120.3, 34.3}, {119.2, 35}, {120.8, 36.4}, {122.5, 37}, {121, 37.8}, {119, 37.2},
+		{118, 38.5}, {117.7, 39}, {121.5, 40.8}, {124.4, 40}, {126, 37.7}, {126.5, 34.5}, {129.3, 35.3},
+		{129.5, 37}, {128.4, 38.6}, {130.7, 42.3}, {135.5, 43.8}, {140.4, 48.3}, {141.4, 52.2}, {137, 54},
+		{143, 59.3}, {150, 59.6}, {155, 60}, {156.6, 51}, {158.5, 52.9}, {162, 56}, {163.3, 58},
+		{160, 60.5}, {166, 60.4}, {173, 61}, {179, 62.5}, {180, 66}, {178, 69}, {170, 70}, {160, 70.5},
+		{150, 71.5}, {140, 72.6}, {130, 71}, {128, 72.8}, {114, 73.6}, {113, 76}, {104, 77.6}, {98, 76},
+		{88, 75.5}, {80, 73.5}, {72.5, 72.8}, {68, 68.5}, {60, 69}, {55, 68.2}, {44, 68.5}, {41, 67},
+		{33, 69.3}, {28, 71}, {24, 71}, {15, 68}, {12, 65}, {5.5, 62}, {5, 58.5}, {7, 58}, {10.5, 59.5},
+		{12, 56}, {8.6, 57.1}, {8.1, 55.5}, {8.6, 53.9}, {6, 53.4}, {4, 51.8}, {1.6, 51}, {1.4, 50},
+		{-1.3, 49.7}, {-1.9, 48.6}, {-4.7, 48.5}, {-1.2, 46}, {-1.5, 43.5}, {-4, 43.4}},
+	// 不列颠群岛
+	{{-5.7, 50}, {1.4, 51.2}, {1.7, 52.7}, {0, 53.5}, {-1.6, 55.5}, {-2, 57.6}, {-3.2, 58.6}, {-5, 58.6},
+		{-6.2, 56.6}, {-4.9, 55}, {-3, 54}, {-4.4, 53.4}, {-5.2, 51.7}, {-3, 51.3}},
+	{{-6, 52.2}, {-6.2, 53.8}, {-5.7, 54.6}, {-7.3, 55.3}, {-8.5, 54.5}, {-10, 53.5}, {-10.3, 51.8}, {-8, 51.6}},
+	// 西西里
+	{{12.4, 38}, {15.6, 38.3}, {15.1, 36.7}},
+	// 日本、库页岛、台湾、海南
+	{{129.7, 33.2}, {130.9, 31.2}, {131.9, 33}, {135, 33.6}, {136.8, 34.3}, {139, 34.8}, {140.8, 35.7},
+		{141, 38.3}, {141.9, 39.9}, {141.3, 41.4}, {144.5, 43}, {145.5, 43.3}, {141.8, 45.4}, {140.3, 41.5},
+		{140, 40.5}, {139.8, 38}, {138.5, 37.3}, {136.8, 37.3}, {135.8, 35.6}, {133, 35.5}, {131, 34.4}},
+	{{142, 46}, {143.5, 49}, {143, 54}, {142.3, 53.5}},
+	{{120.1, 23}, {120.9, 22}, {121.9, 24.8}, {121.5, 25.3}},
+	{{108.6, 19.2}, {110.5, 20.1}, {111, 19.6}, {109.6, 18.2}},
+	// 斯里兰卡
+	{{79.8, 6.2}, {80, 9.8}, {81.9, 7.2}, {80.6, 5.9}},
+	// 菲律宾
+	{{120, 18.5}, {122.2, 18.5}, {122.5, 14}, {124, 12.5}, {126.4, 7.3}, {125.4, 5.6}, {122, 7}, {123, 10},
+		{121, 13.7}, {120.3, 16}},
+	// 苏门答腊、爪哇、加里曼丹、新几内亚
+	{{95.2, 5.6}, {98.3, 4.2}, {103.8, -1}, {106, -3.2}, {105.8, -5.9}, {101.6, -3.2}, {97.5, 2.2}},
+	{{105.3, -6.8}, {106.5, -6}, {110.5, -6.9}, {114.5, -7.8}, {114.4, -8.7}, {108, -7.8}},
+	{{109, 1.5}, {109.7, -1.2}, {110.5, -3}, {114.5, -3.8}, {116.3, -3.5}, {117.5, 0.1}, {117.9, 1.9},
+		{119.2, 5.4}, {116.7, 7}, {115.4, 5}, {113, 3.2}, {111, 1.8}},
+	{{131, -1.2}, {134, -0.8}, {138, -1.6}, {144.5, -4}, {147.5, -6.1}, {150.5, -10.6}, {147, -10.2},
+		{143.3, -9.1}, {141, -9.1}, {138, -8.3}, {137.6, -5.5}, {133.6, -4.1}, {132, -2.8}},
+	// 澳大利亚、塔斯马尼亚、新西兰
+	{{113.5, -22}, {114, -26.5}, {115, -34.3}, {118, -35}, {123.5, -33.9}, {129, -31.7}, {131.5, -31.5},
+		{135, -34.7}, {138, -35.5}, {139.5, -37.5}, {141.5, -38.4}, {146.3, -39.1}, {150, -37.5},
+		{153.5, -28.5}, {153, -25}, {150.8, -22.5}, {146.3, -19}, {145.3, -15}, {142.5, -10.7},
+		{141.5, -13.5}, {140.8, -17.5}, {136.8, -15.9}, {137, -12.2}, {132.5, -11.5}, {130, -13},
+		{129.5, -15}, {126, -14}, {122, -17.5}, {121, -19.5}, {116.8, -20.6}},
+	{{144.6, -40.7}, {148.3, -40.9}, {148, -43.2}, {146, -43.6}},
+	{{172.7, -34.4}, {174.3, -35.3}, {178.5, -37.7}, {177, -39.3}, {175, -41.4}, {173.9, -39.3}, {174.6, -36.5}},
+	{{172.7, -40.5}, {174.3, -41.7}, {171.5, -44.3}, {169, -46.6}, {166.5, -46}, {168.3, -44}},
+}
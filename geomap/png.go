@@ -0,0 +1,193 @@
+package geomap
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+var (
+	oceanColor  = color.RGBA{0xdb, 0xe9, 0xf6, 0xff}
+	landColor   = color.RGBA{0xf4, 0xf1, 0xe8, 0xff}
+	coastColor  = color.RGBA{0xb9, 0xb4, 0xa5, 0xff}
+	pathColor   = color.RGBA{0xd9, 0x48, 0x0f, 0xff}
+	hopColor    = color.RGBA{0x1c, 0x7e, 0xd6, 0xff}
+	targetColor = color.RGBA{0x2b, 0x8a, 0x3e, 0xff}
+	labelColor  = color.RGBA{0x21, 0x25, 0x29, 0xff}
+)
+
+// digits 为 3x5 点阵数字，用于在 PNG 上标注 TTL（标准库不含字体）
+var digits = [10][5]string{
+	{"111", "101", "101", "101", "111"},
+	{"010", "110", "010", "010", "111"},
+	{"111", "001", "111", "100", "111"},
+	{"111", "001", "111", "001", "111"},
+	{"101", "101", "111", "001", "001"},
+	{"111", "100", "111", "001", "111"},
+	{"111", "100", "111", "101", "111"},
+	{"111", "001", "001", "001", "001"},
+	{"111", "101", "111", "101", "111"},
+	{"111", "101", "111", "001", "111"},
+}
+
+// canvas 将投影坐标映射到图片像素
+type canvas struct {
+	img    *image.RGBA
+	p      projection
+	vb     viewBox
+	sx, sy float64
+}
+
+func (c *canvas) px(lng, lat float64) (float64, float64) {
+	x, y := c.p.xy(lng, lat)
+	return (x - c.vb.x) * c.sx, (y - c.vb.y) * c.sy
+}
+
+// PNG writes the same map as SVG as a PNG image, using only the standard library.
+func PNG(w io.Writer, points []Point, opts Options) error {
+	p, vb, points := layout(points, opts)
+	width, height := int(math.Round(p.width)), int(math.Round(p.height))
+	c := &canvas{
+		img: image.NewRGBA(image.Rect(0, 0, width, height)),
+		p:   p,
+		vb:  vb,
+		sx:  float64(width) / vb.w,
+		sy:  float64(height) / vb.h,
+	}
+	c.fillRect(oceanColor)
+
+	for _, offset := range landOffsets(p, vb) {
+		for _, poly := range land {
+			pts := make([][2]float64, len(poly))
+			for i, pt := range poly {
+				pts[i][0], pts[i][1] = c.px(pt[0]+offset, pt[1])
+			}
+			c.fillPolygon(pts, landColor)
+			for i := range pts {
+				next := pts[(i+1)%len(pts)]
+				c.line(pts[i][0], pts[i][1], next[0], next[1], 0.5, coastColor)
+			}
+		}
+	}
+
+	for _, offset := range wrapOffsets {
+		shifted := shift(points, offset)
+		if !p.visible(shifted, vb) {
+			continue
+		}
+		for i := 1; i < len(shifted); i++ {
+			x0, y0 := c.px(shifted[i-1].Lng, shifted[i-1].Lat)
+			x1, y1 := c.px(shifted[i].Lng, shifted[i].Lat)
+			c.line(x0, y0, x1, y1, 1.2, pathColor)
+		}
+		for i, pt := range shifted {
+			x, y := c.px(pt.Lng, pt.Lat)
+			fill := hopColor
+			if i == len(shifted)-1 {
+				fill = targetColor
+			}
+			c.disc(x, y, 5, color.RGBA{0xff, 0xff, 0xff, 0xff})
+			c.disc(x, y, 4, fill)
+			c.text(int(x)+6, int(y)-14, strconv.Itoa(pt.TTL), labelColor)
+		}
+	}
+	return png.Encode(w, c.img)
+}
+
+func (c *canvas) fillRect(col color.RGBA) {
+	b := c.img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c.img.SetRGBA(x, y, col)
+		}
+	}
+}
+
+// fillPolygon 以扫描线（奇偶规则）填充多边形
+func (c *canvas) fillPolygon(pts [][2]float64, col color.RGBA) {
+	b := c.img.Bounds()
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, pt := range pts {
+		minY, maxY = math.Min(minY, pt[1]), math.Max(maxY, pt[1])
+	}
+	y0 := max(b.Min.Y, int(math.Floor(minY)))
+	y1 := min(b.Max.Y-1, int(math.Ceil(maxY)))
+	xs := make([]float64, 0, 8)
+	for y := y0; y <= y1; y++ {
+		cy := float64(y) + 0.5
+		xs = xs[:0]
+		for i := range pts {
+			a, bb := pts[i], pts[(i+1)%len(pts)]
+			if (a[1] <= cy) == (bb[1] <= cy) {
+				continue
+			}
+			xs = append(xs, a[0]+(cy-a[1])/(bb[1]-a[1])*(bb[0]-a[0]))
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			from := max(b.Min.X, int(math.Ceil(xs[i]-0.5)))
+			to := min(b.Max.X-1, int(math.Floor(xs[i+1]-0.5)))
+			for x := from; x <= to; x++ {
+				c.img.SetRGBA(x, y, col)
+			}
+		}
+	}
+}
+
+// line 沿线段以半径 r 的圆盘描边
+func (c *canvas) line(x0, y0, x1, y1, r float64, col color.RGBA) {
+	steps := int(math.Ceil(math.Hypot(x1-x0, y1-y0) * 2))
+	for i := 0; i <= steps; i++ {
+		t := 0.0
+		if steps > 0 {
+			t = float64(i) / float64(steps)
+		}
+		c.disc(x0+(x1-x0)*t, y0+(y1-y0)*t, r, col)
+	}
+}
+
+func (c *canvas) disc(cx, cy, r float64, col color.RGBA) {
+	b := c.img.Bounds()
+	for y := int(math.Floor(cy - r)); y <= int(math.Ceil(cy+r)); y++ {
+		for x := int(math.Floor(cx - r)); x <= int(math.Ceil(cx+r)); x++ {
+			if !(image.Point{X: x, Y: y}).In(b) {
+				continue
+			}
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			if dx*dx+dy*dy <= r*r {
+				c.img.SetRGBA(x, y, col)
+			}
+		}
+	}
+}
+
+// text 以放大两倍的点阵数字绘制 s，非数字字符被忽略
+func (c *canvas) text(x, y int, s string, col color.RGBA) {
+	const scale = 2
+	b := c.img.Bounds()
+	for _, ch := range s {
+		if ch < '0' || ch > '9' {
+			continue
+		}
+		for row, bits := range digits[ch-'0'] {
+			for colIdx, bit := range bits {
+				if bit != '1' {
+					continue
+				}
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						pt := image.Point{X: x + colIdx*scale + dx, Y: y + row*scale + dy}
+						if pt.In(b) {
+							c.img.SetRGBA(pt.X, pt.Y, col)
+						}
+					}
+				}
+			}
+		}
+		x += 4 * scale
+	}
+}
@@ -0,0 +1,333 @@
+// Package history persists trace results as daily JSON-lines segments so that past routes can be queried later.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	KindTrace = "trace"
+	KindMTR   = "mtr"
+
+	segmentPrefix = "history-"
+	segmentSuffix = ".jsonl"
+	segmentLayout = "20060102"
+
+	// DefaultRetention 为默认保留时长，0 表示永久保留
+	DefaultRetention = 30 * 24 * time.Hour
+	pruneInterval    = time.Hour
+	maxLineSize      = 16 << 20
+)
+
+// Record is one stored trace or MTR snapshot. Data holds the payload in the shape the producer emitted
+// (the web console's trace response or MTR snapshot).
+type Record struct {
+	ID           string          `json:"id"`
+	Timestamp    time.Time       `json:"timestamp"`
+	Kind         string          `json:"kind"`
+	Source       string          `json:"source"`
+	Target       string          `json:"target"`
+	ResolvedIP   string          `json:"resolved_ip"`
+	Protocol     string          `json:"protocol"`
+	DataProvider string          `json:"data_provider"`
+	HopCount     int             `json:"hop_count"`
+	Data         json.RawMessage `json:"data,omitempty"`
+	// Owner 为发起追踪的用户名，空表示无归属（命令行、监控或未启用认证）
+	Owner string `json:"owner,omitempty"`
+}
+
+// Query filters records; zero values are ignored.
+type Query struct {
+	// Target 与记录的 Target 或 ResolvedIP 比较（不区分大小写）
+	Target string
+	Since  time.Time
+	Until  time.Time
+	// Limit 保留最新的 Limit 条记录，在 Filter 之后生效
+	Limit int
+	// Filter 非 nil 时仅保留返回 true 的记录
+	Filter func(*Record) bool
+}
+
+// Store is a file-backed history store. It is safe for concurrent use within one process.
+type Store struct {
+	dir       string
+	retention time.Duration
+	mu        sync.Mutex
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// DefaultDir returns $NEXTTRACE_HISTORY_DIR or <user cache dir>/nexttrace/history.
+func DefaultDir() string {
+	if dir := strings.TrimSpace(os.Getenv("NEXTTRACE_HISTORY_DIR")); dir != "" {
+		return dir
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "nexttrace", "history")
+}
+
+// Open opens (and creates) the store in dir. A non-positive retention keeps records forever.
+func Open(dir string, retention time.Duration) (*Store, error) {
+	if dir == "" {
+		dir = DefaultDir()
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create history dir: %w", err)
+	}
+	return &Store{dir: dir, retention: retention, now: time.Now}, nil
+}
+
+// Dir returns the directory backing the store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Append writes rec to the segment of its timestamp, filling ID and Timestamp when empty.
+func (s *Store) Append(rec *Record) error {
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = s.now()
+	}
+	if rec.ID == "" {
+		rec.ID = newID()
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.segmentPath(rec.Timestamp), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if s.now().Sub(s.lastPrune) >= pruneInterval {
+		s.lastPrune = s.now()
+		s.pruneLocked()
+	}
+	return nil
+}
+
+// Query returns the records matching q ordered by timestamp, oldest first.
+func (s *Store) Query(q Query) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	target := strings.ToLower(strings.TrimSpace(q.Target))
+	var records []Record
+	for _, seg := range segments {
+		// 段按 UTC 日期命名，先按日期粗筛
+		if !q.Since.IsZero() && seg.day.Add(24*time.Hour).Before(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && seg.day.After(q.Until) {
+			continue
+		}
+		err := readSegment(seg.path, func(rec Record) {
+			if !q.Since.IsZero() && rec.Timestamp.Before(q.Since) {
+				return
+			}
+			if !q.Until.IsZero() && rec.Timestamp.After(q.Until) {
+				return
+			}
+			if target != "" && strings.ToLower(rec.Target) != target && strings.ToLower(rec.ResolvedIP) != target {
+				return
+			}
+			if q.Filter != nil && !q.Filter(&rec) {
+				return
+			}
+			records = append(records, rec)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[len(records)-q.Limit:]
+	}
+	return records, nil
+}
+
+// Get returns the record with the given id.
+func (s *Store) Get(id string) (*Record, error) {
+	if id == "" {
+		return nil, os.ErrNotExist
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	quoted := []byte(`"` + id + `"`)
+	var found *Record
+	// 新记录更常被查询，从最新的段开始找，命中即停止
+	for i := len(segments) - 1; i >= 0 && found == nil; i-- {
+		err := scanSegment(segments[i].path, func(line []byte) bool {
+			if !bytes.Contains(line, quoted) {
+				return true
+			}
+			// 先只解码 id，避免为不匹配的行复制 Data
+			var head struct {
+				ID string `json:"id"`
+			}
+			if json.Unmarshal(line, &head) != nil || head.ID != id {
+				return true
+			}
+			var rec Record
+			if json.Unmarshal(line, &rec) != nil {
+				return true
+			}
+			found = &rec
+			return false
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if found == nil {
+		return nil, os.ErrNotExist
+	}
+	return found, nil
+}
+
+type segment struct {
+	path string
+	day  time.Time
+}
+
+func (s *Store) segmentPath(t time.Time) string {
+	return filepath.Join(s.dir, segmentPrefix+t.UTC().Format(segmentLayout)+segmentSuffix)
+}
+
+func (s *Store) segments() ([]segment, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []segment
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		day, err := time.Parse(segmentLayout, strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix))
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{path: filepath.Join(s.dir, name), day: day})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].day.Before(segments[j].day) })
+	return segments, nil
+}
+
+// pruneLocked 删除超出保留期的段，调用方必须持有 s.mu
+func (s *Store) pruneLocked() {
+	if s.retention <= 0 {
+		return
+	}
+	segments, err := s.segments()
+	if err != nil {
+		return
+	}
+	cutoff := s.now().Add(-s.retention)
+	for _, seg := range segments {
+		if seg.day.Add(24 * time.Hour).Before(cutoff) {
+			_ = os.Remove(seg.path)
+		}
+	}
+}
+
+func readSegment(path string, fn func(Record)) error {
+	return scanSegment(path, func(line []byte) bool {
+		var rec Record
+		// 跳过写入中断导致的残缺行
+		if err := json.Unmarshal(line, &rec); err == nil {
+			fn(rec)
+		}
+		return true
+	})
+}
+
+// scanSegment 逐行回调段文件内容，fn 返回 false 时停止；line 仅在回调期间有效
+func scanSegment(path string, fn func(line []byte) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		if !fn(scanner.Bytes()) {
+			break
+		}
+	}
+	return scanner.Err()
+}
+
+func newID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// ParseTime parses an absolute time (RFC 3339, "2006-01-02 15:04" or "2006-01-02" in local time)
+// or a duration such as "24h" / "90m", which is interpreted as that long before now.
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339, YYYY-MM-DD[ HH:MM] or a duration like 24h", value)
+}
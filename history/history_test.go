@@ -0,0 +1,122 @@
+package history
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreAppendAndQuery(t *testing.T) {
+	store, err := Open(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	base := time.Date(2026, 10, 17, 23, 30, 0, 0, time.UTC)
+	for i, target := range []string{"example.com", "1.1.1.1", "Example.com"} {
+		rec := &Record{
+			Timestamp:  base.Add(time.Duration(i) * time.Hour),
+			Kind:       KindTrace,
+			Source:     "api",
+			Target:     target,
+			ResolvedIP: "93.184.216.34",
+			Data:       json.RawMessage(`{"hops":[]}`),
+		}
+		if i != 1 {
+			rec.Owner = "alice"
+		}
+		require.NoError(t, store.Append(rec))
+		assert.NotEmpty(t, rec.ID)
+	}
+
+	entries, err := os.ReadDir(store.Dir())
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "records are split into daily segments")
+
+	all, err := store.Query(Query{})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.True(t, all[0].Timestamp.Before(all[2].Timestamp))
+
+	byTarget, err := store.Query(Query{Target: "EXAMPLE.COM"})
+	require.NoError(t, err)
+	assert.Len(t, byTarget, 2)
+
+	byIP, err := store.Query(Query{Target: "93.184.216.34", Since: base.Add(30 * time.Minute)})
+	require.NoError(t, err)
+	assert.Len(t, byIP, 2)
+
+	window, err := store.Query(Query{Since: base.Add(30 * time.Minute), Until: base.Add(90 * time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, window, 1)
+	assert.Equal(t, "1.1.1.1", window[0].Target)
+
+	latest, err := store.Query(Query{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, latest, 1)
+	assert.Equal(t, "Example.com", latest[0].Target)
+
+	owned, err := store.Query(Query{Limit: 2, Filter: func(rec *Record) bool { return rec.Owner == "alice" }})
+	require.NoError(t, err)
+	require.Len(t, owned, 2, "limit applies after the filter")
+	assert.Equal(t, all[0].ID, owned[0].ID)
+
+	got, err := store.Get(window[0].ID)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"hops":[]}`, string(got.Data))
+
+	got, err = store.Get(all[0].ID)
+	require.NoError(t, err, "older segments are searched too")
+	assert.Equal(t, "alice", got.Owner)
+
+	_, err = store.Get("missing")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestStoreSkipsCorruptLinesAndPrunes(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, 48*time.Hour)
+	require.NoError(t, err)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	old := filepath.Join(dir, "history-20261001.jsonl")
+	require.NoError(t, os.WriteFile(old, []byte(`{"id":"old","timestamp":"2026-10-01T00:00:00Z"}`+"\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "history-20261018.jsonl"), []byte("{truncated\n"), 0o644))
+
+	require.NoError(t, store.Append(&Record{Target: "a"}))
+
+	_, err = os.Stat(old)
+	assert.True(t, os.IsNotExist(err), "segments past retention are removed")
+
+	records, err := store.Query(Query{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "a", records[0].Target)
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	got, err := ParseTime("24h", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-24*time.Hour), got)
+
+	got, err = ParseTime("2026-10-17T08:00:00Z", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC), got)
+
+	got, err = ParseTime("2026-10-17", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 17, 0, 0, 0, 0, time.Local), got)
+
+	got, err = ParseTime("", now)
+	require.NoError(t, err)
+	assert.True(t, got.IsZero())
+
+	_, err = ParseTime("yesterday", now)
+	assert.Error(t, err)
+}
@@ -0,0 +1,82 @@
+package i18n
+
+var en = map[Key]string{
+	WebListening:        "Starting the NextTrace web console on %s",
+	WebListenHint:       "For remote access, set --listen explicitly (for example --listen 0.0.0.0:1080).",
+	WebRemoteAccess:     "For remote access, try: %s",
+	WebAuthEnabled:      "Web console access control is enabled",
+	WebAuthDisabled:     "Warning: the web console has no authentication. Only use it where this is safe, or configure tokens, Basic auth, mTLS and an IP allowlist with --deploy-config",
+	QueriesClamped:      "The maximum of --queries is 255, using 255",
+	MaxAttemptsClamped:  "The maximum of --max-attempts is 255, using 255",
+	UDPv6PacketSize:     "In UDPv6 mode the payload size cannot be less than 2, using 2",
+	NoCapabilities:      "You are running NextTrace as a normal user, but it has not been granted the permissions traceroute needs, such as receiving ICMP messages on raw sockets and setting the IP header (TTL)\nAs an administrator, run `sudo setcap cap_net_raw,cap_net_admin+eip ${your_nexttrace_path}/nexttrace` to grant them, then run NextTrace again\nWhy does ping work without root? It was granted these permissions when it was installed, see `getcap /usr/bin/ping`",
+	TraceLogSaved:       "Your trace log has been saved to %s",
+	GlobalpingNoResult:  "Globalping returned no usable results, nothing to print.",
+	UnknownOutputFormat: "unknown output format %q, see --list-formats",
+	ConflictingOutputs:  "%s cannot be used together, choose one (use --output-format to combine formats)",
+	InvalidInput:        "Invalid input",
+	WinDivertReady:      "WinDivert runtime is ready.",
+	HTMLReportSaved:     "HTML report saved to %s",
+	MapSaved:            "Map saved to %s",
+	HistoryEmpty:        "No history records found in %s",
+	HistorySaveFailed:   "Failed to save the history record: %v",
+	EnrichLookupFailed:  "Some address lookups failed: %v",
+
+	ConfigCreating:          "No configuration file found, creating a default nt_config.yaml in the working directory",
+	ConfigCreateFailed:      "Failed to create the default configuration file: %v",
+	ConfigDefaultLoadFailed: "Failed to load the default configuration: %v",
+	ConfigLoadFailed:        "Failed to load the configuration file: %v",
+
+	FastTraceWelcome:      "Hi, welcome to Fast Trace. Fast Trace is meant for beginners:\nnetworks in China are complex and our test targets are limited, so trace your own targets for a more accurate picture of your routes",
+	FastTraceChooseFamily: "Which IP version do you want to test?\n1. IPv4\n2. IPv6",
+	FastTraceChooseISP:    "Which ISP routes do you want to test?\n1. Beijing: Telecom, Unicom and Mobile\n2. Shanghai: Telecom, Unicom and Mobile\n3. Guangzhou: Telecom, Unicom and Mobile\n4. China Telecom nationwide\n5. China Unicom nationwide\n6. China Mobile nationwide\n7. CERNET nationwide\n8. All five networks nationwide",
+	FastTraceOpenFailed:   "Error opening file: %v",
+	FastTraceReadFailed:   "Error reading file: %v",
+	FastTraceInvalidLine:  "Ignoring invalid line: %s",
+	FastTraceInvalidIP:    "Ignoring invalid IP: %s",
+	ChooseOption:          "Your option: ",
+	ChooseIP:              "Please choose the IP you want to trace",
+	InvalidOption:         "Your option is invalid",
+
+	DNSFailed:           "DNS resolution failed, please check your system DNS settings",
+	APITimeout:          "IP connection has timed out (5s), please check your network",
+	PreferredAPI:        "preferred API IP",
+	Interrupted:         "Program interrupted by user",
+	ProxyParseFailed:    "Failed to parse proxy URL: %v",
+	ProviderTimeout:     "%s request timed out (2s), please switch to another API",
+	ProviderRateLimited: "API rate limit exceeded",
+
+	GeoProvider:            "IP Geo Data Provider: %s",
+	TableHop:               "Hop",
+	TableLatency:           "Latency",
+	TableLocation:          "Location",
+	TableOwner:             "Owner",
+	RouteTable:             "Routing table",
+	MapTraceURL:            "MapTrace URL:",
+	RoutePathLab:           "Route-Path (experimental)",
+	RouteBorder:            "Border: %s → %s at hops %d → %d (AS%s → AS%s)",
+	RouteIXP:               "IXP: %s at hop %d (AS%s → AS%s)",
+	RouteDetour:            "Detour: leaves %s after hop %d via %s and returns at hop %d",
+	TableNote:              "Note",
+	AnomalyJump:            "RTT +%.0f ms over hop %d: if the following hops stay this high, the delay is real (a long link or congestion)",
+	AnomalyInversion:       "RTT is lower than at hop %d by %.0f ms: hop %d deprioritizes ICMP replies and only looks slow, this is not congestion",
+	AnomalyImpossible:      "hop %d is %.0f km away, which takes at least %.0f ms, yet the RTT is %.0f ms: the geolocation of this hop is wrong",
+	AnomalyJumpShort:       "RTT jump +%.0f ms",
+	AnomalyInversionShort:  "hop %d: ICMP slow path",
+	AnomalyImpossibleShort: "wrong geolocation",
+
+	ClientNotAllowed: "client address not allowed",
+	AuthRequired:     "authentication required",
+	PermissionDenied: "permission denied",
+	InvalidRequest:   "invalid request",
+	InvalidPayload:   "invalid request payload",
+	InvalidSource:    "invalid source %q",
+	InvalidLimit:     "invalid limit parameter",
+	HistoryDisabled:  "history is disabled",
+	RecordNotFound:   "record not found",
+	JobsSingleOnly:   "jobs only support single trace mode",
+	JobNotFound:      "job not found",
+	JobNoResult:      "job has no result",
+	EncodeFailed:     "failed to encode result",
+	RenderMapFailed:  "failed to render map",
+}
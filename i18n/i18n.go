@@ -0,0 +1,148 @@
+// Package i18n holds the translated messages shown by the CLI, the printers, the reporter and
+// the server. The language of these messages is independent of the language of the geo data,
+// which is chosen per trace with trace.Config.Lang.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Supported languages.
+const (
+	English  = "en"
+	Chinese  = "zh"
+	Japanese = "ja"
+	Russian  = "ru"
+)
+
+// Languages lists the supported languages.
+var Languages = []string{English, Chinese, Japanese, Russian}
+
+// Default is the language used when the environment names no supported language.
+const Default = Chinese
+
+// Key identifies a message in the catalogs.
+type Key string
+
+var catalogs = map[string]map[Key]string{
+	English:  en,
+	Chinese:  zh,
+	Japanese: ja,
+	Russian:  ru,
+}
+
+var current atomic.Value
+
+func init() {
+	current.Store(Detect(os.Getenv))
+}
+
+// Set selects the language of T. It accepts the forms understood by Normalize.
+func Set(lang string) error {
+	l := Normalize(lang)
+	if l == "" {
+		return fmt.Errorf("unsupported language %q, choose one of %s", lang, strings.Join(Languages, ", "))
+	}
+	current.Store(l)
+	return nil
+}
+
+// Current returns the language of T.
+func Current() string { return current.Load().(string) }
+
+// T returns the message key in the current language, formatted with args.
+func T(key Key, args ...any) string { return In(Current(), key, args...) }
+
+// In returns the message key in lang, formatted with args. Messages missing from a catalog
+// fall back to English.
+func In(lang string, key Key, args ...any) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		if msg, ok = en[key]; !ok {
+			msg = string(key)
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Normalize maps a language tag or locale name such as "ja", "ru-RU", "zh_CN.UTF-8" or "cn" to
+// a supported language, or returns "" when it names none.
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	// 去掉编码与修饰符，例如 zh_CN.UTF-8、sr_RS@latin
+	if i := strings.IndexAny(tag, ".@"); i >= 0 {
+		tag = tag[:i]
+	}
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "cn" {
+		tag = Chinese
+	}
+	if _, ok := catalogs[tag]; ok {
+		return tag
+	}
+	return ""
+}
+
+// Detect chooses the language from the environment: NEXTTRACE_LANG, then the LANGUAGE
+// priority list, then the first of LC_ALL, LC_MESSAGES and LANG that is set.
+func Detect(getenv func(string) string) string {
+	if l := Normalize(getenv("NEXTTRACE_LANG")); l != "" {
+		return l
+	}
+	for _, tag := range strings.Split(getenv("LANGUAGE"), ":") {
+		if l := Normalize(tag); l != "" {
+			return l
+		}
+	}
+	// 与 setlocale 相同，第一个设置了的变量决定语言；C 或 POSIX 等不受支持的取值使用默认语言
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := getenv(name); v != "" {
+			if l := Normalize(v); l != "" {
+				return l
+			}
+			break
+		}
+	}
+	return Default
+}
+
+// FromAcceptLanguage returns the supported language the HTTP Accept-Language header prefers,
+// or fallback when it names none.
+func FromAcceptLanguage(header, fallback string) string {
+	type choice struct {
+		lang string
+		q    float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		l := Normalize(tag)
+		if l == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			choices = append(choices, choice{l, q})
+		}
+	}
+	if len(choices) == 0 {
+		return fallback
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	return choices[0].lang
+}
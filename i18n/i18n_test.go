@@ -0,0 +1,80 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var verbs = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+func TestCatalogsComplete(t *testing.T) {
+	for _, lang := range Languages {
+		catalog := catalogs[lang]
+		require.NotNil(t, catalog, lang)
+		assert.Len(t, catalog, len(en), lang)
+		for key, msg := range en {
+			translated, ok := catalog[key]
+			if !assert.True(t, ok, "%s is missing %s", lang, key) {
+				continue
+			}
+			// 译文的格式化动词须与英文一致，否则参数会错位
+			assert.Equal(t, verbs.FindAllString(msg, -1), verbs.FindAllString(translated, -1), "%s %s", lang, key)
+		}
+	}
+}
+
+func TestIn(t *testing.T) {
+	assert.Equal(t, "Map saved to /tmp/a.svg", In(English, MapSaved, "/tmp/a.svg"))
+	assert.Equal(t, "地图已保存至 /tmp/a.svg", In(Chinese, MapSaved, "/tmp/a.svg"))
+	assert.Equal(t, en[JobNotFound], In("xx", JobNotFound))
+	assert.Equal(t, "no.such.key", In(Russian, Key("no.such.key")))
+
+	old := Current()
+	defer func() { require.NoError(t, Set(old)) }()
+	require.NoError(t, Set("ja_JP.UTF-8"))
+	assert.Equal(t, ja[JobNotFound], T(JobNotFound))
+	assert.Error(t, Set("fr"))
+	assert.Equal(t, Japanese, Current())
+}
+
+func TestNormalize(t *testing.T) {
+	for tag, want := range map[string]string{
+		"en":          English,
+		"en_US.UTF-8": English,
+		"zh-Hans-CN":  Chinese,
+		"cn":          Chinese,
+		"ZH_tw":       Chinese,
+		"ru_RU@euro":  Russian,
+		"ja":          Japanese,
+		"C":           "",
+		"POSIX":       "",
+		"fr_FR":       "",
+		"":            "",
+	} {
+		assert.Equal(t, want, Normalize(tag), tag)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	assert.Equal(t, Default, Detect(env(nil)))
+	assert.Equal(t, Russian, Detect(env(map[string]string{"LANG": "ru_RU.UTF-8"})))
+	assert.Equal(t, Japanese, Detect(env(map[string]string{"LC_MESSAGES": "ja_JP.UTF-8", "LANG": "ru_RU.UTF-8"})))
+	// LC_ALL=C 覆盖 LANG
+	assert.Equal(t, Default, Detect(env(map[string]string{"LC_ALL": "C", "LANG": "ru_RU.UTF-8"})))
+	assert.Equal(t, English, Detect(env(map[string]string{"LANGUAGE": "fr:en", "LC_ALL": "ja_JP.UTF-8"})))
+	assert.Equal(t, Japanese, Detect(env(map[string]string{"NEXTTRACE_LANG": "ja", "LANGUAGE": "en"})))
+}
+
+func TestFromAcceptLanguage(t *testing.T) {
+	assert.Equal(t, English, FromAcceptLanguage("", English))
+	assert.Equal(t, English, FromAcceptLanguage("fr-FR,de;q=0.8", English))
+	assert.Equal(t, Russian, FromAcceptLanguage("fr-FR, ru;q=0.9, en;q=0.8", English))
+	assert.Equal(t, Chinese, FromAcceptLanguage("en;q=0.5, zh-CN", English))
+	assert.Equal(t, English, FromAcceptLanguage("ja;q=0, en", Chinese))
+}
@@ -0,0 +1,82 @@
+package i18n
+
+var ja = map[Key]string{
+	WebListening:        "NextTrace Web コンソールを起動しました。待ち受けアドレス: %s",
+	WebListenHint:       "リモートからアクセスするには --listen を明示的に指定してください（例: --listen 0.0.0.0:1080）。",
+	WebRemoteAccess:     "リモートからは次のアドレスを試してください: %s",
+	WebAuthEnabled:      "Web コンソールのアクセス制御が有効です",
+	WebAuthDisabled:     "注意: Web コンソールで認証が有効になっていません。安全な環境でのみ使用するか、--deploy-config でトークン、Basic 認証、mTLS、IP 許可リストを設定してください",
+	QueriesClamped:      "--queries の上限は 255 のため、255 に調整しました",
+	MaxAttemptsClamped:  "--max-attempts の上限は 255 のため、255 に調整しました",
+	UDPv6PacketSize:     "UDPv6 モードではペイロード長を 2 未満にできないため、2 に調整しました",
+	NoCapabilities:      "NextTrace を一般ユーザー権限で実行していますが、raw ソケットでの ICMP メッセージの受信や IP ヘッダー（TTL）の変更など、経路追跡に必要な権限が付与されていません\n管理者ユーザーで `sudo setcap cap_net_raw,cap_net_admin+eip ${your_nexttrace_path}/nexttrace` を実行して権限を付与してから、もう一度実行してください\nping が root 権限なしで動くのは、インストール時に必要な権限が付与されているためです。`getcap /usr/bin/ping` で確認できます",
+	TraceLogSaved:       "トレースログを %s に保存しました",
+	GlobalpingNoResult:  "Globalping から利用できる結果が返されなかったため、出力をスキップしました。",
+	UnknownOutputFormat: "不明な出力形式 %q です。--list-formats を参照してください",
+	ConflictingOutputs:  "%s は同時に指定できません。いずれか一つを選んでください（複数の形式を組み合わせるには --output-format を使用してください）",
+	InvalidInput:        "入力が無効です",
+	WinDivertReady:      "WinDivert ランタイムの準備ができました。",
+	HTMLReportSaved:     "HTML レポートを %s に保存しました",
+	MapSaved:            "地図を %s に保存しました",
+	HistoryEmpty:        "%s に履歴がありません",
+	HistorySaveFailed:   "履歴の保存に失敗しました: %v",
+	EnrichLookupFailed:  "一部のアドレスの照会に失敗しました: %v",
+
+	ConfigCreating:          "設定ファイルが見つからないため、作業ディレクトリにデフォルトの nt_config.yaml を作成します",
+	ConfigCreateFailed:      "デフォルト設定ファイルの作成に失敗しました: %v",
+	ConfigDefaultLoadFailed: "デフォルト設定の読み込みに失敗しました: %v",
+	ConfigLoadFailed:        "設定ファイルの読み込みに失敗しました: %v",
+
+	FastTraceWelcome:      "Fast Trace へようこそ。Fast Trace は初心者向けの機能です\n中国国内のネットワークは複雑で、用意したテスト先も限られているため、より正確な経路を知るにはご自身で対象を指定して追跡することをお勧めします",
+	FastTraceChooseFamily: "テストする IP の種類を選択してください\n1. IPv4\n2. IPv6",
+	FastTraceChooseISP:    "どの ISP への経路をテストしますか？\n1. 北京（電信・聯通・移動）\n2. 上海（電信・聯通・移動）\n3. 広州（電信・聯通・移動）\n4. 中国電信（全国）\n5. 中国聯通（全国）\n6. 中国移動（全国）\n7. CERNET（全国）\n8. 全国の 5 ネットワーク",
+	FastTraceOpenFailed:   "ファイルを開けませんでした: %v",
+	FastTraceReadFailed:   "ファイルの読み込みに失敗しました: %v",
+	FastTraceInvalidLine:  "無効な行を無視します: %s",
+	FastTraceInvalidIP:    "無効な IP を無視します: %s",
+	ChooseOption:          "番号を選択してください: ",
+	ChooseIP:              "追跡する IP を選択してください",
+	InvalidOption:         "選択が無効です",
+
+	DNSFailed:           "DNS の名前解決に失敗しました。システムの DNS 設定を確認してください",
+	APITimeout:          "IP 接続がタイムアウトしました（5 秒）。ネットワークを確認してください",
+	PreferredAPI:        "優先 API IP",
+	Interrupted:         "ユーザーによって中断されました",
+	ProxyParseFailed:    "プロキシ URL の解析に失敗しました: %v",
+	ProviderTimeout:     "%s へのリクエストがタイムアウトしました（2 秒）。別の API に切り替えてください",
+	ProviderRateLimited: "API の利用上限を超えました",
+
+	GeoProvider:            "IP 地理情報の提供元: %s",
+	TableHop:               "ホップ",
+	TableLatency:           "遅延",
+	TableLocation:          "場所",
+	TableOwner:             "所有者",
+	RouteTable:             "ルーティングテーブル",
+	MapTraceURL:            "MapTrace 地図:",
+	RoutePathLab:           "Route-Path（実験的機能）",
+	RouteBorder:            "国境越え：%s → %s、%d → %d ホップ目（AS%s → AS%s）",
+	RouteIXP:               "IXP：%s、%d ホップ目（AS%s → AS%s）",
+	RouteDetour:            "迂回：%s から %d ホップ目の後に %s を経由し、%d ホップ目で戻る",
+	TableNote:              "注記",
+	AnomalyJump:            "RTT が %.0f ms 増加（%d ホップ目比）：以降のホップもこの遅延が続くなら実際の遅延です（長距離リンクまたは輻輳）",
+	AnomalyInversion:       "%d ホップ目より RTT が %.0f ms 低い：%d ホップ目は ICMP 応答の優先度を下げていて遅く見えるだけで、輻輳ではありません",
+	AnomalyImpossible:      "%d ホップ目から %.0f km 離れており最低 %.0f ms かかるはずが、RTT は %.0f ms：このホップの位置情報は誤りです",
+	AnomalyJumpShort:       "RTT 急増 +%.0f ms",
+	AnomalyInversionShort:  "%d ホップ目: ICMP 低速パス",
+	AnomalyImpossibleShort: "位置情報の誤り",
+
+	ClientNotAllowed: "クライアントのアドレスは許可されていません",
+	AuthRequired:     "認証が必要です",
+	PermissionDenied: "権限がありません",
+	InvalidRequest:   "リクエストが無効です",
+	InvalidPayload:   "リクエストの内容が無効です",
+	InvalidSource:    "無効なソース %q",
+	InvalidLimit:     "limit パラメーターが無効です",
+	HistoryDisabled:  "履歴は無効になっています",
+	RecordNotFound:   "記録が見つかりません",
+	JobsSingleOnly:   "ジョブは単発トレースのみ対応しています",
+	JobNotFound:      "ジョブが見つかりません",
+	JobNoResult:      "ジョブに結果がありません",
+	EncodeFailed:     "結果のエンコードに失敗しました",
+	RenderMapFailed:  "地図の描画に失敗しました",
+}
@@ -0,0 +1,102 @@
+package i18n
+
+// 命令行
+const (
+	WebListening        Key = "cmd.web_listening"
+	WebListenHint       Key = "cmd.web_listen_hint"
+	WebRemoteAccess     Key = "cmd.web_remote_access"
+	WebAuthEnabled      Key = "cmd.web_auth_enabled"
+	WebAuthDisabled     Key = "cmd.web_auth_disabled"
+	QueriesClamped      Key = "cmd.queries_clamped"
+	MaxAttemptsClamped  Key = "cmd.max_attempts_clamped"
+	UDPv6PacketSize     Key = "cmd.udpv6_packet_size"
+	NoCapabilities      Key = "cmd.no_capabilities"
+	TraceLogSaved       Key = "cmd.trace_log_saved"
+	GlobalpingNoResult  Key = "cmd.globalping_no_result"
+	UnknownOutputFormat Key = "cmd.unknown_output_format"
+	ConflictingOutputs  Key = "cmd.conflicting_outputs"
+	InvalidInput        Key = "cmd.invalid_input"
+	WinDivertReady      Key = "cmd.windivert_ready"
+	HTMLReportSaved     Key = "cmd.html_report_saved"
+	MapSaved            Key = "cmd.map_saved"
+	HistoryEmpty        Key = "cmd.history_empty"
+	HistorySaveFailed   Key = "cmd.history_save_failed"
+	EnrichLookupFailed  Key = "cmd.enrich_lookup_failed"
+)
+
+// 配置文件
+const (
+	ConfigCreating          Key = "config.creating"
+	ConfigCreateFailed      Key = "config.create_failed"
+	ConfigDefaultLoadFailed Key = "config.default_load_failed"
+	ConfigLoadFailed        Key = "config.load_failed"
+)
+
+// Fast Trace 与交互式选择
+const (
+	FastTraceWelcome      Key = "fasttrace.welcome"
+	FastTraceChooseFamily Key = "fasttrace.choose_family"
+	FastTraceChooseISP    Key = "fasttrace.choose_isp"
+	FastTraceOpenFailed   Key = "fasttrace.open_failed"
+	FastTraceReadFailed   Key = "fasttrace.read_failed"
+	FastTraceInvalidLine  Key = "fasttrace.invalid_line"
+	FastTraceInvalidIP    Key = "fasttrace.invalid_ip"
+	ChooseOption          Key = "prompt.choose_option"
+	ChooseIP              Key = "prompt.choose_ip"
+	InvalidOption         Key = "prompt.invalid_option"
+)
+
+// 网络与数据源
+const (
+	DNSFailed           Key = "net.dns_failed"
+	APITimeout          Key = "net.api_timeout"
+	PreferredAPI        Key = "net.preferred_api"
+	Interrupted         Key = "net.interrupted"
+	ProxyParseFailed    Key = "net.proxy_parse_failed"
+	ProviderTimeout     Key = "geo.provider_timeout"
+	ProviderRateLimited Key = "geo.provider_rate_limited"
+)
+
+// 打印器与报告
+const (
+	GeoProvider   Key = "printer.geo_provider"
+	TableHop      Key = "printer.table_hop"
+	TableLatency  Key = "printer.table_latency"
+	TableLocation Key = "printer.table_location"
+	TableOwner    Key = "printer.table_owner"
+	RouteTable    Key = "printer.route_table"
+	MapTraceURL   Key = "printer.maptrace_url"
+	RoutePathLab  Key = "reporter.route_path_lab"
+	RouteBorder   Key = "reporter.border"
+	RouteIXP      Key = "reporter.ixp"
+	RouteDetour   Key = "reporter.detour"
+	TableNote     Key = "printer.table_note"
+)
+
+// 延迟异常
+const (
+	AnomalyJump            Key = "anomaly.jump"
+	AnomalyInversion       Key = "anomaly.inversion"
+	AnomalyImpossible      Key = "anomaly.impossible"
+	AnomalyJumpShort       Key = "anomaly.jump_short"
+	AnomalyInversionShort  Key = "anomaly.inversion_short"
+	AnomalyImpossibleShort Key = "anomaly.impossible_short"
+)
+
+// Web 控制台接口
+const (
+	ClientNotAllowed Key = "server.client_not_allowed"
+	AuthRequired     Key = "server.auth_required"
+	PermissionDenied Key = "server.permission_denied"
+	InvalidRequest   Key = "server.invalid_request"
+	InvalidPayload   Key = "server.invalid_payload"
+	InvalidSource    Key = "server.invalid_source"
+	InvalidLimit     Key = "server.invalid_limit"
+	HistoryDisabled  Key = "server.history_disabled"
+	RecordNotFound   Key = "server.record_not_found"
+	JobsSingleOnly   Key = "server.jobs_single_only"
+	JobNotFound      Key = "server.job_not_found"
+	JobNoResult      Key = "server.job_no_result"
+	EncodeFailed     Key = "server.encode_failed"
+	RenderMapFailed  Key = "server.render_map_failed"
+)
@@ -0,0 +1,82 @@
+package i18n
+
+var ru = map[Key]string{
+	WebListening:        "Веб-консоль NextTrace запущена, адрес: %s",
+	WebListenHint:       "Для удалённого доступа явно укажите --listen (например, --listen 0.0.0.0:1080).",
+	WebRemoteAccess:     "Для удалённого доступа попробуйте: %s",
+	WebAuthEnabled:      "Контроль доступа к веб-консоли включён",
+	WebAuthDisabled:     "Внимание: аутентификация в веб-консоли не включена. Используйте её только в безопасной среде или настройте токены, Basic-аутентификацию, mTLS и список разрешённых IP через --deploy-config",
+	QueriesClamped:      "Максимальное значение --queries равно 255, используется 255",
+	MaxAttemptsClamped:  "Максимальное значение --max-attempts равно 255, используется 255",
+	UDPv6PacketSize:     "В режиме UDPv6 размер полезной нагрузки не может быть меньше 2, используется 2",
+	NoCapabilities:      "NextTrace запущен от имени обычного пользователя, но ему не выданы права, необходимые для трассировки: приём ICMP-сообщений через raw-сокеты и изменение заголовка IP (TTL)\nВыполните от имени администратора `sudo setcap cap_net_raw,cap_net_admin+eip ${your_nexttrace_path}/nexttrace`, чтобы выдать их, и запустите NextTrace снова\nПочему ping работает без root? Эти права были выданы ему при установке, см. `getcap /usr/bin/ping`",
+	TraceLogSaved:       "Журнал трассировки сохранён в %s",
+	GlobalpingNoResult:  "Globalping не вернул пригодных результатов, вывод пропущен.",
+	UnknownOutputFormat: "неизвестный формат вывода %q, см. --list-formats",
+	ConflictingOutputs:  "%s нельзя использовать вместе, выберите один (несколько форматов сразу задаются через --output-format)",
+	InvalidInput:        "Неверный ввод",
+	WinDivertReady:      "Среда WinDivert готова.",
+	HTMLReportSaved:     "HTML-отчёт сохранён в %s",
+	MapSaved:            "Карта сохранена в %s",
+	HistoryEmpty:        "В %s нет записей истории",
+	HistorySaveFailed:   "Не удалось сохранить запись истории: %v",
+	EnrichLookupFailed:  "Не удалось запросить данные для некоторых адресов: %v",
+
+	ConfigCreating:          "Файл конфигурации не найден, в рабочем каталоге будет создан nt_config.yaml по умолчанию",
+	ConfigCreateFailed:      "Не удалось создать файл конфигурации по умолчанию: %v",
+	ConfigDefaultLoadFailed: "Не удалось загрузить конфигурацию по умолчанию: %v",
+	ConfigLoadFailed:        "Не удалось загрузить файл конфигурации: %v",
+
+	FastTraceWelcome:      "Добро пожаловать в Fast Trace. Этот режим предназначен для новичков:\nсети в Китае устроены сложно, а набор тестовых узлов ограничен, поэтому для точной картины маршрутов лучше трассировать собственные цели",
+	FastTraceChooseFamily: "Выберите версию IP для проверки\n1. IPv4\n2. IPv6",
+	FastTraceChooseISP:    "Маршруты к каким провайдерам проверить?\n1. Пекин: Telecom, Unicom и Mobile\n2. Шанхай: Telecom, Unicom и Mobile\n3. Гуанчжоу: Telecom, Unicom и Mobile\n4. China Telecom по всей стране\n5. China Unicom по всей стране\n6. China Mobile по всей стране\n7. CERNET по всей стране\n8. Все пять сетей по всей стране",
+	FastTraceOpenFailed:   "Не удалось открыть файл: %v",
+	FastTraceReadFailed:   "Не удалось прочитать файл: %v",
+	FastTraceInvalidLine:  "Пропуск неверной строки: %s",
+	FastTraceInvalidIP:    "Пропуск неверного IP: %s",
+	ChooseOption:          "Ваш выбор: ",
+	ChooseIP:              "Выберите IP для трассировки",
+	InvalidOption:         "Неверный выбор",
+
+	DNSFailed:           "Ошибка разрешения DNS, проверьте системные настройки DNS",
+	APITimeout:          "Истекло время ожидания подключения (5 с), проверьте сеть",
+	PreferredAPI:        "выбранный IP API",
+	Interrupted:         "Программа прервана пользователем",
+	ProxyParseFailed:    "Не удалось разобрать адрес прокси: %v",
+	ProviderTimeout:     "Истекло время ожидания запроса к %s (2 с), выберите другой API",
+	ProviderRateLimited: "Превышен лимит запросов к API",
+
+	GeoProvider:            "Источник геоданных IP: %s",
+	TableHop:               "Прыжок",
+	TableLatency:           "Задержка",
+	TableLocation:          "Местоположение",
+	TableOwner:             "Владелец",
+	RouteTable:             "Таблица маршрутизации",
+	MapTraceURL:            "Карта MapTrace:",
+	RoutePathLab:           "Route-Path (экспериментально)",
+	RouteBorder:            "Пересечение границы: %s → %s, хопы %d → %d (AS%s → AS%s)",
+	RouteIXP:               "IXP: %s, хоп %d (AS%s → AS%s)",
+	RouteDetour:            "Крюк: путь покидает %s после хопа %d через %s и возвращается на хопе %d",
+	TableNote:              "Примечание",
+	AnomalyJump:            "RTT вырос на %.0f мс относительно хопа %d: если следующие хопы держат эту задержку, она реальна (длинный канал или перегрузка)",
+	AnomalyInversion:       "RTT ниже, чем на хопе %d, на %.0f мс: хоп %d отвечает на ICMP с низким приоритетом и лишь выглядит медленным, это не перегрузка",
+	AnomalyImpossible:      "до хопа %d %.0f км, это не меньше %.0f мс, а RTT всего %.0f мс: геолокация этого хопа неверна",
+	AnomalyJumpShort:       "скачок RTT +%.0f мс",
+	AnomalyInversionShort:  "хоп %d: медленная обработка ICMP",
+	AnomalyImpossibleShort: "неверная геолокация",
+
+	ClientNotAllowed: "адрес клиента не разрешён",
+	AuthRequired:     "требуется аутентификация",
+	PermissionDenied: "доступ запрещён",
+	InvalidRequest:   "неверный запрос",
+	InvalidPayload:   "неверное содержимое запроса",
+	InvalidSource:    "неверный источник %q",
+	InvalidLimit:     "неверный параметр limit",
+	HistoryDisabled:  "история отключена",
+	RecordNotFound:   "запись не найдена",
+	JobsSingleOnly:   "задания поддерживают только одиночную трассировку",
+	JobNotFound:      "задание не найдено",
+	JobNoResult:      "у задания нет результата",
+	EncodeFailed:     "не удалось закодировать результат",
+	RenderMapFailed:  "не удалось построить карту",
+}
@@ -0,0 +1,82 @@
+package i18n
+
+var zh = map[Key]string{
+	WebListening:        "启动 NextTrace Web 控制台，监听地址: %s",
+	WebListenHint:       "远程访问请显式设置 --listen（例如 --listen 0.0.0.0:1080）。",
+	WebRemoteAccess:     "如需远程访问，请尝试: %s",
+	WebAuthEnabled:      "已启用 Web 控制台访问控制",
+	WebAuthDisabled:     "注意：Web 控制台未启用认证，请在确保安全的前提下使用，或通过 --deploy-config 配置令牌、Basic 认证、mTLS 与 IP 白名单",
+	QueriesClamped:      "Query 最大值为 255，已自动调整为 255",
+	MaxAttemptsClamped:  "MaxAttempt 最大值为 255，已自动调整为 255",
+	UDPv6PacketSize:     "UDPv6 模式下，数据包长度不能小于 2，已自动调整为 2",
+	NoCapabilities:      "您正在以普通用户权限运行 NextTrace，但 NextTrace 未被赋予监听网络套接字的ICMP消息包、修改IP头信息（TTL）等路由跟踪所需的权限\n请使用管理员用户执行 `sudo setcap cap_net_raw,cap_net_admin+eip ${your_nexttrace_path}/nexttrace` 命令，赋予相关权限后再运行~\n什么？为什么 ping 普通用户执行不要 root 权限？因为这些工具在管理员安装时就已经被赋予了一些必要的权限，具体请使用 `getcap /usr/bin/ping` 查看",
+	TraceLogSaved:       "您的追踪日志已经存放在 %s 中",
+	GlobalpingNoResult:  "Globalping 未返回可用的探测结果，已跳过输出。",
+	UnknownOutputFormat: "未知的输出格式 %q，请参阅 --list-formats",
+	ConflictingOutputs:  "%s 不能同时使用，请只选择其一（如需同时输出多种格式，请使用 --output-format）",
+	InvalidInput:        "输入无效",
+	WinDivertReady:      "WinDivert 运行环境已就绪。",
+	HTMLReportSaved:     "HTML 报告已保存至 %s",
+	MapSaved:            "地图已保存至 %s",
+	HistoryEmpty:        "%s 中没有历史记录",
+	HistorySaveFailed:   "保存历史记录失败: %v",
+	EnrichLookupFailed:  "部分地址查询失败: %v",
+
+	ConfigCreating:          "未能找到配置文件，我们将在您的运行目录为您创建 nt_config.yaml 默认配置",
+	ConfigCreateFailed:      "创建默认配置文件失败: %v",
+	ConfigDefaultLoadFailed: "加载默认配置失败: %v",
+	ConfigLoadFailed:        "加载配置文件失败: %v",
+
+	FastTraceWelcome:      "Hi，欢迎使用 Fast Trace 功能，请注意 Fast Trace 功能只适合新手使用\n因为国内网络复杂，我们设置的测试目标有限，建议普通用户自测以获得更加精准的路由情况",
+	FastTraceChooseFamily: "请您选择要测试的 IP 类型\n1. IPv4\n2. IPv6",
+	FastTraceChooseISP:    "您想测试哪些ISP的路由？\n1. 北京三网快速测试\n2. 上海三网快速测试\n3. 广州三网快速测试\n4. 全国电信\n5. 全国联通\n6. 全国移动\n7. 全国教育网\n8. 全国五网",
+	FastTraceOpenFailed:   "打开文件失败: %v",
+	FastTraceReadFailed:   "读取文件失败: %v",
+	FastTraceInvalidLine:  "忽略无效的行: %s",
+	FastTraceInvalidIP:    "忽略无效的 IP: %s",
+	ChooseOption:          "请选择选项：",
+	ChooseIP:              "请选择要追踪的 IP",
+	InvalidOption:         "选项无效",
+
+	DNSFailed:           "DNS 解析失败，请检查系统的 DNS 设置",
+	APITimeout:          "IP 连接超时(5s)，请检查您的网络",
+	PreferredAPI:        "优选 API IP",
+	Interrupted:         "程序已被用户中断",
+	ProxyParseFailed:    "解析代理地址失败: %v",
+	ProviderTimeout:     "%s 请求超时(2s)，请切换其他API使用",
+	ProviderRateLimited: "超过API阈值",
+
+	GeoProvider:            "IP 地理数据源: %s",
+	TableHop:               "跳数",
+	TableLatency:           "延迟",
+	TableLocation:          "位置",
+	TableOwner:             "归属",
+	RouteTable:             "路由表",
+	MapTraceURL:            "MapTrace 地图:",
+	RoutePathLab:           "Route-Path 功能实验室",
+	RouteBorder:            "跨境：%s → %s，第 %d → %d 跳（AS%s → AS%s）",
+	RouteIXP:               "交换中心：%s，第 %d 跳（AS%s → AS%s）",
+	RouteDetour:            "绕行：%s 的流量在第 %d 跳后经 %s 绕行，于第 %d 跳返回",
+	TableNote:              "备注",
+	AnomalyJump:            "延迟增加 %.0f ms（相对第 %d 跳）：若后续各跳保持这一延迟，说明确有时延（长距离链路或拥塞）",
+	AnomalyInversion:       "延迟比第 %d 跳低 %.0f ms：第 %d 跳降低了 ICMP 回复的优先级，只是看起来慢，这不是拥塞",
+	AnomalyImpossible:      "距第 %d 跳 %.0f km，至少需要 %.0f ms，但延迟仅 %.0f ms：此跳的地理位置有误",
+	AnomalyJumpShort:       "延迟突增 +%.0f ms",
+	AnomalyInversionShort:  "第 %d 跳 ICMP 慢路径",
+	AnomalyImpossibleShort: "地理位置有误",
+
+	ClientNotAllowed: "客户端地址不在允许范围内",
+	AuthRequired:     "需要认证",
+	PermissionDenied: "权限不足",
+	InvalidRequest:   "请求无效",
+	InvalidPayload:   "请求内容无效",
+	InvalidSource:    "无效的数据来源 %q",
+	InvalidLimit:     "limit 参数无效",
+	HistoryDisabled:  "未启用历史记录",
+	RecordNotFound:   "记录不存在",
+	JobsSingleOnly:   "任务仅支持单次追踪模式",
+	JobNotFound:      "任务不存在",
+	JobNoResult:      "任务没有结果",
+	EncodeFailed:     "结果编码失败",
+	RenderMapFailed:  "地图渲染失败",
+}
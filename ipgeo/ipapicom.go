@@ -11,6 +11,7 @@ import (
 
 	"github.com/tidwall/gjson"
 
+	"github.com/nxtrace/NTrace-core/i18n"
 	"github.com/nxtrace/NTrace-core/util"
 )
 
@@ -24,14 +25,14 @@ func IPApiCom(ip string, timeout time.Duration, _ string, _ bool) (*IPGeoData, e
 	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:100.0) Gecko/20100101 Firefox/100.0")
 	content, err := client.Do(req)
 	if err != nil {
-		log.Println("ip-api.com 请求超时(2s)，请切换其他API使用")
+		log.Println(i18n.T(i18n.ProviderTimeout, "ip-api.com"))
 		return nil, err
 	}
 	body, _ := io.ReadAll(content.Body)
 	res := gjson.ParseBytes(body)
 
 	if res.Get("status").String() != "success" {
-		return &IPGeoData{}, errors.New("超过API阈值")
+		return &IPGeoData{}, errors.New(i18n.T(i18n.ProviderRateLimited))
 	}
 
 	re := regexp.MustCompile("[0-9]+")
@@ -1,6 +1,7 @@
 package ipgeo
 
 import (
+	"reflect"
 	"strings"
 	"time"
 )
@@ -57,6 +58,38 @@ func GetSource(s string) Source {
 	}
 }
 
+// sourceNames 用于根据函数反查数据源名称（指标标签等场景）
+var sourceNames = []struct {
+	name string
+	src  Source
+}{
+	{"DN42", DN42},
+	{"LeoMoeAPI", LeoIP},
+	{"IP.SB", IPSB},
+	{"IPInsight", IPInSight},
+	{"IPAPI.com", IPApiCom},
+	{"IPInfo", IPInfo},
+	{"IPInfoLocal", IPInfoLocal},
+	{"chunzhen", Chunzhen},
+	{"disable-geoip", disableGeoIP},
+	{"ipdb.one", IPDBOne},
+}
+
+// SourceName returns the canonical provider name of src, or "custom" for
+// sources that are not built in.
+func SourceName(src Source) string {
+	if src == nil {
+		return ""
+	}
+	ptr := reflect.ValueOf(src).Pointer()
+	for _, item := range sourceNames {
+		if reflect.ValueOf(item.src).Pointer() == ptr {
+			return item.name
+		}
+	}
+	return "custom"
+}
+
 func disableGeoIP(string, time.Duration, string, bool) (*IPGeoData, error) {
 	return &IPGeoData{}, nil
 }
@@ -45,3 +45,13 @@ func TestDisableGeoIP(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, &IPGeoData{}, res)
 }
+
+func TestSourceName(t *testing.T) {
+	assert.Equal(t, "LeoMoeAPI", SourceName(GetSource("leomoeapi")))
+	assert.Equal(t, "IPAPI.com", SourceName(GetSource("ip-api.com")))
+	assert.Equal(t, "disable-geoip", SourceName(GetSource("disable-geoip")))
+	assert.Equal(t, "custom", SourceName(func(string, time.Duration, string, bool) (*IPGeoData, error) {
+		return nil, nil
+	}))
+	assert.Equal(t, "", SourceName(nil))
+}
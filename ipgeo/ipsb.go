@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/tidwall/gjson"
+
+	"github.com/nxtrace/NTrace-core/i18n"
 )
 
 func IPSB(ip string, timeout time.Duration, _ string, _ bool) (*IPGeoData, error) {
@@ -21,7 +23,7 @@ func IPSB(ip string, timeout time.Duration, _ string, _ bool) (*IPGeoData, error
 	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:100.0) Gecko/20100101 Firefox/100.0")
 	content, err := client.Do(req)
 	if err != nil {
-		log.Println("api.ip.sb 请求超时(2s)，请切换其他API使用")
+		log.Println(i18n.T(i18n.ProviderTimeout, "api.ip.sb"))
 		return nil, err
 	}
 	body, _ := io.ReadAll(content.Body)
@@ -0,0 +1,239 @@
+// Package metrics 提供一个不依赖第三方库的轻量 Prometheus 文本格式导出实现
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	typeCounter = "counter"
+	typeGauge   = "gauge"
+
+	// ContentType 为 Prometheus 文本暴露格式的 MIME 类型
+	ContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// Default 为进程级默认注册表，/metrics 端点导出其内容
+var Default = NewRegistry()
+
+// Registry 保存一组指标，按注册顺序导出
+type Registry struct {
+	mu   sync.Mutex
+	vecs []*Vec
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Vec 为带标签的一组同名时间序列
+type Vec struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+}
+
+func (r *Registry) newVec(typ, name, help string, labels []string) *Vec {
+	v := &Vec{
+		name:   name,
+		help:   help,
+		typ:    typ,
+		labels: labels,
+		series: make(map[string]*series),
+	}
+	r.mu.Lock()
+	r.vecs = append(r.vecs, v)
+	r.mu.Unlock()
+	return v
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *Vec {
+	return r.newVec(typeCounter, name, help, labels)
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *Vec {
+	return r.newVec(typeGauge, name, help, labels)
+}
+
+// NewCounterVec 在默认注册表中创建计数器
+func NewCounterVec(name, help string, labels ...string) *Vec {
+	return Default.NewCounterVec(name, help, labels...)
+}
+
+// NewGaugeVec 在默认注册表中创建仪表盘指标
+func NewGaugeVec(name, help string, labels ...string) *Vec {
+	return Default.NewGaugeVec(name, help, labels...)
+}
+
+func (v *Vec) get(labelValues []string) *series {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s := v.series[key]
+	if s == nil {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		v.series[key] = s
+	}
+	return s
+}
+
+// Add 为计数器或仪表盘增加 delta
+func (v *Vec) Add(delta float64, labelValues ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.get(labelValues).value += delta
+}
+
+func (v *Vec) Inc(labelValues ...string) {
+	v.Add(1, labelValues...)
+}
+
+// Set 设置仪表盘的当前值
+func (v *Vec) Set(value float64, labelValues ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.get(labelValues).value = value
+}
+
+// Value 返回指定序列的当前值，不存在时返回 0
+func (v *Vec) Value(labelValues ...string) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.series[strings.Join(labelValues, "\xff")]; ok {
+		return s.value
+	}
+	return 0
+}
+
+// DeleteMatching 删除标签 label 取值为 value 的全部序列，用于清理过期的逐跳指标
+func (v *Vec) DeleteMatching(label, value string) {
+	idx := -1
+	for i, l := range v.labels {
+		if l == label {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for key, s := range v.series {
+		if s.labelValues[idx] == value {
+			delete(v.series, key)
+		}
+	}
+}
+
+// WriteText 以 Prometheus 文本格式写出注册表中的全部指标
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	vecs := append([]*Vec(nil), r.vecs...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, v := range vecs {
+		v.writeText(bw)
+	}
+	return bw.Flush()
+}
+
+func (v *Vec) writeText(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// 带标签的指标在没有序列时不输出；无标签指标则输出 0，便于抓取端发现
+	if len(v.series) == 0 && len(v.labels) > 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, escapeHelp(v.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.typ)
+
+	if len(v.series) == 0 {
+		fmt.Fprintf(w, "%s 0\n", v.name)
+		return
+	}
+
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := v.series[k]
+		w.WriteString(v.name)
+		if len(v.labels) > 0 {
+			w.WriteByte('{')
+			for i, l := range v.labels {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				fmt.Fprintf(w, "%s=\"%s\"", l, escapeLabel(s.labelValues[i]))
+			}
+			w.WriteByte('}')
+		}
+		w.WriteByte(' ')
+		w.WriteString(formatValue(s.value))
+		w.WriteByte('\n')
+	}
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// Handler 返回导出注册表的 HTTP 处理器
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = r.WriteText(w)
+	})
+}
+
+// Handler 返回导出默认注册表的 HTTP 处理器
+func Handler() http.Handler {
+	return Default.Handler()
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryScrape(t *testing.T) {
+	r := NewRegistry()
+	sent := r.NewCounterVec("probes_sent_total", "Probes sent.", "method")
+	rtt := r.NewGaugeVec("hop_rtt_ms", "Hop RTT.", "target", "ip")
+	r.NewCounterVec("empty_total", "Never incremented.")
+	r.NewGaugeVec("empty_labelled", "Never set.", "target")
+
+	sent.Inc("icmp")
+	sent.Add(2, "icmp")
+	sent.Inc("tcp")
+	rtt.Set(12.5, "a\"b", "1.1.1.1")
+
+	srv := httptest.NewServer(r.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, ContentType, resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `# HELP probes_sent_total Probes sent.
+# TYPE probes_sent_total counter
+probes_sent_total{method="icmp"} 3
+probes_sent_total{method="tcp"} 1
+# HELP hop_rtt_ms Hop RTT.
+# TYPE hop_rtt_ms gauge
+hop_rtt_ms{target="a\"b",ip="1.1.1.1"} 12.5
+# HELP empty_total Never incremented.
+# TYPE empty_total counter
+empty_total 0
+`, string(body))
+}
+
+func TestDeleteMatching(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGaugeVec("hop_loss_ratio", "Loss.", "target", "ttl")
+	g.Set(0.1, "a", "1")
+	g.Set(0.2, "a", "2")
+	g.Set(0.3, "b", "1")
+
+	g.DeleteMatching("target", "a")
+	assert.Equal(t, 0.0, g.Value("a", "1"))
+	assert.Equal(t, 0.3, g.Value("b", "1"))
+}
@@ -8,8 +8,10 @@ import (
 	"github.com/fatih/color"
 
 	"github.com/nxtrace/NTrace-core/config"
+	"github.com/nxtrace/NTrace-core/i18n"
+	"github.com/nxtrace/NTrace-core/redact"
+	"github.com/nxtrace/NTrace-core/schema"
 	"github.com/nxtrace/NTrace-core/trace"
-	"github.com/nxtrace/NTrace-core/util"
 )
 
 var version = config.Version
@@ -58,24 +60,27 @@ func sponsor() {
 	)
 }
 
-func PrintTraceRouteNav(ip net.IP, domain string, dataOrigin string, maxHops int, packetSize int, srcAddr string, mode string) {
-	fmt.Println("IP Geo Data Provider: " + dataOrigin)
-	if srcAddr == "" {
+// PrintTraceRouteNav prints the header of a trace; r redacts the addresses and target shown.
+func PrintTraceRouteNav(ip net.IP, domain string, dataOrigin string, maxHops int, packetSize int, srcAddr string, mode string, r *redact.Redactor) {
+	fmt.Println(i18n.T(i18n.GeoProvider, dataOrigin))
+	meta := r.Metadata(schema.Metadata{Target: domain, DstIP: ip.String(), SrcIP: srcAddr})
+	if meta.SrcIP == "" {
 		srcAddr = "traceroute to"
 	} else {
-		srcAddr += " ->"
+		srcAddr = meta.SrcIP + " ->"
 	}
-	if !util.EnableHidDstIP {
-		if ip.String() == domain {
-			fmt.Printf("%s %s, %d hops max, %d bytes payload, %s mode\n", srcAddr, ip.String(), maxHops, packetSize, strings.ToUpper(mode))
-		} else {
-			fmt.Printf("%s %s (%s), %d hops max, %d bytes payload, %s mode\n", srcAddr, ip.String(), domain, maxHops, packetSize, strings.ToUpper(mode))
-		}
+	if meta.Target == meta.DstIP {
+		fmt.Printf("%s %s, %d hops max, %d bytes payload, %s mode\n", srcAddr, meta.DstIP, maxHops, packetSize, strings.ToUpper(mode))
 	} else {
-		fmt.Printf("%s %s, %d hops max, %d bytes payload, %s mode\n", srcAddr, util.HideIPPart(ip.String()), maxHops, packetSize, strings.ToUpper(mode))
+		fmt.Printf("%s %s (%s), %d hops max, %d bytes payload, %s mode\n", srcAddr, meta.DstIP, meta.Target, maxHops, packetSize, strings.ToUpper(mode))
 	}
 }
 
+// ipv6Layout 判断地址是否按 IPv6 的宽度排版；脱敏后的标签按 IPv4 排版
+func ipv6Layout(ip string) bool {
+	return strings.Contains(ip, ":")
+}
+
 func applyLangSetting(h *trace.Hop) {
 	if h.Geo == nil || h.Geo.Source == trace.PendingGeoSource {
 		return
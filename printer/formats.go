@@ -0,0 +1,274 @@
+package printer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nxtrace/NTrace-core/export"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/trace"
+	"github.com/nxtrace/NTrace-core/tracelog"
+)
+
+// Names of the built-in formats.
+const (
+	FormatRealtime = "realtime"
+	FormatTable    = "table"
+	FormatClassic  = "classic"
+	FormatRaw      = "raw"
+	FormatLog      = "log"
+	FormatJSON     = "json"
+	FormatNDJSON   = "ndjson"
+	FormatCSV      = "csv"
+	FormatTSV      = "tsv"
+	FormatGeoJSON  = "geojson"
+	FormatKML      = "kml"
+	FormatDOT      = "dot"
+	FormatMermaid  = "mermaid"
+	FormatTemplate = "template"
+)
+
+func init() {
+	hopFormat := func(name, desc string, fn func(res *trace.Result, ttl int)) {
+		Register(Format{Name: name, Description: desc, New: func(Options) (Printer, error) {
+			return hopPrinter(fn), nil
+		}})
+	}
+	hopFormat(FormatRealtime, "hops as they complete (default)", RealtimePrinter)
+	hopFormat(FormatClassic, "classic output like BestTrace", ClassicPrinter)
+	hopFormat(FormatRaw, "one line per reply, separated by |", EasyPrinter)
+	Register(Format{Name: FormatTable, Description: "a table refreshed while tracing", New: func(opts Options) (Printer, error) {
+		return &tablePrinter{report: opts.Report}, nil
+	}})
+	Register(Format{Name: FormatLog, Description: "append the trace to a rotating log file (see --output)", New: newLogPrinter})
+
+	docFormat := func(name, desc string, write func(w io.Writer, doc schema.Document, opts Options) error) {
+		Register(Format{Name: name, Description: desc, Structured: true, New: func(opts Options) (Printer, error) {
+			return &docPrinter{w: opts.Writer, opts: opts, write: write}, nil
+		}})
+	}
+	docFormat(FormatJSON, "the result document as one JSON line", func(w io.Writer, doc schema.Document, _ Options) error {
+		out, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	})
+	docFormat(FormatCSV, "one row per reply, comma separated", func(w io.Writer, doc schema.Document, _ Options) error {
+		return export.WriteTrace(w, doc, export.CSV)
+	})
+	docFormat(FormatTSV, "one row per reply, tab separated", func(w io.Writer, doc schema.Document, _ Options) error {
+		return export.WriteTrace(w, doc, export.TSV)
+	})
+	docFormat(FormatGeoJSON, "the geolocated path as GeoJSON", func(w io.Writer, doc schema.Document, opts Options) error {
+		return export.WriteGeoJSON(w, doc, opts.Geo)
+	})
+	docFormat(FormatKML, "the geolocated path as KML", func(w io.Writer, doc schema.Document, opts Options) error {
+		return export.WriteKML(w, doc, opts.Geo)
+	})
+	docFormat(FormatDOT, "the path as a Graphviz DOT graph", func(w io.Writer, doc schema.Document, _ Options) error {
+		g := export.NewGraph()
+		g.Add(doc)
+		return g.WriteDOT(w)
+	})
+	docFormat(FormatMermaid, "the path as a Mermaid flowchart", func(w io.Writer, doc schema.Document, _ Options) error {
+		g := export.NewGraph()
+		g.Add(doc)
+		return g.WriteMermaid(w)
+	})
+	compat := map[string]string{
+		export.CompatTraceroute: "the text output of Linux traceroute",
+		export.CompatMTRJSON:    "the report of mtr --json",
+		export.CompatAtlas:      "a RIPE Atlas traceroute result",
+	}
+	for _, name := range export.CompatFormats {
+		docFormat(name, compat[name], func(w io.Writer, doc schema.Document, _ Options) error {
+			return export.WriteCompat(w, doc, name)
+		})
+	}
+
+	Register(Format{Name: FormatNDJSON, Description: "newline-delimited JSON streamed while tracing", Structured: true, New: func(opts Options) (Printer, error) {
+		p := NewNDJSONPrinter(opts.Writer, opts.Lang)
+		return p, p.Header(opts.Metadata)
+	}})
+	Register(Format{Name: FormatTemplate, Description: "the text/template file given with --format-template", Structured: true, New: func(opts Options) (Printer, error) {
+		if opts.Template == "" {
+			return nil, errors.New("no template file given")
+		}
+		p, err := NewTemplatePrinter(opts.Writer, opts.Template, opts.Lang)
+		if err != nil {
+			return nil, err
+		}
+		return p, p.Header(opts.Metadata)
+	}})
+}
+
+// hopPrinter 为只在每跳完成时输出的终端打印器
+type hopPrinter func(res *trace.Result, ttl int)
+
+func (p hopPrinter) OnHop(res *trace.Result, ttl int)                { p(res, ttl) }
+func (p hopPrinter) OnComplete(*trace.Result, schema.Document) error { return nil }
+func (p hopPrinter) Close() error                                    { return nil }
+
+// tablePrinter 在追踪过程中不断重绘表格，结束时输出最终结果
+type tablePrinter struct {
+	report bool
+}
+
+func (p *tablePrinter) OnHop(*trace.Result, int) {}
+
+func (p *tablePrinter) OnUpdate(res *trace.Result) {
+	if !p.report {
+		TracerouteTablePrinter(res)
+	}
+}
+
+func (p *tablePrinter) OnComplete(res *trace.Result, _ schema.Document) error {
+	TracerouteTablePrinter(res)
+	return nil
+}
+
+func (p *tablePrinter) Close() error { return nil }
+
+// docPrinter 在追踪结束后一次性输出整个结果文档
+type docPrinter struct {
+	w     io.Writer
+	opts  Options
+	write func(w io.Writer, doc schema.Document, opts Options) error
+}
+
+func (p *docPrinter) OnHop(*trace.Result, int) {}
+
+func (p *docPrinter) OnComplete(_ *trace.Result, doc schema.Document) error {
+	return p.write(p.w, doc, p.opts)
+}
+
+func (p *docPrinter) Close() error { return nil }
+
+// newLogPrinter 打开 Options.Log 指定的日志文件，按文本或 JSON Lines 格式写入本次追踪
+func newLogPrinter(opts Options) (Printer, error) {
+	pattern := opts.Log.Path
+	if pattern == "" {
+		pattern = tracelog.DefaultPath
+	}
+	meta := opts.Metadata
+	started := meta.StartedAt
+	if started.IsZero() {
+		started = time.Now()
+	}
+	w, err := tracelog.Open(tracelog.ExpandPath(pattern, tracelog.Fields{Target: meta.Target, IP: meta.DstIP, Time: started}), opts.Log)
+	if err != nil {
+		return nil, err
+	}
+	switch opts.Log.Format {
+	case "", tracelog.FormatText:
+		// 每次追踪以一行标题开头，便于在同一文件中区分
+		p := &logPrinter{w: w}
+		fmt.Fprintf(&p.buf, "[%s] traceroute to %s (%s), %d hops max, %d byte packets, %s mode\n",
+			started.Format("2006-01-02 15:04:05"), meta.Target, meta.DstIP, meta.Options.MaxHops, meta.Options.PacketSize, strings.ToUpper(meta.Method))
+		return p, nil
+	case tracelog.FormatJSONL:
+		p := NewNDJSONPrinter(w, opts.Lang)
+		p.TraceID = newTraceID()
+		if err := p.Header(meta); err != nil {
+			_ = w.Close()
+			return nil, err
+		}
+		return &jsonLogPrinter{NDJSONPrinter: p, w: w}, nil
+	}
+	_ = w.Close()
+	return nil, fmt.Errorf("unknown log format %q", opts.Log.Format)
+}
+
+// logPrinter 以文本格式写入日志。整次追踪在结束时一次写入，与并行的其他追踪不会交错；
+// 追踪被中断时 Close 写入已完成的部分
+type logPrinter struct {
+	mu  sync.Mutex
+	w   *tracelog.Writer
+	buf bytes.Buffer
+}
+
+func (p *logPrinter) OnHop(res *trace.Result, ttl int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf.WriteString(tracelog.FormatHop(res, ttl))
+}
+
+func (p *logPrinter) OnComplete(*trace.Result, schema.Document) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.flush()
+}
+
+func (p *logPrinter) flush() error {
+	if p.buf.Len() == 0 {
+		return nil
+	}
+	p.buf.WriteByte('\n')
+	_, err := p.w.Write(p.buf.Bytes())
+	p.buf.Reset()
+	return err
+}
+
+func (p *logPrinter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return errors.Join(p.flush(), p.w.Close())
+}
+
+// jsonLogPrinter 以 JSON Lines 写入日志，记录与 --ndjson 相同
+type jsonLogPrinter struct {
+	*NDJSONPrinter
+	w *tracelog.Writer
+}
+
+func (p *jsonLogPrinter) logError(err error) { _ = p.Error(err) }
+
+func (p *jsonLogPrinter) Close() error { return p.w.Close() }
+
+func newTraceID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// OnHop implements Printer.
+func (p *NDJSONPrinter) OnHop(res *trace.Result, ttl int) { p.RealtimePrinter(res, ttl) }
+
+// OnUpdate implements Updater.
+func (p *NDJSONPrinter) OnUpdate(res *trace.Result) { p.AsyncPrinter(res) }
+
+// OnComplete implements Printer by writing the summary record.
+func (p *NDJSONPrinter) OnComplete(res *trace.Result, doc schema.Document) error {
+	return p.Summary(res, doc.Metadata)
+}
+
+// OnError implements ErrorReporter.
+func (p *NDJSONPrinter) OnError(err error) error { return p.Error(err) }
+
+// Close implements Printer.
+func (p *NDJSONPrinter) Close() error { return nil }
+
+// OnHop implements Printer.
+func (p *TemplatePrinter) OnHop(res *trace.Result, ttl int) {
+	if p.Realtime() {
+		p.RealtimePrinter(res, ttl)
+	}
+}
+
+// OnComplete implements Printer.
+func (p *TemplatePrinter) OnComplete(_ *trace.Result, doc schema.Document) error {
+	return p.Finish(doc)
+}
+
+// Close implements Printer.
+func (p *TemplatePrinter) Close() error { return nil }
@@ -0,0 +1,169 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/nxtrace/NTrace-core/anomaly"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+// NDJSON record types.
+const (
+	NDJSONHeader    = "header"
+	NDJSONHop       = "hop"
+	NDJSONHopUpdate = "hop_update"
+	NDJSONSummary   = "summary"
+	NDJSONError     = "error"
+)
+
+// NDJSONRecord is one line of the --ndjson stream.
+type NDJSONRecord struct {
+	Type          string           `json:"type"`
+	TraceID       string           `json:"trace_id,omitempty"`
+	SchemaVersion string           `json:"schema_version,omitempty"`
+	Metadata      *schema.Metadata `json:"metadata,omitempty"`
+	Hop           *schema.Hop      `json:"hop,omitempty"`
+	HopCount      int              `json:"hop_count,omitempty"`
+	Reached       *bool            `json:"destination_reached,omitempty"`
+	TraceMapURL   string           `json:"trace_map_url,omitempty"`
+	Error         string           `json:"error,omitempty"`
+}
+
+// NDJSONPrinter streams a trace as newline-delimited JSON: a header, one record per completed
+// TTL, an update whenever geo or rDNS data of an emitted TTL changes, and a final summary.
+type NDJSONPrinter struct {
+	// TraceID, when set, is added to every record so that traces sharing one stream can be told apart.
+	TraceID string
+
+	w    io.Writer
+	lang string
+
+	mu      sync.Mutex
+	emitted map[int][]byte
+	err     error
+}
+
+// NewNDJSONPrinter returns a printer writing to w; lang selects the localized geo fields.
+func NewNDJSONPrinter(w io.Writer, lang string) *NDJSONPrinter {
+	return &NDJSONPrinter{w: w, lang: lang, emitted: make(map[int][]byte)}
+}
+
+// Header writes the header record; FinishedAt and DurationMs of meta are not known yet and are left out.
+func (p *NDJSONPrinter) Header(meta schema.Metadata) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	meta.FinishedAt = time.Time{}
+	meta.DurationMs = 0
+	return p.write(NDJSONRecord{Type: NDJSONHeader, SchemaVersion: schema.Version, Metadata: &meta})
+}
+
+// RealtimePrinter is a trace.Config.RealtimePrinter emitting the hop record of a completed TTL.
+func (p *NDJSONPrinter) RealtimePrinter(res *trace.Result, ttl int) {
+	hops := res.Snapshot()
+	if ttl < 0 || ttl >= len(hops) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.emit(hops, ttl)
+}
+
+// AsyncPrinter is a trace.Config.AsyncPrinter emitting updates for TTLs whose data changed after they were written.
+func (p *NDJSONPrinter) AsyncPrinter(res *trace.Result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.updates(res.Snapshot(), false)
+}
+
+// Summary writes the remaining updates of res, then the summary record carrying the final metadata.
+func (p *NDJSONPrinter) Summary(res *trace.Result, meta schema.Metadata) error {
+	hops := res.Snapshot()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// 追平 RealtimePrinter 之后才到达的地理位置 / rDNS 结果，以及未被打印的 TTL
+	p.updates(hops, true)
+
+	reached := false
+	if len(hops) > 0 {
+		for _, h := range hops[len(hops)-1] {
+			if h.Address != nil && meta.DstIP != "" && h.Address.String() == meta.DstIP {
+				reached = true
+				break
+			}
+		}
+	}
+	if err := p.write(NDJSONRecord{
+		Type:          NDJSONSummary,
+		SchemaVersion: schema.Version,
+		Metadata:      &meta,
+		HopCount:      len(hops),
+		Reached:       &reached,
+		TraceMapURL:   res.TraceMapUrl,
+	}); err != nil {
+		return err
+	}
+	return p.err
+}
+
+// Error writes an error record, e.g. when the trace was aborted.
+func (p *NDJSONPrinter) Error(err error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.write(NDJSONRecord{Type: NDJSONError, Error: err.Error()})
+}
+
+func (p *NDJSONPrinter) updates(hops [][]trace.Hop, all bool) {
+	for ttl := range hops {
+		if _, ok := p.emitted[ttl]; !ok && !all {
+			continue
+		}
+		p.emit(hops, ttl)
+	}
+}
+
+// emit 仅在该 TTL 的内容与上次输出不同时才写出一行
+func (p *NDJSONPrinter) emit(hops [][]trace.Hop, ttl int) {
+	hop := schema.NewHop(ttl+1, hops[ttl], p.lang)
+	if len(hop.Attempts) == 0 {
+		return
+	}
+	hop.Annotations = anomaly.At(hops, ttl, anomaly.Options{})
+	encoded, err := json.Marshal(hop)
+	if err != nil {
+		p.err = err
+		return
+	}
+	recordType := NDJSONHop
+	if prev, ok := p.emitted[ttl]; ok {
+		if bytes.Equal(prev, encoded) {
+			return
+		}
+		recordType = NDJSONHopUpdate
+	}
+	p.emitted[ttl] = encoded
+	if err := p.write(NDJSONRecord{Type: recordType, Hop: &hop}); err != nil && p.err == nil {
+		p.err = err
+	}
+}
+
+// write 每条记录单独一行，写完立即刷新
+func (p *NDJSONPrinter) write(rec NDJSONRecord) error {
+	rec.TraceID = p.TraceID
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := p.w.Write(line); err != nil {
+		return err
+	}
+	if f, ok := p.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package printer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+func decodeNDJSON(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var rec map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec), scanner.Text())
+		records = append(records, rec)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func TestNDJSONPrinter(t *testing.T) {
+	dst := net.ParseIP("192.0.2.9")
+	res := &trace.Result{Hops: [][]trace.Hop{
+		{{Success: true, Address: &net.IPAddr{IP: net.ParseIP("10.0.0.1")}, TTL: 1, RTT: 2 * time.Millisecond}},
+		{{Success: true, Address: &net.IPAddr{IP: dst}, TTL: 2, RTT: 9 * time.Millisecond}},
+	}}
+	conf := trace.Config{DstIP: dst, Lang: "en", MaxHops: 30}
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var buf bytes.Buffer
+	p := NewNDJSONPrinter(&buf, "en")
+	require.NoError(t, p.Header(schema.NewMetadata("example.com", trace.ICMPTrace, "LeoMoeAPI", conf, started, started)))
+	p.RealtimePrinter(res, 0)
+	// 没有变化时不重复输出
+	p.AsyncPrinter(res)
+
+	// 第一跳的地理位置在打印之后才到达
+	res.Hops[0][0].Geo = &ipgeo.IPGeoData{Asnumber: "64500", CountryEn: "Testland"}
+	p.AsyncPrinter(res)
+
+	meta := schema.NewMetadata("example.com", trace.ICMPTrace, "LeoMoeAPI", conf, started, started.Add(1500*time.Millisecond))
+	require.NoError(t, p.Summary(res, meta))
+
+	records := decodeNDJSON(t, buf.Bytes())
+	require.Len(t, records, 5)
+
+	types := make([]string, 0, len(records))
+	for _, rec := range records {
+		types = append(types, rec["type"].(string))
+	}
+	assert.Equal(t, []string{NDJSONHeader, NDJSONHop, NDJSONHopUpdate, NDJSONHop, NDJSONSummary}, types)
+
+	header := records[0]["metadata"].(map[string]any)
+	assert.Equal(t, schema.Version, records[0]["schema_version"])
+	assert.NotContains(t, header, "finished_at")
+	assert.NotContains(t, header, "duration_ms")
+
+	update := records[2]["hop"].(map[string]any)
+	assert.EqualValues(t, 1, update["ttl"])
+	geo := update["attempts"].([]any)[0].(map[string]any)["geo"].(map[string]any)
+	assert.Equal(t, "64500", geo["asnumber"])
+
+	summary := records[4]
+	assert.EqualValues(t, 2, summary["hop_count"])
+	assert.Equal(t, true, summary["destination_reached"])
+	assert.EqualValues(t, 1500, summary["metadata"].(map[string]any)["duration_ms"])
+}
+
+func TestNDJSONPrinterError(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewNDJSONPrinter(&buf, "cn")
+	require.NoError(t, p.Error(assert.AnError))
+
+	records := decodeNDJSON(t, buf.Bytes())
+	require.Len(t, records, 1)
+	assert.Equal(t, NDJSONError, records[0]["type"])
+	assert.Equal(t, assert.AnError.Error(), records[0]["error"])
+}
@@ -2,14 +2,13 @@ package printer
 
 import (
 	"fmt"
-	"net"
 	"strconv"
 	"strings"
 
 	"github.com/fatih/color"
 
+	"github.com/nxtrace/NTrace-core/anomaly"
 	"github.com/nxtrace/NTrace-core/trace"
-	"github.com/nxtrace/NTrace-core/util"
 )
 
 func RealtimePrinter(res *trace.Result, ttl int) {
@@ -51,17 +50,13 @@ func RealtimePrinter(res *trace.Result, ttl int) {
 		if blockDisplay {
 			fmt.Printf("%4s", "")
 		}
-		ipStr := ip
-		if util.EnableHidDstIP && ip == util.DstIP {
-			ipStr = util.HideIPPart(ip)
-		}
-		if net.ParseIP(ip).To4() == nil {
+		if ipv6Layout(ip) {
 			fmt.Fprintf(color.Output, "%s",
-				color.New(color.FgWhite, color.Bold).Sprintf("%-25s", ipStr),
+				color.New(color.FgWhite, color.Bold).Sprintf("%-25s", ip),
 			)
 		} else {
 			fmt.Fprintf(color.Output, "%s",
-				color.New(color.FgWhite, color.Bold).Sprintf("%-15s", ipStr),
+				color.New(color.FgWhite, color.Bold).Sprintf("%-15s", ip),
 			)
 		}
 
@@ -102,7 +97,7 @@ func RealtimePrinter(res *trace.Result, ttl int) {
 			fmt.Printf(" %-8s", "*")
 		}
 
-		if net.ParseIP(ip).To4() != nil {
+		if !ipv6Layout(ip) {
 			whoisFormat := strings.Split(res.Hops[ttl][i].Geo.Whois, "-")
 			if len(whoisFormat) > 1 {
 				whoisFormat[0] = strings.Join(whoisFormat[:2], "-")
@@ -148,11 +143,8 @@ func RealtimePrinter(res *trace.Result, ttl int) {
 		applyLangSetting(&res.Hops[ttl][i]) // 应用语言设置
 
 		hostname := res.Hops[ttl][i].Hostname
-		if util.EnableHidDstIP && ip == util.DstIP {
-			hostname = ""
-		}
 
-		if net.ParseIP(ip).To4() != nil {
+		if !ipv6Layout(ip) {
 			fmt.Fprintf(color.Output, " %s %s %s %s %s\n    %s   ",
 				color.New(color.FgWhite, color.Bold).Sprintf("%s", res.Hops[ttl][i].Geo.Country),
 				color.New(color.FgWhite, color.Bold).Sprintf("%s", res.Hops[ttl][i].Geo.Prov),
@@ -191,4 +183,12 @@ func RealtimePrinter(res *trace.Result, ttl int) {
 		fmt.Println()
 		blockDisplay = true
 	}
+	printAnnotations(res, ttl)
+}
+
+// printAnnotations 在本跳之后打印延迟注释，帮助区分 ICMP 限速与真实的拥塞
+func printAnnotations(res *trace.Result, ttl int) {
+	for _, a := range anomaly.At(res.Hops, ttl, anomaly.Options{}) {
+		fmt.Fprintf(color.Output, "    %s\n", color.New(color.FgYellow).Sprintf("⚠ %s", a.Text()))
+	}
 }
@@ -2,14 +2,13 @@ package printer
 
 import (
 	"fmt"
-	"net"
 	"strconv"
 	"strings"
 
 	"github.com/fatih/color"
 
+	"github.com/nxtrace/NTrace-core/i18n"
 	"github.com/nxtrace/NTrace-core/trace"
-	"github.com/nxtrace/NTrace-core/util"
 )
 
 func RealtimePrinterWithRouter(res *trace.Result, ttl int) {
@@ -52,17 +51,13 @@ func RealtimePrinterWithRouter(res *trace.Result, ttl int) {
 		if blockDisplay {
 			fmt.Printf("%4s", "")
 		}
-		ipStr := ip
-		if util.EnableHidDstIP && ip == util.DstIP {
-			ipStr = util.HideIPPart(ip)
-		}
-		if net.ParseIP(ip).To4() == nil {
+		if ipv6Layout(ip) {
 			fmt.Fprintf(color.Output, "%s",
-				color.New(color.FgWhite, color.Bold).Sprintf("%-25s", ipStr),
+				color.New(color.FgWhite, color.Bold).Sprintf("%-25s", ip),
 			)
 		} else {
 			fmt.Fprintf(color.Output, "%s",
-				color.New(color.FgWhite, color.Bold).Sprintf("%-15s", ipStr),
+				color.New(color.FgWhite, color.Bold).Sprintf("%-15s", ip),
 			)
 		}
 
@@ -73,7 +68,7 @@ func RealtimePrinterWithRouter(res *trace.Result, ttl int) {
 			fmt.Printf(" %-8s", "*")
 		}
 
-		if net.ParseIP(ip).To4() != nil {
+		if !ipv6Layout(ip) {
 			whoisFormat := strings.Split(res.Hops[ttl][i].Geo.Whois, "-")
 			if len(whoisFormat) > 1 {
 				whoisFormat[0] = strings.Join(whoisFormat[:2], "-")
@@ -90,11 +85,8 @@ func RealtimePrinterWithRouter(res *trace.Result, ttl int) {
 		}
 
 		hostname := res.Hops[ttl][i].Hostname
-		if util.EnableHidDstIP && ip == util.DstIP {
-			hostname = ""
-		}
 
-		if net.ParseIP(ip).To4() != nil {
+		if !ipv6Layout(ip) {
 			fmt.Fprintf(color.Output, " %s %s %s %s %s\n    %s   ",
 				color.New(color.FgWhite, color.Bold).Sprintf("%s", res.Hops[ttl][i].Geo.Country),
 				color.New(color.FgWhite, color.Bold).Sprintf("%s", res.Hops[ttl][i].Geo.Prov),
@@ -131,7 +123,7 @@ func RealtimePrinterWithRouter(res *trace.Result, ttl int) {
 			fmt.Fprintf(color.Output, "%s   %s %s %s   %s\n",
 				color.New(color.FgWhite, color.Bold).Sprintf("-"),
 				color.New(color.FgHiYellow, color.Bold).Sprintf("%s", res.Hops[ttl][i].Geo.Prefix),
-				color.New(color.FgWhite, color.Bold).Sprintf("路由表"),
+				color.New(color.FgWhite, color.Bold).Sprint(i18n.T(i18n.RouteTable)),
 				color.New(color.FgHiCyan, color.Bold).Sprintf("Beta"),
 				color.New(color.FgWhite, color.Bold).Sprintf("-"),
 			)
@@ -139,6 +131,7 @@ func RealtimePrinterWithRouter(res *trace.Result, ttl int) {
 		}
 		blockDisplay = true
 	}
+	printAnnotations(res, ttl)
 }
 
 func GetRouter(r *map[string][]string, node string) {
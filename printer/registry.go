@@ -0,0 +1,294 @@
+package printer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nxtrace/NTrace-core/export"
+	"github.com/nxtrace/NTrace-core/redact"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/trace"
+	"github.com/nxtrace/NTrace-core/tracelog"
+)
+
+// Printer receives the results of a trace. Printers are selected by name with --output-format;
+// several of them can run during the same trace.
+type Printer interface {
+	// OnHop is called when TTL ttl (0-based) of res has completed.
+	OnHop(res *trace.Result, ttl int)
+	// OnComplete is called once with the finished trace and its document.
+	OnComplete(res *trace.Result, doc schema.Document) error
+	// Close releases the resources of the printer, e.g. an open log file.
+	Close() error
+}
+
+// Updater is implemented by printers that also refresh their output while hops are still being
+// probed, such as the live table. OnUpdate is called periodically during the trace.
+type Updater interface {
+	OnUpdate(res *trace.Result)
+}
+
+// ErrorReporter is implemented by printers that record a failed trace in their own output.
+type ErrorReporter interface {
+	OnError(err error) error
+}
+
+// Options configures the printers created by NewPrinter.
+type Options struct {
+	// Writer receives the output of the structured formats; nil means standard output.
+	// The terminal printers always write to standard output.
+	Writer io.Writer
+	// Lang selects the localized geo fields ("cn" or "en").
+	Lang string
+	// Metadata describes the trace before it starts; FinishedAt and DurationMs are not known yet.
+	Metadata schema.Metadata
+	// Template is the file used by the "template" format.
+	Template string
+	// Report makes the "table" format print once at the end instead of refreshing while tracing.
+	Report bool
+	// Geo configures the "geojson" and "kml" formats.
+	Geo export.GeoOptions
+	// Log configures the "log" format; the path defaults to tracelog.DefaultPath.
+	Log tracelog.Options
+	// Redact is applied to everything the printers receive, including Metadata.
+	Redact redact.Policy
+}
+
+// Format is a named printer that can be selected with --output-format.
+type Format struct {
+	Name        string
+	Description string
+	// Structured marks formats writing machine-readable data to Options.Writer. Banners and
+	// other decorations are kept off standard output while such a format is selected.
+	Structured bool
+	New        func(opts Options) (Printer, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Format)
+)
+
+// Register makes a format available by name. Code embedding NextTrace can register its own
+// printers before the trace starts. Register panics if the name is empty or already taken.
+func Register(f Format) {
+	name := strings.ToLower(strings.TrimSpace(f.Name))
+	if name == "" || f.New == nil {
+		panic("printer: Register needs a name and a constructor")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[name]; dup {
+		panic("printer: Register called twice for format " + name)
+	}
+	f.Name = name
+	registry[name] = f
+}
+
+// Lookup returns the format registered under name.
+func Lookup(name string) (Format, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	f, ok := registry[strings.ToLower(strings.TrimSpace(name))]
+	return f, ok
+}
+
+// Formats returns the registered formats sorted by name.
+func Formats() []Format {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	formats := make([]Format, 0, len(registry))
+	for _, f := range registry {
+		formats = append(formats, f)
+	}
+	sort.Slice(formats, func(i, j int) bool { return formats[i].Name < formats[j].Name })
+	return formats
+}
+
+// ParseFormats splits a comma separated --output-format value into format names.
+func ParseFormats(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Structured reports whether any of the named formats writes machine-readable output.
+func Structured(names []string) bool {
+	for _, name := range names {
+		if f, ok := Lookup(name); ok && f.Structured {
+			return true
+		}
+	}
+	return false
+}
+
+// NewPrinter creates the printers named in names. A single name returns that printer itself;
+// otherwise the result forwards every call to each of them in order.
+func NewPrinter(names []string, opts Options) (Printer, error) {
+	if opts.Writer == nil {
+		opts.Writer = os.Stdout
+	}
+	r := opts.Redact.For(opts.Metadata)
+	opts.Metadata = r.Metadata(opts.Metadata)
+	var printers multiPrinter
+	seen := make(map[string]bool)
+	for _, name := range names {
+		f, ok := Lookup(name)
+		if !ok {
+			_ = printers.Close()
+			return nil, fmt.Errorf("unknown output format %q (available: %s)", name, formatNames())
+		}
+		if seen[f.Name] {
+			continue
+		}
+		seen[f.Name] = true
+		p, err := f.New(opts)
+		if err != nil {
+			_ = printers.Close()
+			return nil, fmt.Errorf("output format %s: %w", f.Name, err)
+		}
+		printers = append(printers, p)
+	}
+	// 未选择任何格式时返回不输出任何内容的打印器
+	var p Printer = printers
+	if len(printers) == 1 {
+		p = printers[0]
+	}
+	if r != nil {
+		p = &redactPrinter{inner: p, r: r}
+	}
+	return p, nil
+}
+
+func formatNames() string {
+	var names []string
+	for _, f := range Formats() {
+		names = append(names, f.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Attach installs p as the RealtimePrinter, and as the AsyncPrinter when it is an Updater, of conf.
+func Attach(p Printer, conf *trace.Config) {
+	conf.RealtimePrinter = p.OnHop
+	conf.AsyncPrinter = nil
+	if u, ok := p.(Updater); ok && updates(p) {
+		conf.AsyncPrinter = u.OnUpdate
+	}
+}
+
+// updates 判断打印器是否确实需要 OnUpdate；组合与脱敏打印器总是实现 Updater
+func updates(p Printer) bool {
+	switch v := p.(type) {
+	case multiPrinter:
+		for _, child := range v {
+			if updates(child) {
+				return true
+			}
+		}
+		return false
+	case *redactPrinter:
+		return updates(v.inner)
+	}
+	_, ok := p.(Updater)
+	return ok
+}
+
+// ReportError passes err to the printers recording errors and reports whether one of them wrote
+// it to Options.Writer, in which case the caller should not print it again.
+func ReportError(p Printer, err error) bool {
+	switch v := p.(type) {
+	case multiPrinter:
+		reported := false
+		for _, child := range v {
+			reported = ReportError(child, err) || reported
+		}
+		return reported
+	case *redactPrinter:
+		return ReportError(v.inner, errors.New(v.r.Text(err.Error())))
+	case errorLogger:
+		v.logError(err)
+		return false
+	case ErrorReporter:
+		return v.OnError(err) == nil
+	}
+	return false
+}
+
+// errorLogger 为把错误写入日志文件的打印器，错误仍需在终端上显示
+type errorLogger interface {
+	logError(err error)
+}
+
+// Replay feeds a finished trace, e.g. one loaded from a file, to p as if it had just run.
+func Replay(p Printer, res *trace.Result, doc schema.Document) error {
+	for ttl := range res.Hops {
+		p.OnHop(res, ttl)
+	}
+	return p.OnComplete(res, doc)
+}
+
+// multiPrinter 将每次调用依次转发给多个打印器
+type multiPrinter []Printer
+
+func (m multiPrinter) OnHop(res *trace.Result, ttl int) {
+	for _, p := range m {
+		p.OnHop(res, ttl)
+	}
+}
+
+func (m multiPrinter) OnUpdate(res *trace.Result) {
+	for _, p := range m {
+		if u, ok := p.(Updater); ok {
+			u.OnUpdate(res)
+		}
+	}
+}
+
+func (m multiPrinter) OnComplete(res *trace.Result, doc schema.Document) error {
+	var errs []error
+	for _, p := range m {
+		errs = append(errs, p.OnComplete(res, doc))
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiPrinter) Close() error {
+	var errs []error
+	for _, p := range m {
+		errs = append(errs, p.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// redactPrinter 在转发前对结果脱敏，内部打印器只能看到脱敏后的副本
+type redactPrinter struct {
+	inner Printer
+	r     *redact.Redactor
+}
+
+// OnHop 转发完整的副本：本跳的延迟注释需要此前各跳的数据
+func (p *redactPrinter) OnHop(res *trace.Result, ttl int) {
+	p.inner.OnHop(p.r.Result(res), ttl)
+}
+
+func (p *redactPrinter) OnUpdate(res *trace.Result) {
+	if u, ok := p.inner.(Updater); ok {
+		u.OnUpdate(p.r.Result(res))
+	}
+}
+
+func (p *redactPrinter) OnComplete(res *trace.Result, doc schema.Document) error {
+	return p.inner.OnComplete(p.r.Result(res), p.r.Document(doc))
+}
+
+func (p *redactPrinter) Close() error { return p.inner.Close() }
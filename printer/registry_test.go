@@ -0,0 +1,189 @@
+package printer
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/redact"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/trace"
+	"github.com/nxtrace/NTrace-core/tracelog"
+)
+
+// recordingPrinter 记录收到的调用，模拟第三方注册的打印器
+type recordingPrinter struct {
+	hops     []int
+	complete int
+	closed   bool
+}
+
+func (p *recordingPrinter) OnHop(_ *trace.Result, ttl int) { p.hops = append(p.hops, ttl) }
+
+func (p *recordingPrinter) OnComplete(*trace.Result, schema.Document) error {
+	p.complete++
+	return nil
+}
+
+func (p *recordingPrinter) Close() error {
+	p.closed = true
+	return nil
+}
+
+func registryResult() (*trace.Result, schema.Document) {
+	dst := net.ParseIP("192.0.2.9")
+	res := &trace.Result{Hops: [][]trace.Hop{
+		{{Success: true, Address: &net.IPAddr{IP: net.ParseIP("10.0.0.1")}, TTL: 1, RTT: 2 * time.Millisecond, Geo: &ipgeo.IPGeoData{}}},
+		{{Success: true, Address: &net.IPAddr{IP: dst}, TTL: 2, RTT: 9 * time.Millisecond, Geo: &ipgeo.IPGeoData{}}},
+	}}
+	conf := trace.Config{DstIP: dst, Lang: "en", MaxHops: 30}
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	meta := schema.NewMetadata("example.com", trace.ICMPTrace, "LeoMoeAPI", conf, started, started.Add(time.Second))
+	return res, schema.NewDocument(res, meta)
+}
+
+func TestRegisterCustomPrinter(t *testing.T) {
+	rec := &recordingPrinter{}
+	Register(Format{Name: "Test-Recorder", Description: "records calls", New: func(Options) (Printer, error) {
+		return rec, nil
+	}})
+	f, ok := Lookup("test-recorder")
+	require.True(t, ok)
+	assert.Equal(t, "test-recorder", f.Name)
+	assert.False(t, Structured([]string{"test-recorder", FormatRealtime}))
+
+	assert.Panics(t, func() {
+		Register(Format{Name: "test-recorder", New: f.New})
+	})
+
+	res, doc := registryResult()
+	var buf bytes.Buffer
+	p, err := NewPrinter([]string{"test-recorder", FormatJSON, "test-recorder"}, Options{Writer: &buf})
+	require.NoError(t, err)
+	require.NoError(t, Replay(p, res, doc))
+	require.NoError(t, p.Close())
+
+	assert.Equal(t, []int{0, 1}, rec.hops)
+	assert.Equal(t, 1, rec.complete)
+	assert.True(t, rec.closed)
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+	assert.Contains(t, buf.String(), `"dst_ip":"192.0.2.9"`)
+}
+
+func TestNewPrinterUnknownFormat(t *testing.T) {
+	_, err := NewPrinter([]string{FormatJSON, "nope"}, Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"nope"`)
+
+	_, err = NewPrinter([]string{FormatTemplate}, Options{})
+	assert.Error(t, err)
+}
+
+func TestAttachAndReportError(t *testing.T) {
+	res, doc := registryResult()
+	var buf bytes.Buffer
+	opts := Options{Writer: &buf, Lang: "en", Metadata: doc.Metadata}
+
+	p, err := NewPrinter([]string{FormatJSON}, opts)
+	require.NoError(t, err)
+	var conf trace.Config
+	Attach(p, &conf)
+	assert.NotNil(t, conf.RealtimePrinter)
+	assert.Nil(t, conf.AsyncPrinter)
+	assert.False(t, ReportError(p, errors.New("boom")))
+
+	p, err = NewPrinter(ParseFormats(" JSON, ndjson ,"), opts)
+	require.NoError(t, err)
+	assert.True(t, Structured(ParseFormats("realtime,ndjson")))
+	Attach(p, &conf)
+	require.NotNil(t, conf.AsyncPrinter)
+	conf.RealtimePrinter(res, 0)
+	assert.True(t, ReportError(p, errors.New("boom")))
+
+	records := decodeNDJSON(t, buf.Bytes())
+	require.Len(t, records, 3)
+	assert.Equal(t, NDJSONHeader, records[0]["type"])
+	assert.Equal(t, NDJSONHop, records[1]["type"])
+	assert.Equal(t, NDJSONError, records[2]["type"])
+}
+
+func TestLogPrinter(t *testing.T) {
+	res, doc := registryResult()
+	dir := t.TempDir()
+
+	opts := Options{Lang: "en", Metadata: doc.Metadata, Log: tracelog.Options{Path: filepath.Join(dir, "{date}.log")}}
+	p, err := NewPrinter([]string{FormatLog}, opts)
+	require.NoError(t, err)
+	require.NoError(t, Replay(p, res, doc))
+	require.NoError(t, p.Close())
+	text, err := os.ReadFile(filepath.Join(dir, "2025-01-02.log"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(text), "[2025-01-02 03:04:05] traceroute to example.com (192.0.2.9), 30 hops max"))
+	assert.Contains(t, string(text), "10.0.0.1")
+
+	opts.Log = tracelog.Options{Path: filepath.Join(dir, "trace.jsonl"), Format: tracelog.FormatJSONL}
+	p, err = NewPrinter([]string{FormatLog}, opts)
+	require.NoError(t, err)
+	require.NoError(t, Replay(p, res, doc))
+	require.NoError(t, p.Close())
+	data, err := os.ReadFile(opts.Log.Path)
+	require.NoError(t, err)
+	records := decodeNDJSON(t, data)
+	require.Len(t, records, 4)
+	id := records[0]["trace_id"]
+	assert.NotEmpty(t, id)
+	for _, r := range records {
+		assert.Equal(t, id, r["trace_id"])
+	}
+
+	opts.Log.Format = "xml"
+	_, err = NewPrinter([]string{FormatLog}, opts)
+	assert.Error(t, err)
+}
+
+func TestRedactPrinter(t *testing.T) {
+	res, doc := registryResult()
+	var buf bytes.Buffer
+	opts := Options{
+		Writer:   &buf,
+		Lang:     "en",
+		Metadata: doc.Metadata,
+		Redact:   redact.Policy{HideDestination: true, HideHops: 1},
+	}
+	p, err := NewPrinter([]string{FormatNDJSON}, opts)
+	require.NoError(t, err)
+	var conf trace.Config
+	Attach(p, &conf)
+	require.NotNil(t, conf.AsyncPrinter)
+	conf.RealtimePrinter(res, 0)
+	conf.RealtimePrinter(res, 1)
+	require.NoError(t, p.OnComplete(res, doc))
+	assert.True(t, ReportError(p, errors.New("no reply from 192.0.2.9")))
+
+	out := buf.String()
+	assert.NotContains(t, out, "192.0.2.9")
+	assert.NotContains(t, out, "10.0.0.1")
+	assert.NotContains(t, out, "example.com")
+	records := decodeNDJSON(t, buf.Bytes())
+	require.Len(t, records, 5)
+	assert.Equal(t, "192.0.0.0/16", records[0]["metadata"].(map[string]any)["dst_ip"])
+	assert.Equal(t, true, records[3]["destination_reached"])
+	assert.Equal(t, "no reply from 192.0.0.0/16", records[4]["error"])
+
+	// 原结果不受影响
+	assert.Equal(t, "10.0.0.1", res.Hops[0][0].Address.String())
+
+	p, err = NewPrinter([]string{FormatJSON}, opts)
+	require.NoError(t, err)
+	Attach(p, &conf)
+	assert.Nil(t, conf.AsyncPrinter)
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/fatih/color"
 	"github.com/rodaine/table"
 
+	"github.com/nxtrace/NTrace-core/anomaly"
+	"github.com/nxtrace/NTrace-core/i18n"
 	"github.com/nxtrace/NTrace-core/ipgeo"
 	"github.com/nxtrace/NTrace-core/trace"
 )
@@ -21,26 +23,30 @@ type rowData struct {
 	City     string
 	District string
 	Owner    string
+	Note     string
 }
 
 func TracerouteTablePrinter(res *trace.Result) {
 	// 初始化表格
 	tbl := New()
-	for _, hop := range res.Hops {
+	annotations := anomaly.Analyze(res.Hops, anomaly.Options{})
+	for i, hop := range res.Hops {
 		for k, h := range hop {
 			data := tableDataGenerator(h)
 			if k > 0 {
 				data.Hop = ""
+			} else {
+				data.Note = tableNote(annotations[i])
 			}
 			if data.Country == "" && data.Prov == "" && data.City == "" {
-				tbl.AddRow(data.Hop, data.IP, data.Latency, data.Asnumber, "", data.Owner)
+				tbl.AddRow(data.Hop, data.IP, data.Latency, data.Asnumber, "", data.Owner, data.Note)
 			} else {
 				if data.City != "" {
-					tbl.AddRow(data.Hop, data.IP, data.Latency, data.Asnumber, data.City+", "+data.Prov+", "+data.Country, data.Owner)
+					tbl.AddRow(data.Hop, data.IP, data.Latency, data.Asnumber, data.City+", "+data.Prov+", "+data.Country, data.Owner, data.Note)
 				} else if data.Prov != "" {
-					tbl.AddRow(data.Hop, data.IP, data.Latency, data.Asnumber, data.Prov+", "+data.Country, data.Owner)
+					tbl.AddRow(data.Hop, data.IP, data.Latency, data.Asnumber, data.Prov+", "+data.Country, data.Owner, data.Note)
 				} else {
-					tbl.AddRow(data.Hop, data.IP, data.Latency, data.Asnumber, data.Country, data.Owner)
+					tbl.AddRow(data.Hop, data.IP, data.Latency, data.Asnumber, data.Country, data.Owner, data.Note)
 				}
 
 			}
@@ -51,12 +57,20 @@ func TracerouteTablePrinter(res *trace.Result) {
 	tbl.Print()
 }
 
+func tableNote(annotations []anomaly.Annotation) string {
+	notes := make([]string, len(annotations))
+	for i, a := range annotations {
+		notes[i] = a.Short()
+	}
+	return strings.Join(notes, "; ")
+}
+
 func New() table.Table {
 	// 初始化表格
 	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
 	columnFmt := color.New(color.FgYellow).SprintfFunc()
 
-	tbl := table.New("Hop", "IP", "Latency", "ASN", "Location", "Owner")
+	tbl := table.New(i18n.T(i18n.TableHop), "IP", i18n.T(i18n.TableLatency), "ASN", i18n.T(i18n.TableLocation), i18n.T(i18n.TableOwner), i18n.T(i18n.TableNote))
 	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
 	return tbl
 }
@@ -0,0 +1,307 @@
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/fatih/color"
+
+	"github.com/nxtrace/NTrace-core/anomaly"
+	"github.com/nxtrace/NTrace-core/i18n"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+// Names of the optional templates a --format-template file may define.
+const (
+	TemplateNameHeader = "header"
+	TemplateNameHop    = "hop"
+)
+
+// TemplateTrace is the data of the top-level template and of the "header" template.
+// Hops is empty in "header", which runs before the first probe.
+type TemplateTrace struct {
+	schema.Metadata
+	Hops        []TemplateHop
+	TraceMapURL string
+}
+
+// TemplateHop is the data of the "hop" template and the elements of TemplateTrace.Hops.
+// IP, Hostname, Geo and MPLS come from the first responding attempt; RTTs are in milliseconds.
+type TemplateHop struct {
+	TTL      int
+	Attempts []schema.Attempt
+	IP       string
+	Hostname string
+	Geo      *schema.Geo
+	MPLS     []string
+	IPs      []string
+	RTTs     []float64
+	Sent     int
+	Received int
+	Loss     float64
+	Best     float64
+	Avg      float64
+	Worst    float64
+	Lang     string
+}
+
+// NewTemplateHop summarizes one TTL of a document.
+func NewTemplateHop(hop schema.Hop, lang string) TemplateHop {
+	h := TemplateHop{TTL: hop.TTL, Attempts: hop.Attempts, Sent: len(hop.Attempts), Lang: lang}
+	for _, a := range hop.Attempts {
+		if !a.Success || a.IP == "" {
+			continue
+		}
+		if h.Received == 0 {
+			h.IP, h.Hostname, h.Geo, h.MPLS = a.IP, a.Hostname, a.Geo, a.MPLS
+			h.Best = a.RTT
+		}
+		if !slices.Contains(h.IPs, a.IP) {
+			h.IPs = append(h.IPs, a.IP)
+		}
+		h.RTTs = append(h.RTTs, a.RTT)
+		h.Received++
+		h.Best, h.Worst, h.Avg = math.Min(h.Best, a.RTT), math.Max(h.Worst, a.RTT), h.Avg+a.RTT
+	}
+	if h.Received > 0 {
+		h.Avg /= float64(h.Received)
+	}
+	if h.Sent > 0 {
+		h.Loss = float64(h.Sent-h.Received) * 100 / float64(h.Sent)
+	}
+	return h
+}
+
+// TemplatePrinter renders a trace with a user-defined text/template. The "header" template runs
+// before the first probe and "hop" once for every completed TTL; the top-level template runs once
+// with the whole trace at the end. Without a "hop" template nothing is printed until the trace finishes.
+type TemplatePrinter struct {
+	w    io.Writer
+	tmpl *template.Template
+	lang string
+
+	mu      sync.Mutex
+	printed map[int]bool
+	err     error
+}
+
+// NewTemplatePrinter parses the template file at path.
+func NewTemplatePrinter(w io.Writer, path, lang string) (*TemplatePrinter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(TemplateFuncs()).Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return &TemplatePrinter{w: w, tmpl: tmpl, lang: lang, printed: make(map[int]bool)}, nil
+}
+
+// Header runs the "header" template, if defined; FinishedAt and DurationMs of meta are not known yet and are cleared.
+func (p *TemplatePrinter) Header(meta schema.Metadata) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	meta.FinishedAt = time.Time{}
+	meta.DurationMs = 0
+	return p.execute(TemplateNameHeader, TemplateTrace{Metadata: meta})
+}
+
+// Realtime reports whether the template prints hops as they complete.
+func (p *TemplatePrinter) Realtime() bool {
+	return p.tmpl.Lookup(TemplateNameHop) != nil
+}
+
+// RealtimePrinter is a trace.Config.RealtimePrinter running the "hop" template for a completed TTL.
+func (p *TemplatePrinter) RealtimePrinter(res *trace.Result, ttl int) {
+	hops := res.Snapshot()
+	if ttl < 0 || ttl >= len(hops) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	hop := schema.NewHop(ttl+1, hops[ttl], p.lang)
+	if len(hop.Attempts) == 0 {
+		return
+	}
+	hop.Annotations = anomaly.At(hops, ttl, anomaly.Options{})
+	p.hop(hop)
+}
+
+// Finish prints the TTLs the "hop" template has not seen yet, then runs the top-level template with doc.
+func (p *TemplatePrinter) Finish(doc schema.Document) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	data := TemplateTrace{Metadata: doc.Metadata, TraceMapURL: doc.TraceMapURL}
+	for _, hop := range doc.Hops {
+		if p.Realtime() && !p.printed[hop.TTL] {
+			p.hop(hop)
+		}
+		data.Hops = append(data.Hops, NewTemplateHop(hop, p.lang))
+	}
+	if p.err != nil {
+		return p.err
+	}
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	// 只定义了子模板的文件，顶层输出只剩空白
+	if strings.TrimSpace(buf.String()) == "" {
+		return nil
+	}
+	_, err := p.w.Write(buf.Bytes())
+	return err
+}
+
+// Render prints a finished document: "header", "hop" for every TTL, then the top-level template.
+func (p *TemplatePrinter) Render(doc schema.Document) error {
+	if err := p.Header(doc.Metadata); err != nil {
+		return err
+	}
+	return p.Finish(doc)
+}
+
+func (p *TemplatePrinter) hop(hop schema.Hop) {
+	p.printed[hop.TTL] = true
+	if err := p.execute(TemplateNameHop, NewTemplateHop(hop, p.lang)); err != nil && p.err == nil {
+		p.err = err
+	}
+}
+
+func (p *TemplatePrinter) execute(name string, data any) error {
+	if p.tmpl.Lookup(name) == nil {
+		return nil
+	}
+	return p.tmpl.ExecuteTemplate(p.w, name, data)
+}
+
+// templateColors 为 color 函数可用的颜色与样式名
+var templateColors = map[string]color.Attribute{
+	"bold":      color.Bold,
+	"faint":     color.Faint,
+	"italic":    color.Italic,
+	"underline": color.Underline,
+	"black":     color.FgBlack,
+	"red":       color.FgRed,
+	"green":     color.FgGreen,
+	"yellow":    color.FgYellow,
+	"blue":      color.FgBlue,
+	"magenta":   color.FgMagenta,
+	"cyan":      color.FgCyan,
+	"white":     color.FgWhite,
+	"hired":     color.FgHiRed,
+	"higreen":   color.FgHiGreen,
+	"hiyellow":  color.FgHiYellow,
+	"hiblue":    color.FgHiBlue,
+	"himagenta": color.FgHiMagenta,
+	"hicyan":    color.FgHiCyan,
+	"hiwhite":   color.FgHiWhite,
+	"hiblack":   color.FgHiBlack,
+}
+
+// TemplateFuncs returns the helper functions available to --format-template files; tr follows
+// the UI language of package i18n and falls back to English.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// color "red bold" .IP：按空格分隔的颜色与样式；输出不是终端时不着色
+		"color": func(names string, v any) (string, error) {
+			var attrs []color.Attribute
+			for _, name := range strings.Fields(names) {
+				attr, ok := templateColors[strings.ToLower(name)]
+				if !ok {
+					return "", fmt.Errorf("unknown color %q", name)
+				}
+				attrs = append(attrs, attr)
+			}
+			return color.New(attrs...).Sprint(v), nil
+		},
+		"pad":     func(width int, v any) string { return padText(fmt.Sprint(v), width, false) },
+		"padLeft": func(width int, v any) string { return padText(fmt.Sprint(v), width, true) },
+		"ms":      func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) },
+		"join": func(sep string, v any) string {
+			switch list := v.(type) {
+			case []string:
+				return strings.Join(list, sep)
+			case []float64:
+				parts := make([]string, len(list))
+				for i, f := range list {
+					parts[i] = strconv.FormatFloat(f, 'f', 2, 64)
+				}
+				return strings.Join(parts, sep)
+			}
+			return fmt.Sprint(v)
+		},
+		// tr "中文" "English"：按界面语言（--ui-language）选择文本，中文以外的语言使用英文
+		"tr": func(zh, en string) string {
+			if i18n.Current() == i18n.Chinese {
+				return zh
+			}
+			return en
+		},
+		"asn": func(g *schema.Geo) string {
+			if g == nil || g.ASN == "" {
+				return ""
+			}
+			return "AS" + strings.TrimPrefix(strings.ToUpper(g.ASN), "AS")
+		},
+		"location": func(g *schema.Geo) string {
+			if g == nil {
+				return ""
+			}
+			var parts []string
+			for _, s := range []string{g.Country, g.Prov, g.City, g.District} {
+				if s != "" && (len(parts) == 0 || parts[len(parts)-1] != s) {
+					parts = append(parts, s)
+				}
+			}
+			return strings.Join(parts, " ")
+		},
+		"owner": func(g *schema.Geo) string {
+			if g == nil {
+				return ""
+			}
+			if g.Owner != "" {
+				return g.Owner
+			}
+			return g.ISP
+		},
+		"default": func(def string, v any) string {
+			if s := fmt.Sprint(v); v != nil && s != "" {
+				return s
+			}
+			return def
+		},
+	}
+}
+
+// padText 按显示宽度补齐空格，中日韩字符计为两列
+func padText(s string, width int, left bool) string {
+	w := 0
+	for _, r := range s {
+		w++
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || (r >= 0xFF01 && r <= 0xFF60) || (r >= 0x3000 && r <= 0x303F) {
+			w++
+		}
+	}
+	if w >= width {
+		return s
+	}
+	fill := strings.Repeat(" ", width-w)
+	if left {
+		return fill + s
+	}
+	return s + fill
+}
@@ -0,0 +1,114 @@
+package printer
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/i18n"
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+func writeTemplate(t *testing.T, text string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "out.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte(text), 0o644))
+	return path
+}
+
+func TestTemplatePrinterRealtime(t *testing.T) {
+	old := i18n.Current()
+	defer func() { require.NoError(t, i18n.Set(old)) }()
+	require.NoError(t, i18n.Set(i18n.English))
+
+	path := writeTemplate(t, `{{define "header"}}{{tr "追踪" "trace"}} {{.Target}}
+{{end}}{{define "hop"}}{{padLeft 2 .TTL}} {{pad 12 (default "*" .IP)}}|{{join "," .RTTs}}|{{asn .Geo}}|{{location .Geo}}|{{printf "%.0f" .Loss}}
+{{end}}`)
+	var buf bytes.Buffer
+	p, err := NewTemplatePrinter(&buf, path, "en")
+	require.NoError(t, err)
+	require.True(t, p.Realtime())
+
+	res := &trace.Result{Hops: [][]trace.Hop{
+		{
+			{Success: true, Address: &net.IPAddr{IP: net.ParseIP("10.0.0.1")}, TTL: 1, RTT: 2 * time.Millisecond,
+				Geo: &ipgeo.IPGeoData{Asnumber: "64500", Country: "中国", CountryEn: "China", City: "上海", CityEn: "Shanghai"}},
+			{TTL: 1, Error: trace.HopError("hop timeout")},
+		},
+		{{TTL: 2, Error: trace.HopError("hop timeout")}},
+	}}
+	meta := schema.Metadata{Target: "example.com", Language: "en"}
+	require.NoError(t, p.Header(meta))
+	p.RealtimePrinter(res, 0)
+	// TTL 2 未经 RealtimePrinter 输出，由 Finish 补齐；顶层模板为空白时不输出
+	require.NoError(t, p.Finish(schema.NewDocument(res, meta)))
+
+	assert.Equal(t, "trace example.com\n 1 10.0.0.1    |2.00|AS64500|China Shanghai|50\n 2 *           ||||100\n", buf.String())
+}
+
+func TestTemplateTr(t *testing.T) {
+	old := i18n.Current()
+	defer func() { require.NoError(t, i18n.Set(old)) }()
+
+	tmpl := template.Must(template.New("tr").Funcs(TemplateFuncs()).Parse(`{{tr "追踪" "trace"}}`))
+	for lang, want := range map[string]string{i18n.Chinese: "追踪", i18n.English: "trace", i18n.Japanese: "trace", i18n.Russian: "trace"} {
+		require.NoError(t, i18n.Set(lang))
+		var buf bytes.Buffer
+		require.NoError(t, tmpl.Execute(&buf, nil))
+		assert.Equal(t, want, buf.String(), lang)
+	}
+}
+
+func TestTemplatePrinterWholeTrace(t *testing.T) {
+	path := writeTemplate(t, `{{.Target}}{{range .Hops}} {{.TTL}}:{{ms .Avg}}{{end}}
+{{color "red bold" "x"}}`)
+	var buf bytes.Buffer
+	p, err := NewTemplatePrinter(&buf, path, "cn")
+	require.NoError(t, err)
+	assert.False(t, p.Realtime())
+
+	prev := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = prev }()
+
+	doc := schema.Document{Metadata: schema.Metadata{Target: "1.1.1.1"}, Hops: []schema.Hop{
+		{TTL: 1, Attempts: []schema.Attempt{{Success: true, IP: "10.0.0.1", RTT: 1}, {Success: true, IP: "10.0.0.2", RTT: 2}}},
+	}}
+	require.NoError(t, p.Render(doc))
+	assert.Equal(t, "1.1.1.1 1:1.50\nx", buf.String())
+}
+
+func TestTemplatePrinterErrors(t *testing.T) {
+	_, err := NewTemplatePrinter(&bytes.Buffer{}, writeTemplate(t, `{{.Target`), "en")
+	assert.Error(t, err)
+
+	p, err := NewTemplatePrinter(&bytes.Buffer{}, writeTemplate(t, `{{color "rainbow" .Target}}`), "en")
+	require.NoError(t, err)
+	assert.ErrorContains(t, p.Render(schema.Document{}), "unknown color")
+}
+
+func TestNewTemplateHop(t *testing.T) {
+	h := NewTemplateHop(schema.Hop{TTL: 3, Attempts: []schema.Attempt{
+		{Success: true, IP: "10.0.0.1", RTT: 4},
+		{},
+		{Success: true, IP: "10.0.0.2", RTT: 2},
+		{Success: true, IP: "10.0.0.1", RTT: 6},
+	}}, "en")
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, h.IPs)
+	assert.Equal(t, []float64{4, 2, 6}, h.RTTs)
+	assert.Equal(t, 4, h.Sent)
+	assert.Equal(t, 3, h.Received)
+	assert.Equal(t, 25.0, h.Loss)
+	assert.Equal(t, [3]float64{2, 4, 6}, [3]float64{h.Best, h.Avg, h.Worst})
+	assert.Equal(t, "中国  |", padText("中国", 6, false)+"|")
+}
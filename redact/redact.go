@@ -0,0 +1,341 @@
+// Package redact removes private details from trace results before they are shown, exported,
+// uploaded or served, so that traces can be shared in public.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"path"
+	"strings"
+
+	"github.com/nxtrace/NTrace-core/anomaly"
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/trace"
+	"github.com/nxtrace/NTrace-core/util"
+)
+
+// Labels replacing redacted addresses.
+const (
+	Hidden = string(trace.LabelHidden)
+	Self   = string(trace.LabelSelf)
+)
+
+// SelfSource is the Policy.Self entry standing for the source address of the trace.
+const SelfSource = "auto"
+
+// Policy describes what to remove from a trace. The zero Policy keeps everything.
+type Policy struct {
+	// HideDestination shortens the destination address to its /16 (IPv4) or /32 (IPv6) prefix
+	// and drops its hostname. A target given as a domain name is replaced the same way.
+	HideDestination bool `mapstructure:"hide_destination"`
+	// HideHops hides the address, hostname and location of the first HideHops hops, e.g. the
+	// home network.
+	HideHops int `mapstructure:"hide_hops"`
+	// Self lists our own addresses and prefixes, replaced by "self" wherever they appear.
+	// SelfSource stands for the source address of the trace.
+	Self []string `mapstructure:"self"`
+	// DropHostnames are glob patterns, e.g. "*.home.arpa"; matching hostnames are removed.
+	DropHostnames []string `mapstructure:"drop_hostnames"`
+	// Key, when set, replaces the remaining addresses with pseudonyms derived from it with
+	// HMAC-SHA256. Traces redacted with the same key keep the same pseudonym for an address.
+	Key string `mapstructure:"key"`
+}
+
+// Enabled reports whether p removes anything.
+func (p Policy) Enabled() bool {
+	return p.HideDestination || p.HideHops > 0 || len(p.Self) > 0 || len(p.DropHostnames) > 0 || p.Key != ""
+}
+
+// Validate checks the addresses and patterns of p.
+func (p Policy) Validate() error {
+	var errs []error
+	if p.HideHops < 0 {
+		errs = append(errs, fmt.Errorf("hide_hops must not be negative: %d", p.HideHops))
+	}
+	for _, s := range p.Self {
+		if _, ok := parseSelf(s); !ok && s != SelfSource {
+			errs = append(errs, fmt.Errorf("invalid address or prefix %q", s))
+		}
+	}
+	for _, pattern := range p.DropHostnames {
+		if _, err := path.Match(strings.ToLower(pattern), ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid hostname pattern %q: %w", pattern, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ParseList splits a comma separated flag value.
+func ParseList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func parseSelf(s string) (netip.Prefix, bool) {
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return prefix.Masked(), true
+	}
+	if addr, err := netip.ParseAddr(s); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), true
+	}
+	return netip.Prefix{}, false
+}
+
+// Redactor applies a Policy to one trace.
+type Redactor struct {
+	policy Policy
+	dst    netip.Addr
+	self   []netip.Prefix
+	key    []byte
+}
+
+// For prepares p for the trace described by meta. It returns nil, which redacts nothing,
+// when p is not enabled.
+func (p Policy) For(meta schema.Metadata) *Redactor {
+	if !p.Enabled() {
+		return nil
+	}
+	r := &Redactor{policy: p}
+	r.dst, _ = parseAddr(meta.DstIP)
+	for _, s := range p.Self {
+		if s == SelfSource {
+			if src, ok := parseAddr(meta.SrcIP); ok {
+				r.self = append(r.self, netip.PrefixFrom(src, src.BitLen()))
+			}
+			continue
+		}
+		if prefix, ok := parseSelf(s); ok {
+			r.self = append(r.self, prefix)
+		}
+	}
+	if p.Key != "" {
+		r.key = []byte(p.Key)
+	}
+	return r
+}
+
+func parseAddr(s string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// masked 表示地址被整体替换（隐藏的跳、本机地址或目的地址），其主机名与网段也应去掉
+func (r *Redactor) address(s string) (string, bool) {
+	addr, ok := parseAddr(s)
+	if !ok {
+		return s, false
+	}
+	for _, prefix := range r.self {
+		if prefix.Contains(addr) {
+			return Self, true
+		}
+	}
+	if r.policy.HideDestination && addr == r.dst {
+		return util.HideIPPart(addr.String()), true
+	}
+	if r.key != nil {
+		mac := hmac.New(sha256.New, r.key)
+		mac.Write(addr.AsSlice())
+		return "anon-" + hex.EncodeToString(mac.Sum(nil)[:4]), false
+	}
+	return s, false
+}
+
+// IP returns the address s as it may be shown. Anything that is not an address is returned
+// unchanged.
+func (r *Redactor) IP(s string) string {
+	if r == nil {
+		return s
+	}
+	ip, _ := r.address(s)
+	return ip
+}
+
+// Hostname returns "" when name matches one of the patterns of the policy.
+func (r *Redactor) Hostname(name string) string {
+	if r == nil || name == "" {
+		return name
+	}
+	host := strings.ToLower(strings.TrimSuffix(name, "."))
+	for _, pattern := range r.policy.DropHostnames {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return ""
+		}
+	}
+	return name
+}
+
+// Text replaces the destination and our own addresses where they appear in s, e.g. in an
+// error message.
+func (r *Redactor) Text(s string) string {
+	if r == nil || !r.dst.IsValid() {
+		return s
+	}
+	if ip := r.IP(r.dst.String()); ip != r.dst.String() {
+		s = strings.ReplaceAll(s, r.dst.String(), ip)
+	}
+	for _, prefix := range r.self {
+		if prefix.IsSingleIP() {
+			s = strings.ReplaceAll(s, prefix.Addr().String(), Self)
+		}
+	}
+	return s
+}
+
+func (r *Redactor) hidden(ttl int) bool { return ttl <= r.policy.HideHops }
+
+// Metadata returns meta with the target, destination and source redacted.
+func (r *Redactor) Metadata(meta schema.Metadata) schema.Metadata {
+	if r == nil {
+		return meta
+	}
+	if _, err := netip.ParseAddr(meta.Target); err == nil {
+		meta.Target = r.IP(meta.Target)
+	} else if r.policy.HideDestination && meta.DstIP != "" {
+		meta.Target = r.IP(meta.DstIP)
+	}
+	meta.DstIP = r.IP(meta.DstIP)
+	meta.SrcIP = r.IP(meta.SrcIP)
+	return meta
+}
+
+// Document returns a redacted copy of doc.
+func (r *Redactor) Document(doc schema.Document) schema.Document {
+	if r == nil {
+		return doc
+	}
+	doc.Metadata = r.Metadata(doc.Metadata)
+	hops := make([]schema.Hop, len(doc.Hops))
+	// located 为脱敏后仍带坐标的 TTL
+	located := make(map[int]bool)
+	for i, hop := range doc.Hops {
+		hops[i] = schema.Hop{TTL: hop.TTL, Attempts: make([]schema.Attempt, len(hop.Attempts))}
+		for j, a := range hop.Attempts {
+			a = r.attempt(hop.TTL, a)
+			if a.Geo != nil && (a.Geo.Lat != 0 || a.Geo.Lng != 0) {
+				located[hop.TTL] = true
+			}
+			hops[i].Attempts[j] = a
+		}
+	}
+	for i, hop := range doc.Hops {
+		hops[i].Annotations = annotations(hop.TTL, hop.Annotations, located)
+	}
+	doc.Hops = hops
+	return doc
+}
+
+// annotations 去掉涉及已隐藏或替换为 self 的跳的 Impossible 注释，其距离会暴露这些跳的位置
+func annotations(ttl int, in []anomaly.Annotation, located map[int]bool) []anomaly.Annotation {
+	var out []anomaly.Annotation
+	for _, a := range in {
+		if a.Kind == anomaly.Impossible && (!located[ttl] || !located[a.RefTTL]) {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func (r *Redactor) attempt(ttl int, a schema.Attempt) schema.Attempt {
+	if a.IP == "" {
+		return a
+	}
+	if r.hidden(ttl) {
+		a.IP, a.Hostname, a.Geo = Hidden, "", nil
+		return a
+	}
+	ip, masked := r.address(a.IP)
+	if ip == Self {
+		a.IP, a.Hostname, a.Geo = Self, "", nil
+		return a
+	}
+	a.IP, a.Hostname = ip, r.Hostname(a.Hostname)
+	if masked {
+		a.Hostname = ""
+	}
+	// 网段会暴露被替换的地址
+	if a.Geo != nil && a.Geo.Prefix != "" && (masked || r.key != nil) {
+		g := *a.Geo
+		g.Prefix = ""
+		a.Geo = &g
+	}
+	return a
+}
+
+// Result returns a redacted copy of res; it is safe to call while the trace is running.
+func (r *Redactor) Result(res *trace.Result) *trace.Result {
+	if r == nil || res == nil {
+		return res
+	}
+	hops := res.Snapshot()
+	for i := range hops {
+		hops[i] = r.Hops(hops[i], i+1)
+	}
+	return &trace.Result{Hops: hops, TraceMapUrl: res.TraceMapUrl}
+}
+
+// Hops returns redacted copies of the attempts of TTL ttl (1-based).
+func (r *Redactor) Hops(attempts []trace.Hop, ttl int) []trace.Hop {
+	if r == nil {
+		return attempts
+	}
+	out := make([]trace.Hop, len(attempts))
+	for i, h := range attempts {
+		out[i] = r.hop(ttl, h)
+	}
+	return out
+}
+
+func (r *Redactor) hop(ttl int, h trace.Hop) trace.Hop {
+	ip := util.AddrIP(h.Address)
+	if ip == nil {
+		return h
+	}
+	if r.hidden(ttl) {
+		return hiddenHop(h, Hidden)
+	}
+	label, masked := r.address(ip.String())
+	if label == Self {
+		return hiddenHop(h, Self)
+	}
+	h.Address = Label(label)
+	h.Hostname = r.Hostname(h.Hostname)
+	if masked {
+		h.Hostname = ""
+	}
+	if h.Geo != nil && h.Geo.Prefix != "" && (masked || r.key != nil) {
+		g := *h.Geo
+		g.Prefix = ""
+		h.Geo = &g
+	}
+	return h
+}
+
+// hiddenHop 去掉地址、主机名与地理信息，保留延迟；终端打印器要求 Geo 不为空
+func hiddenHop(h trace.Hop, label string) trace.Hop {
+	h.Address = Label(label)
+	h.Hostname = ""
+	if h.Geo != nil {
+		h.Geo = &ipgeo.IPGeoData{Country: "已隐藏", CountryEn: "Hidden", Source: h.Geo.Source}
+	}
+	return h
+}
+
+// Label is the net.Addr of a redacted address; it prints as the label. It is defined in package
+// trace so that loaded results can hold it.
+type Label = trace.Label
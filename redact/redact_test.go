@@ -0,0 +1,202 @@
+package redact
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/anomaly"
+	"github.com/nxtrace/NTrace-core/enrich"
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+var meta = schema.Metadata{Target: "example.com", DstIP: "1.1.1.1", SrcIP: "203.0.113.7"}
+
+func hop(ip, hostname string) trace.Hop {
+	return trace.Hop{
+		Success:  true,
+		Address:  &net.IPAddr{IP: net.ParseIP(ip)},
+		Hostname: hostname,
+		RTT:      5 * time.Millisecond,
+		Geo:      &ipgeo.IPGeoData{Asnumber: "13335", Country: "美国", CountryEn: "United States", Prefix: ip + "/24"},
+	}
+}
+
+func testResult() *trace.Result {
+	return &trace.Result{Hops: [][]trace.Hop{
+		{hop("192.168.1.1", "router.home.arpa")},
+		{hop("203.0.113.1", "gw.isp.example")},
+		{hop("198.51.100.9", "core1.lan.example.net")},
+		{hop("1.1.1.1", "one.one.one.one"), {TTL: 4}},
+	}}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	assert.False(t, Policy{}.Enabled())
+	assert.Nil(t, Policy{}.For(meta))
+	assert.NoError(t, Policy{Self: []string{"auto", "203.0.113.0/24", "2001:db8::1"}, DropHostnames: []string{"*.lan.*"}}.Validate())
+
+	err := Policy{HideHops: -1, Self: []string{"home"}, DropHostnames: []string{"[a-"}}.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "hide_hops")
+	assert.Contains(t, err.Error(), `"home"`)
+	assert.Contains(t, err.Error(), `"[a-"`)
+
+	assert.Equal(t, []string{"a", "b"}, ParseList(" a,, b ,"))
+}
+
+func TestRedactResult(t *testing.T) {
+	r := Policy{
+		HideDestination: true,
+		HideHops:        1,
+		Self:            []string{SelfSource, "203.0.113.0/24"},
+		DropHostnames:   []string{"*.LAN.example.net"},
+	}.For(meta)
+	res := testResult()
+	out := r.Result(res)
+
+	// 原结果保持不变
+	assert.Equal(t, "192.168.1.1", res.Hops[0][0].Address.String())
+	assert.Equal(t, "one.one.one.one", res.Hops[3][0].Hostname)
+
+	assert.Equal(t, Hidden, out.Hops[0][0].Address.String())
+	assert.Empty(t, out.Hops[0][0].Hostname)
+	assert.Equal(t, "Hidden", out.Hops[0][0].Geo.CountryEn)
+	assert.Equal(t, 5*time.Millisecond, out.Hops[0][0].RTT)
+	data, err := json.Marshal(out.Hops[0][0].Address)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"IP":"hidden","Zone":""}`, string(data))
+
+	assert.Equal(t, Self, out.Hops[1][0].Address.String())
+	assert.Empty(t, out.Hops[1][0].Hostname)
+	assert.Empty(t, out.Hops[1][0].Geo.Asnumber)
+
+	assert.Equal(t, "198.51.100.9", out.Hops[2][0].Address.String())
+	assert.Empty(t, out.Hops[2][0].Hostname)
+	assert.Equal(t, "198.51.100.9/24", out.Hops[2][0].Geo.Prefix)
+
+	assert.Equal(t, "1.1.0.0/16", out.Hops[3][0].Address.String())
+	assert.Empty(t, out.Hops[3][0].Hostname)
+	assert.Empty(t, out.Hops[3][0].Geo.Prefix)
+	assert.Equal(t, "13335", out.Hops[3][0].Geo.Asnumber)
+	assert.Nil(t, out.Hops[3][1].Address)
+
+	// 已脱敏的结果再次脱敏不变
+	assert.Equal(t, out.Hops, r.Result(out).Hops)
+
+	assert.Equal(t, "dial 1.1.0.0/16 from self failed", r.Text("dial 1.1.1.1 from 203.0.113.7 failed"))
+}
+
+func TestRedactDocument(t *testing.T) {
+	doc := schema.NewDocument(testResult(), meta)
+	out := Policy{HideDestination: true, HideHops: 1, Self: []string{SelfSource}}.For(meta).Document(doc)
+
+	assert.Equal(t, "1.1.0.0/16", out.Metadata.Target)
+	assert.Equal(t, "1.1.0.0/16", out.Metadata.DstIP)
+	assert.Equal(t, Self, out.Metadata.SrcIP)
+	assert.Equal(t, "192.168.1.1", doc.Hops[0].Attempts[0].IP)
+
+	first := out.Hops[0].Attempts[0]
+	assert.Equal(t, Hidden, first.IP)
+	assert.Empty(t, first.Hostname)
+	assert.Nil(t, first.Geo)
+	assert.Equal(t, 5.0, first.RTT)
+
+	assert.Equal(t, "gw.isp.example", out.Hops[1].Attempts[0].Hostname)
+	assert.Equal(t, "1.1.0.0/16", out.Hops[3].Attempts[0].IP)
+	assert.Empty(t, out.Hops[3].Attempts[1].IP)
+
+	// 不透露与隐藏跳之间的距离
+	doc.Hops[1].Annotations = []anomaly.Annotation{{Kind: anomaly.Impossible, RefTTL: 1}, {Kind: anomaly.Jump, RefTTL: 1}}
+	out = Policy{HideHops: 1}.For(meta).Document(doc)
+	assert.Equal(t, []anomaly.Annotation{{Kind: anomaly.Jump, RefTTL: 1}}, out.Hops[1].Annotations)
+
+	// 替换为 self 的跳同样没有位置
+	located := func(ip string, ann ...anomaly.Annotation) schema.Hop {
+		return schema.Hop{Attempts: []schema.Attempt{{IP: ip, Success: true, Geo: &schema.Geo{Lat: 40, Lng: 116}}}, Annotations: ann}
+	}
+	doc.Hops = []schema.Hop{
+		located("203.0.113.7"),
+		located("198.51.100.9", anomaly.Annotation{Kind: anomaly.Impossible, RefTTL: 1}),
+		located("1.1.1.1", anomaly.Annotation{Kind: anomaly.Impossible, RefTTL: 2}),
+	}
+	for i := range doc.Hops {
+		doc.Hops[i].TTL = i + 1
+	}
+	out = Policy{Self: []string{SelfSource}}.For(meta).Document(doc)
+	assert.Equal(t, Self, out.Hops[0].Attempts[0].IP)
+	assert.Empty(t, out.Hops[1].Annotations)
+	assert.Equal(t, []anomaly.Annotation{{Kind: anomaly.Impossible, RefTTL: 2}}, out.Hops[2].Annotations)
+}
+
+func TestPseudonyms(t *testing.T) {
+	p := Policy{Key: "secret"}
+	a, b := p.For(meta), p.For(schema.Metadata{DstIP: "9.9.9.9"})
+
+	ip := a.IP("198.51.100.9")
+	assert.Regexp(t, `^anon-[0-9a-f]{8}$`, ip)
+	// 相同密钥在不同追踪中得到相同的假名，不同密钥则不同
+	assert.Equal(t, ip, b.IP("198.51.100.9"))
+	assert.Equal(t, ip, a.IP("::ffff:198.51.100.9"))
+	assert.NotEqual(t, ip, a.IP("198.51.100.10"))
+	assert.NotEqual(t, ip, Policy{Key: "other"}.For(meta).IP("198.51.100.9"))
+	assert.Equal(t, "example.com", a.IP("example.com"))
+
+	out := a.Result(testResult())
+	assert.Equal(t, ip, out.Hops[2][0].Address.String())
+	assert.Equal(t, "core1.lan.example.net", out.Hops[2][0].Hostname)
+	assert.Empty(t, out.Hops[2][0].Geo.Prefix)
+
+	m := a.Metadata(meta)
+	assert.Equal(t, "example.com", m.Target)
+	assert.Equal(t, a.IP("1.1.1.1"), m.DstIP)
+}
+
+func TestRedactedResultLoadsAgain(t *testing.T) {
+	r := Policy{HideHops: 1, Self: []string{"203.0.113.0/24"}, Key: "secret"}.For(meta)
+	res := testResult()
+	doc := r.Document(schema.NewDocument(res, meta))
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+
+	// --load：脱敏后的 IP 字段还原为标签而不是报错
+	loaded, err := schema.ParseDocument(data)
+	require.NoError(t, err)
+	back, err := loaded.Result()
+	require.NoError(t, err)
+	assert.Equal(t, trace.Label(Hidden), back.Hops[0][0].Address)
+	assert.Equal(t, trace.Label(Self), back.Hops[1][0].Address)
+	pseudonym, ok := back.Hops[2][0].Address.(Label)
+	require.True(t, ok)
+	assert.False(t, pseudonym.Masked())
+	assert.Equal(t, doc.Hops[2].Attempts[0].IP, pseudonym.String())
+	assert.NotNil(t, back.Hops[0][0].Geo, "printers expect geo on responding hops")
+
+	// 旧版 --json 结构的脱敏结果
+	legacy, err := json.Marshal(r.Result(res))
+	require.NoError(t, err)
+	legacyDoc, err := schema.ParseDocument(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, Hidden, legacyDoc.Hops[0].Attempts[0].IP)
+
+	// --enrich：标签没有可查询的地址，保持原样
+	lookups := 0
+	source := func(ip string, _ time.Duration, _ string, _ bool) (*ipgeo.IPGeoData, error) {
+		lookups++
+		return &ipgeo.IPGeoData{Asnumber: "64500"}, nil
+	}
+	out, err := enrich.Document(data, enrich.Options{Sources: []ipgeo.Source{source}})
+	require.NoError(t, err)
+	var enriched schema.Document
+	require.NoError(t, json.Unmarshal(out, &enriched))
+	assert.Equal(t, Hidden, enriched.Hops[0].Attempts[0].IP)
+	assert.Nil(t, enriched.Hops[0].Attempts[0].Geo)
+	assert.Zero(t, lookups, "pseudonyms are not looked up either")
+	assert.Equal(t, doc.Hops[2].Attempts[0].Geo, enriched.Hops[2].Attempts[0].Geo)
+}
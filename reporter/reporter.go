@@ -2,10 +2,10 @@ package reporter
 
 import (
 	"fmt"
-	"net"
+	"net/netip"
 	"strings"
-	"sync"
 
+	"github.com/nxtrace/NTrace-core/i18n"
 	"github.com/nxtrace/NTrace-core/ipgeo"
 	"github.com/nxtrace/NTrace-core/trace"
 )
@@ -24,69 +24,81 @@ func New(rs *trace.Result, ip string) Reporter {
 }
 
 type reporter struct {
-	targetTTL       uint16
-	targetIP        string
-	routeReport     map[uint16][]routeReportNode
-	routeReportLock sync.Mutex
-	routeResult     *trace.Result
-	wg              sync.WaitGroup
+	targetIP    string
+	routeResult *trace.Result
 }
 
 type routeReportNode struct {
-	asn string
-	isp string
-	geo []string
-	ix  bool
+	asn      string
+	inferred bool
+	isp      string
+	geo      []string
+	ix       bool
 }
 
 func experimentTag() {
-	fmt.Println("Route-Path 功能实验室")
+	fmt.Println(i18n.T(i18n.RoutePathLab))
 }
 
-func (r *reporter) generateRouteReportNode(ip string, ipGeoData ipgeo.IPGeoData, ttl uint16) {
-
-	var success = true
-
-	defer r.wg.Done()
+// inferredASNs 为常常没有 ASN 或登记在其他 AS 下的骨干网段
+var inferredASNs = []struct {
+	prefix netip.Prefix
+	asn    string
+}{
+	// CN2 的设备地址
+	{netip.MustParsePrefix("59.43.0.0/16"), "4809"},
+}
 
-	rpn := routeReportNode{}
-	ptr, err := net.LookupAddr(ip)
+// ixpWords 为交换中心名称中出现的单词，按整词匹配，避免 "Phoenix"、"Telix" 之类的误判
+var ixpWords = map[string]bool{
+	"ix": true, "ixp": true, "exchange": true,
+	"cix": true, "nix": true, "linx": true, "decix": true, "amsix": true,
+	"hkix": true, "jpix": true, "bbix": true, "sgix": true, "mskix": true,
+}
 
-	if err == nil {
-		if strings.Contains(strings.ToLower(ptr[0]), "ix") {
-			rpn.ix = true
-		} else {
-			rpn.ix = false
+// isIXP 判断反向解析的域名或地理位置数据库中的 ISP、所有者名称是否指向交换中心
+func isIXP(names ...string) bool {
+	for _, name := range names {
+		words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+			return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+		})
+		for _, w := range words {
+			if ixpWords[w] {
+				return true
+			}
 		}
 	}
-	// TODO: 这种写法不好，后面再重构一下
-	// 判断反向解析的域名中又或者是IP地理位置数据库中，是否出现了 IX
-	if strings.Contains(strings.ToLower(ipGeoData.Isp), "exchange") || strings.Contains(strings.ToLower(ipGeoData.Isp), "ix") || strings.Contains(strings.ToLower(ipGeoData.Owner), "exchange") || strings.Contains(strings.ToLower(ipGeoData.Owner), "ix") {
-		rpn.ix = true
+	return false
+}
+
+// newRouteReportNode 根据反向解析结果与地理位置生成路由节点，无有效地理位置时返回 false
+func newRouteReportNode(ip, ptr string, ipGeoData ipgeo.IPGeoData, targetIP string) (routeReportNode, bool) {
+	rpn := routeReportNode{
+		asn: ipGeoData.Asnumber,
+		ix:  isIXP(ptr, ipGeoData.Isp, ipGeoData.Owner),
 	}
 
 	// TODO: 正则判断POP并且提取带宽大小等信息
 
-	// CN2 需要特殊处理，因为他们很多没有ASN
-	// 但是目前这种写法是不规范的，属于凭空标记4809的IP
-	// TODO: 用更好的方式显示 CN2 骨干网的路由 Path
-	if strings.HasPrefix(ip, "59.43") {
-		rpn.asn = "4809"
-	} else {
-		rpn.asn = ipGeoData.Asnumber
+	if addr, err := netip.ParseAddr(ip); err == nil {
+		for _, known := range inferredASNs {
+			if known.prefix.Contains(addr.Unmap()) && rpn.asn != known.asn {
+				rpn.asn, rpn.inferred = known.asn, true
+				break
+			}
+		}
 	}
 
 	// 无论最后一跳是否为存在地理位置信息（AnyCast），都应该给予显示
-	if (ipGeoData.Country == "" || ipGeoData.Country == "LAN Address" || ipGeoData.Country == "-") && ip != r.targetIP {
-		success = false
+	if (ipGeoData.Country == "" || ipGeoData.Country == "LAN Address" || ipGeoData.Country == "-") && ip != targetIP {
+		return rpn, false
+	}
+	if ipGeoData.City == "" {
+		rpn.geo = []string{ipGeoData.Country, ipGeoData.Prov}
 	} else {
-		if ipGeoData.City == "" {
-			rpn.geo = []string{ipGeoData.Country, ipGeoData.Prov}
-		} else {
-			rpn.geo = []string{ipGeoData.Country, ipGeoData.City}
-		}
+		rpn.geo = []string{ipGeoData.Country, ipGeoData.City}
 	}
-	if ipGeoData.Asnumber == "" {
+	if rpn.asn == "" {
 		rpn.asn = "*"
 	}
 
@@ -95,91 +107,30 @@ func (r *reporter) generateRouteReportNode(ip string, ipGeoData ipgeo.IPGeoData,
 	} else {
 		rpn.isp = ipGeoData.Isp
 	}
-
-	// 有效记录
-	if success {
-		// 锁住资源，防止同时写panic
-		r.routeReportLock.Lock()
-		// 添加到MAP中
-		r.routeReport[ttl] = append(r.routeReport[ttl], rpn)
-		// 写入完成，解锁释放资源给其他协程
-		r.routeReportLock.Unlock()
-	}
-}
-
-func (r *reporter) InitialBaseData() Reporter {
-	reportNodes := map[uint16][]routeReportNode{}
-
-	r.routeReport = reportNodes
-	r.targetTTL = uint16(len(r.routeResult.Hops))
-
-	for i := uint16(0); i < r.targetTTL; i++ {
-		if i < uint16(len(r.routeResult.Hops)) && len(r.routeResult.Hops[i]) > 0 {
-			traceHop := r.routeResult.Hops[i][0]
-			if traceHop.Success {
-				currentIP := traceHop.Address.String()
-				r.wg.Add(1)
-				go r.generateRouteReportNode(currentIP, *traceHop.Geo, i)
-			}
-		}
-	}
-
-	// 等待所有的子协程运行完毕
-	r.wg.Wait()
-	return r
+	return rpn, true
 }
 
+// Print 输出 AS 路径，随后列出跨境、交换中心与绕行
 func (r *reporter) Print() {
-	var beforeActiveTTL uint16 = 0
-	r.InitialBaseData()
-	// 尝试首个有效 TTL
-	for i := uint16(0); i < r.targetTTL; i++ {
-		if len(r.routeReport[i]) != 0 {
-			beforeActiveTTL = i
-			// 找到以后便不再循环
-			break
+	path := Analyze(r.routeResult, r.targetIP)
+	for i, seg := range path.Segments {
+		if i > 0 {
+			// 部分 Shell 客户端可能无法很好的展示这个特殊字符
+			// TODO: 寻找其他替代字符
+			fmt.Printf("\n ╭╯\n ╰")
 		}
+		fmt.Print(seg.format("\033[42;37mIXP\033[0m"))
 	}
-
-	for i := beforeActiveTTL; i < r.targetTTL; i++ {
-		// 计算该TTL内的数据长度，如果为0，则代表没有有效数据
-		if len(r.routeReport[i]) == 0 {
-			// 跳过改跃点的数据整理
-			continue
-		}
-		nodeReport := r.routeReport[i][0]
-
-		if i == beforeActiveTTL {
-			fmt.Printf("AS%s %s「%s『%s", nodeReport.asn, nodeReport.isp, nodeReport.geo[0], nodeReport.geo[1])
-		} else {
-			nodeReportBefore := r.routeReport[beforeActiveTTL][0]
-			// ASN 相同，同个 ISP 内部的数据传递
-			if nodeReportBefore.asn == nodeReport.asn {
-				// Same ASN but Coutry or City Changed
-				if nodeReportBefore.geo[0] != nodeReport.geo[0] {
-					fmt.Printf("』→ %s『%s", nodeReport.geo[0], nodeReport.geo[1])
-				} else {
-					if nodeReportBefore.geo[1] != nodeReport.geo[1] {
-						fmt.Printf(" → %s", nodeReport.geo[1])
-					}
-				}
-			} else {
-				// ASN 不同，跨 ISP 的数据传递，这里可能会出现 POP、IP Transit、Peer、Exchange
-				fmt.Printf("』」")
-				if int(i) != len(r.routeReport)+1 {
-					// 部分 Shell 客户端可能无法很好的展示这个特殊字符
-					// TODO: 寻找其他替代字符
-					fmt.Printf("\n ╭╯\n ╰")
-				}
-				if nodeReport.ix {
-					fmt.Printf("AS%s \033[42;37mIXP\033[0m %s「%s『%s", nodeReport.asn, nodeReport.isp, nodeReport.geo[0], nodeReport.geo[1])
-				} else {
-					fmt.Printf("AS%s %s「%s『%s", nodeReport.asn, nodeReport.isp, nodeReport.geo[0], nodeReport.geo[1])
-				}
-			}
-		}
-		// 标记为最新的一个有效跃点
-		beforeActiveTTL = i
+	if len(path.Segments) > 0 {
+		fmt.Println()
+	}
+	for _, b := range path.Borders {
+		fmt.Println(b.String())
+	}
+	for _, x := range path.IXPs {
+		fmt.Println(x.String())
+	}
+	for _, d := range path.Detours {
+		fmt.Println(d.String())
 	}
-	fmt.Println("』」")
 }
@@ -1,7 +1,10 @@
 package reporter
 
 import (
+	"encoding/json"
+	"fmt"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
@@ -113,3 +116,100 @@ func TestPrint(t *testing.T) {
 	r := New(testResult, "213.226.68.73")
 	r.Print()
 }
+
+func TestSummary(t *testing.T) {
+	segments := Summary(testResult, "213.226.68.73")
+	if len(segments) != 4 {
+		t.Fatalf("expected 4 segments, got %d: %v", len(segments), segments)
+	}
+	if got, want := segments[0].String(), "AS4808 中国联通「中国『北京市』」"; got != want {
+		t.Errorf("segment 0 = %q, want %q", got, want)
+	}
+	if segments[0].FirstTTL != 1 || segments[0].LastTTL != 2 {
+		t.Errorf("segment 0 covers TTL %d-%d, want 1-2", segments[0].FirstTTL, segments[0].LastTTL)
+	}
+	// 同一 AS 内城市为空时回退到省份
+	if got, want := segments[3].String(), "AS56630 Melbikomas UAB「Germany『Hesse, Frankfurt』」"; got != want {
+		t.Errorf("segment 3 = %q, want %q", got, want)
+	}
+}
+
+func pathHop(ip, hostname, asn, country, city, isp string) []trace.Hop {
+	return []trace.Hop{{
+		Success:  true,
+		Address:  &net.IPAddr{IP: net.ParseIP(ip)},
+		Hostname: hostname,
+		RTT:      10 * time.Millisecond,
+		Geo:      &ipgeo.IPGeoData{Asnumber: asn, Country: country, City: city, Isp: isp},
+	}}
+}
+
+// maskedAddr 模拟脱敏后被隐藏的地址
+type maskedAddr string
+
+func (a maskedAddr) Network() string { return "ip" }
+func (a maskedAddr) String() string  { return string(a) }
+func (a maskedAddr) Masked() bool    { return true }
+
+func TestAnalyze(t *testing.T) {
+	hidden := pathHop("192.0.2.1", "", "", "已隐藏", "", "")
+	hidden[0].Address = maskedAddr("hidden")
+	rs := &trace.Result{Hops: [][]trace.Hop{
+		hidden,
+		pathHop("202.97.1.1", "", "4134", "中国", "上海", "中国电信"),
+		pathHop("59.43.1.1", "", "", "中国", "上海", "中国电信"),
+		pathHop("59.43.2.2", "", "", "日本", "东京", "中国电信"),
+		pathHop("80.81.192.1", "decix-fra.example.net", "6695", "德国", "法兰克福", "DE-CIX Management GmbH"),
+		pathHop("62.115.1.1", "", "1299", "美国", "洛杉矶", "Phoenix Telix"),
+		pathHop("62.115.2.2", "", "1299", "日本", "东京", "Telia"),
+		nil,
+		pathHop("203.0.113.9", "", "64500", "日本", "东京", "Example"),
+	}}
+	path := Analyze(rs, "203.0.113.9")
+
+	var asns []string
+	for _, seg := range path.Segments {
+		asns = append(asns, seg.ASN)
+	}
+	if got, want := strings.Join(asns, " "), "4134 4809 6695 1299 64500"; got != want {
+		t.Fatalf("AS path = %q, want %q", got, want)
+	}
+	cn2 := path.Segments[1]
+	if !cn2.ASNInferred || cn2.Ingress.TTL != 3 || cn2.Egress.TTL != 4 || cn2.Egress.IP != "59.43.2.2" {
+		t.Errorf("CN2 segment = %+v", cn2)
+	}
+	if path.Segments[0].ASNInferred || path.Segments[0].Ingress.TTL != 2 {
+		t.Errorf("hidden hop must be skipped: %+v", path.Segments[0])
+	}
+
+	if len(path.IXPs) != 1 {
+		t.Fatalf("IXPs = %+v, want only DE-CIX", path.IXPs)
+	}
+	if x := path.IXPs[0]; x.Hop.TTL != 5 || x.FromASN != "4809" || x.ToASN != "1299" || !path.Segments[2].IXP {
+		t.Errorf("IXP = %+v", x)
+	}
+
+	var borders []string
+	for _, b := range path.Borders {
+		borders = append(borders, fmt.Sprintf("%s>%s@%d", b.From.Location.Country, b.To.Location.Country, b.To.TTL))
+	}
+	if got, want := strings.Join(borders, " "), "中国>日本@4 日本>德国@5 德国>美国@6 美国>日本@7"; got != want {
+		t.Errorf("borders = %q, want %q", got, want)
+	}
+
+	if len(path.Detours) != 1 {
+		t.Fatalf("detours = %+v, want one", path.Detours)
+	}
+	d := path.Detours[0]
+	if d.Country != "日本" || d.Leave.TTL != 4 || d.Return.TTL != 7 || strings.Join(d.Via, ",") != "德国,美国" {
+		t.Errorf("detour = %+v", d)
+	}
+
+	out, err := json.Marshal(Analyze(&trace.Result{}, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), `{"segments":[],"borders":[],"ixps":[],"detours":[]}`; got != want {
+		t.Errorf("empty path = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,248 @@
+package reporter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nxtrace/NTrace-core/i18n"
+	"github.com/nxtrace/NTrace-core/trace"
+	"github.com/nxtrace/NTrace-core/util"
+)
+
+// Location is a place on the route-path summary; Region is the city, or the province when the city is unknown.
+type Location struct {
+	Country string `json:"country"`
+	Region  string `json:"region"`
+}
+
+// Hop is a hop of the route path.
+type Hop struct {
+	TTL      int      `json:"ttl"`
+	IP       string   `json:"ip"`
+	Hostname string   `json:"hostname,omitempty"`
+	ASN      string   `json:"asn"`
+	Location Location `json:"location"`
+}
+
+// Segment is a run of consecutive hops within the same AS.
+type Segment struct {
+	ASN string `json:"asn"`
+	// ASNInferred marks an ASN taken from a known backbone prefix instead of the geo data,
+	// e.g. AS4809 for CN2 addresses in 59.43.0.0/16.
+	ASNInferred bool       `json:"asn_inferred,omitempty"`
+	ISP         string     `json:"isp"`
+	IXP         bool       `json:"ixp"`
+	FirstTTL    int        `json:"first_ttl"`
+	LastTTL     int        `json:"last_ttl"`
+	Ingress     Hop        `json:"ingress"`
+	Egress      Hop        `json:"egress"`
+	Locations   []Location `json:"locations"`
+}
+
+// Border is a change of country between two consecutive located hops.
+type Border struct {
+	From Hop `json:"from"`
+	To   Hop `json:"to"`
+}
+
+// IXP is an internet exchange on the path, between the AS of the hop before and the hop after it.
+type IXP struct {
+	Hop     Hop    `json:"hop"`
+	Name    string `json:"name"`
+	FromASN string `json:"from_asn,omitempty"`
+	ToASN   string `json:"to_asn,omitempty"`
+}
+
+// Detour is a "trombone": the path leaves Country after Leave, passes through Via and comes
+// back to Country at Return.
+type Detour struct {
+	Country string   `json:"country"`
+	Via     []string `json:"via"`
+	Leave   Hop      `json:"leave"`
+	Return  Hop      `json:"return"`
+}
+
+// Path is the structured route-path analysis of a trace.
+type Path struct {
+	Segments []Segment `json:"segments"`
+	Borders  []Border  `json:"borders"`
+	IXPs     []IXP     `json:"ixps"`
+	Detours  []Detour  `json:"detours"`
+}
+
+// masker 由脱敏后的地址实现（trace.Label）；被隐藏的跳与本机地址没有可用的位置
+type masker interface{ Masked() bool }
+
+type pathNode struct {
+	hop Hop
+	routeReportNode
+}
+
+// nodes 取每个 TTL 的首个响应，跳过没有地理位置的跳
+func nodes(rs *trace.Result, targetIP string) []pathNode {
+	var out []pathNode
+	for i, attempts := range rs.Hops {
+		if len(attempts) == 0 {
+			continue
+		}
+		hop := attempts[0]
+		if !hop.Success || hop.Address == nil || hop.Geo == nil {
+			continue
+		}
+		if m, ok := hop.Address.(masker); ok && m.Masked() {
+			continue
+		}
+		// 脱敏后的假名与网段不是 IP，原样保留
+		ip := hop.Address.String()
+		if addr := util.AddrIP(hop.Address); addr != nil {
+			ip = addr.String()
+		}
+		node, ok := newRouteReportNode(ip, hop.Hostname, *hop.Geo, targetIP)
+		if !ok {
+			continue
+		}
+		out = append(out, pathNode{
+			hop: Hop{
+				TTL:      i + 1,
+				IP:       ip,
+				Hostname: hop.Hostname,
+				ASN:      node.asn,
+				Location: Location{Country: node.geo[0], Region: node.geo[1]},
+			},
+			routeReportNode: node,
+		})
+	}
+	return out
+}
+
+// Analyze builds the AS-level path of rs with the ingress and egress hop of every AS, the
+// country borders it crosses, the IXPs it traverses and its detours through other countries.
+// Only the first reply of every TTL is used. Analyze does no reverse DNS lookups; IXPs are
+// recognised from the hostnames already stored on the hops.
+func Analyze(rs *trace.Result, targetIP string) Path {
+	path := Path{Segments: []Segment{}, Borders: []Border{}, IXPs: []IXP{}, Detours: []Detour{}}
+	if rs == nil {
+		return path
+	}
+	list := nodes(rs, targetIP)
+	for i, node := range list {
+		if node.ix {
+			x := IXP{Hop: node.hop, Name: node.isp}
+			if i > 0 {
+				x.FromASN = list[i-1].asn
+			}
+			if i+1 < len(list) {
+				x.ToASN = list[i+1].asn
+			}
+			path.IXPs = append(path.IXPs, x)
+		}
+		if n := len(path.Segments); n > 0 && path.Segments[n-1].ASN == node.asn {
+			last := &path.Segments[n-1]
+			last.LastTTL, last.Egress = node.hop.TTL, node.hop
+			last.IXP = last.IXP || node.ix
+			if last.Locations[len(last.Locations)-1] != node.hop.Location {
+				last.Locations = append(last.Locations, node.hop.Location)
+			}
+			continue
+		}
+		path.Segments = append(path.Segments, Segment{
+			ASN:         node.asn,
+			ASNInferred: node.inferred,
+			ISP:         node.isp,
+			IXP:         node.ix,
+			FirstTTL:    node.hop.TTL,
+			LastTTL:     node.hop.TTL,
+			Ingress:     node.hop,
+			Egress:      node.hop,
+			Locations:   []Location{node.hop.Location},
+		})
+	}
+
+	// 国家未知的跳（如无地理位置的目的地址）不参与跨境与绕行的判断
+	var located []Hop
+	for _, node := range list {
+		if node.hop.Location.Country != "" {
+			located = append(located, node.hop)
+		}
+	}
+	// runs 为连续位于同一国家的跳，记录首尾两跳
+	var runs [][2]Hop
+	for i, hop := range located {
+		if i > 0 && located[i-1].Location.Country != hop.Location.Country {
+			path.Borders = append(path.Borders, Border{From: located[i-1], To: hop})
+		}
+		if n := len(runs); n > 0 && runs[n-1][1].Location.Country == hop.Location.Country {
+			runs[n-1][1] = hop
+			continue
+		}
+		runs = append(runs, [2]Hop{hop, hop})
+	}
+	for i, run := range runs {
+		country := run[0].Location.Country
+		for j := i + 1; j < len(runs); j++ {
+			if runs[j][0].Location.Country != country {
+				continue
+			}
+			d := Detour{Country: country, Leave: run[1], Return: runs[j][0]}
+			for _, via := range runs[i+1 : j] {
+				if !slices.Contains(d.Via, via[0].Location.Country) {
+					d.Via = append(d.Via, via[0].Location.Country)
+				}
+			}
+			path.Detours = append(path.Detours, d)
+			break
+		}
+	}
+	return path
+}
+
+// Summary returns the AS segments of Analyze.
+func Summary(rs *trace.Result, targetIP string) []Segment {
+	return Analyze(rs, targetIP).Segments
+}
+
+// String renders s the way Print does, without colors: AS4134 中国电信「中国『上海 → 广州』」
+func (s Segment) String() string {
+	return s.format("IXP")
+}
+
+func (s Segment) format(ixp string) string {
+	var b strings.Builder
+	if s.IXP {
+		fmt.Fprintf(&b, "AS%s %s %s「", s.ASN, ixp, s.ISP)
+	} else {
+		fmt.Fprintf(&b, "AS%s %s「", s.ASN, s.ISP)
+	}
+	for i, loc := range s.Locations {
+		switch {
+		case i == 0:
+			fmt.Fprintf(&b, "%s『%s", loc.Country, loc.Region)
+		case loc.Country != s.Locations[i-1].Country:
+			fmt.Fprintf(&b, "』→ %s『%s", loc.Country, loc.Region)
+		default:
+			fmt.Fprintf(&b, " → %s", loc.Region)
+		}
+	}
+	b.WriteString("』」")
+	return b.String()
+}
+
+func (b Border) String() string {
+	return i18n.T(i18n.RouteBorder, b.From.Location.Country, b.To.Location.Country, b.From.TTL, b.To.TTL, b.From.ASN, b.To.ASN)
+}
+
+func (x IXP) String() string {
+	return i18n.T(i18n.RouteIXP, x.Name, x.Hop.TTL, orAny(x.FromASN), orAny(x.ToASN))
+}
+
+func (d Detour) String() string {
+	return i18n.T(i18n.RouteDetour, d.Country, d.Leave.TTL, strings.Join(d.Via, ", "), d.Return.TTL)
+}
+
+func orAny(asn string) string {
+	if asn == "" {
+		return "*"
+	}
+	return asn
+}
@@ -0,0 +1,312 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MPLSLabel formats one MPLS label stack entry the way the tracers record it.
+func MPLSLabel(label, tc, s, ttl int) string {
+	return fmt.Sprintf("[MPLS: Lbl %d, TC %d, S %d, TTL %d]", label, tc, s, ttl)
+}
+
+// ParseMPLSLabel is the inverse of MPLSLabel.
+func ParseMPLSLabel(str string) (label, tc, s, ttl int, ok bool) {
+	n, err := fmt.Sscanf(str, "[MPLS: Lbl %d, TC %d, S %d, TTL %d]", &label, &tc, &s, &ttl)
+	return label, tc, s, ttl, err == nil && n == 4
+}
+
+// Import converts the output of other traceroute tools into a Document: RIPE Atlas traceroute
+// results (a single result or the list returned by the Atlas API, of which the first traceroute
+// is used) and `mtr --json` reports. ok is false when data is in neither format.
+func Import(data []byte) (doc *Document, ok bool, err error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var list []json.RawMessage
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, false, nil
+		}
+		for _, item := range list {
+			if isAtlas(item) {
+				doc, err := importAtlas(item)
+				return doc, true, err
+			}
+		}
+		return nil, false, nil
+	}
+	switch {
+	case isAtlas(data):
+		doc, err := importAtlas(data)
+		return doc, true, err
+	case isMTR(data):
+		doc, err := importMTR(data)
+		return doc, true, err
+	}
+	return nil, false, nil
+}
+
+func isAtlas(data []byte) bool {
+	var probe struct {
+		Type   string          `json:"type"`
+		Result json.RawMessage `json:"result"`
+	}
+	return json.Unmarshal(data, &probe) == nil && probe.Type == "traceroute" && len(probe.Result) > 0
+}
+
+func isMTR(data []byte) bool {
+	var probe struct {
+		Report struct {
+			Hubs json.RawMessage `json:"hubs"`
+		} `json:"report"`
+	}
+	return json.Unmarshal(data, &probe) == nil && len(probe.Report.Hubs) > 0
+}
+
+// atlasResult 为 RIPE Atlas traceroute 结果中导入所需的字段
+type atlasResult struct {
+	DstName   string `json:"dst_name"`
+	DstAddr   string `json:"dst_addr"`
+	SrcAddr   string `json:"src_addr"`
+	Proto     string `json:"proto"`
+	Size      int    `json:"size"`
+	Timestamp int64  `json:"timestamp"`
+	EndTime   int64  `json:"endtime"`
+	Result    []struct {
+		Hop    int          `json:"hop"`
+		Error  string       `json:"error"`
+		Result []atlasReply `json:"result"`
+	} `json:"result"`
+}
+
+type atlasReply struct {
+	X       string   `json:"x"`
+	From    string   `json:"from"`
+	RTT     *float64 `json:"rtt"`
+	Dup     bool     `json:"dup"`
+	Late    int      `json:"late"`
+	ICMPExt *struct {
+		Obj []struct {
+			Class int `json:"class"`
+			MPLS  []struct {
+				Label int `json:"label"`
+				Exp   int `json:"exp"`
+				S     int `json:"s"`
+				TTL   int `json:"ttl"`
+			} `json:"mpls"`
+		} `json:"obj"`
+	} `json:"icmpext"`
+}
+
+func importAtlas(data []byte) (*Document, error) {
+	var in atlasResult
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("decode atlas result: %w", err)
+	}
+	doc := &Document{SchemaVersion: Version, Metadata: Metadata{
+		Tool:   "ripe-atlas",
+		Method: strings.ToLower(in.Proto),
+		Target: in.DstName,
+		DstIP:  in.DstAddr,
+		SrcIP:  in.SrcAddr,
+	}}
+	if doc.Metadata.Target == "" {
+		doc.Metadata.Target = in.DstAddr
+	}
+	if in.Timestamp > 0 {
+		doc.Metadata.StartedAt = time.Unix(in.Timestamp, 0).UTC()
+	}
+	if in.EndTime >= in.Timestamp && in.Timestamp > 0 {
+		doc.Metadata.FinishedAt = time.Unix(in.EndTime, 0).UTC()
+		doc.Metadata.DurationMs = (in.EndTime - in.Timestamp) * 1000
+	}
+	doc.Metadata.Options.PacketSize = in.Size
+	for _, h := range in.Result {
+		// hop 255 带 error 字段表示探测本身失败，不是一跳
+		if h.Error != "" || h.Hop <= 0 {
+			continue
+		}
+		if err := checkHop(h.Hop, len(h.Result)); err != nil {
+			return nil, err
+		}
+		hop := Hop{TTL: h.Hop}
+		for _, r := range h.Result {
+			switch {
+			case r.Dup || r.Late > 0:
+				// 重复与迟到的响应不计入探测次数
+				continue
+			case r.X == "*" || r.From == "" || r.RTT == nil:
+				hop.Attempts = append(hop.Attempts, Attempt{Error: "hop timeout", ErrorCode: ErrorCodeTimeout})
+			default:
+				a := Attempt{Success: true, IP: r.From, RTT: *r.RTT}
+				if r.ICMPExt != nil {
+					for _, obj := range r.ICMPExt.Obj {
+						if obj.Class != 1 {
+							continue
+						}
+						for _, m := range obj.MPLS {
+							a.MPLS = append(a.MPLS, MPLSLabel(m.Label, m.Exp, m.S, m.TTL))
+						}
+					}
+				}
+				hop.Attempts = append(hop.Attempts, a)
+			}
+		}
+		doc.Metadata.Options.Queries = max(doc.Metadata.Options.Queries, len(hop.Attempts))
+		doc.Hops = append(doc.Hops, hop)
+	}
+	if len(doc.Hops) == 0 {
+		return nil, errors.New("no hops in atlas result")
+	}
+	sort.SliceStable(doc.Hops, func(i, j int) bool { return doc.Hops[i].TTL < doc.Hops[j].TTL })
+	return doc, nil
+}
+
+// mtrReport 为 `mtr --json` 的输出；旧版本的 count 与 psize 可能是字符串
+type mtrReport struct {
+	Report struct {
+		MTR struct {
+			Src   string      `json:"src"`
+			Dst   string      `json:"dst"`
+			Tests json.Number `json:"tests"`
+			PSize json.Number `json:"psize"`
+		} `json:"mtr"`
+		Hubs []struct {
+			Count json.Number `json:"count"`
+			Host  string      `json:"host"`
+			ASN   string      `json:"ASN"`
+			Loss  float64     `json:"Loss%"`
+			Snt   json.Number `json:"Snt"`
+			Last  float64     `json:"Last"`
+			Avg   float64     `json:"Avg"`
+			Best  float64     `json:"Best"`
+			Wrst  float64     `json:"Wrst"`
+		} `json:"hubs"`
+	} `json:"report"`
+}
+
+func importMTR(data []byte) (*Document, error) {
+	var in mtrReport
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&in); err != nil {
+		return nil, fmt.Errorf("decode mtr report: %w", err)
+	}
+	meta := in.Report.MTR
+	doc := &Document{SchemaVersion: Version, Metadata: Metadata{
+		Tool:   "mtr",
+		Method: "icmp",
+		Target: meta.Dst,
+	}}
+	if net.ParseIP(meta.Dst) != nil {
+		doc.Metadata.DstIP = meta.Dst
+	}
+	if net.ParseIP(meta.Src) != nil {
+		doc.Metadata.SrcIP = meta.Src
+	}
+	doc.Metadata.Options.Queries = numberInt(meta.Tests)
+	doc.Metadata.Options.PacketSize = numberInt(meta.PSize)
+
+	for i, hub := range in.Report.Hubs {
+		ttl := numberInt(hub.Count)
+		if ttl <= 0 {
+			ttl = i + 1
+		}
+		sent := numberInt(hub.Snt)
+		if sent <= 0 {
+			sent = doc.Metadata.Options.Queries
+		}
+		// 先校验再按探测次数分配样本
+		if err := checkHop(ttl, sent); err != nil {
+			return nil, err
+		}
+		hop := Hop{TTL: ttl}
+		ip, hostname := splitMTRHost(hub.Host)
+		received := 0
+		if hub.Host != "???" {
+			received = min(max(int(math.Round(float64(sent)*(100-hub.Loss)/100)), 0), sent)
+		}
+		for _, rtt := range mtrSamples(received, hub.Last, hub.Avg, hub.Best, hub.Wrst) {
+			a := Attempt{Success: true, IP: ip, Hostname: hostname, RTT: rtt}
+			if asn := strings.TrimPrefix(strings.ToUpper(hub.ASN), "AS"); asn != "" && asn != "???" {
+				a.Geo = &Geo{ASN: asn}
+			}
+			hop.Attempts = append(hop.Attempts, a)
+		}
+		for range sent - received {
+			hop.Attempts = append(hop.Attempts, Attempt{Error: "hop timeout", ErrorCode: ErrorCodeTimeout})
+		}
+		// 同一 TTL 的多条记录（多路径）合并为一跳
+		if n := len(doc.Hops); n > 0 && doc.Hops[n-1].TTL == ttl {
+			if err := checkHop(ttl, len(doc.Hops[n-1].Attempts)+len(hop.Attempts)); err != nil {
+				return nil, err
+			}
+			doc.Hops[n-1].Attempts = append(doc.Hops[n-1].Attempts, hop.Attempts...)
+			continue
+		}
+		doc.Hops = append(doc.Hops, hop)
+	}
+	if len(doc.Hops) == 0 {
+		return nil, errors.New("no hops in mtr report")
+	}
+	// mtr 报告中目的地址即最后一跳
+	if doc.Metadata.DstIP == "" {
+		for _, a := range doc.Hops[len(doc.Hops)-1].Attempts {
+			if a.IP != "" {
+				doc.Metadata.DstIP = a.IP
+				break
+			}
+		}
+	}
+	return doc, nil
+}
+
+// splitMTRHost 解析 "hostname (ip)"（mtr -b）、纯 IP 或纯主机名
+func splitMTRHost(host string) (ip, hostname string) {
+	if host == "???" {
+		return "", ""
+	}
+	if name, rest, ok := strings.Cut(host, " ("); ok && strings.HasSuffix(rest, ")") {
+		return strings.TrimSuffix(rest, ")"), name
+	}
+	if net.ParseIP(host) != nil {
+		return host, ""
+	}
+	return "", host
+}
+
+// mtrSamples 根据统计值还原 n 个 RTT 样本，使其最小、平均与最大值与报告一致
+func mtrSamples(n int, last, avg, best, worst float64) []float64 {
+	switch {
+	case n <= 0:
+		return nil
+	case n == 1:
+		return []float64{last}
+	}
+	samples := make([]float64, 0, n)
+	samples = append(samples, best)
+	if n > 2 {
+		rest := (avg*float64(n) - best - worst) / float64(n-2)
+		for range n - 2 {
+			samples = append(samples, rest)
+		}
+	}
+	return append(samples, worst)
+}
+
+func numberInt(n json.Number) int {
+	if i, err := strconv.Atoi(strings.TrimSpace(n.String())); err == nil {
+		return i
+	}
+	if f, err := n.Float64(); err == nil {
+		return int(f)
+	}
+	return 0
+}
@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const atlasSample = `[{"fw":5080,"lts":12,"endtime":1700000012,"from":"203.0.113.5","msm_id":5001,"prb_id":1234,
+"timestamp":1700000010,"dst_name":"example.com","dst_addr":"93.184.216.34","src_addr":"192.168.1.10","proto":"UDP",
+"af":4,"size":48,"paris_id":1,"msm_name":"Traceroute","type":"traceroute","result":[
+{"hop":1,"result":[{"from":"192.168.1.1","ttl":64,"size":76,"rtt":1.234},{"x":"*"},{"from":"192.168.1.1","ttl":64,"size":76,"rtt":1.1,"dup":true},{"from":"192.168.1.1","rtt":1.5}]},
+{"hop":2,"result":[{"from":"10.0.0.1","rtt":5.5,"icmpext":{"version":2,"rfc4884":0,"obj":[{"class":1,"type":1,"mpls":[{"exp":0,"label":24001,"s":1,"ttl":1}]}]}}]},
+{"hop":255,"error":"sendto failed"}]}]`
+
+const mtrSample = `{"report":{"mtr":{"src":"host","dst":"one.one.one.one","tos":0,"tests":4,"psize":"64","bitpattern":"0x00"},
+"hubs":[
+{"count":1,"host":"_gateway (192.168.1.1)","ASN":"AS???","Loss%":0.0,"Snt":4,"Last":0.5,"Avg":0.6,"Best":0.4,"Wrst":0.9,"StDev":0.2},
+{"count":"2","host":"???","Loss%":100.0,"Snt":4,"Last":0,"Avg":0,"Best":0,"Wrst":0,"StDev":0},
+{"count":3,"host":"1.1.1.1","ASN":"AS13335","Loss%":50.0,"Snt":4,"Last":3,"Avg":3,"Best":2,"Wrst":4,"StDev":1}]}}`
+
+func TestImportAtlas(t *testing.T) {
+	doc, ok, err := Import([]byte(atlasSample))
+	require.True(t, ok)
+	require.NoError(t, err)
+	assert.Equal(t, "ripe-atlas", doc.Metadata.Tool)
+	assert.Equal(t, "udp", doc.Metadata.Method)
+	assert.Equal(t, "example.com", doc.Metadata.Target)
+	assert.Equal(t, "93.184.216.34", doc.Metadata.DstIP)
+	assert.Equal(t, time.Unix(1700000010, 0).UTC(), doc.Metadata.StartedAt)
+	assert.EqualValues(t, 2000, doc.Metadata.DurationMs)
+	assert.Equal(t, 3, doc.Metadata.Options.Queries)
+	require.Len(t, doc.Hops, 2)
+	// 重复响应被忽略，超时保留为失败的尝试
+	require.Len(t, doc.Hops[0].Attempts, 3)
+	assert.Equal(t, ErrorCodeTimeout, doc.Hops[0].Attempts[1].ErrorCode)
+	assert.Equal(t, 1.5, doc.Hops[0].Attempts[2].RTT)
+	assert.Equal(t, []string{"[MPLS: Lbl 24001, TC 0, S 1, TTL 1]"}, doc.Hops[1].Attempts[0].MPLS)
+
+	res, err := doc.Result()
+	require.NoError(t, err)
+	require.Len(t, res.Hops, 2)
+	assert.Equal(t, "10.0.0.1", res.Hops[1][0].Address.String())
+}
+
+func TestImportMTR(t *testing.T) {
+	doc, err := ParseDocument([]byte(mtrSample))
+	require.NoError(t, err)
+	assert.Equal(t, "mtr", doc.Metadata.Tool)
+	assert.Equal(t, "one.one.one.one", doc.Metadata.Target)
+	assert.Equal(t, "1.1.1.1", doc.Metadata.DstIP)
+	assert.Equal(t, 64, doc.Metadata.Options.PacketSize)
+	require.Len(t, doc.Hops, 3)
+
+	first := doc.Hops[0].Attempts
+	require.Len(t, first, 4)
+	assert.Equal(t, "192.168.1.1", first[0].IP)
+	assert.Equal(t, "_gateway", first[0].Hostname)
+	assert.Nil(t, first[0].Geo)
+	// 还原的样本保持最小、平均与最大值
+	sum := 0.0
+	for _, a := range first {
+		sum += a.RTT
+	}
+	assert.InDelta(t, 0.6, sum/4, 1e-9)
+	assert.Equal(t, 0.4, first[0].RTT)
+	assert.Equal(t, 0.9, first[3].RTT)
+
+	assert.Equal(t, 2, doc.Hops[1].TTL)
+	for _, a := range doc.Hops[1].Attempts {
+		assert.False(t, a.Success)
+	}
+	last := doc.Hops[2].Attempts
+	require.Len(t, last, 4)
+	assert.True(t, last[1].Success)
+	assert.False(t, last[2].Success)
+	assert.Equal(t, "13335", last[0].Geo.ASN)
+
+	// 打印器要求响应地址带有地理位置
+	res, err := doc.Result()
+	require.NoError(t, err)
+	require.NotNil(t, res.Hops[0][0].Geo)
+	assert.Empty(t, res.Hops[0][0].Geo.Asnumber)
+	assert.Nil(t, res.Hops[1][0].Geo)
+}
+
+func TestImportUnknown(t *testing.T) {
+	_, ok, err := Import([]byte(`{"hops":[]}`))
+	assert.False(t, ok)
+	assert.NoError(t, err)
+	_, ok, _ = Import([]byte(`[1,2]`))
+	assert.False(t, ok)
+}
+
+func TestImportLimits(t *testing.T) {
+	for _, data := range []string{
+		`{"report":{"mtr":{"dst":"1.1.1.1"},"hubs":[{"count":1,"host":"1.1.1.1","Snt":2000000000}]}}`,
+		`{"report":{"mtr":{"dst":"1.1.1.1"},"hubs":[{"count":2000000000,"host":"1.1.1.1","Snt":1}]}}`,
+		`{"type":"traceroute","dst_addr":"1.1.1.1","result":[{"hop":2000000000,"result":[{"x":"*"}]}]}`,
+	} {
+		_, ok, err := Import([]byte(data))
+		assert.True(t, ok, data)
+		assert.Error(t, err, data)
+	}
+
+	// 丢包率为负时收到的样本数不超过发送数
+	doc, _, err := Import([]byte(`{"report":{"mtr":{"dst":"1.1.1.1"},"hubs":[{"count":1,"host":"1.1.1.1","Snt":2,"Loss%":-1e12}]}}`))
+	require.NoError(t, err)
+	assert.Len(t, doc.Hops[0].Attempts, 2)
+
+	_, err = (&Document{Hops: []Hop{{TTL: 2000000000}}}).Result()
+	assert.Error(t, err)
+	_, err = (&Document{Hops: []Hop{{TTL: 1, Attempts: make([]Attempt, MaxHopAttempts+1)}}}).Result()
+	assert.Error(t, err)
+}
+
+func TestMPLSLabel(t *testing.T) {
+	label, tc, s, ttl, ok := ParseMPLSLabel(MPLSLabel(16, 1, 1, 255))
+	assert.True(t, ok)
+	assert.Equal(t, [4]int{16, 1, 1, 255}, [4]int{label, tc, s, ttl})
+	_, _, _, _, ok = ParseMPLSLabel("Lbl 16")
+	assert.False(t, ok)
+}
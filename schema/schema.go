@@ -0,0 +1,458 @@
+// Package schema defines the versioned JSON document shared by `nexttrace --json` and the web console API.
+package schema
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nxtrace/NTrace-core/anomaly"
+	"github.com/nxtrace/NTrace-core/config"
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/reporter"
+	"github.com/nxtrace/NTrace-core/trace"
+	"github.com/nxtrace/NTrace-core/util"
+)
+
+// Version is the schema version written to every document. The major version
+// changes only when fields are removed or change meaning.
+const Version = "1.0"
+
+// Error codes of failed attempts.
+const (
+	ErrorCodeTimeout = "timeout"
+	ErrorCodeUnknown = "unknown"
+)
+
+// Limits of loaded and imported results. They bound the memory a small but hostile file can
+// make us allocate, e.g. through a huge TTL or probe count.
+const (
+	MaxTTL         = 255
+	MaxHopAttempts = 255
+)
+
+// JSONSchema is the JSON Schema (draft 2020-12) describing Document.
+//
+//go:embed trace.schema.json
+var JSONSchema []byte
+
+// Document is a complete trace result.
+type Document struct {
+	SchemaVersion string   `json:"schema_version"`
+	Metadata      Metadata `json:"metadata"`
+	Hops          []Hop    `json:"hops"`
+	TraceMapURL   string   `json:"trace_map_url,omitempty"`
+	// RoutePath is the AS-level analysis of the path, filled by `nexttrace -P --json` and
+	// the web console. It must be computed from the same (redacted) data as Hops.
+	RoutePath *reporter.Path `json:"route_path,omitempty"`
+}
+
+// Metadata describes how and when the trace was run.
+type Metadata struct {
+	Tool         string    `json:"tool"`
+	ToolVersion  string    `json:"tool_version"`
+	Method       string    `json:"method"`
+	Target       string    `json:"target"`
+	DstIP        string    `json:"dst_ip"`
+	DstPort      int       `json:"dst_port,omitempty"`
+	SrcIP        string    `json:"src_ip,omitempty"`
+	SrcPort      int       `json:"src_port,omitempty"`
+	DataProvider string    `json:"data_provider"`
+	Language     string    `json:"language"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at,omitzero"`
+	DurationMs   int64     `json:"duration_ms,omitzero"`
+	Options      Options   `json:"options"`
+}
+
+// Options are the probe settings of the trace.
+type Options struct {
+	BeginHop         int  `json:"begin_hop"`
+	MaxHops          int  `json:"max_hops"`
+	Queries          int  `json:"queries"`
+	MaxAttempts      int  `json:"max_attempts,omitempty"`
+	ParallelRequests int  `json:"parallel_requests"`
+	PacketSize       int  `json:"packet_size"`
+	PacketIntervalMs int  `json:"packet_interval_ms"`
+	TTLIntervalMs    int  `json:"ttl_interval_ms"`
+	TimeoutMs        int  `json:"timeout_ms"`
+	RDNS             bool `json:"rdns"`
+	DN42             bool `json:"dn42,omitempty"`
+}
+
+// Hop holds every attempt of one TTL.
+type Hop struct {
+	TTL      int       `json:"ttl"`
+	Attempts []Attempt `json:"attempts"`
+	// Annotations flag RTTs that are easy to misread, see package anomaly.
+	Annotations []anomaly.Annotation `json:"annotations,omitempty"`
+}
+
+// Attempt is a single probe.
+type Attempt struct {
+	Success   bool     `json:"success"`
+	IP        string   `json:"ip,omitempty"`
+	Hostname  string   `json:"hostname,omitempty"`
+	RTT       float64  `json:"rtt_ms,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	ErrorCode string   `json:"error_code,omitempty"`
+	MPLS      []string `json:"mpls,omitempty"`
+	Geo       *Geo     `json:"geo,omitempty"`
+}
+
+// Geo is the geolocation of an address. Field names follow the provider layer but the
+// type is decoupled from it so provider changes do not leak into the output.
+type Geo struct {
+	ASN       string  `json:"asnumber,omitempty"`
+	Country   string  `json:"country,omitempty"`
+	CountryEn string  `json:"country_en,omitempty"`
+	Prov      string  `json:"prov,omitempty"`
+	ProvEn    string  `json:"prov_en,omitempty"`
+	City      string  `json:"city,omitempty"`
+	CityEn    string  `json:"city_en,omitempty"`
+	District  string  `json:"district,omitempty"`
+	Owner     string  `json:"owner,omitempty"`
+	ISP       string  `json:"isp,omitempty"`
+	Domain    string  `json:"domain,omitempty"`
+	Whois     string  `json:"whois,omitempty"`
+	Lat       float64 `json:"lat,omitempty"`
+	Lng       float64 `json:"lng,omitempty"`
+	Prefix    string  `json:"prefix,omitempty"`
+	Source    string  `json:"source,omitempty"`
+}
+
+// NewMetadata fills Metadata from the trace configuration.
+func NewMetadata(target string, method trace.Method, dataProvider string, conf trace.Config, started, finished time.Time) Metadata {
+	meta := Metadata{
+		Tool:         "nexttrace",
+		ToolVersion:  config.Version,
+		Method:       string(method),
+		Target:       target,
+		DstPort:      conf.DstPort,
+		SrcIP:        conf.SrcAddr,
+		DataProvider: dataProvider,
+		Language:     conf.Lang,
+		StartedAt:    started.UTC(),
+		FinishedAt:   finished.UTC(),
+		DurationMs:   finished.Sub(started).Milliseconds(),
+		Options: Options{
+			BeginHop:         conf.BeginHop,
+			MaxHops:          conf.MaxHops,
+			Queries:          conf.NumMeasurements,
+			MaxAttempts:      conf.MaxAttempts,
+			ParallelRequests: conf.ParallelRequests,
+			PacketSize:       conf.PktSize,
+			PacketIntervalMs: conf.PacketInterval,
+			TTLIntervalMs:    conf.TTLInterval,
+			TimeoutMs:        int(conf.Timeout / time.Millisecond),
+			RDNS:             conf.RDNS,
+			DN42:             conf.DN42,
+		},
+	}
+	if conf.DstIP != nil {
+		meta.DstIP = conf.DstIP.String()
+	}
+	if conf.SrcPort > 0 {
+		meta.SrcPort = conf.SrcPort
+	}
+	// ICMP 没有端口
+	if method == trace.ICMPTrace {
+		meta.DstPort = 0
+	}
+	return meta
+}
+
+// NewDocument converts res into a Document; geo names are localized for meta.Language.
+func NewDocument(res *trace.Result, meta Metadata) Document {
+	doc := Document{SchemaVersion: Version, Metadata: meta, Hops: NewHops(res, meta.Language)}
+	if res != nil {
+		doc.TraceMapURL = res.TraceMapUrl
+	}
+	return doc
+}
+
+// NewHops converts every TTL of res that has attempts, with their annotations.
+func NewHops(res *trace.Result, lang string) []Hop {
+	if res == nil {
+		return nil
+	}
+	hops := make([]Hop, 0, len(res.Hops))
+	annotations := anomaly.Analyze(res.Hops, anomaly.Options{})
+	for idx, attempts := range res.Hops {
+		hop := NewHop(idx+1, attempts, lang)
+		if len(hop.Attempts) == 0 {
+			continue
+		}
+		hop.Annotations = annotations[idx]
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// NewHop converts the attempts of one TTL.
+func NewHop(ttl int, attempts []trace.Hop, lang string) Hop {
+	hop := Hop{TTL: ttl, Attempts: make([]Attempt, 0, len(attempts))}
+	for _, attempt := range attempts {
+		hop.Attempts = append(hop.Attempts, NewAttempt(attempt, lang))
+	}
+	return hop
+}
+
+// NewAttempt converts a single probe.
+func NewAttempt(h trace.Hop, lang string) Attempt {
+	a := Attempt{
+		Success:  h.Success,
+		Hostname: h.Hostname,
+		MPLS:     h.MPLS,
+		Geo:      NewGeo(LocalizeGeo(h.Geo, lang)),
+	}
+	if ip := util.AddrIP(h.Address); ip != nil {
+		a.IP = ip.String()
+	} else if h.Address != nil {
+		a.IP = h.Address.String()
+	}
+	if h.RTT > 0 {
+		a.RTT = float64(h.RTT) / float64(time.Millisecond)
+	}
+	if h.Error != nil {
+		a.Error = h.Error.Error()
+		a.ErrorCode = ErrorCode(h.Error)
+	}
+	return a
+}
+
+// ErrorCode classifies a hop error into one of the ErrorCode constants.
+func ErrorCode(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case trace.IsHopTimeout(err):
+		return ErrorCodeTimeout
+	default:
+		return ErrorCodeUnknown
+	}
+}
+
+// NewGeo copies the public fields of g.
+func NewGeo(g *ipgeo.IPGeoData) *Geo {
+	if g == nil {
+		return nil
+	}
+	return &Geo{
+		ASN:       g.Asnumber,
+		Country:   g.Country,
+		CountryEn: g.CountryEn,
+		Prov:      g.Prov,
+		ProvEn:    g.ProvEn,
+		City:      g.City,
+		CityEn:    g.CityEn,
+		District:  g.District,
+		Owner:     g.Owner,
+		ISP:       g.Isp,
+		Domain:    g.Domain,
+		Whois:     g.Whois,
+		Lat:       g.Lat,
+		Lng:       g.Lng,
+		Prefix:    g.Prefix,
+		Source:    g.Source,
+	}
+}
+
+// IPGeoData converts g back to the provider type.
+func (g *Geo) IPGeoData() *ipgeo.IPGeoData {
+	if g == nil {
+		return nil
+	}
+	return &ipgeo.IPGeoData{
+		Asnumber:  g.ASN,
+		Country:   g.Country,
+		CountryEn: g.CountryEn,
+		Prov:      g.Prov,
+		ProvEn:    g.ProvEn,
+		City:      g.City,
+		CityEn:    g.CityEn,
+		District:  g.District,
+		Owner:     g.Owner,
+		Isp:       g.ISP,
+		Domain:    g.Domain,
+		Whois:     g.Whois,
+		Lat:       g.Lat,
+		Lng:       g.Lng,
+		Prefix:    g.Prefix,
+		Source:    g.Source,
+	}
+}
+
+// LocalizeGeo returns a copy of src whose country, province and city use the
+// English names for "en" and fall back to them when the local names are empty otherwise.
+func LocalizeGeo(src *ipgeo.IPGeoData, lang string) *ipgeo.IPGeoData {
+	if src == nil {
+		return nil
+	}
+
+	dst := *src
+	switch strings.ToLower(lang) {
+	case "en":
+		if dst.CountryEn != "" {
+			dst.Country = dst.CountryEn
+		}
+		if dst.ProvEn != "" {
+			dst.Prov = dst.ProvEn
+		}
+		if dst.CityEn != "" {
+			dst.City = dst.CityEn
+		}
+	default:
+		if dst.Country == "" && dst.CountryEn != "" {
+			dst.Country = dst.CountryEn
+		}
+		if dst.Prov == "" && dst.ProvEn != "" {
+			dst.Prov = dst.ProvEn
+		}
+		if dst.City == "" && dst.CityEn != "" {
+			dst.City = dst.CityEn
+		}
+	}
+	return &dst
+}
+
+// checkHop 校验 TTL 与探测次数不超过 MaxTTL / MaxHopAttempts
+func checkHop(ttl, attempts int) error {
+	if ttl < 1 || ttl > MaxTTL {
+		return fmt.Errorf("invalid ttl %d", ttl)
+	}
+	if attempts > MaxHopAttempts {
+		return fmt.Errorf("too many attempts at ttl %d: %d (max %d)", ttl, attempts, MaxHopAttempts)
+	}
+	return nil
+}
+
+// Result converts the document back into a trace.Result so it can be rendered by the printers.
+func (d *Document) Result() (*trace.Result, error) {
+	maxTTL := 0
+	for _, hop := range d.Hops {
+		if err := checkHop(hop.TTL, len(hop.Attempts)); err != nil {
+			return nil, err
+		}
+		if hop.TTL > maxTTL {
+			maxTTL = hop.TTL
+		}
+	}
+	res := &trace.Result{Hops: make([][]trace.Hop, maxTTL), TraceMapUrl: d.TraceMapURL}
+	for _, hop := range d.Hops {
+		attempts := make([]trace.Hop, 0, len(hop.Attempts))
+		for _, a := range hop.Attempts {
+			h := trace.Hop{
+				Success:  a.Success,
+				Hostname: a.Hostname,
+				TTL:      hop.TTL,
+				RTT:      time.Duration(a.RTT * float64(time.Millisecond)),
+				Error:    trace.HopError(a.Error),
+				Geo:      a.Geo.IPGeoData(),
+				Lang:     d.Metadata.Language,
+				MPLS:     a.MPLS,
+			}
+			if a.IP != "" {
+				// 脱敏后的地址（如 hidden、self 或假名）不是 IP，按标签还原
+				if ip := net.ParseIP(a.IP); ip != nil {
+					h.Address = &net.IPAddr{IP: ip}
+				} else {
+					h.Address = trace.Label(a.IP)
+				}
+				// 打印器要求响应地址带有地理位置，导入的结果可能没有
+				if h.Geo == nil {
+					h.Geo = &ipgeo.IPGeoData{}
+				}
+			}
+			if h.Error == nil && a.ErrorCode == ErrorCodeTimeout {
+				h.Error = trace.HopError("hop timeout")
+			}
+			attempts = append(attempts, h)
+		}
+		res.Hops[hop.TTL-1] = attempts
+	}
+	// 补齐未出现在文档中的 TTL，保持与实时结果相同的下标含义
+	for i := range res.Hops {
+		if res.Hops[i] == nil {
+			res.Hops[i] = []trace.Hop{{TTL: i + 1}}
+		}
+	}
+	return res, nil
+}
+
+// LoadDocument reads a Document from path. Legacy trace.Result JSON written by older
+// releases is converted, with metadata limited to what the hops themselves carry.
+func LoadDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// ParseDocument is LoadDocument for data already in memory. Results of other tools
+// accepted by Import are converted as well.
+func ParseDocument(data []byte) (*Document, error) {
+	if doc, ok, err := Import(data); ok {
+		return doc, err
+	}
+	var probe struct {
+		SchemaVersion string `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	if probe.SchemaVersion == "" {
+		var res trace.Result
+		if err := json.Unmarshal(data, &res); err != nil {
+			return nil, err
+		}
+		if len(res.Hops) == 0 {
+			return nil, errors.New("no hops in result")
+		}
+		doc := NewDocument(&res, legacyMetadata(&res))
+		return &doc, nil
+	}
+	if major, _, _ := strings.Cut(probe.SchemaVersion, "."); major != strings.SplitN(Version, ".", 2)[0] {
+		return nil, fmt.Errorf("unsupported schema version %s", probe.SchemaVersion)
+	}
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Hops) == 0 {
+		return nil, errors.New("no hops in result")
+	}
+	return &doc, nil
+}
+
+// legacyMetadata 从旧版结果的跳中尽量恢复元数据：目标取最后一个响应地址，语言取跳上记录的语言
+func legacyMetadata(res *trace.Result) Metadata {
+	meta := Metadata{Tool: "nexttrace"}
+	for i := len(res.Hops) - 1; i >= 0 && meta.DstIP == ""; i-- {
+		for _, h := range res.Hops[i] {
+			if ip := util.AddrIP(h.Address); ip != nil {
+				meta.DstIP = ip.String()
+				break
+			}
+		}
+	}
+	for _, attempts := range res.Hops {
+		for _, h := range attempts {
+			if meta.Language == "" {
+				meta.Language = h.Lang
+			}
+		}
+	}
+	meta.Target = meta.DstIP
+	return meta
+}
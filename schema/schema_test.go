@@ -0,0 +1,152 @@
+package schema
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/anomaly"
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/reporter"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+func testResult() *trace.Result {
+	return &trace.Result{Hops: [][]trace.Hop{
+		{{TTL: 1, Error: trace.HopError("hop timeout")}},
+		nil,
+		{{
+			Success:  true,
+			TTL:      3,
+			Address:  &net.IPAddr{IP: net.ParseIP("1.1.1.1")},
+			Hostname: "one.one.one.one",
+			RTT:      1500 * time.Microsecond,
+			MPLS:     []string{"Lbl 16"},
+			Geo:      &ipgeo.IPGeoData{Asnumber: "13335", Country: "美国", CountryEn: "United States", Router: map[string][]string{"x": nil}},
+		}},
+	}}
+}
+
+func TestNewDocument(t *testing.T) {
+	started := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+	conf := trace.Config{DstIP: net.ParseIP("1.1.1.1"), DstPort: 80, MaxHops: 30, NumMeasurements: 3, Lang: "en", Timeout: time.Second, RDNS: true}
+	meta := NewMetadata("one.one.one.one", trace.ICMPTrace, "LeoMoeAPI", conf, started, started.Add(2*time.Second))
+	doc := NewDocument(testResult(), meta)
+
+	assert.Equal(t, Version, doc.SchemaVersion)
+	assert.Equal(t, "1.1.1.1", doc.Metadata.DstIP)
+	assert.Zero(t, doc.Metadata.DstPort, "icmp has no port")
+	assert.EqualValues(t, 2000, doc.Metadata.DurationMs)
+	assert.Equal(t, 1000, doc.Metadata.Options.TimeoutMs)
+
+	require.Len(t, doc.Hops, 2, "TTLs without attempts are skipped")
+	assert.Equal(t, ErrorCodeTimeout, doc.Hops[0].Attempts[0].ErrorCode)
+	assert.Equal(t, "hop timeout", doc.Hops[0].Attempts[0].Error)
+
+	a := doc.Hops[1].Attempts[0]
+	assert.Equal(t, 3, doc.Hops[1].TTL)
+	assert.Equal(t, "1.1.1.1", a.IP)
+	assert.InDelta(t, 1.5, a.RTT, 1e-9)
+	assert.Equal(t, "United States", a.Geo.Country, "geo is localized")
+
+	out, err := json.Marshal(doc)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "router")
+	assert.NotContains(t, string(out), "annotations")
+	assert.Contains(t, string(out), `"schema_version":"1.0"`)
+
+	res, err := doc.Result()
+	require.NoError(t, err)
+	require.Len(t, res.Hops, 3)
+	assert.True(t, trace.IsHopTimeout(res.Hops[0][0].Error))
+	assert.Equal(t, 2, res.Hops[1][0].TTL, "missing TTLs are filled")
+	assert.Equal(t, "1.1.1.1", res.Hops[2][0].Address.String())
+	assert.Equal(t, 1500*time.Microsecond, res.Hops[2][0].RTT)
+	assert.Equal(t, "13335", res.Hops[2][0].Geo.Asnumber)
+}
+
+func TestNewHopsAnnotations(t *testing.T) {
+	res := testResult()
+	slow := res.Hops[2][0]
+	slow.RTT = 80 * time.Millisecond
+	res.Hops = append(res.Hops, []trace.Hop{slow})
+	hops := NewHops(res, "en")
+	require.Len(t, hops, 3)
+	assert.Empty(t, hops[1].Annotations)
+	require.Len(t, hops[2].Annotations, 1)
+	assert.Equal(t, anomaly.Jump, hops[2].Annotations[0].Kind)
+	assert.Equal(t, 3, hops[2].Annotations[0].RefTTL)
+}
+
+func TestLoadDocument(t *testing.T) {
+	dir := t.TempDir()
+
+	legacy, err := json.Marshal(testResult())
+	require.NoError(t, err)
+	legacyPath := filepath.Join(dir, "legacy.json")
+	require.NoError(t, os.WriteFile(legacyPath, legacy, 0o644))
+	doc, err := LoadDocument(legacyPath)
+	require.NoError(t, err)
+	assert.Equal(t, Version, doc.SchemaVersion)
+	assert.Len(t, doc.Hops, 2)
+
+	current, err := json.Marshal(doc)
+	require.NoError(t, err)
+	currentPath := filepath.Join(dir, "current.json")
+	require.NoError(t, os.WriteFile(currentPath, current, 0o644))
+	again, err := LoadDocument(currentPath)
+	require.NoError(t, err)
+	assert.Equal(t, doc, again)
+
+	futurePath := filepath.Join(dir, "future.json")
+	require.NoError(t, os.WriteFile(futurePath, []byte(`{"schema_version":"2.0","hops":[{"ttl":1,"attempts":[]}]}`), 0o644))
+	_, err = LoadDocument(futurePath)
+	assert.ErrorContains(t, err, "unsupported schema version")
+}
+
+// TestJSONSchemaMatchesTypes 保证 JSON Schema 与 Go 类型的字段保持一致
+func TestJSONSchemaMatchesTypes(t *testing.T) {
+	var doc struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(JSONSchema, &doc))
+
+	check := func(name string, typ reflect.Type, props map[string]json.RawMessage) {
+		var fields, documented []string
+		for i := 0; i < typ.NumField(); i++ {
+			tag, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			fields = append(fields, tag)
+		}
+		for key := range props {
+			documented = append(documented, key)
+		}
+		sort.Strings(fields)
+		sort.Strings(documented)
+		assert.Equal(t, fields, documented, name)
+	}
+	check("document", reflect.TypeOf(Document{}), doc.Properties)
+	check("metadata", reflect.TypeOf(Metadata{}), doc.Defs["metadata"].Properties)
+	check("options", reflect.TypeOf(Options{}), doc.Defs["options"].Properties)
+	check("hop", reflect.TypeOf(Hop{}), doc.Defs["hop"].Properties)
+	check("attempt", reflect.TypeOf(Attempt{}), doc.Defs["attempt"].Properties)
+	check("geo", reflect.TypeOf(Geo{}), doc.Defs["geo"].Properties)
+	check("annotation", reflect.TypeOf(anomaly.Annotation{}), doc.Defs["annotation"].Properties)
+	check("route_path", reflect.TypeOf(reporter.Path{}), doc.Defs["route_path"].Properties)
+	check("route_segment", reflect.TypeOf(reporter.Segment{}), doc.Defs["route_segment"].Properties)
+	check("route_hop", reflect.TypeOf(reporter.Hop{}), doc.Defs["route_hop"].Properties)
+	check("route_border", reflect.TypeOf(reporter.Border{}), doc.Defs["route_border"].Properties)
+	check("route_ixp", reflect.TypeOf(reporter.IXP{}), doc.Defs["route_ixp"].Properties)
+	check("route_detour", reflect.TypeOf(reporter.Detour{}), doc.Defs["route_detour"].Properties)
+}
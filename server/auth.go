@@ -0,0 +1,385 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nxtrace/NTrace-core/i18n"
+)
+
+type role string
+
+const (
+	roleViewer   role = "viewer"
+	roleOperator role = "operator"
+	roleAdmin    role = "admin"
+)
+
+type permission int
+
+const (
+	// permView 允许访问页面、选项与 /metrics
+	permView permission = iota
+	// permTrace 允许发起追踪（HTTP、WebSocket 与 /probe）
+	permTrace
+	// permClearCache 允许清空缓存
+	permClearCache
+	// permSourceSelect 允许指定源地址、源端口或源网卡
+	permSourceSelect
+)
+
+var rolePermissions = map[role][]permission{
+	roleViewer:   {permView},
+	roleOperator: {permView, permTrace},
+	roleAdmin:    {permView, permTrace, permClearCache, permSourceSelect},
+}
+
+const (
+	principalKey    = "nexttrace.principal"
+	authCookieName  = "nexttrace_token"
+	basicAuthRealm  = `Basic realm="NextTrace", charset="UTF-8"`
+	passwordSHA256  = "sha256:"
+	tokenQueryParam = "token"
+)
+
+func parseRole(name string) (role, bool) {
+	r := role(strings.ToLower(strings.TrimSpace(name)))
+	_, ok := rolePermissions[r]
+	return r, ok
+}
+
+func (r role) allows(perm permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// principal 为通过认证的调用方
+type principal struct {
+	Name   string
+	Role   role
+	Method string
+}
+
+type authenticator struct {
+	enabled       bool
+	allow         []*net.IPNet
+	tokens        []tokenEntry
+	users         []userEntry
+	anonymousRole role
+	clientCerts   []clientCertEntry
+	defaultCert   string
+	secureCookie  bool
+}
+
+func newAuthenticator(cfg *Config) (*authenticator, error) {
+	allow, err := parseCIDRs(cfg.Auth.AllowCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	a := &authenticator{
+		enabled:      cfg.AuthEnabled(),
+		allow:        allow,
+		tokens:       cfg.Auth.Tokens,
+		users:        cfg.Auth.Users,
+		clientCerts:  cfg.TLS.ClientCerts,
+		defaultCert:  cfg.TLS.DefaultClientRole,
+		secureCookie: cfg.TLS.CertFile != "",
+	}
+	if a.enabled {
+		if r, ok := parseRole(cfg.Auth.AnonymousRole); ok {
+			a.anonymousRole = r
+		}
+	} else {
+		// 未配置任何凭据时保持旧行为：所有人均为 admin
+		a.anonymousRole = roleAdmin
+	}
+	return a, nil
+}
+
+func parseCIDRs(list []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(list))
+	for _, raw := range list {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if !strings.Contains(raw, "/") {
+			if ip := net.ParseIP(raw); ip != nil {
+				if ip.To4() != nil {
+					raw += "/32"
+				} else {
+					raw += "/128"
+				}
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", raw, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func (a *authenticator) clientAllowed(addr string) bool {
+	if len(a.allow) == 0 {
+		return true
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range a.allow {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// middleware 校验客户端地址与凭据，并将 principal 写入请求上下文
+func (a *authenticator) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientIP := c.ClientIP()
+		if !a.clientAllowed(clientIP) {
+			log.Printf("[deploy] (auth) client rejected ip=%s path=%s", clientIP, c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": localize(c, i18n.ClientNotAllowed)})
+			return
+		}
+
+		p, err := a.authenticate(c)
+		if err != nil {
+			log.Printf("[deploy] (auth) authentication failed ip=%s path=%s error=%v", clientIP, c.Request.URL.Path, err)
+			if len(a.users) > 0 {
+				c.Header("WWW-Authenticate", basicAuthRealm)
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": localize(c, i18n.AuthRequired)})
+			return
+		}
+		c.Set(principalKey, p)
+		c.Next()
+	}
+}
+
+var errNoCredentials = errors.New("no credentials")
+
+func (a *authenticator) authenticate(c *gin.Context) (*principal, error) {
+	if p := a.fromClientCert(c.Request); p != nil {
+		return p, nil
+	}
+
+	if token, fromQuery := requestToken(c); token != "" {
+		entry, ok := a.matchToken(token)
+		if !ok {
+			return nil, errors.New("invalid token")
+		}
+		// 通过 ?token= 打开页面时写入 Cookie，使页面后续的 fetch 与 WebSocket 请求沿用该凭据
+		if fromQuery && c.Request.Method == http.MethodGet {
+			c.SetSameSite(http.SameSiteStrictMode)
+			c.SetCookie(authCookieName, token, 0, "/", "", a.secureCookie, true)
+		}
+		r, _ := parseRole(entry.Role)
+		return &principal{Name: entry.Name, Role: r, Method: "token"}, nil
+	}
+
+	if username, password, ok := c.Request.BasicAuth(); ok {
+		entry, ok := a.matchUser(username, password)
+		if !ok {
+			return nil, fmt.Errorf("invalid password for user %q", username)
+		}
+		r, _ := parseRole(entry.Role)
+		return &principal{Name: entry.Username, Role: r, Method: "basic"}, nil
+	}
+
+	if a.anonymousRole != "" {
+		return &principal{Name: "anonymous", Role: a.anonymousRole, Method: "anonymous"}, nil
+	}
+	return nil, errNoCredentials
+}
+
+// fromClientCert 根据已验证的客户端证书 CN 映射角色
+func (a *authenticator) fromClientCert(r *http.Request) *principal {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	name := a.defaultCert
+	for _, entry := range a.clientCerts {
+		if entry.CommonName == cn {
+			name = entry.Role
+			break
+		}
+	}
+	rl, ok := parseRole(name)
+	if !ok {
+		return nil
+	}
+	return &principal{Name: cn, Role: rl, Method: "mtls"}
+}
+
+// requestToken 依次从 Authorization、X-API-Token、查询参数与 Cookie 中读取令牌
+func requestToken(c *gin.Context) (string, bool) {
+	if auth := c.GetHeader("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:]), false
+	}
+	if token := strings.TrimSpace(c.GetHeader("X-API-Token")); token != "" {
+		return token, false
+	}
+	if token := strings.TrimSpace(c.Query(tokenQueryParam)); token != "" {
+		return token, true
+	}
+	if token, err := c.Cookie(authCookieName); err == nil && token != "" {
+		return token, false
+	}
+	return "", false
+}
+
+// accessLogFormatter 与 gin 默认的访问日志格式相同，但去掉 ?token= 中的令牌，避免其以明文写入日志
+func accessLogFormatter(param gin.LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor, methodColor, resetColor = param.StatusCodeColor(), param.MethodColor(), param.ResetColor()
+	}
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		redactTokenQuery(param.Path),
+		param.ErrorMessage,
+	)
+}
+
+func redactTokenQuery(path string) string {
+	p, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// 无法解析时不冒险输出原始查询串
+		return p + "?REDACTED"
+	}
+	if !query.Has(tokenQueryParam) {
+		return path
+	}
+	query.Set(tokenQueryParam, "REDACTED")
+	return p + "?" + query.Encode()
+}
+
+func (a *authenticator) matchToken(token string) (tokenEntry, bool) {
+	sum := sha256.Sum256([]byte(token))
+	var found tokenEntry
+	matched := false
+	for _, entry := range a.tokens {
+		candidate := sha256.Sum256([]byte(entry.Token))
+		if subtle.ConstantTimeCompare(sum[:], candidate[:]) == 1 && !matched {
+			found = entry
+			matched = true
+		}
+	}
+	return found, matched
+}
+
+func (a *authenticator) matchUser(username, password string) (userEntry, bool) {
+	for _, entry := range a.users {
+		if entry.Username != username {
+			continue
+		}
+		return entry, checkPassword(entry.Password, password)
+	}
+	return userEntry{}, false
+}
+
+func checkPassword(stored, given string) bool {
+	if strings.HasPrefix(stored, passwordSHA256) {
+		sum := sha256.Sum256([]byte(given))
+		digest := hex.EncodeToString(sum[:])
+		expected := strings.ToLower(strings.TrimPrefix(stored, passwordSHA256))
+		return subtle.ConstantTimeCompare([]byte(digest), []byte(expected)) == 1
+	}
+	a := sha256.Sum256([]byte(stored))
+	b := sha256.Sum256([]byte(given))
+	return subtle.ConstantTimeCompare(a[:], b[:]) == 1
+}
+
+func currentPrincipal(c *gin.Context) *principal {
+	if v, ok := c.Get(principalKey); ok {
+		if p, ok := v.(*principal); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// principalName 返回当前调用方的用户名，未认证时为空
+func principalName(c *gin.Context) string {
+	if p := currentPrincipal(c); p != nil {
+		return p.Name
+	}
+	return ""
+}
+
+// ownedBy 判断调用方能否访问归属于 owner 的任务或历史记录：仅限本人与 admin 角色，无归属的仅 admin 可见
+func ownedBy(owner string, p *principal) bool {
+	if p == nil {
+		return false
+	}
+	return p.Role == roleAdmin || (owner != "" && owner == p.Name)
+}
+
+// hasPermission 判断当前请求的调用方是否具备 perm；未经过认证中间件的请求视为无权限
+func hasPermission(c *gin.Context, perm permission) bool {
+	p := currentPrincipal(c)
+	return p != nil && p.Role.allows(perm)
+}
+
+// requirePermission 返回要求 perm 的路由中间件
+func requirePermission(perm permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasPermission(c, perm) {
+			denyPermission(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+func denyPermission(c *gin.Context) {
+	name := "unknown"
+	if p := currentPrincipal(c); p != nil {
+		name = p.Name + "/" + string(p.Role)
+	}
+	log.Printf("[deploy] (auth) permission denied principal=%s path=%s", name, c.Request.URL.Path)
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": localize(c, i18n.PermissionDenied)})
+}
+
+// checkSourcePermission 校验追踪请求中的源地址相关参数是否被当前角色允许
+func checkSourcePermission(c *gin.Context, req traceRequest) error {
+	if req.SourceAddress == "" && req.SourceDevice == "" && req.SourcePort == 0 {
+		return nil
+	}
+	if hasPermission(c, permSourceSelect) {
+		return nil
+	}
+	return errors.New("selecting source address, port or device requires the admin role")
+}
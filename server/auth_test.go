@@ -0,0 +1,162 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter(t *testing.T, cfg *Config) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	jobs := newJobManager(JobsConfig{})
+	t.Cleanup(jobs.Close)
+	router, err := newRouter(cfg, jobs)
+	require.NoError(t, err)
+	return router
+}
+
+func doRequest(router http.Handler, method, path string, body []byte, setup func(*http.Request)) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.RemoteAddr = "10.1.2.3:40000"
+	if setup != nil {
+		setup(req)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthDisabledKeepsConsoleOpen(t *testing.T) {
+	router := newTestRouter(t, nil)
+	w := doRequest(router, http.MethodGet, "/api/options", nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"role":"admin"`)
+}
+
+func TestAuthTokensAndRoles(t *testing.T) {
+	cfg := &Config{Auth: AuthConfig{Tokens: []tokenEntry{
+		{Name: "viewer", Token: "v-token", Role: "viewer"},
+		{Name: "ops", Token: "o-token", Role: "operator"},
+	}}}
+	router := newTestRouter(t, cfg)
+
+	w := doRequest(router, http.MethodGet, "/api/options", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Header().Get("WWW-Authenticate"))
+
+	w = doRequest(router, http.MethodGet, "/api/options", nil, func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer wrong")
+	})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doRequest(router, http.MethodGet, "/api/options", nil, func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer v-token")
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(router, http.MethodPost, "/api/trace", []byte(`{}`), func(r *http.Request) {
+		r.Header.Set("X-API-Token", "v-token")
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code, "viewer must not run traces")
+
+	w = doRequest(router, http.MethodPost, "/api/cache/clear", nil, func(r *http.Request) {
+		r.Header.Set("X-API-Token", "o-token")
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code, "operator must not clear caches")
+
+	w = doRequest(router, http.MethodPost, "/api/trace", []byte(`{"target":"1.1.1.1","source_device":"eth0"}`), func(r *http.Request) {
+		r.Header.Set("X-API-Token", "o-token")
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code, "operator must not choose the source device")
+	assert.Contains(t, w.Body.String(), "admin")
+}
+
+func TestAuthQueryTokenSetsCookie(t *testing.T) {
+	cfg := &Config{Auth: AuthConfig{Tokens: []tokenEntry{{Name: "ops", Token: "o-token", Role: "operator"}}}}
+	router := newTestRouter(t, cfg)
+
+	w := doRequest(router, http.MethodGet, "/?token=o-token", nil, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, authCookieName, cookies[0].Name)
+	assert.True(t, cookies[0].HttpOnly)
+
+	w = doRequest(router, http.MethodGet, "/api/options", nil, func(r *http.Request) {
+		r.AddCookie(cookies[0])
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAccessLogHidesQueryToken(t *testing.T) {
+	line := accessLogFormatter(gin.LogFormatterParams{Method: http.MethodGet, StatusCode: http.StatusOK, Path: "/?lang=en&token=o-token"})
+	assert.NotContains(t, line, "o-token")
+	assert.Contains(t, line, "lang=en")
+	assert.Equal(t, "/api/options", redactTokenQuery("/api/options"))
+	assert.Equal(t, "/?REDACTED", redactTokenQuery("/?token=o-token&x=%zz"))
+}
+
+func TestAuthBasicUsers(t *testing.T) {
+	cfg := &Config{Auth: AuthConfig{Users: []userEntry{
+		// sha256("password")
+		{Username: "support", Password: "sha256:5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8", Role: "operator"},
+		{Username: "admin", Password: "secret", Role: "admin"},
+	}}}
+	router := newTestRouter(t, cfg)
+
+	w := doRequest(router, http.MethodGet, "/api/options", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+
+	w = doRequest(router, http.MethodGet, "/api/options", nil, func(r *http.Request) {
+		r.SetBasicAuth("support", "password")
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(router, http.MethodGet, "/api/options", nil, func(r *http.Request) {
+		r.SetBasicAuth("admin", "wrong")
+	})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthAllowCIDRs(t *testing.T) {
+	cfg := &Config{Auth: AuthConfig{AllowCIDRs: []string{"192.168.0.0/16", "10.1.2.3"}}}
+	router := newTestRouter(t, cfg)
+
+	w := doRequest(router, http.MethodGet, "/api/options", nil, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(router, http.MethodGet, "/api/options", nil, func(r *http.Request) {
+		r.RemoteAddr = "203.0.113.7:5555"
+		r.Header.Set("X-Forwarded-For", "192.168.1.1")
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code, "untrusted X-Forwarded-For must be ignored")
+}
+
+func TestParseEnvTokens(t *testing.T) {
+	tokens := parseEnvTokens(" abc , def:viewer, ghi:jkl ")
+	require.Len(t, tokens, 3)
+	assert.Equal(t, tokenEntry{Name: "env#1", Token: "abc", Role: "admin"}, tokens[0])
+	assert.Equal(t, tokenEntry{Name: "env#2", Token: "def", Role: "viewer"}, tokens[1])
+	assert.Equal(t, tokenEntry{Name: "env#3", Token: "ghi:jkl", Role: "admin"}, tokens[2])
+}
+
+func TestLoadConfigExample(t *testing.T) {
+	cfg, err := LoadConfig("../deploy.example.yaml")
+	require.NoError(t, err)
+	assert.True(t, cfg.AuthEnabled())
+	assert.Len(t, cfg.Auth.AllowCIDRs, 3)
+	require.Len(t, cfg.TLS.ClientCerts, 1)
+	assert.Equal(t, "noc.example.com", cfg.TLS.ClientCerts[0].CommonName)
+	assert.True(t, cfg.Policy.DenyPrivate)
+	assert.Equal(t, []string{"80", "443", "33434-33534"}, cfg.Policy.Ports)
+
+	_, err = newAuthenticator(cfg)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/nxtrace/NTrace-core/redact"
+	"github.com/nxtrace/NTrace-core/util"
+)
+
+// Config holds the optional settings of the web console, loaded from a YAML file via LoadConfig.
+// A nil or zero Config keeps the historical behaviour: no authentication and no restrictions.
+type Config struct {
+	Auth    AuthConfig    `mapstructure:"auth"`
+	TLS     TLSConfig     `mapstructure:"tls"`
+	Policy  PolicyConfig  `mapstructure:"policy"`
+	Jobs    JobsConfig    `mapstructure:"jobs"`
+	History HistoryConfig `mapstructure:"history"`
+	// Redact 应用于全部追踪响应、历史记录与地图上传
+	Redact redact.Policy `mapstructure:"redact"`
+}
+
+// AuthConfig 描述 Web 控制台的认证与访问控制
+type AuthConfig struct {
+	// AllowCIDRs 为客户端 IP 白名单，为空表示不限制
+	AllowCIDRs []string `mapstructure:"allow_cidrs"`
+	// TrustedProxies 为可信反向代理，仅来自这些地址的 X-Forwarded-For 才会被采信
+	TrustedProxies []string     `mapstructure:"trusted_proxies"`
+	Tokens         []tokenEntry `mapstructure:"tokens"`
+	Users          []userEntry  `mapstructure:"users"`
+	// AnonymousRole 为未携带凭据请求的角色，为空表示拒绝
+	AnonymousRole string `mapstructure:"anonymous_role"`
+}
+
+// TLSConfig 描述 HTTPS 与可选的 mTLS 客户端证书认证
+type TLSConfig struct {
+	CertFile          string            `mapstructure:"cert_file"`
+	KeyFile           string            `mapstructure:"key_file"`
+	ClientCAFile      string            `mapstructure:"client_ca_file"`
+	RequireClientCert bool              `mapstructure:"require_client_cert"`
+	ClientCerts       []clientCertEntry `mapstructure:"client_certs"`
+	DefaultClientRole string            `mapstructure:"default_client_role"`
+}
+
+// clientCertEntry 将客户端证书 CN 映射为角色
+type clientCertEntry struct {
+	CommonName string `mapstructure:"common_name"`
+	Role       string `mapstructure:"role"`
+}
+
+type tokenEntry struct {
+	Name  string `mapstructure:"name"`
+	Token string `mapstructure:"token"`
+	Role  string `mapstructure:"role"`
+}
+
+type userEntry struct {
+	Username string `mapstructure:"username"`
+	// Password 可以是明文，也可以是 "sha256:<hex>" 形式的摘要
+	Password string `mapstructure:"password"`
+	Role     string `mapstructure:"role"`
+}
+
+// LoadConfig reads the web console config from path and merges tokens from NEXTTRACE_DEPLOY_TOKENS.
+// An empty path only applies the environment.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if path != "" {
+		v := viper.New()
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("read deploy config: %w", err)
+		}
+		if err := v.Unmarshal(cfg); err != nil {
+			return nil, fmt.Errorf("parse deploy config: %w", err)
+		}
+	}
+
+	cfg.Auth.Tokens = append(cfg.Auth.Tokens, parseEnvTokens(util.EnvDeployTokens)...)
+	cfg.Redact.HideDestination = cfg.Redact.HideDestination || util.EnableHidDstIP
+	if cfg.Redact.Key == "" {
+		cfg.Redact.Key = util.EnvRedactKey
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parseEnvTokens 解析 "token[:role],token[:role]" 格式，未指定角色时为 admin
+func parseEnvTokens(raw string) []tokenEntry {
+	var tokens []tokenEntry
+	for i, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		entry := tokenEntry{Name: fmt.Sprintf("env#%d", i+1), Token: item, Role: string(roleAdmin)}
+		if idx := strings.LastIndex(item, ":"); idx > 0 {
+			if _, ok := parseRole(item[idx+1:]); ok {
+				entry.Token = item[:idx]
+				entry.Role = item[idx+1:]
+			}
+		}
+		tokens = append(tokens, entry)
+	}
+	return tokens
+}
+
+func (cfg *Config) validate() error {
+	for i, t := range cfg.Auth.Tokens {
+		if t.Token == "" {
+			return fmt.Errorf("auth token #%d is empty", i+1)
+		}
+		if _, ok := parseRole(t.Role); !ok {
+			return fmt.Errorf("auth token %q has unknown role %q", t.Name, t.Role)
+		}
+	}
+	for _, u := range cfg.Auth.Users {
+		if u.Username == "" || u.Password == "" {
+			return fmt.Errorf("auth user %q requires username and password", u.Username)
+		}
+		if _, ok := parseRole(u.Role); !ok {
+			return fmt.Errorf("auth user %q has unknown role %q", u.Username, u.Role)
+		}
+	}
+	if cfg.Auth.AnonymousRole != "" {
+		if _, ok := parseRole(cfg.Auth.AnonymousRole); !ok {
+			return fmt.Errorf("unknown anonymous_role %q", cfg.Auth.AnonymousRole)
+		}
+	}
+	for _, cert := range cfg.TLS.ClientCerts {
+		if _, ok := parseRole(cert.Role); !ok {
+			return fmt.Errorf("client certificate %q has unknown role %q", cert.CommonName, cert.Role)
+		}
+	}
+	if cfg.TLS.DefaultClientRole != "" {
+		if _, ok := parseRole(cfg.TLS.DefaultClientRole); !ok {
+			return fmt.Errorf("unknown default_client_role %q", cfg.TLS.DefaultClientRole)
+		}
+	}
+	if err := cfg.Redact.Validate(); err != nil {
+		return fmt.Errorf("redact: %w", err)
+	}
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		return errors.New("tls requires both cert_file and key_file")
+	}
+	if cfg.TLS.ClientCAFile != "" && cfg.TLS.CertFile == "" {
+		return errors.New("tls client_ca_file requires cert_file and key_file")
+	}
+	if _, err := parseCIDRs(cfg.Auth.AllowCIDRs); err != nil {
+		return fmt.Errorf("auth allow_cidrs: %w", err)
+	}
+	if _, err := parseCIDRs(cfg.Auth.TrustedProxies); err != nil {
+		return fmt.Errorf("auth trusted_proxies: %w", err)
+	}
+	if _, err := newTargetPolicy(cfg.Policy); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AuthEnabled reports whether any credential source is configured.
+func (cfg *Config) AuthEnabled() bool {
+	if cfg == nil {
+		return false
+	}
+	return len(cfg.Auth.Tokens) > 0 || len(cfg.Auth.Users) > 0 || cfg.TLS.ClientCAFile != ""
+}
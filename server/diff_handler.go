@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nxtrace/NTrace-core/i18n"
+	"github.com/nxtrace/NTrace-core/tracediff"
+)
+
+// diffSource 描述比较的一侧：历史记录、已完成的任务或内联的追踪 JSON，三者取其一
+type diffSource struct {
+	HistoryID string          `json:"history_id"`
+	JobID     string          `json:"job_id"`
+	Label     string          `json:"label"`
+	Result    json.RawMessage `json:"result"`
+}
+
+type diffRequest struct {
+	A diffSource `json:"a"`
+	B diffSource `json:"b"`
+}
+
+// diffSourceError 携带加载来源失败时的 HTTP 状态码，未包装的错误按 400 返回
+type diffSourceError struct {
+	status int
+	err    error
+}
+
+func (e *diffSourceError) Error() string { return e.err.Error() }
+
+func (m *jobManager) diffHandler(c *gin.Context) {
+	var req diffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, i18n.InvalidRequest), "details": err.Error()})
+		return
+	}
+	// 任务结果仅对可发起追踪的角色可见，与 /api/jobs 保持一致
+	if (req.A.JobID != "" || req.B.JobID != "") && !hasPermission(c, permTrace) {
+		denyPermission(c)
+		return
+	}
+
+	a, err := m.loadDiffSource(req.A, "a", currentPrincipal(c))
+	if err != nil {
+		respondDiffError(c, "a", err)
+		return
+	}
+	b, err := m.loadDiffSource(req.B, "b", currentPrincipal(c))
+	if err != nil {
+		respondDiffError(c, "b", err)
+		return
+	}
+	c.JSON(http.StatusOK, tracediff.Compare(a, b))
+}
+
+func respondDiffError(c *gin.Context, side string, err error) {
+	status := http.StatusBadRequest
+	var srcErr *diffSourceError
+	if errors.As(err, &srcErr) {
+		status = srcErr.status
+	}
+	c.JSON(status, gin.H{"error": localize(c, i18n.InvalidSource, side), "details": err.Error()})
+}
+
+func (m *jobManager) loadDiffSource(src diffSource, side string, viewer *principal) (tracediff.Path, error) {
+	set := 0
+	for _, ok := range []bool{src.HistoryID != "", src.JobID != "", len(src.Result) > 0} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return tracediff.Path{}, errors.New("exactly one of history_id, job_id or result is required")
+	}
+
+	var (
+		label = src.Label
+		data  []byte
+	)
+	switch {
+	case src.HistoryID != "":
+		if historyStore == nil {
+			return tracediff.Path{}, &diffSourceError{http.StatusNotFound, errors.New("history is disabled")}
+		}
+		rec, err := historyStore.Get(src.HistoryID)
+		if errors.Is(err, os.ErrNotExist) || (err == nil && !ownedBy(rec.Owner, viewer)) {
+			return tracediff.Path{}, &diffSourceError{http.StatusNotFound, errors.New("record not found")}
+		}
+		if err != nil {
+			return tracediff.Path{}, &diffSourceError{http.StatusInternalServerError, err}
+		}
+		if label == "" {
+			label = fmt.Sprintf("%s@%s", rec.Target, rec.Timestamp.Format("2006-01-02 15:04:05"))
+		}
+		data = rec.Data
+	case src.JobID != "":
+		job, ok := m.get(src.JobID)
+		if !ok || !job.visibleTo(viewer) {
+			return tracediff.Path{}, &diffSourceError{http.StatusNotFound, errors.New("job not found")}
+		}
+		m.mu.Lock()
+		status, result := job.status, job.result
+		m.mu.Unlock()
+		if status != jobDone {
+			return tracediff.Path{}, &diffSourceError{http.StatusConflict, fmt.Errorf("job is %s", status)}
+		}
+		if label == "" {
+			label = "job " + job.id
+		}
+		payload, err := json.Marshal(result)
+		if err != nil {
+			return tracediff.Path{}, &diffSourceError{http.StatusInternalServerError, err}
+		}
+		data = payload
+	default:
+		if label == "" {
+			label = side
+		}
+		data = src.Result
+	}
+	return tracediff.Parse(label, data)
+}
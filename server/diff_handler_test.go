@@ -0,0 +1,52 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/history"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/tracediff"
+)
+
+func TestDiffAPI(t *testing.T) {
+	store, err := history.Open(t.TempDir(), 0)
+	require.NoError(t, err)
+	historyStore = store
+	defer func() { historyStore = nil }()
+
+	setup := testSetup()
+	recordHistory(history.KindTrace, "api", "", setup, traceResponse{Target: setup.Target, Document: schema.Document{Hops: []schema.Hop{
+		{TTL: 1, Attempts: []schema.Attempt{{Success: true, IP: "10.0.0.1", RTT: 1}}},
+		{TTL: 2, Attempts: []schema.Attempt{{Success: true, IP: "1.1.1.1", RTT: 5}}},
+	}}}, 2)
+	records, err := store.Query(history.Query{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	router := newTestRouter(t, nil)
+	body := `{"a":{"history_id":"` + records[0].ID + `"},"b":{"label":"new","result":{"hops":[` +
+		`{"ttl":1,"attempts":[{"success":true,"ip":"10.0.0.2","rtt_ms":1}]},` +
+		`{"ttl":2,"attempts":[{"success":true,"ip":"1.1.1.1","rtt_ms":8}]}]}}}`
+	w := doRequest(router, http.MethodPost, "/api/diff", []byte(body), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var report tracediff.Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, "new", report.B)
+	require.Len(t, report.Hops, 2)
+	assert.Equal(t, tracediff.ChangeIP, report.Hops[0].Change)
+	assert.Equal(t, tracediff.ChangeSame, report.Hops[1].Change)
+	require.NotNil(t, report.Reconverge)
+	assert.Equal(t, "1.1.1.1", report.Reconverge.IP)
+
+	w = doRequest(router, http.MethodPost, "/api/diff", []byte(`{"a":{"history_id":"missing"},"b":{"result":{"hops":[]}}}`), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(router, http.MethodPost, "/api/diff", []byte(`{"a":{},"b":{"job_id":"x"}}`), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/nxtrace/NTrace-core/i18n"
 )
 
 var (
@@ -32,12 +34,32 @@ var (
 		"data_provider":     "LeoMoeAPI",
 		"disable_maptrace":  false,
 	}
+	// powProviders 与 dotServers 为服务端可以代为连接的上游，与命令行的 --pow-provider、--dot-server 一致
+	powProviders = []string{"api.nxtrace.org", "sakura"}
+	dotServers   = []string{"dnssb", "aliyun", "dnspod", "google", "cloudflare"}
 )
 
 func optionsHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"protocols":      supportedProtocols,
+	resp := gin.H{
+		"protocols":      tracePolicy.allowedProtocols(),
 		"dataProviders":  dataProviders,
 		"defaultOptions": defaults,
-	})
+	}
+	if tracePolicy != nil {
+		resp["limits"] = gin.H{
+			"max_hops":        tracePolicy.cfg.MaxHops,
+			"max_queries":     tracePolicy.cfg.MaxQueries,
+			"max_packet_size": tracePolicy.cfg.MaxPacketSize,
+			"max_rounds":      tracePolicy.cfg.MaxRounds,
+		}
+	}
+	if p := currentPrincipal(c); p != nil {
+		resp["role"] = p.Role
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// localize 按请求头 Accept-Language 返回接口消息，未指定或不受支持时使用英文
+func localize(c *gin.Context, key i18n.Key, args ...any) string {
+	return i18n.In(i18n.FromAcceptLanguage(c.GetHeader("Accept-Language"), i18n.English), key, args...)
 }
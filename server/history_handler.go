@@ -0,0 +1,165 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nxtrace/NTrace-core/history"
+	"github.com/nxtrace/NTrace-core/i18n"
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+const defaultHistoryLimit = 100
+
+// HistoryConfig 描述追踪历史的持久化设置
+type HistoryConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Dir 为空时使用 history.DefaultDir()
+	Dir       string        `mapstructure:"dir"`
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+// historyStore 为当前生效的历史存储，nil 表示不记录；由 Run / RunMonitor 在启动前设置
+var historyStore *history.Store
+
+func openHistory(cfg HistoryConfig) (*history.Store, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	retention := cfg.Retention
+	if retention == 0 {
+		retention = history.DefaultRetention
+	}
+	store, err := history.Open(cfg.Dir, retention)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[history] recording trace history in %s", store.Dir())
+	return store, nil
+}
+
+// recordHistory 将一次追踪结果写入历史存储，owner 为发起者用户名，失败只记录日志
+func recordHistory(kind, source, owner string, setup *traceExecution, data any, hopCount int) {
+	store := historyStore
+	if store == nil {
+		return
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("[history] encode failed target=%s error=%v", setup.Target, err)
+		return
+	}
+	target, ip := setup.shown()
+	rec := &history.Record{
+		Kind:         kind,
+		Source:       source,
+		Target:       target,
+		ResolvedIP:   ip,
+		Protocol:     setup.Protocol,
+		DataProvider: setup.DataProvider,
+		HopCount:     hopCount,
+		Data:         payload,
+		Owner:        owner,
+	}
+	if err := store.Append(rec); err != nil {
+		log.Printf("[history] append failed target=%s error=%v", setup.Target, err)
+	}
+}
+
+// TraceHistoryRecord converts a finished trace into a history record whose payload matches the web console's trace response.
+func TraceHistoryRecord(source string, doc schema.Document) (*history.Record, error) {
+	meta := doc.Metadata
+	response := traceResponse{
+		Document:     doc,
+		Target:       meta.Target,
+		ResolvedIP:   meta.DstIP,
+		Protocol:     meta.Method,
+		DataProvider: meta.DataProvider,
+		Language:     meta.Language,
+		DurationMs:   meta.DurationMs,
+	}
+	payload, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	return &history.Record{
+		Timestamp:    meta.StartedAt,
+		Kind:         history.KindTrace,
+		Source:       source,
+		Target:       meta.Target,
+		ResolvedIP:   meta.DstIP,
+		Protocol:     meta.Method,
+		DataProvider: meta.DataProvider,
+		HopCount:     len(response.Hops),
+		Data:         payload,
+	}, nil
+}
+
+func historyHandler(c *gin.Context) {
+	if historyStore == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": localize(c, i18n.HistoryDisabled)})
+		return
+	}
+
+	now := time.Now()
+	since, err := history.ParseTime(c.Query("since"), now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	until, err := history.ParseTime(c.Query("until"), now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit := defaultHistoryLimit
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, i18n.InvalidLimit)})
+			return
+		}
+	}
+
+	viewer := currentPrincipal(c)
+	records, err := historyStore.Query(history.Query{
+		Target: c.Query("target"),
+		Since:  since,
+		Until:  until,
+		Limit:  limit,
+		// 与任务一致，记录仅对发起者本人与 admin 可见
+		Filter: func(rec *history.Record) bool { return ownedBy(rec.Owner, viewer) },
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if records == nil {
+		records = []history.Record{}
+	}
+	c.JSON(http.StatusOK, gin.H{"records": records})
+}
+
+func historyRecordHandler(c *gin.Context) {
+	if historyStore == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": localize(c, i18n.HistoryDisabled)})
+		return
+	}
+	rec, err := historyStore.Get(c.Param("id"))
+	// 他人的记录与不存在的记录一样返回 404，不暴露其存在
+	if errors.Is(err, os.ErrNotExist) || (err == nil && !ownedBy(rec.Owner, currentPrincipal(c))) {
+		c.JSON(http.StatusNotFound, gin.H{"error": localize(c, i18n.RecordNotFound)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rec)
+}
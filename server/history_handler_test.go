@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/history"
+	"github.com/nxtrace/NTrace-core/schema"
+)
+
+func TestHistoryAPI(t *testing.T) {
+	store, err := history.Open(t.TempDir(), 0)
+	require.NoError(t, err)
+	historyStore = store
+	defer func() { historyStore = nil }()
+
+	setup := testSetup()
+	recordHistory(history.KindTrace, "api", "", setup, traceResponse{Target: setup.Target, Document: schema.Document{Hops: []schema.Hop{{TTL: 1}}}}, 1)
+	recordHistory(history.KindMTR, "ws", "", setup, mtrSnapshot{Iteration: 3}, 0)
+
+	router := newTestRouter(t, nil)
+	w := doRequest(router, http.MethodGet, "/api/history?target=1.1.1.1&since=1h", nil, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Records []history.Record `json:"records"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Records, 2)
+	assert.Equal(t, history.KindTrace, resp.Records[0].Kind)
+	assert.Equal(t, "icmp", resp.Records[0].Protocol)
+	assert.Equal(t, 1, resp.Records[0].HopCount)
+
+	w = doRequest(router, http.MethodGet, "/api/history/"+resp.Records[1].ID, nil, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"iteration":3`)
+
+	w = doRequest(router, http.MethodGet, "/api/history?since=tomorrow", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHistoryVisibleToOwnerOnly(t *testing.T) {
+	store, err := history.Open(t.TempDir(), 0)
+	require.NoError(t, err)
+	historyStore = store
+	defer func() { historyStore = nil }()
+
+	setup := testSetup()
+	recordHistory(history.KindTrace, "job", "alice", setup, traceResponse{Target: setup.Target}, 0)
+	recordHistory(history.KindMTR, "monitor", "", setup, mtrSnapshot{Iteration: 3}, 0)
+	records, err := store.Query(history.Query{})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	owned, unowned := records[0].ID, records[1].ID
+
+	router := newTestRouter(t, &Config{Auth: AuthConfig{Tokens: []tokenEntry{
+		{Name: "alice", Token: "a-token", Role: "viewer"},
+		{Name: "bob", Token: "b-token", Role: "viewer"},
+		{Name: "root", Token: "r-token", Role: "admin"},
+	}}})
+	as := func(token string) func(*http.Request) {
+		return func(r *http.Request) { r.Header.Set("X-API-Token", token) }
+	}
+	list := func(token string) []history.Record {
+		w := doRequest(router, http.MethodGet, "/api/history", nil, as(token))
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Records []history.Record `json:"records"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Records
+	}
+
+	require.Len(t, list("a-token"), 1)
+	assert.Equal(t, owned, list("a-token")[0].ID)
+	assert.Empty(t, list("b-token"))
+	assert.Len(t, list("r-token"), 2)
+
+	assert.Equal(t, http.StatusOK, doRequest(router, http.MethodGet, "/api/history/"+owned, nil, as("a-token")).Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(router, http.MethodGet, "/api/history/"+owned, nil, as("b-token")).Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(router, http.MethodGet, "/api/history/"+unowned, nil, as("a-token")).Code, "unowned records are admin-only")
+	assert.Equal(t, http.StatusOK, doRequest(router, http.MethodGet, "/api/history/"+unowned, nil, as("r-token")).Code)
+
+	body := []byte(`{"a":{"history_id":"` + owned + `"},"b":{"result":{"hops":[]}}}`)
+	assert.Equal(t, http.StatusNotFound, doRequest(router, http.MethodPost, "/api/diff", body, as("b-token")).Code)
+	assert.Equal(t, http.StatusOK, doRequest(router, http.MethodPost, "/api/diff", body, as("a-token")).Code)
+}
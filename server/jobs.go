@@ -0,0 +1,472 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nxtrace/NTrace-core/anomaly"
+	"github.com/nxtrace/NTrace-core/export"
+	"github.com/nxtrace/NTrace-core/geomap"
+	"github.com/nxtrace/NTrace-core/history"
+	"github.com/nxtrace/NTrace-core/i18n"
+	"github.com/nxtrace/NTrace-core/printer"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+const (
+	defaultJobWorkers   = 2
+	defaultJobQueueSize = 32
+	defaultJobRetention = time.Hour
+	defaultJobTimeout   = 5 * time.Minute
+	defaultMaxJobs      = 1000
+)
+
+type jobStatus string
+
+const (
+	jobQueued   jobStatus = "queued"
+	jobRunning  jobStatus = "running"
+	jobDone     jobStatus = "done"
+	jobFailed   jobStatus = "failed"
+	jobCanceled jobStatus = "canceled"
+)
+
+func (s jobStatus) finished() bool {
+	return s == jobDone || s == jobFailed || s == jobCanceled
+}
+
+// JobsConfig 描述异步追踪任务的工作池与队列限制
+type JobsConfig struct {
+	// Workers 为并发执行的任务数；追踪本身仍受 traceMu 串行化，多余的 worker 会排队等待
+	Workers   int           `mapstructure:"workers"`
+	QueueSize int           `mapstructure:"queue_size"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+	Retention time.Duration `mapstructure:"retention"`
+	MaxJobs   int           `mapstructure:"max_jobs"`
+}
+
+func (cfg JobsConfig) withDefaults() JobsConfig {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultJobWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultJobQueueSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultJobTimeout
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaultJobRetention
+	}
+	if cfg.MaxJobs <= 0 {
+		cfg.MaxJobs = defaultMaxJobs
+	}
+	return cfg
+}
+
+type traceJob struct {
+	id       string
+	owner    string
+	setup    *traceExecution
+	created  time.Time
+	started  time.Time
+	finished time.Time
+	status   jobStatus
+	err      string
+	hops     []schema.Hop
+	result   *traceResponse
+	cancel   context.CancelFunc
+}
+
+// jobView 为 GET /api/jobs/{id} 的响应
+type jobView struct {
+	ID           string       `json:"id"`
+	Status       jobStatus    `json:"status"`
+	Owner        string       `json:"owner,omitempty"`
+	Target       string       `json:"target"`
+	ResolvedIP   string       `json:"resolved_ip"`
+	Protocol     string       `json:"protocol"`
+	DataProvider string       `json:"data_provider"`
+	CreatedAt    time.Time    `json:"created_at"`
+	StartedAt    *time.Time   `json:"started_at,omitempty"`
+	FinishedAt   *time.Time   `json:"finished_at,omitempty"`
+	Error        string       `json:"error,omitempty"`
+	Hops         []schema.Hop `json:"hops"`
+}
+
+type jobManager struct {
+	cfg   JobsConfig
+	mu    sync.Mutex
+	jobs  map[string]*traceJob
+	queue chan *traceJob
+	ctx   context.Context
+	stop  context.CancelFunc
+	wg    sync.WaitGroup
+	// run 执行一次追踪，测试中可替换
+	run func(ctx context.Context, job *traceJob) (*trace.Result, error)
+}
+
+var (
+	errJobQueueFull = errors.New("job queue is full")
+	errTooManyJobs  = errors.New("too many jobs retained")
+)
+
+func newJobManager(cfg JobsConfig) *jobManager {
+	cfg = cfg.withDefaults()
+	ctx, stop := context.WithCancel(context.Background())
+	m := &jobManager{
+		cfg:   cfg,
+		jobs:  make(map[string]*traceJob),
+		queue: make(chan *traceJob, cfg.QueueSize),
+		ctx:   ctx,
+		stop:  stop,
+	}
+	m.run = m.runTrace
+	for i := 0; i < cfg.Workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	return m
+}
+
+// Close 取消所有任务并等待 worker 退出
+func (m *jobManager) Close() {
+	m.stop()
+	m.wg.Wait()
+}
+
+func newJobID() string {
+	buf := make([]byte, 12)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func (m *jobManager) submit(setup *traceExecution, owner string) (*traceJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pruneLocked(time.Now())
+	if len(m.jobs) >= m.cfg.MaxJobs {
+		return nil, errTooManyJobs
+	}
+
+	job := &traceJob{
+		id:      newJobID(),
+		owner:   owner,
+		setup:   setup,
+		created: time.Now(),
+		status:  jobQueued,
+	}
+	select {
+	case m.queue <- job:
+	default:
+		return nil, errJobQueueFull
+	}
+	m.jobs[job.id] = job
+	return job, nil
+}
+
+// pruneLocked 清理超过保留期的已结束任务，调用方必须持有 m.mu
+func (m *jobManager) pruneLocked(now time.Time) {
+	for id, job := range m.jobs {
+		if job.status.finished() && now.Sub(job.finished) > m.cfg.Retention {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+func (m *jobManager) get(id string) (*traceJob, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	return job, ok
+}
+
+// visibleTo 判断调用方能否访问任务：仅限提交者本人与 admin 角色
+func (job *traceJob) visibleTo(p *principal) bool {
+	return ownedBy(job.owner, p)
+}
+
+// lookup 返回当前调用方可访问的任务；他人的任务与不存在的任务一样返回 404，不暴露其存在
+func (m *jobManager) lookup(c *gin.Context) (*traceJob, bool) {
+	job, ok := m.get(c.Param("id"))
+	if !ok || !job.visibleTo(currentPrincipal(c)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": localize(c, i18n.JobNotFound)})
+		return nil, false
+	}
+	return job, true
+}
+
+// cancelJob 取消排队或运行中的任务，返回任务取消后的状态
+func (m *jobManager) cancelJob(id string) (jobStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return "", false
+	}
+	switch job.status {
+	case jobQueued:
+		job.status = jobCanceled
+		job.finished = time.Now()
+	case jobRunning:
+		job.cancel()
+	}
+	return job.status, true
+}
+
+func (m *jobManager) worker() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case job := <-m.queue:
+			m.execute(job)
+		}
+	}
+}
+
+func (m *jobManager) execute(job *traceJob) {
+	m.mu.Lock()
+	if job.status != jobQueued {
+		m.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithTimeout(m.ctx, m.cfg.Timeout)
+	defer cancel()
+	job.status = jobRunning
+	job.started = time.Now()
+	job.cancel = cancel
+	m.mu.Unlock()
+
+	setup := job.setup
+	log.Printf("[deploy] (job) started id=%s target=%s proto=%s", job.id, setup.Target, setup.Protocol)
+
+	res, err := m.run(ctx, job)
+	duration := time.Since(job.started)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job.finished = time.Now()
+	switch {
+	case ctx.Err() != nil && errors.Is(ctx.Err(), context.Canceled):
+		job.status = jobCanceled
+		job.err = "job canceled"
+	case ctx.Err() != nil:
+		job.status = jobFailed
+		job.err = "job timed out after " + m.cfg.Timeout.String()
+	case err != nil:
+		job.status = jobFailed
+		job.err = setup.Redactor.Text(err.Error())
+	default:
+		job.status = jobDone
+		response := newTraceResponse(setup, setup.Config, res, "", job.started, job.started.Add(duration))
+		job.result = &response
+		job.hops = job.result.Hops
+	}
+	log.Printf("[deploy] (job) finished id=%s target=%s status=%s duration=%s", job.id, setup.Target, job.status, duration)
+	if job.result != nil {
+		recordHistory(history.KindTrace, "job", job.owner, setup, job.result, len(job.result.Hops))
+	}
+}
+
+func (m *jobManager) runTrace(ctx context.Context, job *traceJob) (*trace.Result, error) {
+	if !lockTraceMu(ctx) {
+		return nil, ctx.Err()
+	}
+	defer traceMu.Unlock()
+
+	restore := applyTraceGlobals(job.setup, "[deploy] (job)")
+	defer restore()
+
+	config := job.setup.Config
+	printer.Attach(&jobRecorder{m: m, job: job, lang: config.Lang}, &config)
+	return trace.TracerouteContext(ctx, job.setup.Method, config)
+}
+
+// jobRecorder 将每一跳完成的结果追加到任务中，供轮询接口读取
+type jobRecorder struct {
+	m    *jobManager
+	job  *traceJob
+	lang string
+}
+
+func (r *jobRecorder) OnHop(res *trace.Result, ttl int) {
+	// 与打印器一致，在脱敏后的结果上分析，注释不会泄露被替换跳的位置
+	hops := r.job.setup.Redactor.Result(res).Hops
+	if ttl >= len(hops) {
+		return
+	}
+	hop := schema.NewHop(ttl+1, append([]trace.Hop(nil), hops[ttl]...), r.lang)
+	hop.Annotations = anomaly.At(hops, ttl, anomaly.Options{})
+	r.m.mu.Lock()
+	r.job.hops = append(r.job.hops, hop)
+	r.m.mu.Unlock()
+}
+
+func (r *jobRecorder) OnComplete(*trace.Result, schema.Document) error { return nil }
+
+func (r *jobRecorder) Close() error { return nil }
+
+func (m *jobManager) view(job *traceJob) jobView {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	setup := job.setup
+	target, ip := setup.shown()
+	v := jobView{
+		ID:           job.id,
+		Status:       job.status,
+		Owner:        job.owner,
+		Target:       target,
+		ResolvedIP:   ip,
+		Protocol:     setup.Protocol,
+		DataProvider: setup.DataProvider,
+		CreatedAt:    job.created,
+		Error:        job.err,
+		Hops:         append([]schema.Hop{}, job.hops...),
+	}
+	if !job.started.IsZero() {
+		started := job.started
+		v.StartedAt = &started
+	}
+	if !job.finished.IsZero() {
+		finished := job.finished
+		v.FinishedAt = &finished
+	}
+	return v
+}
+
+func (m *jobManager) createHandler(c *gin.Context) {
+	var req traceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, i18n.InvalidPayload), "details": err.Error()})
+		return
+	}
+	if err := checkSourcePermission(c, req); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if mode := strings.ToLower(strings.TrimSpace(req.Mode)); mode != "" && mode != "single" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, i18n.JobsSingleOnly)})
+		return
+	}
+	// 任务结果通过 API 获取，不生成 tracemap
+	req.DisableMaptrace = true
+	req.Maptrace = nil
+
+	setup, statusCode, err := prepareTrace(req)
+	if err != nil {
+		if statusCode == 0 {
+			statusCode = http.StatusInternalServerError
+		}
+		log.Printf("[deploy] (job) prepare trace failed target=%s error=%v", strings.TrimSpace(req.Target), err)
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := m.submit(setup, principalName(c))
+	if err != nil {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("[deploy] (job) queued id=%s target=%s resolved=%s", job.id, setup.Target, setup.IP)
+	c.Header("Location", "/api/jobs/"+job.id)
+	c.JSON(http.StatusAccepted, m.view(job))
+}
+
+func (m *jobManager) statusHandler(c *gin.Context) {
+	job, ok := m.lookup(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, m.view(job))
+}
+
+func (m *jobManager) resultHandler(c *gin.Context) {
+	result, ok := m.doneResult(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// resultCSVHandler 以 CSV 返回任务结果，每次探测一行
+func (m *jobManager) resultCSVHandler(c *gin.Context) {
+	result, ok := m.doneResult(c)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	if err := export.WriteTrace(&buf, result.Document, export.CSV); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": localize(c, i18n.EncodeFailed), "details": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="nexttrace-`+c.Param("id")+`.csv"`)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// mapSVGHandler 在本地渲染任务结果的地图，不依赖 tracemap 服务
+func (m *jobManager) mapSVGHandler(c *gin.Context) {
+	result, ok := m.doneResult(c)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	if err := geomap.SVG(&buf, geomap.Points(result.Document), geomap.Options{Fit: c.Query("view") != "world"}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": localize(c, i18n.RenderMapFailed), "details": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "image/svg+xml", buf.Bytes())
+}
+
+// doneResult 返回已完成任务的结果；任务不存在或未完成时写出错误响应
+func (m *jobManager) doneResult(c *gin.Context) (*traceResponse, bool) {
+	job, ok := m.lookup(c)
+	if !ok {
+		return nil, false
+	}
+	m.mu.Lock()
+	status, result, errMsg := job.status, job.result, job.err
+	m.mu.Unlock()
+
+	if status != jobDone {
+		resp := gin.H{"error": localize(c, i18n.JobNoResult), "status": status}
+		if errMsg != "" {
+			resp["details"] = errMsg
+		}
+		c.JSON(http.StatusConflict, resp)
+		return nil, false
+	}
+	return result, true
+}
+
+func (m *jobManager) cancelHandler(c *gin.Context) {
+	job, ok := m.lookup(c)
+	if !ok {
+		return
+	}
+	status, ok := m.cancelJob(job.id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": localize(c, i18n.JobNotFound)})
+		return
+	}
+	code := http.StatusOK
+	if status == jobRunning {
+		// 运行中的任务会在追踪退出后转为 canceled
+		code = http.StatusAccepted
+	}
+	c.JSON(code, gin.H{"id": c.Param("id"), "status": status})
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/redact"
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+func testSetup() *traceExecution {
+	return &traceExecution{
+		Target:   "1.1.1.1",
+		Protocol: "icmp",
+		IP:       net.ParseIP("1.1.1.1"),
+		Config:   trace.Config{Lang: "en", NumMeasurements: 1},
+	}
+}
+
+func waitJob(t *testing.T, m *jobManager, id string, want jobStatus) jobView {
+	t.Helper()
+	var v jobView
+	require.Eventually(t, func() bool {
+		job, ok := m.get(id)
+		require.True(t, ok)
+		v = m.view(job)
+		return v.Status == want
+	}, 2*time.Second, 5*time.Millisecond)
+	return v
+}
+
+func TestJobManagerRunsJobs(t *testing.T) {
+	m := newJobManager(JobsConfig{Workers: 1})
+	defer m.Close()
+	m.run = func(ctx context.Context, job *traceJob) (*trace.Result, error) {
+		return &trace.Result{Hops: [][]trace.Hop{{
+			{Success: true, Address: &net.IPAddr{IP: net.ParseIP("1.1.1.1")}, TTL: 1, RTT: 5 * time.Millisecond},
+		}}}, nil
+	}
+
+	job, err := m.submit(testSetup(), "tester")
+	require.NoError(t, err)
+
+	v := waitJob(t, m, job.id, jobDone)
+	require.Len(t, v.Hops, 1)
+	assert.Equal(t, "1.1.1.1", v.Hops[0].Attempts[0].IP)
+	assert.NotNil(t, v.StartedAt)
+	assert.NotNil(t, v.FinishedAt)
+	assert.Equal(t, "tester", v.Owner)
+}
+
+func TestJobManagerCancelAndQueueLimit(t *testing.T) {
+	m := newJobManager(JobsConfig{Workers: 1, QueueSize: 1})
+	defer m.Close()
+	started := make(chan struct{}, 1)
+	m.run = func(ctx context.Context, job *traceJob) (*trace.Result, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	running, err := m.submit(testSetup(), "")
+	require.NoError(t, err)
+	<-started
+
+	queued, err := m.submit(testSetup(), "")
+	require.NoError(t, err)
+	_, err = m.submit(testSetup(), "")
+	assert.ErrorIs(t, err, errJobQueueFull)
+
+	status, ok := m.cancelJob(queued.id)
+	require.True(t, ok)
+	assert.Equal(t, jobCanceled, status)
+
+	status, ok = m.cancelJob(running.id)
+	require.True(t, ok)
+	assert.Equal(t, jobRunning, status)
+	v := waitJob(t, m, running.id, jobCanceled)
+	assert.Equal(t, "job canceled", v.Error)
+
+	_, ok = m.cancelJob("missing")
+	assert.False(t, ok)
+}
+
+func TestJobManagerTimeout(t *testing.T) {
+	m := newJobManager(JobsConfig{Workers: 1, Timeout: 20 * time.Millisecond})
+	defer m.Close()
+	m.run = func(ctx context.Context, job *traceJob) (*trace.Result, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	job, err := m.submit(testSetup(), "")
+	require.NoError(t, err)
+	v := waitJob(t, m, job.id, jobFailed)
+	assert.Contains(t, v.Error, "timed out")
+}
+
+func TestJobAPI(t *testing.T) {
+	jobs := newJobManager(JobsConfig{Workers: 1})
+	defer jobs.Close()
+	release := make(chan struct{})
+	jobs.run = func(ctx context.Context, job *traceJob) (*trace.Result, error) {
+		<-release
+		return &trace.Result{Hops: [][]trace.Hop{{{Success: false, TTL: 1, Error: trace.HopError("hop timeout")}}}}, nil
+	}
+	router, err := newRouter(nil, jobs)
+	require.NoError(t, err)
+
+	w := doRequest(router, http.MethodPost, "/api/jobs", []byte(`{"target":"1.1.1.1","data_provider":"disable-geoip"}`), nil)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var created jobView
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "/api/jobs/"+created.ID, w.Header().Get("Location"))
+
+	w = doRequest(router, http.MethodGet, "/api/jobs/"+created.ID+"/result", nil, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/jobs/"+created.ID+"/result.csv", nil, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	close(release)
+	waitJob(t, jobs, created.ID, jobDone)
+
+	w = doRequest(router, http.MethodGet, "/api/jobs/"+created.ID+"/result", nil, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var result traceResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "1.1.1.1", result.ResolvedIP)
+	assert.Len(t, result.Hops, 1)
+	assert.Equal(t, schema.Version, result.SchemaVersion)
+	assert.Equal(t, "icmp", result.Metadata.Method)
+	assert.Equal(t, "1.1.1.1", result.Metadata.DstIP)
+	assert.Equal(t, schema.ErrorCodeTimeout, result.Hops[0].Attempts[0].ErrorCode)
+	require.NotNil(t, result.RoutePath)
+	assert.Empty(t, result.RoutePath.Segments)
+
+	w = doRequest(router, http.MethodGet, "/api/jobs/"+created.ID+"/result.csv", nil, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "ttl,index,ip,hostname,rtt_ms,asn,country,prov,city,owner,mpls\n1,1,,,,,,,,,\n", w.Body.String())
+
+	w = doRequest(router, http.MethodGet, "/api/jobs/"+created.ID+"/map.svg", nil, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(w.Body.String(), "<svg "))
+
+	w = doRequest(router, http.MethodGet, "/api/schema", nil, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, string(schema.JSONSchema), w.Body.String())
+
+	w = doRequest(router, http.MethodGet, "/api/jobs/unknown", nil, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":"job not found"}`, w.Body.String())
+	w = doRequest(router, http.MethodGet, "/api/jobs/unknown", nil, func(r *http.Request) {
+		r.Header.Set("Accept-Language", "ru-RU,ru;q=0.9,en;q=0.8")
+	})
+	assert.JSONEq(t, `{"error":"задание не найдено"}`, w.Body.String())
+
+	w = doRequest(router, http.MethodPost, "/api/jobs", []byte(`{"target":"1.1.1.1","mode":"mtr"}`), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestJobAPIRedaction(t *testing.T) {
+	redactPolicy = redact.Policy{HideDestination: true}
+	defer func() { redactPolicy = redact.Policy{} }()
+
+	jobs := newJobManager(JobsConfig{Workers: 1})
+	defer jobs.Close()
+	jobs.run = func(ctx context.Context, job *traceJob) (*trace.Result, error) {
+		return &trace.Result{Hops: [][]trace.Hop{{
+			{Success: true, Address: &net.IPAddr{IP: net.ParseIP("1.1.1.1")}, Hostname: "one.one.one.one", TTL: 1, RTT: 5 * time.Millisecond,
+				Geo: &ipgeo.IPGeoData{Asnumber: "13335", Country: "美国"}},
+		}}}, nil
+	}
+	router, err := newRouter(nil, jobs)
+	require.NoError(t, err)
+
+	w := doRequest(router, http.MethodPost, "/api/jobs", []byte(`{"target":"1.1.1.1","data_provider":"disable-geoip"}`), nil)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var created jobView
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "1.1.0.0/16", created.ResolvedIP)
+	waitJob(t, jobs, created.ID, jobDone)
+
+	w = doRequest(router, http.MethodGet, "/api/jobs/"+created.ID+"/result", nil, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "1.1.1.1")
+	assert.NotContains(t, w.Body.String(), "one.one.one.one")
+	var result traceResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "1.1.0.0/16", result.Target)
+	assert.Equal(t, "1.1.0.0/16", result.Hops[0].Attempts[0].IP)
+	require.NotNil(t, result.RoutePath)
+	require.Len(t, result.RoutePath.Segments, 1)
+	assert.Equal(t, "1.1.0.0/16", result.RoutePath.Segments[0].Egress.IP)
+}
+
+func TestJobAPIOwnership(t *testing.T) {
+	cfg := &Config{Auth: AuthConfig{Tokens: []tokenEntry{
+		{Name: "alice", Token: "a-token", Role: "operator"},
+		{Name: "bob", Token: "b-token", Role: "operator"},
+		{Name: "root", Token: "r-token", Role: "admin"},
+	}}}
+	jobs := newJobManager(JobsConfig{Workers: 1})
+	defer jobs.Close()
+	jobs.run = func(ctx context.Context, job *traceJob) (*trace.Result, error) {
+		return &trace.Result{Hops: [][]trace.Hop{{{Success: false, TTL: 1, Error: trace.HopError("hop timeout")}}}}, nil
+	}
+	router, err := newRouter(cfg, jobs)
+	require.NoError(t, err)
+	as := func(token string) func(*http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+
+	w := doRequest(router, http.MethodPost, "/api/jobs", []byte(`{"target":"1.1.1.1","data_provider":"disable-geoip"}`), as("a-token"))
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var created jobView
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	waitJob(t, jobs, created.ID, jobDone)
+
+	// 其他用户看到的与不存在的任务相同
+	for _, path := range []string{"", "/result", "/result.csv", "/map.svg"} {
+		w = doRequest(router, http.MethodGet, "/api/jobs/"+created.ID+path, nil, as("b-token"))
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+	w = doRequest(router, http.MethodDelete, "/api/jobs/"+created.ID, nil, as("b-token"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(router, http.MethodPost, "/api/diff", []byte(`{"a":{"job_id":"`+created.ID+`"},"b":{"job_id":"`+created.ID+`"}}`), as("b-token"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(router, http.MethodGet, "/api/jobs/"+created.ID+"/result", nil, as("a-token"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(router, http.MethodPost, "/api/diff", []byte(`{"a":{"job_id":"`+created.ID+`"},"b":{"job_id":"`+created.ID+`"}}`), as("a-token"))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(router, http.MethodGet, "/api/jobs/"+created.ID, nil, as("r-token"))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestJobRecorderAnnotatesRedactedHops(t *testing.T) {
+	setup := testSetup()
+	setup.Redactor = redact.Policy{Self: []string{"192.0.2.1"}}.For(schema.Metadata{DstIP: "1.1.1.1"})
+	job := &traceJob{setup: setup}
+	rec := &jobRecorder{m: newJobManager(JobsConfig{}), job: job, lang: "en"}
+	defer rec.m.Close()
+
+	located := func(ip string, rtt time.Duration, lat, lng float64) []trace.Hop {
+		return []trace.Hop{{Success: true, Address: &net.IPAddr{IP: net.ParseIP(ip)}, RTT: rtt, Geo: &ipgeo.IPGeoData{Lat: lat, Lng: lng}}}
+	}
+	// 北京的本机地址与 4 ms 外的纽约：未脱敏时会以本机为锚点报告距离
+	res := &trace.Result{Hops: [][]trace.Hop{
+		located("192.0.2.1", 2*time.Millisecond, 39.9, 116.4),
+		located("198.51.100.1", 4*time.Millisecond, 40.7, -74.0),
+	}}
+	rec.OnHop(res, 0)
+	rec.OnHop(res, 1)
+	require.Len(t, job.hops, 2)
+	assert.Equal(t, redact.Self, job.hops[0].Attempts[0].IP)
+	assert.Empty(t, job.hops[1].Annotations)
+}
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/nxtrace/NTrace-core/history"
+	"github.com/nxtrace/NTrace-core/metrics"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+const (
+	defaultMonitorInterval = 5 * time.Minute
+	defaultMonitorRounds   = 5
+	defaultAlertCooldown   = 15 * time.Minute
+)
+
+type monitorConfig struct {
+	Listen   string          `mapstructure:"listen"`
+	Interval time.Duration   `mapstructure:"interval"`
+	Rounds   int             `mapstructure:"rounds"`
+	Cooldown time.Duration   `mapstructure:"cooldown"`
+	Rules    monitorRules    `mapstructure:"rules"`
+	Webhooks []webhookConfig `mapstructure:"webhooks"`
+	Targets  []monitorTarget `mapstructure:"targets"`
+	History  HistoryConfig   `mapstructure:"history"`
+}
+
+// monitorRules 描述告警规则，数值为 0 表示禁用对应规则
+type monitorRules struct {
+	LossPercent    float64 `mapstructure:"loss_percent"`
+	LatencyMs      float64 `mapstructure:"latency_ms"`
+	BaselineFactor float64 `mapstructure:"baseline_factor"`
+	PathChange     *bool   `mapstructure:"path_change"`
+	NewASN         *bool   `mapstructure:"new_asn"`
+}
+
+type monitorTarget struct {
+	Name          string        `mapstructure:"name"`
+	Target        string        `mapstructure:"target"`
+	Protocol      string        `mapstructure:"protocol"`
+	Port          int           `mapstructure:"port"`
+	Queries       int           `mapstructure:"queries"`
+	MaxHops       int           `mapstructure:"max_hops"`
+	TimeoutMs     int           `mapstructure:"timeout_ms"`
+	PacketSize    int           `mapstructure:"packet_size"`
+	DataProvider  string        `mapstructure:"data_provider"`
+	Language      string        `mapstructure:"language"`
+	IPv4Only      bool          `mapstructure:"ipv4_only"`
+	IPv6Only      bool          `mapstructure:"ipv6_only"`
+	DisableRDNS   bool          `mapstructure:"disable_rdns"`
+	SourceAddress string        `mapstructure:"source_address"`
+	SourceDevice  string        `mapstructure:"source_device"`
+	Interval      time.Duration `mapstructure:"interval"`
+	Rounds        int           `mapstructure:"rounds"`
+	Rules         *monitorRules `mapstructure:"rules"`
+}
+
+// monitorState 保存单个目标跨周期的状态，用于基线与路径比较
+type monitorState struct {
+	baseline  float64
+	samples   int
+	path      []string
+	knownASNs map[string]struct{}
+}
+
+func loadMonitorConfig(path string) (*monitorConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read monitor config: %w", err)
+	}
+
+	cfg := &monitorConfig{}
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("parse monitor config: %w", err)
+	}
+
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultMonitorInterval
+	}
+	if cfg.Rounds <= 0 {
+		cfg.Rounds = defaultMonitorRounds
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaultAlertCooldown
+	}
+	if len(cfg.Targets) == 0 {
+		return nil, errors.New("monitor config has no targets")
+	}
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+		if strings.TrimSpace(t.Target) == "" {
+			return nil, fmt.Errorf("monitor target #%d has no target", i+1)
+		}
+		if t.Name == "" {
+			t.Name = t.Target
+		}
+		if t.Interval <= 0 {
+			t.Interval = cfg.Interval
+		}
+		if t.Rounds <= 0 {
+			t.Rounds = cfg.Rounds
+		}
+	}
+	return cfg, nil
+}
+
+// effectiveRules 以全局规则为基础，叠加目标自身的覆盖项
+func (t *monitorTarget) effectiveRules(defaults monitorRules) monitorRules {
+	rules := defaults
+	if t.Rules == nil {
+		return rules
+	}
+	if t.Rules.LossPercent != 0 {
+		rules.LossPercent = t.Rules.LossPercent
+	}
+	if t.Rules.LatencyMs != 0 {
+		rules.LatencyMs = t.Rules.LatencyMs
+	}
+	if t.Rules.BaselineFactor != 0 {
+		rules.BaselineFactor = t.Rules.BaselineFactor
+	}
+	if t.Rules.PathChange != nil {
+		rules.PathChange = t.Rules.PathChange
+	}
+	if t.Rules.NewASN != nil {
+		rules.NewASN = t.Rules.NewASN
+	}
+	return rules
+}
+
+func (t *monitorTarget) traceRequest() traceRequest {
+	return traceRequest{
+		Target:          t.Target,
+		Protocol:        t.Protocol,
+		Port:            t.Port,
+		Queries:         t.Queries,
+		MaxHops:         t.MaxHops,
+		TimeoutMs:       t.TimeoutMs,
+		PacketSize:      t.PacketSize,
+		DataProvider:    t.DataProvider,
+		Language:        t.Language,
+		IPv4Only:        t.IPv4Only,
+		IPv6Only:        t.IPv6Only,
+		DisableRDNS:     t.DisableRDNS,
+		DisableMaptrace: true,
+		SourceAddress:   t.SourceAddress,
+		SourceDevice:    t.SourceDevice,
+	}
+}
+
+// RunMonitor starts the monitoring daemon described by the YAML file at configPath.
+// It blocks until SIGINT/SIGTERM is received.
+func RunMonitor(configPath string) error {
+	cfg, err := loadMonitorConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	store, err := openHistory(cfg.History)
+	if err != nil {
+		return err
+	}
+	historyStore = store
+
+	dispatcher := newAlertDispatcher(cfg.Webhooks, cfg.Cooldown)
+
+	if cfg.Listen != "" {
+		metricsSrv := &http.Server{Addr: cfg.Listen, Handler: metrics.Handler()}
+		go func() {
+			log.Printf("[monitor] serving metrics on %s/metrics", cfg.Listen)
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("[monitor] metrics listener failed: %v", err)
+			}
+		}()
+		defer func() {
+			_ = metricsSrv.Close()
+		}()
+	}
+
+	log.Printf("[monitor] started targets=%d webhooks=%d cooldown=%s", len(cfg.Targets), len(cfg.Webhooks), cfg.Cooldown)
+
+	var wg sync.WaitGroup
+	for i := range cfg.Targets {
+		target := &cfg.Targets[i]
+		rules := target.effectiveRules(cfg.Rules)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runMonitorTarget(ctx, target, rules, dispatcher)
+		}()
+	}
+	wg.Wait()
+
+	log.Println("[monitor] stopped")
+	return nil
+}
+
+func runMonitorTarget(ctx context.Context, target *monitorTarget, rules monitorRules, dispatcher *alertDispatcher) {
+	state := &monitorState{knownASNs: make(map[string]struct{})}
+	for {
+		if err := runMonitorCycle(ctx, target, rules, state, dispatcher); err != nil {
+			monitorCycles.Inc(target.Name, "error")
+			log.Printf("[monitor] cycle failed target=%s error=%v", target.Name, err)
+		} else {
+			monitorCycles.Inc(target.Name, "ok")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(target.Interval):
+		}
+	}
+}
+
+func runMonitorCycle(ctx context.Context, target *monitorTarget, rules monitorRules, state *monitorState, dispatcher *alertDispatcher) error {
+	setup, _, err := prepareTrace(target.traceRequest())
+	if err != nil {
+		return err
+	}
+
+	aggregator := newMTRAggregator()
+	interval := time.Duration(setup.Req.IntervalMs) * time.Millisecond
+	var stats []mtrHopJSON
+	samples := make(map[hopSampleKey][]float64)
+	for round := 0; round < target.Rounds; round++ {
+		if round > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(interval):
+			}
+		}
+
+		res, err := runMonitorTrace(ctx, setup)
+		if ctx.Err() != nil {
+			// 收到退出信号时放弃本轮未完成的结果
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		stats = aggregator.Update(res, setup.Config.NumMeasurements)
+		collectRTTSamples(samples, res)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	publishMonitorMetrics(target.Name, stats, samples)
+	recordHistory(history.KindMTR, "monitor", "", setup, mtrSnapshot{Iteration: target.Rounds, Stats: stats}, len(stats))
+	alerts := evaluateMonitorCycle(target, rules, state, stats, setup.IP.String())
+	for _, alert := range alerts {
+		dispatcher.Dispatch(alert)
+	}
+	log.Printf("[monitor] cycle completed target=%s resolved=%s hops=%d", target.Name, setup.IP, len(stats))
+	return nil
+}
+
+func runMonitorTrace(ctx context.Context, setup *traceExecution) (*trace.Result, error) {
+	if !lockTraceMu(ctx) {
+		return nil, ctx.Err()
+	}
+	defer traceMu.Unlock()
+
+	restore := applyTraceGlobals(setup, "[monitor]")
+	defer restore()
+
+	config := setup.Config
+	config.RealtimePrinter = nil
+	config.AsyncPrinter = nil
+	return trace.TracerouteContext(ctx, setup.Method, config)
+}
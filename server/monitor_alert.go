@@ -0,0 +1,341 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nxtrace/NTrace-core/util"
+)
+
+const (
+	ruleLoss       = "loss"
+	ruleLatency    = "latency"
+	rulePathChange = "path_change"
+	ruleNewASN     = "new_asn"
+
+	// baselineAlpha 为延迟基线的指数滑动平均系数
+	baselineAlpha = 0.2
+	// baselineWarmup 为基线生效前需要积累的周期数
+	baselineWarmup = 3
+)
+
+type webhookConfig struct {
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+	Timeout time.Duration     `mapstructure:"timeout"`
+}
+
+type monitorAlert struct {
+	Status     string         `json:"status"`
+	Rule       string         `json:"rule"`
+	Target     string         `json:"target"`
+	Host       string         `json:"host"`
+	ResolvedIP string         `json:"resolved_ip"`
+	Message    string         `json:"message"`
+	Value      float64        `json:"value,omitempty"`
+	Threshold  float64        `json:"threshold,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+	Timestamp  time.Time      `json:"timestamp"`
+
+	key        string
+	firing     bool
+	resolvable bool
+}
+
+// evaluateMonitorCycle 根据一次 MTR 周期的聚合结果评估告警规则。
+// 阈值类规则（丢包、延迟）无论是否触发都会返回，以便分发器发送恢复通知；
+// 事件类规则（路径变化、新 ASN）仅在触发时返回。
+func evaluateMonitorCycle(target *monitorTarget, rules monitorRules, state *monitorState, stats []mtrHopJSON, dstIP string) []monitorAlert {
+	now := time.Now()
+	base := monitorAlert{
+		Target:     target.Name,
+		Host:       target.Target,
+		ResolvedIP: dstIP,
+		Timestamp:  now,
+	}
+	var alerts []monitorAlert
+
+	reached, loss, avg := destinationStats(stats, dstIP)
+
+	if rules.LossPercent > 0 {
+		alert := base
+		alert.Rule = ruleLoss
+		alert.key = target.Name + "|" + ruleLoss
+		alert.resolvable = true
+		alert.Value = loss
+		alert.Threshold = rules.LossPercent
+		alert.firing = loss > rules.LossPercent
+		alert.Message = fmt.Sprintf("destination loss %.1f%% (threshold %.1f%%)", loss, rules.LossPercent)
+		alerts = append(alerts, alert)
+	}
+
+	if reached && (rules.LatencyMs > 0 || rules.BaselineFactor > 0) {
+		alert := base
+		alert.Rule = ruleLatency
+		alert.key = target.Name + "|" + ruleLatency
+		alert.resolvable = true
+		alert.Value = avg
+		switch {
+		case rules.LatencyMs > 0 && avg > rules.LatencyMs:
+			alert.firing = true
+			alert.Threshold = rules.LatencyMs
+			alert.Message = fmt.Sprintf("destination latency %.2fms above %.2fms", avg, rules.LatencyMs)
+		case rules.BaselineFactor > 0 && state.samples >= baselineWarmup && avg > state.baseline*rules.BaselineFactor:
+			alert.firing = true
+			alert.Threshold = state.baseline * rules.BaselineFactor
+			alert.Message = fmt.Sprintf("destination latency %.2fms above baseline %.2fms x%.2f", avg, state.baseline, rules.BaselineFactor)
+			alert.Details = map[string]any{"baseline_ms": state.baseline}
+		default:
+			alert.Message = fmt.Sprintf("destination latency %.2fms", avg)
+		}
+		alerts = append(alerts, alert)
+	}
+	if reached {
+		if state.samples == 0 {
+			state.baseline = avg
+		} else {
+			state.baseline = baselineAlpha*avg + (1-baselineAlpha)*state.baseline
+		}
+		state.samples++
+	}
+
+	path := pathFromStats(stats)
+	pathChanged := state.path != nil && !samePath(state.path, path)
+	if pathChanged {
+		monitorPathChanges.Inc(target.Name)
+	}
+	if rules.PathChange != nil && *rules.PathChange && pathChanged {
+		alert := base
+		alert.Rule = rulePathChange
+		alert.key = target.Name + "|" + rulePathChange + "|" + strings.Join(path, ",")
+		alert.firing = true
+		alert.Message = "path changed"
+		alert.Details = map[string]any{"previous": state.path, "current": path}
+		alerts = append(alerts, alert)
+	}
+	if len(path) > 0 {
+		state.path = path
+	}
+
+	asns := asnsFromStats(stats)
+	firstCycle := len(state.knownASNs) == 0
+	var fresh []string
+	for _, asn := range asns {
+		if _, ok := state.knownASNs[asn]; ok {
+			continue
+		}
+		state.knownASNs[asn] = struct{}{}
+		if !firstCycle {
+			fresh = append(fresh, asn)
+		}
+	}
+	if rules.NewASN != nil && *rules.NewASN && len(fresh) > 0 {
+		alert := base
+		alert.Rule = ruleNewASN
+		alert.key = target.Name + "|" + ruleNewASN + "|" + strings.Join(fresh, ",")
+		alert.firing = true
+		alert.Message = "new ASN in path: AS" + strings.Join(fresh, ", AS")
+		alert.Details = map[string]any{"asns": fresh}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts
+}
+
+// destinationStats 计算目的地所在 TTL 的丢包率与平均延迟；未到达目的地时视为 100% 丢包
+func destinationStats(stats []mtrHopJSON, dstIP string) (bool, float64, float64) {
+	dstTTL := 0
+	for _, row := range stats {
+		if row.IP == dstIP && row.Received > 0 {
+			dstTTL = row.TTL
+		}
+	}
+	if dstTTL == 0 {
+		return false, 100, 0
+	}
+
+	sent, received := 0, 0
+	sum := 0.0
+	for _, row := range stats {
+		if row.TTL != dstTTL {
+			continue
+		}
+		sent += row.Sent
+		if row.IP == dstIP {
+			received += row.Received
+			sum += row.Avg * float64(row.Received)
+		}
+	}
+	if sent == 0 || received == 0 {
+		return false, 100, 0
+	}
+	return true, float64(sent-received) / float64(sent) * 100, sum / float64(received)
+}
+
+// pathFromStats 为每个 TTL 选取收包最多的 IP，无响应的 TTL 以 "*" 表示
+func pathFromStats(stats []mtrHopJSON) []string {
+	best := make(map[int]mtrHopJSON)
+	maxTTL := 0
+	for _, row := range stats {
+		if row.TTL > maxTTL {
+			maxTTL = row.TTL
+		}
+		if row.IP == "" || row.Received == 0 {
+			continue
+		}
+		if cur, ok := best[row.TTL]; !ok || row.Received > cur.Received {
+			best[row.TTL] = row
+		}
+	}
+	path := make([]string, 0, maxTTL)
+	for ttl := 1; ttl <= maxTTL; ttl++ {
+		if row, ok := best[ttl]; ok {
+			path = append(path, row.IP)
+		} else {
+			path = append(path, "*")
+		}
+	}
+	return path
+}
+
+// trimTimeouts 去掉最后一个响应之后的 "*"，这些 TTL 的数量取决于 max_hops 与何时放弃，不代表路径变化
+func trimTimeouts(path []string) []string {
+	n := len(path)
+	for n > 0 && path[n-1] == "*" {
+		n--
+	}
+	return path[:n]
+}
+
+// samePath 比较两条路径，"*" 视为通配，末尾无响应的 TTL 不参与比较
+func samePath(a, b []string) bool {
+	a, b = trimTimeouts(a), trimTimeouts(b)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] == "*" || b[i] == "*" {
+			continue
+		}
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func asnsFromStats(stats []mtrHopJSON) []string {
+	set := make(map[string]struct{})
+	for _, row := range stats {
+		if row.Geo == nil || row.Geo.Asnumber == "" {
+			continue
+		}
+		set[row.Geo.Asnumber] = struct{}{}
+	}
+	return sortedSet(set)
+}
+
+// alertDispatcher 负责告警去重、冷却以及 Webhook 投递
+type alertDispatcher struct {
+	mu       sync.Mutex
+	webhooks []webhookConfig
+	cooldown time.Duration
+	lastSent map[string]time.Time
+	active   map[string]bool
+	client   *http.Client
+}
+
+func newAlertDispatcher(webhooks []webhookConfig, cooldown time.Duration) *alertDispatcher {
+	return &alertDispatcher{
+		webhooks: webhooks,
+		cooldown: cooldown,
+		lastSent: make(map[string]time.Time),
+		active:   make(map[string]bool),
+		client:   &http.Client{},
+	}
+}
+
+// Dispatch 投递告警并返回是否实际发送。
+// 同一 key 的告警在冷却时间内只发送一次；持续中的告警恢复后发送一次 resolved 通知。
+func (d *alertDispatcher) Dispatch(alert monitorAlert) bool {
+	d.mu.Lock()
+	if !alert.firing {
+		if !d.active[alert.key] {
+			d.mu.Unlock()
+			return false
+		}
+		delete(d.active, alert.key)
+		delete(d.lastSent, alert.key)
+		d.mu.Unlock()
+		alert.Status = "resolved"
+		d.send(alert)
+		return true
+	}
+
+	if last, ok := d.lastSent[alert.key]; ok && alert.Timestamp.Sub(last) < d.cooldown {
+		d.mu.Unlock()
+		return false
+	}
+	d.lastSent[alert.key] = alert.Timestamp
+	if alert.resolvable {
+		d.active[alert.key] = true
+	}
+	d.mu.Unlock()
+
+	alert.Status = "firing"
+	d.send(alert)
+	return true
+}
+
+func (d *alertDispatcher) send(alert monitorAlert) {
+	log.Printf("[monitor] alert status=%s rule=%s target=%s message=%q", alert.Status, alert.Rule, alert.Target, alert.Message)
+
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		log.Printf("[monitor] encode alert failed: %v", err)
+		return
+	}
+
+	for _, hook := range d.webhooks {
+		if err := d.post(hook, payload); err != nil {
+			log.Printf("[monitor] webhook failed url=%s error=%v", hook.URL, err)
+		}
+	}
+}
+
+func (d *alertDispatcher) post(hook webhookConfig, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", util.UserAgent)
+	for k, v := range hook.Headers {
+		req.Header.Set(k, v)
+	}
+
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	client := *d.client
+	client.Timeout = timeout
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nxtrace/NTrace-core/metrics"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+var (
+	monitorHopLoss     = metrics.NewGaugeVec("nexttrace_monitor_hop_loss_ratio", "Packet loss ratio per hop in the last monitor cycle.", "target", "ttl", "ip", "asn")
+	monitorHopRTT      = metrics.NewGaugeVec("nexttrace_monitor_hop_rtt_ms", "RTT percentiles per hop in the last monitor cycle, in milliseconds.", "target", "ttl", "ip", "quantile")
+	monitorHopCount    = metrics.NewGaugeVec("nexttrace_monitor_hop_count", "Number of hops to the destination in the last monitor cycle.", "target")
+	monitorPathChanges = metrics.NewCounterVec("nexttrace_monitor_path_changes_total", "Path changes detected between monitor cycles.", "target")
+	monitorCycles      = metrics.NewCounterVec("nexttrace_monitor_cycles_total", "Completed monitor cycles by outcome.", "target", "result")
+
+	rttQuantiles = []float64{0.5, 0.9, 0.99}
+)
+
+type hopSampleKey struct {
+	ttl int
+	ip  string
+}
+
+// collectRTTSamples 收集每轮追踪中各跳的 RTT 样本，用于计算分位数
+func collectRTTSamples(samples map[hopSampleKey][]float64, res *trace.Result) {
+	if res == nil {
+		return
+	}
+	for idx, attempts := range res.Hops {
+		for _, attempt := range attempts {
+			if !attempt.Success || attempt.Address == nil {
+				continue
+			}
+			key := hopSampleKey{ttl: idx + 1, ip: attempt.Address.String()}
+			samples[key] = append(samples[key], float64(attempt.RTT)/float64(time.Millisecond))
+		}
+	}
+}
+
+// percentile 使用最近秩法计算分位数，sorted 必须已升序排列
+func percentile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// publishMonitorMetrics 用最新一次周期的数据替换目标的逐跳指标
+func publishMonitorMetrics(target string, stats []mtrHopJSON, samples map[hopSampleKey][]float64) {
+	monitorHopLoss.DeleteMatching("target", target)
+	monitorHopRTT.DeleteMatching("target", target)
+
+	for _, row := range stats {
+		if row.IP == "" {
+			continue
+		}
+		ttl := strconv.Itoa(row.TTL)
+		asn := ""
+		if row.Geo != nil {
+			asn = row.Geo.Asnumber
+		}
+		monitorHopLoss.Set(row.LossPercent/100, target, ttl, row.IP, asn)
+
+		values := append([]float64(nil), samples[hopSampleKey{ttl: row.TTL, ip: row.IP}]...)
+		if len(values) == 0 {
+			continue
+		}
+		sort.Float64s(values)
+		for _, q := range rttQuantiles {
+			monitorHopRTT.Set(percentile(values, q), target, ttl, row.IP, strconv.FormatFloat(q, 'g', -1, 64))
+		}
+	}
+	// 以最后一个响应的跳（到达时即目的地址）的 TTL 计数，末尾超时的 TTL 不计入
+	monitorHopCount.Set(float64(len(trimTimeouts(pathFromStats(stats)))), target)
+}
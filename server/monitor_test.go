@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/ipgeo"
+)
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func TestDestinationStats(t *testing.T) {
+	stats := []mtrHopJSON{
+		{TTL: 1, IP: "192.168.1.1", Sent: 10, Received: 10, Avg: 1},
+		{TTL: 2, IP: "1.1.1.1", Sent: 8, Received: 8, Avg: 20},
+		{TTL: 2, Sent: 2, Received: 0},
+	}
+
+	reached, loss, avg := destinationStats(stats, "1.1.1.1")
+	assert.True(t, reached)
+	assert.InDelta(t, 20.0, loss, 0.001)
+	assert.InDelta(t, 20.0, avg, 0.001)
+
+	reached, loss, _ = destinationStats(stats, "8.8.8.8")
+	assert.False(t, reached)
+	assert.Equal(t, 100.0, loss)
+}
+
+func TestEvaluateMonitorCycleRules(t *testing.T) {
+	target := &monitorTarget{Name: "demo", Target: "example.com"}
+	rules := monitorRules{LossPercent: 10, PathChange: boolPtr(true), NewASN: boolPtr(true)}
+	state := &monitorState{knownASNs: make(map[string]struct{})}
+
+	first := []mtrHopJSON{
+		{TTL: 1, IP: "10.0.0.1", Sent: 5, Received: 5, Avg: 1, Geo: &ipgeo.IPGeoData{Asnumber: "64512"}},
+		{TTL: 2, IP: "1.1.1.1", Sent: 5, Received: 5, Avg: 10, Geo: &ipgeo.IPGeoData{Asnumber: "13335"}},
+	}
+	alerts := evaluateMonitorCycle(target, rules, state, first, "1.1.1.1")
+	require.Len(t, alerts, 1)
+	assert.Equal(t, ruleLoss, alerts[0].Rule)
+	assert.False(t, alerts[0].firing)
+
+	second := []mtrHopJSON{
+		{TTL: 1, IP: "10.0.0.2", Sent: 5, Received: 5, Avg: 1, Geo: &ipgeo.IPGeoData{Asnumber: "64513"}},
+		{TTL: 2, IP: "1.1.1.1", Sent: 5, Received: 2, Avg: 10, Geo: &ipgeo.IPGeoData{Asnumber: "13335"}},
+	}
+	alerts = evaluateMonitorCycle(target, rules, state, second, "1.1.1.1")
+	rulesFired := make(map[string]bool)
+	for _, alert := range alerts {
+		rulesFired[alert.Rule] = alert.firing
+	}
+	assert.True(t, rulesFired[ruleLoss])
+	assert.True(t, rulesFired[rulePathChange])
+	assert.True(t, rulesFired[ruleNewASN])
+}
+
+func TestSamePathIgnoresTrailingTimeouts(t *testing.T) {
+	a := pathFromStats([]mtrHopJSON{
+		{TTL: 1, IP: "10.0.0.1", Received: 3},
+		{TTL: 2, Received: 0},
+		{TTL: 3, IP: "10.0.0.3", Received: 3},
+		{TTL: 4, Received: 0},
+	})
+	assert.Equal(t, []string{"10.0.0.1", "*", "10.0.0.3", "*"}, a)
+	assert.True(t, samePath(a, append(a, "*", "*")))
+	assert.True(t, samePath(a, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}))
+	assert.False(t, samePath(a, []string{"10.0.0.1", "*", "10.0.0.3", "10.0.0.4"}))
+}
+
+func TestEvaluateMonitorCycleBaseline(t *testing.T) {
+	target := &monitorTarget{Name: "demo", Target: "example.com"}
+	rules := monitorRules{BaselineFactor: 2}
+	state := &monitorState{knownASNs: make(map[string]struct{})}
+
+	stats := func(avg float64) []mtrHopJSON {
+		return []mtrHopJSON{{TTL: 1, IP: "1.1.1.1", Sent: 3, Received: 3, Avg: avg}}
+	}
+	for i := 0; i < baselineWarmup; i++ {
+		alerts := evaluateMonitorCycle(target, rules, state, stats(10), "1.1.1.1")
+		require.Len(t, alerts, 1)
+		assert.False(t, alerts[0].firing)
+	}
+
+	alerts := evaluateMonitorCycle(target, rules, state, stats(50), "1.1.1.1")
+	require.Len(t, alerts, 1)
+	assert.True(t, alerts[0].firing)
+	assert.Equal(t, ruleLatency, alerts[0].Rule)
+}
+
+func TestAlertDispatcherCooldownAndResolve(t *testing.T) {
+	var mu sync.Mutex
+	var received []monitorAlert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert monitorAlert
+		_ = json.NewDecoder(r.Body).Decode(&alert)
+		mu.Lock()
+		received = append(received, alert)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d := newAlertDispatcher([]webhookConfig{{URL: srv.URL}}, time.Hour)
+	now := time.Now()
+	alert := monitorAlert{Rule: ruleLoss, Target: "demo", Timestamp: now, key: "demo|loss", firing: true, resolvable: true}
+
+	assert.True(t, d.Dispatch(alert))
+	alert.Timestamp = now.Add(time.Minute)
+	assert.False(t, d.Dispatch(alert), "duplicate alert inside cooldown must be suppressed")
+
+	alert.firing = false
+	assert.True(t, d.Dispatch(alert))
+	assert.False(t, d.Dispatch(alert), "resolved is only sent once")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 2)
+	assert.Equal(t, "firing", received[0].Status)
+	assert.Equal(t, "resolved", received[1].Status)
+}
+
+func TestLoadMonitorConfigExample(t *testing.T) {
+	cfg, err := loadMonitorConfig("../monitor.example.yaml")
+	require.NoError(t, err)
+	require.Len(t, cfg.Targets, 2)
+
+	assert.Equal(t, 5*time.Minute, cfg.Targets[0].Interval)
+	assert.Equal(t, time.Minute, cfg.Targets[1].Interval)
+	assert.Equal(t, 3, cfg.Targets[1].Rounds)
+
+	rules := cfg.Targets[1].effectiveRules(cfg.Rules)
+	assert.Equal(t, 5.0, rules.LossPercent)
+	assert.Equal(t, 1.5, rules.BaselineFactor)
+	require.NotNil(t, rules.PathChange)
+	assert.True(t, *rules.PathChange)
+}
+
+func TestMonitorHopCountStopsAtLastReply(t *testing.T) {
+	stats := []mtrHopJSON{
+		{TTL: 1, IP: "10.0.0.1", Sent: 3, Received: 3, Avg: 1},
+		{TTL: 2, Sent: 3},
+		{TTL: 3, IP: "10.0.0.3", Sent: 3, Received: 3, Avg: 3},
+		{TTL: 4, Sent: 3},
+		{TTL: 5, Sent: 3},
+	}
+	publishMonitorMetrics("hop-count", stats, nil)
+	assert.Equal(t, 3.0, monitorHopCount.Value("hop-count"))
+}
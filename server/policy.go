@@ -0,0 +1,303 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/nxtrace/NTrace-core/redact"
+)
+
+// PolicyConfig 限制 Web 控制台可以追踪的目标与参数，零值表示不限制
+type PolicyConfig struct {
+	// DenyPrivate 拒绝回环、私有、链路本地、CGNAT、组播与未指定地址
+	DenyPrivate bool     `mapstructure:"deny_private"`
+	DenyCIDRs   []string `mapstructure:"deny_cidrs"`
+	// AllowCIDRs 非空时，解析后的目标地址必须落在其中之一
+	AllowCIDRs []string `mapstructure:"allow_cidrs"`
+	// DenyDomains / AllowDomains 为域名通配模式，例如 "*.internal"、"localhost"
+	DenyDomains  []string `mapstructure:"deny_domains"`
+	AllowDomains []string `mapstructure:"allow_domains"`
+	Protocols    []string `mapstructure:"protocols"`
+	// Ports 为 TCP/UDP 允许的目的端口，支持 "443" 与 "33434-33534" 两种写法
+	Ports         []string `mapstructure:"ports"`
+	MaxHops       int      `mapstructure:"max_hops"`
+	MaxQueries    int      `mapstructure:"max_queries"`
+	MaxPacketSize int      `mapstructure:"max_packet_size"`
+	// MaxRounds 限制 MTR 模式的轮数；未指定轮数（持续运行）的请求被收紧到该值
+	MaxRounds int `mapstructure:"max_rounds"`
+	// PowProviders / DotServers 非空时，请求只能选择其中的 PoW 服务与 DoT 解析器
+	PowProviders []string `mapstructure:"pow_providers"`
+	DotServers   []string `mapstructure:"dot_servers"`
+}
+
+type portRange struct {
+	low, high int
+}
+
+// targetPolicy 为编译后的 PolicyConfig
+type targetPolicy struct {
+	cfg       PolicyConfig
+	denyNets  []*net.IPNet
+	allowNets []*net.IPNet
+	protocols map[string]struct{}
+	ports     []portRange
+}
+
+// specialNets 为 deny_private 额外覆盖、net.IP 方法未包含的地址段
+var specialNets = mustParseCIDRs("0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4", "64:ff9b:1::/48")
+
+// tracePolicy 为当前生效的目标策略，nil 表示不限制；由 Run 在启动前设置
+var tracePolicy *targetPolicy
+
+// redactPolicy 为对外输出的脱敏策略；由 Run 在启动前设置
+var redactPolicy redact.Policy
+
+func mustParseCIDRs(list ...string) []*net.IPNet {
+	nets, err := parseCIDRs(list)
+	if err != nil {
+		panic(err)
+	}
+	return nets
+}
+
+func newTargetPolicy(cfg PolicyConfig) (*targetPolicy, error) {
+	p := &targetPolicy{cfg: cfg}
+
+	var err error
+	if p.denyNets, err = parseCIDRs(cfg.DenyCIDRs); err != nil {
+		return nil, fmt.Errorf("policy deny_cidrs: %w", err)
+	}
+	if p.allowNets, err = parseCIDRs(cfg.AllowCIDRs); err != nil {
+		return nil, fmt.Errorf("policy allow_cidrs: %w", err)
+	}
+
+	for _, pattern := range append(append([]string{}, cfg.DenyDomains...), cfg.AllowDomains...) {
+		if _, err := path.Match(normalizeDomain(pattern), ""); err != nil {
+			return nil, fmt.Errorf("policy domain pattern %q: %w", pattern, err)
+		}
+	}
+
+	if len(cfg.Protocols) > 0 {
+		p.protocols = make(map[string]struct{}, len(cfg.Protocols))
+		for _, proto := range cfg.Protocols {
+			proto = strings.ToLower(strings.TrimSpace(proto))
+			if !contains(supportedProtocols, proto) {
+				return nil, fmt.Errorf("policy protocols: unsupported protocol %q", proto)
+			}
+			p.protocols[proto] = struct{}{}
+		}
+	}
+
+	for _, name := range cfg.PowProviders {
+		if !contains(powProviders, strings.TrimSpace(name)) {
+			return nil, fmt.Errorf("policy pow_providers: unknown provider %q", name)
+		}
+	}
+	for _, name := range cfg.DotServers {
+		if !contains(dotServers, strings.ToLower(strings.TrimSpace(name))) {
+			return nil, fmt.Errorf("policy dot_servers: unknown server %q", name)
+		}
+	}
+
+	for _, raw := range cfg.Ports {
+		r, err := parsePortRange(raw)
+		if err != nil {
+			return nil, fmt.Errorf("policy ports: %w", err)
+		}
+		p.ports = append(p.ports, r)
+	}
+	return p, nil
+}
+
+func parsePortRange(raw string) (portRange, error) {
+	raw = strings.TrimSpace(raw)
+	lowStr, highStr, isRange := strings.Cut(raw, "-")
+	low, err := strconv.Atoi(strings.TrimSpace(lowStr))
+	if err != nil {
+		return portRange{}, fmt.Errorf("invalid port %q", raw)
+	}
+	high := low
+	if isRange {
+		if high, err = strconv.Atoi(strings.TrimSpace(highStr)); err != nil {
+			return portRange{}, fmt.Errorf("invalid port range %q", raw)
+		}
+	}
+	if low < 1 || high > 65535 || low > high {
+		return portRange{}, fmt.Errorf("invalid port range %q", raw)
+	}
+	return portRange{low: low, high: high}, nil
+}
+
+func normalizeDomain(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+func matchDomain(patterns []string, host string) (string, bool) {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(normalizeDomain(pattern), host); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// checkTarget 在 DNS 解析之前校验目标域名
+func (p *targetPolicy) checkTarget(target string) error {
+	if p == nil || net.ParseIP(target) != nil {
+		return nil
+	}
+	host := normalizeDomain(target)
+	if pattern, ok := matchDomain(p.cfg.DenyDomains, host); ok {
+		return fmt.Errorf("target %s is denied by policy (domain pattern %q)", target, pattern)
+	}
+	if len(p.cfg.AllowDomains) > 0 {
+		if _, ok := matchDomain(p.cfg.AllowDomains, host); !ok {
+			return fmt.Errorf("target %s is not in the allowed domains", target)
+		}
+	}
+	return nil
+}
+
+// checkProtocol 校验探测协议与目的端口，port 为 0 时表示 ICMP
+func (p *targetPolicy) checkProtocol(protocol string, port int) error {
+	if p == nil {
+		return nil
+	}
+	if p.protocols != nil {
+		if _, ok := p.protocols[protocol]; !ok {
+			return fmt.Errorf("protocol %s is not allowed by policy", protocol)
+		}
+	}
+	if protocol == "icmp" || len(p.ports) == 0 {
+		return nil
+	}
+	for _, r := range p.ports {
+		if port >= r.low && port <= r.high {
+			return nil
+		}
+	}
+	return fmt.Errorf("port %d is not allowed by policy", port)
+}
+
+// checkLimits 校验显式指定的跳数、探测次数与包大小
+func (p *targetPolicy) checkLimits(req traceRequest) error {
+	if p == nil {
+		return nil
+	}
+	if p.cfg.MaxHops > 0 && req.MaxHops > p.cfg.MaxHops {
+		return fmt.Errorf("max_hops %d exceeds policy limit %d", req.MaxHops, p.cfg.MaxHops)
+	}
+	if p.cfg.MaxHops > 0 && req.BeginHop > p.cfg.MaxHops {
+		return fmt.Errorf("begin_hop %d exceeds policy limit %d", req.BeginHop, p.cfg.MaxHops)
+	}
+	if p.cfg.MaxQueries > 0 && req.Queries > p.cfg.MaxQueries {
+		return fmt.Errorf("queries %d exceeds policy limit %d", req.Queries, p.cfg.MaxQueries)
+	}
+	if p.cfg.MaxPacketSize > 0 && req.PacketSize > p.cfg.MaxPacketSize {
+		return fmt.Errorf("packet_size %d exceeds policy limit %d", req.PacketSize, p.cfg.MaxPacketSize)
+	}
+	if p.cfg.MaxRounds > 0 && req.MaxRounds > p.cfg.MaxRounds {
+		return fmt.Errorf("max_rounds %d exceeds policy limit %d", req.MaxRounds, p.cfg.MaxRounds)
+	}
+	return nil
+}
+
+// checkUpstreams 校验请求指定的 PoW 服务与 DoT 解析器，二者都是服务端代为连接的外部主机
+func (p *targetPolicy) checkUpstreams(req traceRequest) error {
+	if p == nil {
+		return nil
+	}
+	pow := strings.TrimSpace(req.PowProvider)
+	dot := strings.ToLower(strings.TrimSpace(req.DotServer))
+	if pow != "" && len(p.cfg.PowProviders) > 0 && !containsFold(p.cfg.PowProviders, pow) {
+		return fmt.Errorf("pow_provider %s is not allowed by policy", pow)
+	}
+	if dot != "" && len(p.cfg.DotServers) > 0 && !containsFold(p.cfg.DotServers, dot) {
+		return fmt.Errorf("dot_server %s is not allowed by policy", dot)
+	}
+	return nil
+}
+
+func containsFold(list []string, v string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), v) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyLimits 在请求未显式指定时，将默认值收紧到策略上限以内
+func (p *targetPolicy) applyLimits(req *traceRequest) {
+	if p == nil {
+		return
+	}
+	if p.cfg.MaxHops > 0 && req.MaxHops <= 0 && defaults["max_hops"].(int) > p.cfg.MaxHops {
+		req.MaxHops = p.cfg.MaxHops
+	}
+	if p.cfg.MaxQueries > 0 && req.Queries <= 0 && defaults["queries"].(int) > p.cfg.MaxQueries {
+		req.Queries = p.cfg.MaxQueries
+	}
+	if p.cfg.MaxPacketSize > 0 && req.PacketSize <= 0 && defaults["packet_size"].(int) > p.cfg.MaxPacketSize {
+		req.PacketSize = p.cfg.MaxPacketSize
+	}
+	// 未指定轮数的 MTR 会一直运行
+	if p.cfg.MaxRounds > 0 && req.MaxRounds <= 0 {
+		req.MaxRounds = p.cfg.MaxRounds
+	}
+}
+
+// checkIP 在 DNS 解析之后校验目标地址
+func (p *targetPolicy) checkIP(target string, ip net.IP) error {
+	if p == nil {
+		return nil
+	}
+	if p.cfg.DenyPrivate && isPrivateTarget(ip) {
+		return fmt.Errorf("target %s resolves to non-public address %s, which is denied by policy", target, ip)
+	}
+	for _, n := range p.denyNets {
+		if n.Contains(ip) {
+			return fmt.Errorf("target %s resolves to %s in denied range %s", target, ip, n)
+		}
+	}
+	if len(p.allowNets) == 0 {
+		return nil
+	}
+	for _, n := range p.allowNets {
+		if n.Contains(ip) {
+			return nil
+		}
+	}
+	return fmt.Errorf("target %s resolves to %s, which is outside the allowed ranges", target, ip)
+}
+
+func isPrivateTarget(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, n := range specialNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedProtocols 返回策略允许的协议列表，用于 /api/options
+func (p *targetPolicy) allowedProtocols() []string {
+	if p == nil || p.protocols == nil {
+		return supportedProtocols
+	}
+	list := make([]string, 0, len(p.protocols))
+	for _, proto := range supportedProtocols {
+		if _, ok := p.protocols[proto]; ok {
+			list = append(list, proto)
+		}
+	}
+	return list
+}
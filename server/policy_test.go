@@ -0,0 +1,117 @@
+package server
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetPolicyIP(t *testing.T) {
+	p, err := newTargetPolicy(PolicyConfig{DenyPrivate: true, DenyCIDRs: []string{"203.0.113.0/24"}})
+	require.NoError(t, err)
+
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "192.168.1.1", "100.64.0.1", "169.254.1.1", "::1", "fd00::1", "fe80::1", "::ffff:10.0.0.1", "0.0.0.0"} {
+		assert.Error(t, p.checkIP("x", net.ParseIP(ip)), ip)
+	}
+	assert.ErrorContains(t, p.checkIP("x", net.ParseIP("203.0.113.9")), "203.0.113.0/24")
+	assert.NoError(t, p.checkIP("x", net.ParseIP("1.1.1.1")))
+	assert.NoError(t, p.checkIP("x", net.ParseIP("2606:4700:4700::1111")))
+
+	p, err = newTargetPolicy(PolicyConfig{AllowCIDRs: []string{"1.1.1.0/24"}})
+	require.NoError(t, err)
+	assert.NoError(t, p.checkIP("x", net.ParseIP("1.1.1.1")))
+	assert.Error(t, p.checkIP("x", net.ParseIP("8.8.8.8")))
+
+	var disabled *targetPolicy
+	assert.NoError(t, disabled.checkIP("x", net.ParseIP("127.0.0.1")))
+}
+
+func TestTargetPolicyDomains(t *testing.T) {
+	p, err := newTargetPolicy(PolicyConfig{
+		DenyDomains:  []string{"localhost", "*.internal"},
+		AllowDomains: []string{"*.example.com", "example.com"},
+	})
+	require.NoError(t, err)
+
+	assert.Error(t, p.checkTarget("localhost"))
+	assert.Error(t, p.checkTarget("db.corp.internal."))
+	assert.Error(t, p.checkTarget("example.org"))
+	assert.NoError(t, p.checkTarget("WWW.Example.com"))
+	assert.NoError(t, p.checkTarget("example.com"))
+	assert.NoError(t, p.checkTarget("10.0.0.1"), "IP literals are checked after resolution")
+
+	_, err = newTargetPolicy(PolicyConfig{DenyDomains: []string{"[bad"}})
+	assert.Error(t, err)
+}
+
+func TestTargetPolicyProtocolsAndLimits(t *testing.T) {
+	p, err := newTargetPolicy(PolicyConfig{
+		Protocols:  []string{"icmp", "TCP"},
+		Ports:      []string{"443", "8000-8080"},
+		MaxHops:    20,
+		MaxQueries: 3,
+	})
+	require.NoError(t, err)
+
+	assert.NoError(t, p.checkProtocol("icmp", 0))
+	assert.NoError(t, p.checkProtocol("tcp", 443))
+	assert.NoError(t, p.checkProtocol("tcp", 8080))
+	assert.Error(t, p.checkProtocol("tcp", 22))
+	assert.Error(t, p.checkProtocol("udp", 443))
+	assert.Equal(t, []string{"icmp", "tcp"}, p.allowedProtocols())
+
+	assert.Error(t, p.checkLimits(traceRequest{MaxHops: 64}))
+	assert.Error(t, p.checkLimits(traceRequest{Queries: 10}))
+	assert.NoError(t, p.checkLimits(traceRequest{MaxHops: 20, Queries: 3, PacketSize: 9000}))
+
+	req := traceRequest{}
+	p.applyLimits(&req)
+	assert.Equal(t, 20, req.MaxHops)
+	assert.Equal(t, 0, req.Queries, "default queries already within limit")
+
+	p, err = newTargetPolicy(PolicyConfig{MaxRounds: 10, PowProviders: []string{"api.nxtrace.org"}, DotServers: []string{"Cloudflare"}})
+	require.NoError(t, err)
+	assert.Error(t, p.checkLimits(traceRequest{MaxRounds: 11}))
+	req = traceRequest{Mode: "mtr"}
+	p.applyLimits(&req)
+	assert.Equal(t, 10, req.MaxRounds, "continuous MTR is capped")
+	assert.NoError(t, p.checkUpstreams(traceRequest{PowProvider: "api.nxtrace.org", DotServer: "cloudflare"}))
+	assert.Error(t, p.checkUpstreams(traceRequest{PowProvider: "sakura"}))
+	assert.Error(t, p.checkUpstreams(traceRequest{DotServer: "google"}))
+
+	_, err = newTargetPolicy(PolicyConfig{PowProviders: []string{"pow.example.com"}})
+	assert.Error(t, err)
+	_, err = newTargetPolicy(PolicyConfig{Ports: []string{"70000"}})
+	assert.Error(t, err)
+	_, err = newTargetPolicy(PolicyConfig{Protocols: []string{"sctp"}})
+	assert.Error(t, err)
+}
+
+func TestPrepareTraceAppliesPolicy(t *testing.T) {
+	p, err := newTargetPolicy(PolicyConfig{DenyPrivate: true, Protocols: []string{"icmp"}})
+	require.NoError(t, err)
+	tracePolicy = p
+	defer func() { tracePolicy = nil }()
+
+	_, status, err := prepareTrace(traceRequest{Target: "127.0.0.1", DataProvider: "disable-geoip"})
+	assert.Equal(t, 403, status)
+	assert.ErrorContains(t, err, "non-public")
+
+	_, status, err = prepareTrace(traceRequest{Target: "1.1.1.1", Protocol: "tcp", DataProvider: "disable-geoip"})
+	assert.Equal(t, 403, status)
+	assert.ErrorContains(t, err, "protocol tcp")
+
+	_, status, err = prepareTrace(traceRequest{Target: "1.1.1.1", DotServer: "dns.attacker.example", DataProvider: "disable-geoip"})
+	assert.Equal(t, 400, status)
+	assert.ErrorContains(t, err, "dot_server")
+	_, status, err = prepareTrace(traceRequest{Target: "1.1.1.1", PowProvider: "pow.attacker.example", DataProvider: "disable-geoip"})
+	assert.Equal(t, 400, status)
+	assert.ErrorContains(t, err, "pow_provider")
+
+	setup, status, err := prepareTrace(traceRequest{Target: "1.1.1.1", DataProvider: "disable-geoip"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, status)
+	assert.Equal(t, "1.1.1.1", setup.IP.String())
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nxtrace/NTrace-core/metrics"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+const (
+	defaultProbeTimeout = 30 * time.Second
+	maxProbeTimeout     = 120 * time.Second
+	// probeTimeoutMargin 为抓取超时预留的余量，保证在 Prometheus 放弃之前返回结果
+	probeTimeoutMargin = 500 * time.Millisecond
+	maxProbeInflight   = 4
+)
+
+// probeSlots 限制同时进行（含排队等待 traceMu）的 /probe 请求数量
+var probeSlots = make(chan struct{}, maxProbeInflight)
+
+// probeHandler 实现类似 blackbox_exporter 的 /probe 接口：执行一次受限的追踪并以 Prometheus 文本格式返回结果
+func probeHandler(c *gin.Context) {
+	target := strings.TrimSpace(c.Query("target"))
+	if target == "" {
+		c.String(http.StatusBadRequest, "target parameter is missing")
+		return
+	}
+
+	req := traceRequest{
+		Target:          target,
+		Protocol:        c.Query("protocol"),
+		DataProvider:    c.Query("data_provider"),
+		Language:        c.Query("language"),
+		DisableMaptrace: true,
+		IPv4Only:        c.Query("ipv4_only") == "true",
+		IPv6Only:        c.Query("ipv6_only") == "true",
+	}
+	for name, dst := range map[string]*int{
+		"port":     &req.Port,
+		"queries":  &req.Queries,
+		"max_hops": &req.MaxHops,
+	} {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			c.String(http.StatusBadRequest, "invalid %s parameter", name)
+			return
+		}
+		*dst = v
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), probeTimeout(c))
+	defer cancel()
+
+	select {
+	case probeSlots <- struct{}{}:
+		defer func() { <-probeSlots }()
+	default:
+		c.String(http.StatusServiceUnavailable, "too many concurrent probes")
+		return
+	}
+
+	setup, statusCode, err := prepareTrace(req)
+	if err != nil {
+		if statusCode == 0 {
+			statusCode = http.StatusInternalServerError
+		}
+		log.Printf("[deploy] (probe) prepare trace failed target=%s error=%v", target, err)
+		c.String(statusCode, err.Error())
+		return
+	}
+
+	start := time.Now()
+	res, err := runProbeTrace(ctx, setup)
+	duration := time.Since(start)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+		log.Printf("[deploy] (probe) trace failed target=%s error=%v", setup.Target, err)
+		c.String(http.StatusInternalServerError, setup.Redactor.Text(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Printf("[deploy] (probe) trace aborted target=%s error=%v", setup.Target, err)
+	}
+
+	c.Header("Content-Type", metrics.ContentType)
+	c.Status(http.StatusOK)
+	_ = writeProbeMetrics(c.Writer, res, setup, duration)
+}
+
+// probeTimeout 取请求参数、Prometheus 抓取超时与上限中的最小值
+func probeTimeout(c *gin.Context) time.Duration {
+	timeout := defaultProbeTimeout
+	if raw := c.GetHeader("X-Prometheus-Scrape-Timeout-Seconds"); raw != "" {
+		if secs, err := strconv.ParseFloat(raw, 64); err == nil && secs > 0 {
+			timeout = time.Duration(secs*float64(time.Second)) - probeTimeoutMargin
+		}
+	}
+	if raw := c.Query("timeout"); raw != "" {
+		if secs, err := strconv.ParseFloat(raw, 64); err == nil && secs > 0 {
+			if d := time.Duration(secs * float64(time.Second)); d < timeout {
+				timeout = d
+			}
+		}
+	}
+	if timeout > maxProbeTimeout {
+		timeout = maxProbeTimeout
+	}
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	return timeout
+}
+
+func runProbeTrace(ctx context.Context, setup *traceExecution) (*trace.Result, error) {
+	if !lockTraceMu(ctx) {
+		return nil, ctx.Err()
+	}
+	defer traceMu.Unlock()
+
+	restore := applyTraceGlobals(setup, "[deploy] (probe)")
+	defer restore()
+
+	config := setup.Config
+	config.RealtimePrinter = nil
+	config.AsyncPrinter = nil
+	return trace.TracerouteContext(ctx, setup.Method, config)
+}
+
+// lockTraceMu 在 ctx 结束前尝试获取 traceMu，成功返回 true
+func lockTraceMu(ctx context.Context) bool {
+	for {
+		if traceMu.TryLock() {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func writeProbeMetrics(w io.Writer, res *trace.Result, setup *traceExecution, duration time.Duration) error {
+	reg := metrics.NewRegistry()
+	success := reg.NewGaugeVec("probe_success", "Whether the trace reached the destination.")
+	durationGauge := reg.NewGaugeVec("probe_duration_seconds", "How long the probe took to complete in seconds.")
+	hopCount := reg.NewGaugeVec("nexttrace_probe_hop_count", "Number of TTLs traversed by the trace.")
+	finalRTT := reg.NewGaugeVec("nexttrace_probe_final_rtt_ms", "Average RTT to the destination in milliseconds.")
+	hopRTT := reg.NewGaugeVec("nexttrace_probe_hop_rtt_ms", "Average RTT per hop in milliseconds.", "ttl", "ip", "asn")
+	hopLoss := reg.NewGaugeVec("nexttrace_probe_hop_loss_ratio", "Packet loss ratio per hop.", "ttl", "ip", "asn")
+
+	durationGauge.Set(duration.Seconds())
+
+	var stats []mtrHopJSON
+	if res != nil {
+		// 超时返回时地理位置与反向解析可能仍在写入结果，只读取加锁复制的快照
+		snap := &trace.Result{Hops: res.Snapshot()}
+		stats = newMTRAggregator().Update(setup.Redactor.Result(snap), setup.Config.NumMeasurements)
+		hopCount.Set(float64(len(snap.Hops)))
+	}
+	for _, row := range stats {
+		if row.IP == "" {
+			continue
+		}
+		asn := ""
+		if row.Geo != nil {
+			asn = row.Geo.Asnumber
+		}
+		ttl := strconv.Itoa(row.TTL)
+		hopLoss.Set(row.LossPercent/100, ttl, row.IP, asn)
+		if row.Received > 0 {
+			hopRTT.Set(row.Avg, ttl, row.IP, asn)
+		}
+	}
+
+	_, dst := setup.shown()
+	reached, _, avg := destinationStats(stats, dst)
+	if reached {
+		success.Set(1)
+		finalRTT.Set(avg)
+	} else {
+		success.Set(0)
+	}
+
+	return reg.WriteText(w)
+}
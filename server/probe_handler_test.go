@@ -0,0 +1,56 @@
+package server
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+func TestWriteProbeMetrics(t *testing.T) {
+	res := &trace.Result{Hops: [][]trace.Hop{
+		{
+			{Success: true, Address: &net.IPAddr{IP: net.ParseIP("10.0.0.1")}, TTL: 1, RTT: 2 * time.Millisecond},
+			{Success: false, TTL: 1},
+		},
+		{
+			{Success: true, Address: &net.IPAddr{IP: net.ParseIP("1.1.1.1")}, TTL: 2, RTT: 10 * time.Millisecond, Geo: &ipgeo.IPGeoData{Asnumber: "13335"}},
+			{Success: true, Address: &net.IPAddr{IP: net.ParseIP("1.1.1.1")}, TTL: 2, RTT: 20 * time.Millisecond, Geo: &ipgeo.IPGeoData{Asnumber: "13335"}},
+		},
+	}}
+	setup := &traceExecution{IP: net.ParseIP("1.1.1.1"), Config: trace.Config{NumMeasurements: 2}}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeProbeMetrics(&buf, res, setup, 1500*time.Millisecond))
+	out := buf.String()
+
+	assert.Contains(t, out, "probe_success 1\n")
+	assert.Contains(t, out, "probe_duration_seconds 1.5\n")
+	assert.Contains(t, out, "nexttrace_probe_hop_count 2\n")
+	assert.Contains(t, out, "nexttrace_probe_final_rtt_ms 15\n")
+	assert.Contains(t, out, `nexttrace_probe_hop_rtt_ms{ttl="2",ip="1.1.1.1",asn="13335"} 15`)
+	assert.Contains(t, out, `nexttrace_probe_hop_loss_ratio{ttl="1",ip="10.0.0.1",asn=""} 0`)
+}
+
+func TestProbeHandlerRequiresTarget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/probe", probeHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/probe?protocol=tcp", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/probe?target=1.1.1.1&port=abc", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
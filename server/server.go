@@ -2,6 +2,8 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"embed"
 	"errors"
 	"fmt"
@@ -14,6 +16,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/nxtrace/NTrace-core/metrics"
+	"github.com/nxtrace/NTrace-core/schema"
 )
 
 //go:embed web/*
@@ -38,27 +43,45 @@ func init() {
 }
 
 // Run starts the Gin HTTP server that exposes the traceroute UI and APIs.
-func Run(listenAddr string) error {
+// cfg may be nil, in which case the console is served over plain HTTP without authentication.
+func Run(listenAddr string, cfg *Config) error {
 	if listenAddr == "" {
 		listenAddr = defaultListenAddr
 	}
+	if cfg == nil {
+		cfg = &Config{}
+	}
 
-	gin.SetMode(gin.ReleaseMode)
-	router := gin.New()
-	router.Use(gin.Logger(), gin.Recovery())
+	policy, err := newTargetPolicy(cfg.Policy)
+	if err != nil {
+		return err
+	}
+	tracePolicy = policy
+	redactPolicy = cfg.Redact
 
-	router.GET("/", func(c *gin.Context) {
-		c.Data(http.StatusOK, "text/html; charset=utf-8", indexPage)
-	})
+	store, err := openHistory(cfg.History)
+	if err != nil {
+		return err
+	}
+	historyStore = store
 
-	router.StaticFS("/assets", http.FS(assetsFS))
+	jobs := newJobManager(cfg.Jobs)
+	defer jobs.Close()
 
-	router.GET("/api/options", optionsHandler)
-	router.POST("/api/trace", traceHandler)
-	router.POST("/api/cache/clear", cacheClearHandler)
-	router.GET("/ws/trace", traceWebsocketHandler)
+	gin.SetMode(gin.ReleaseMode)
+	router, err := newRouter(cfg, jobs)
+	if err != nil {
+		return err
+	}
 
 	srv := &http.Server{Addr: listenAddr, Handler: router}
+	if cfg.TLS.CertFile != "" {
+		tlsConfig, err := buildTLSConfig(cfg.TLS)
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = tlsConfig
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
@@ -70,7 +93,11 @@ func Run(listenAddr string) error {
 		_ = srv.Shutdown(shutdownCtx)
 	}()
 
-	err := srv.ListenAndServe()
+	if cfg.TLS.CertFile != "" {
+		err = srv.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		if strings.Contains(err.Error(), "address already in use") {
 			return fmt.Errorf("listen %s: %w", listenAddr, err)
@@ -80,3 +107,74 @@ func Run(listenAddr string) error {
 
 	return nil
 }
+
+func newRouter(cfg *Config, jobs *jobManager) (*gin.Engine, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	auth, err := newAuthenticator(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	router := gin.New()
+	// 仅采信可信代理转发的客户端地址，避免伪造 X-Forwarded-For 绕过白名单
+	if err := router.SetTrustedProxies(cfg.Auth.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted_proxies: %w", err)
+	}
+	router.Use(gin.LoggerWithFormatter(accessLogFormatter), gin.Recovery(), auth.middleware())
+
+	view := requirePermission(permView)
+	run := requirePermission(permTrace)
+
+	router.GET("/", view, func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", indexPage)
+	})
+
+	assets := router.Group("/assets", view)
+	assets.StaticFS("/", http.FS(assetsFS))
+
+	router.GET("/api/options", view, optionsHandler)
+	router.GET("/api/schema", view, func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/schema+json", schema.JSONSchema)
+	})
+	router.POST("/api/trace", run, traceHandler)
+	router.POST("/api/cache/clear", requirePermission(permClearCache), cacheClearHandler)
+	router.GET("/ws/trace", run, traceWebsocketHandler)
+	router.GET("/metrics", view, gin.WrapH(metrics.Handler()))
+	router.GET("/probe", run, probeHandler)
+
+	router.GET("/api/history", view, historyHandler)
+	router.GET("/api/history/:id", view, historyRecordHandler)
+
+	router.POST("/api/jobs", run, jobs.createHandler)
+	router.GET("/api/jobs/:id", run, jobs.statusHandler)
+	router.GET("/api/jobs/:id/result", run, jobs.resultHandler)
+	router.GET("/api/jobs/:id/result.csv", run, jobs.resultCSVHandler)
+	router.GET("/api/jobs/:id/map.svg", run, jobs.mapSVGHandler)
+	router.DELETE("/api/jobs/:id", run, jobs.cancelHandler)
+
+	router.POST("/api/diff", view, jobs.diffHandler)
+	return router, nil
+}
+
+func buildTLSConfig(cfg TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read client_ca_file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("client_ca_file %s contains no certificates", cfg.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	if cfg.RequireClientCert {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
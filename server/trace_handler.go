@@ -14,8 +14,14 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/nxtrace/NTrace-core/anomaly"
 	"github.com/nxtrace/NTrace-core/config"
+	"github.com/nxtrace/NTrace-core/history"
+	"github.com/nxtrace/NTrace-core/i18n"
 	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/redact"
+	"github.com/nxtrace/NTrace-core/reporter"
+	"github.com/nxtrace/NTrace-core/schema"
 	"github.com/nxtrace/NTrace-core/trace"
 	"github.com/nxtrace/NTrace-core/tracemap"
 	"github.com/nxtrace/NTrace-core/util"
@@ -35,6 +41,19 @@ type traceExecution struct {
 	Config       trace.Config
 	NeedsLeoWS   bool
 	PowProvider  string
+	// Redactor 为本次追踪的脱敏器，nil 表示不脱敏
+	Redactor *redact.Redactor
+}
+
+// shown 返回响应中展示的目标与解析地址
+func (e *traceExecution) shown() (target, ip string) {
+	meta := e.Redactor.Metadata(schema.Metadata{Target: e.Target, DstIP: e.IP.String(), SrcIP: e.Config.SrcAddr})
+	return meta.Target, meta.DstIP
+}
+
+// mapPayload 返回上传到 tracemap 的脱敏结果
+func (e *traceExecution) mapPayload(res *trace.Result) ([]byte, error) {
+	return json.Marshal(e.Redactor.Result(res))
 }
 
 type traceRequest struct {
@@ -74,30 +93,44 @@ type traceRequest struct {
 	MaxRounds         int    `json:"max_rounds"`
 }
 
-type hopAttempt struct {
-	Success  bool             `json:"success"`
-	IP       string           `json:"ip,omitempty"`
-	Hostname string           `json:"hostname,omitempty"`
-	RTT      float64          `json:"rtt_ms,omitempty"`
-	Error    string           `json:"error,omitempty"`
-	MPLS     []string         `json:"mpls,omitempty"`
-	Geo      *ipgeo.IPGeoData `json:"geo,omitempty"`
-}
-
-type hopResponse struct {
-	TTL      int          `json:"ttl"`
-	Attempts []hopAttempt `json:"attempts"`
+// traceResponse 为单次追踪的响应：内嵌版本化的 schema.Document，
+// 顶层的 target / resolved_ip 等字段与 metadata 重复，保留给现有的控制台与 API 客户端
+type traceResponse struct {
+	schema.Document
+	Target       string `json:"target"`
+	ResolvedIP   string `json:"resolved_ip"`
+	Protocol     string `json:"protocol"`
+	DataProvider string `json:"data_provider"`
+	Language     string `json:"language"`
+	DurationMs   int64  `json:"duration_ms"`
 }
 
-type traceResponse struct {
-	Target       string        `json:"target"`
-	ResolvedIP   string        `json:"resolved_ip"`
-	Protocol     string        `json:"protocol"`
-	DataProvider string        `json:"data_provider"`
-	TraceMapURL  string        `json:"trace_map_url,omitempty"`
-	Language     string        `json:"language"`
-	Hops         []hopResponse `json:"hops"`
-	DurationMs   int64         `json:"duration_ms"`
+func newTraceResponse(setup *traceExecution, conf trace.Config, res *trace.Result, traceMapURL string, started, finished time.Time) traceResponse {
+	meta := schema.NewMetadata(setup.Target, setup.Method, setup.DataProvider, conf, started, finished)
+	doc := setup.Redactor.Document(schema.NewDocument(res, meta))
+	doc.TraceMapURL = traceMapURL
+	redacted := setup.Redactor.Result(res)
+	if setup.Redactor != nil {
+		// 与逐跳推送的注释一致，在脱敏后的结果上重新分析
+		annotations := anomaly.Analyze(redacted.Hops, anomaly.Options{})
+		for i, hop := range doc.Hops {
+			if hop.TTL <= len(annotations) {
+				doc.Hops[i].Annotations = annotations[hop.TTL-1]
+			}
+		}
+	}
+	target, ip := setup.shown()
+	path := reporter.Analyze(redacted, ip)
+	doc.RoutePath = &path
+	return traceResponse{
+		Document:     doc,
+		Target:       target,
+		ResolvedIP:   ip,
+		Protocol:     setup.Protocol,
+		DataProvider: setup.DataProvider,
+		Language:     conf.Lang,
+		DurationMs:   meta.DurationMs,
+	}
 }
 
 func prepareTrace(req traceRequest) (*traceExecution, int, error) {
@@ -117,6 +150,25 @@ func prepareTrace(req traceRequest) (*traceExecution, int, error) {
 	}
 	exec.Target = target
 
+	policy := tracePolicy
+	if err := policy.checkTarget(target); err != nil {
+		return nil, 403, err
+	}
+	if err := policy.checkLimits(exec.Req); err != nil {
+		return nil, 403, err
+	}
+	// 只接受已知的上游，避免服务端连接调用方给出的任意主机
+	if pow := strings.TrimSpace(exec.Req.PowProvider); pow != "" && !contains(powProviders, pow) {
+		return nil, 400, fmt.Errorf("unknown pow_provider %q", pow)
+	}
+	if dot := strings.ToLower(strings.TrimSpace(exec.Req.DotServer)); dot != "" && !contains(dotServers, dot) {
+		return nil, 400, fmt.Errorf("unknown dot_server %q", dot)
+	}
+	if err := policy.checkUpstreams(exec.Req); err != nil {
+		return nil, 403, err
+	}
+	policy.applyLimits(&exec.Req)
+
 	if exec.Req.IPv4Only && exec.Req.IPv6Only {
 		return nil, 400, errors.New("ipv4_only and ipv6_only cannot be true at the same time")
 	}
@@ -169,6 +221,9 @@ func prepareTrace(req traceRequest) (*traceExecution, int, error) {
 	if err != nil {
 		return nil, 500, err
 	}
+	if err := policy.checkIP(target, ip); err != nil {
+		return nil, 403, err
+	}
 	exec.IP = ip
 
 	method := trace.ICMPTrace
@@ -192,10 +247,15 @@ func prepareTrace(req traceRequest) (*traceExecution, int, error) {
 		}
 	}
 
+	if err := policy.checkProtocol(protocol, dstPort); err != nil {
+		return nil, 403, err
+	}
+
 	exec.DataProvider = dataProvider
 	exec.PowProvider = strings.TrimSpace(exec.Req.PowProvider)
 	exec.NeedsLeoWS = needsLeoWS
 	exec.Config = buildTraceConfig(exec.Req, ip, dataProvider, dstPort)
+	exec.Redactor = redactPolicy.For(schema.Metadata{DstIP: ip.String(), SrcIP: exec.Config.SrcAddr})
 
 	return exec, 0, nil
 }
@@ -203,7 +263,11 @@ func prepareTrace(req traceRequest) (*traceExecution, int, error) {
 func traceHandler(c *gin.Context) {
 	var req traceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": "invalid request payload", "details": err.Error()})
+		c.JSON(400, gin.H{"error": localize(c, i18n.InvalidPayload), "details": err.Error()})
+		return
+	}
+	if err := checkSourcePermission(c, req); err != nil {
+		c.JSON(403, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -223,36 +287,62 @@ func traceHandler(c *gin.Context) {
 	traceMu.Lock()
 	defer traceMu.Unlock()
 
+	restore := applyTraceGlobals(setup, "[deploy]")
+	defer restore()
+
+	configured := setup.Config
+	log.Printf("[deploy] starting trace target=%s resolved=%s method=%s lang=%s queries=%d maxHops=%d", setup.Target, setup.IP.String(), string(setup.Method), configured.Lang, configured.NumMeasurements, configured.MaxHops)
+
+	start := time.Now()
+	res, err := trace.Traceroute(setup.Method, configured)
+	duration := time.Since(start)
+	if err != nil {
+		log.Printf("[deploy] trace failed target=%s error=%v", setup.Target, err)
+		c.JSON(500, gin.H{"error": setup.Redactor.Text(err.Error())})
+		return
+	}
+
+	traceMapURL := ""
+	if configured.Maptrace && shouldGenerateMap(setup.DataProvider) {
+		if payload, err := setup.mapPayload(res); err == nil {
+			if mapUrl, err := tracemap.GetMapUrl(string(payload)); err == nil {
+				traceMapURL = mapUrl
+				log.Printf("[deploy] trace map generated target=%s mapUrl=%s", setup.Target, traceMapURL)
+			}
+		}
+	}
+
+	response := newTraceResponse(setup, configured, res, traceMapURL, start, start.Add(duration))
+
+	log.Printf("[deploy] trace completed target=%s hops=%d duration=%s", setup.Target, len(response.Hops), duration)
+	recordHistory(history.KindTrace, "api", principalName(c), setup, response, len(response.Hops))
+	c.JSON(200, response)
+}
+
+// applyTraceGlobals 将本次追踪所需的参数写入 util 包级变量，并返回恢复原值的函数。
+// 调用方必须持有 traceMu。
+func applyTraceGlobals(setup *traceExecution, logPrefix string) func() {
 	prevSrcPort := util.SrcPort
-	prevDstIP := util.DstIP
 	prevSrcDev := util.SrcDev
 	prevDisableMPLS := util.DisableMPLS
 	prevPowProvider := util.PowProviderParam
-	defer func() {
-		util.SrcPort = prevSrcPort
-		util.DstIP = prevDstIP
-		util.SrcDev = prevSrcDev
-		util.DisableMPLS = prevDisableMPLS
-		util.PowProviderParam = prevPowProvider
-	}()
 
 	if setup.NeedsLeoWS {
 		if setup.PowProvider != "" {
-			log.Printf("[deploy] LeoMoeAPI using custom PoW provider=%s", setup.PowProvider)
+			log.Printf("%s LeoMoeAPI using custom PoW provider=%s", logPrefix, setup.PowProvider)
 		} else {
-			log.Printf("[deploy] LeoMoeAPI using default PoW provider")
+			log.Printf("%s LeoMoeAPI using default PoW provider", logPrefix)
 		}
 		util.PowProviderParam = setup.PowProvider
 		ensureLeoMoeConnection()
 	} else if setup.PowProvider != "" {
-		log.Printf("[deploy] overriding PoW provider=%s", setup.PowProvider)
+		log.Printf("%s overriding PoW provider=%s", logPrefix, setup.PowProvider)
 		util.PowProviderParam = setup.PowProvider
 	} else {
 		util.PowProviderParam = ""
 	}
 
 	util.SrcPort = setup.Req.SourcePort
-	util.DstIP = setup.IP.String()
 	if setup.Req.SourceDevice != "" {
 		util.SrcDev = setup.Req.SourceDevice
 	} else {
@@ -260,41 +350,12 @@ func traceHandler(c *gin.Context) {
 	}
 	util.DisableMPLS = setup.Req.DisableMPLS
 
-	configured := setup.Config
-	log.Printf("[deploy] starting trace target=%s resolved=%s method=%s lang=%s queries=%d maxHops=%d", setup.Target, setup.IP.String(), string(setup.Method), configured.Lang, configured.NumMeasurements, configured.MaxHops)
-
-	start := time.Now()
-	res, err := trace.Traceroute(setup.Method, configured)
-	duration := time.Since(start)
-	if err != nil {
-		log.Printf("[deploy] trace failed target=%s error=%v", setup.Target, err)
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
-	}
-
-	traceMapURL := ""
-	if configured.Maptrace && shouldGenerateMap(setup.DataProvider) {
-		if payload, err := json.Marshal(res); err == nil {
-			if mapUrl, err := tracemap.GetMapUrl(string(payload)); err == nil {
-				traceMapURL = mapUrl
-				log.Printf("[deploy] trace map generated target=%s mapUrl=%s", setup.Target, traceMapURL)
-			}
-		}
-	}
-
-	response := traceResponse{
-		Target:       setup.Target,
-		ResolvedIP:   setup.IP.String(),
-		Protocol:     setup.Protocol,
-		DataProvider: setup.DataProvider,
-		TraceMapURL:  traceMapURL,
-		Language:     configured.Lang,
-		Hops:         convertHops(res, configured.Lang),
-		DurationMs:   duration.Milliseconds(),
+	return func() {
+		util.SrcPort = prevSrcPort
+		util.SrcDev = prevSrcDev
+		util.DisableMPLS = prevDisableMPLS
+		util.PowProviderParam = prevPowProvider
 	}
-
-	log.Printf("[deploy] trace completed target=%s hops=%d duration=%s", setup.Target, len(response.Hops), duration)
-	c.JSON(200, response)
 }
 
 func buildTraceConfig(req traceRequest, ip net.IP, dataProvider string, port int) trace.Config {
@@ -375,53 +436,6 @@ func buildTraceConfig(req traceRequest, ip net.IP, dataProvider string, port int
 	}
 }
 
-func convertHops(res *trace.Result, lang string) []hopResponse {
-	if res == nil || len(res.Hops) == 0 {
-		return nil
-	}
-
-	hops := make([]hopResponse, 0, len(res.Hops))
-	for idx, attempts := range res.Hops {
-		resp := buildHopResponse(attempts, idx, lang)
-		if len(resp.Attempts) == 0 {
-			continue
-		}
-		hops = append(hops, resp)
-	}
-	return hops
-}
-
-func buildHopResponse(attempts []trace.Hop, idx int, lang string) hopResponse {
-	resp := hopResponse{
-		TTL:      idx + 1,
-		Attempts: make([]hopAttempt, 0, len(attempts)),
-	}
-
-	for _, attempt := range attempts {
-		ha := hopAttempt{
-			Success: attempt.Success,
-			MPLS:    attempt.MPLS,
-		}
-		if attempt.Address != nil {
-			ha.IP = attempt.Address.String()
-		}
-		if attempt.Hostname != "" {
-			ha.Hostname = attempt.Hostname
-		}
-		if attempt.RTT > 0 {
-			ha.RTT = float64(attempt.RTT) / float64(time.Millisecond)
-		}
-		if attempt.Error != nil {
-			ha.Error = attempt.Error.Error()
-		}
-		if attempt.Geo != nil {
-			ha.Geo = localizeGeo(attempt.Geo, lang)
-		}
-		resp.Attempts = append(resp.Attempts, ha)
-	}
-	return resp
-}
-
 func normalizeTarget(input string) (string, error) {
 	target := strings.TrimSpace(input)
 	if target == "" {
@@ -554,34 +568,3 @@ func ensureLeoMoeConnection() {
 		wshandle.New()
 	}
 }
-
-func localizeGeo(src *ipgeo.IPGeoData, lang string) *ipgeo.IPGeoData {
-	if src == nil {
-		return nil
-	}
-
-	dst := *src
-	switch strings.ToLower(lang) {
-	case "en":
-		if dst.CountryEn != "" {
-			dst.Country = dst.CountryEn
-		}
-		if dst.ProvEn != "" {
-			dst.Prov = dst.ProvEn
-		}
-		if dst.CityEn != "" {
-			dst.City = dst.CityEn
-		}
-	default:
-		if dst.Country == "" && dst.CountryEn != "" {
-			dst.Country = dst.CountryEn
-		}
-		if dst.Prov == "" && dst.ProvEn != "" {
-			dst.Prov = dst.ProvEn
-		}
-		if dst.City == "" && dst.CityEn != "" {
-			dst.City = dst.CityEn
-		}
-	}
-	return &dst
-}
@@ -12,9 +12,12 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 
+	"github.com/nxtrace/NTrace-core/anomaly"
+	"github.com/nxtrace/NTrace-core/history"
+	"github.com/nxtrace/NTrace-core/i18n"
+	"github.com/nxtrace/NTrace-core/schema"
 	"github.com/nxtrace/NTrace-core/trace"
 	"github.com/nxtrace/NTrace-core/tracemap"
-	"github.com/nxtrace/NTrace-core/util"
 )
 
 var traceUpgrader = websocket.Upgrader{
@@ -38,6 +41,8 @@ type wsTraceSession struct {
 	closed  atomic.Bool
 	lang    string
 	seen    map[int]int
+	// owner 为发起追踪的用户名，写入历史记录
+	owner string
 }
 
 func (s *wsTraceSession) send(msg wsEnvelope) error {
@@ -73,7 +78,11 @@ func traceWebsocketHandler(c *gin.Context) {
 
 	var req traceRequest
 	if err := json.Unmarshal(message, &req); err != nil {
-		_ = conn.WriteJSON(wsEnvelope{Type: "error", Error: "invalid request payload", Status: 400})
+		_ = conn.WriteJSON(wsEnvelope{Type: "error", Error: localize(c, i18n.InvalidPayload), Status: 400})
+		return
+	}
+	if err := checkSourcePermission(c, req); err != nil {
+		_ = conn.WriteJSON(wsEnvelope{Type: "error", Error: err.Error(), Status: 403})
 		return
 	}
 
@@ -88,9 +97,10 @@ func traceWebsocketHandler(c *gin.Context) {
 	}
 
 	session := &wsTraceSession{
-		conn: conn,
-		lang: setup.Config.Lang,
-		seen: make(map[int]int),
+		conn:  conn,
+		lang:  setup.Config.Lang,
+		seen:  make(map[int]int),
+		owner: principalName(c),
 	}
 
 	startPayload := gin.H{
@@ -132,10 +142,13 @@ func traceWebsocketHandler(c *gin.Context) {
 func runSingleTrace(session *wsTraceSession, setup *traceExecution) {
 	session.seen = make(map[int]int)
 
+	started := time.Now()
 	res, duration, err := executeTrace(session, setup, func(cfg *trace.Config) {
 		cfg.RealtimePrinter = nil
 		cfg.AsyncPrinter = func(result *trace.Result) {
-			for idx, attempts := range result.Hops {
+			// 与打印器一致，在脱敏后的结果上分析，注释不会泄露被替换跳的位置
+			hops := setup.Redactor.Result(result).Hops
+			for idx, attempts := range hops {
 				if len(attempts) == 0 {
 					continue
 				}
@@ -149,10 +162,11 @@ func runSingleTrace(session *wsTraceSession, setup *traceExecution) {
 				}
 				session.seen[idx] = newLen
 
-				hop := buildHopResponse(snapshot, idx, session.lang)
+				hop := schema.NewHop(idx+1, snapshot, session.lang)
 				if len(hop.Attempts) == 0 {
 					continue
 				}
+				hop.Annotations = anomaly.At(hops, idx, anomaly.Options{})
 				if err := session.send(wsEnvelope{Type: "hop", Data: hop}); err != nil {
 					log.Printf("[deploy] websocket hop send failed ttl=%d err=%v", hop.TTL, err)
 					return
@@ -163,7 +177,7 @@ func runSingleTrace(session *wsTraceSession, setup *traceExecution) {
 
 	if err != nil {
 		log.Printf("[deploy] websocket trace failed target=%s error=%v", setup.Target, err)
-		_ = session.send(wsEnvelope{Type: "error", Error: err.Error(), Status: 500})
+		_ = session.send(wsEnvelope{Type: "error", Error: setup.Redactor.Text(err.Error()), Status: 500})
 		return
 	}
 
@@ -173,7 +187,7 @@ func runSingleTrace(session *wsTraceSession, setup *traceExecution) {
 
 	traceMapURL := ""
 	if setup.Config.Maptrace && shouldGenerateMap(setup.DataProvider) {
-		if payload, err := json.Marshal(res); err == nil {
+		if payload, err := setup.mapPayload(res); err == nil {
 			if url, err := tracemap.GetMapUrl(string(payload)); err == nil {
 				traceMapURL = url
 				log.Printf("[deploy] (ws) trace map generated target=%s url=%s", setup.Target, traceMapURL)
@@ -181,17 +195,9 @@ func runSingleTrace(session *wsTraceSession, setup *traceExecution) {
 		}
 	}
 
-	final := traceResponse{
-		Target:       setup.Target,
-		ResolvedIP:   setup.IP.String(),
-		Protocol:     setup.Protocol,
-		DataProvider: setup.DataProvider,
-		TraceMapURL:  traceMapURL,
-		Language:     setup.Config.Lang,
-		Hops:         convertHops(res, setup.Config.Lang),
-		DurationMs:   duration.Milliseconds(),
-	}
+	final := newTraceResponse(setup, setup.Config, res, traceMapURL, started, started.Add(duration))
 
+	recordHistory(history.KindTrace, "ws", session.owner, setup, final, len(final.Hops))
 	if err := session.send(wsEnvelope{Type: "complete", Data: final}); err != nil {
 		log.Printf("[deploy] websocket send complete failed: %v", err)
 	}
@@ -224,12 +230,12 @@ func runMTRTrace(session *wsTraceSession, setup *traceExecution) {
 
 		if err != nil {
 			log.Printf("[deploy] websocket MTR trace failed target=%s error=%v", setup.Target, err)
-			_ = session.send(wsEnvelope{Type: "error", Error: err.Error(), Status: 500})
+			_ = session.send(wsEnvelope{Type: "error", Error: setup.Redactor.Text(err.Error()), Status: 500})
 			break
 		}
 
 		iteration++
-		stats := aggregator.Update(res, queries)
+		stats := aggregator.Update(setup.Redactor.Result(res), queries)
 		snapshot := mtrSnapshot{Iteration: iteration, Stats: stats}
 		if err := session.send(wsEnvelope{Type: "mtr", Data: snapshot}); err != nil {
 			session.closed.Store(true)
@@ -248,8 +254,12 @@ func runMTRTrace(session *wsTraceSession, setup *traceExecution) {
 	}
 
 	finalStats := aggregator.Snapshot()
+	finalSnapshot := mtrSnapshot{Iteration: iteration, Stats: finalStats}
+	if iteration > 0 {
+		recordHistory(history.KindMTR, "ws", session.owner, setup, finalSnapshot, len(finalStats))
+	}
 	if !session.closed.Load() {
-		_ = session.send(wsEnvelope{Type: "complete", Data: mtrSnapshot{Iteration: iteration, Stats: finalStats}})
+		_ = session.send(wsEnvelope{Type: "complete", Data: finalSnapshot})
 	}
 }
 
@@ -257,42 +267,8 @@ func executeTrace(session *wsTraceSession, setup *traceExecution, configure func
 	traceMu.Lock()
 	defer traceMu.Unlock()
 
-	prevSrcPort := util.SrcPort
-	prevDstIP := util.DstIP
-	prevSrcDev := util.SrcDev
-	prevDisableMPLS := util.DisableMPLS
-	prevPowProvider := util.PowProviderParam
-	defer func() {
-		util.SrcPort = prevSrcPort
-		util.DstIP = prevDstIP
-		util.SrcDev = prevSrcDev
-		util.DisableMPLS = prevDisableMPLS
-		util.PowProviderParam = prevPowProvider
-	}()
-
-	if setup.NeedsLeoWS {
-		if setup.PowProvider != "" {
-			log.Printf("[deploy] (ws) LeoMoeAPI using custom PoW provider=%s", setup.PowProvider)
-		} else {
-			log.Printf("[deploy] (ws) LeoMoeAPI using default PoW provider")
-		}
-		util.PowProviderParam = setup.PowProvider
-		ensureLeoMoeConnection()
-	} else if setup.PowProvider != "" {
-		log.Printf("[deploy] (ws) overriding PoW provider=%s", setup.PowProvider)
-		util.PowProviderParam = setup.PowProvider
-	} else {
-		util.PowProviderParam = ""
-	}
-
-	util.SrcPort = setup.Req.SourcePort
-	util.DstIP = setup.IP.String()
-	if setup.Req.SourceDevice != "" {
-		util.SrcDev = setup.Req.SourceDevice
-	} else {
-		util.SrcDev = ""
-	}
-	util.DisableMPLS = setup.Req.DisableMPLS
+	restore := applyTraceGlobals(setup, "[deploy] (ws)")
+	defer restore()
 
 	config := setup.Config
 	if configure != nil {
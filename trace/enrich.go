@@ -0,0 +1,127 @@
+package trace
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/util"
+)
+
+// EnrichHop replaces the geo data of h by querying each source in order; later sources only fill
+// fields left empty by earlier ones. With c.RDNS set the hostname is looked up again as well;
+// the existing hostname is kept when the lookup finds none.
+func EnrichHop(h *Hop, c Config, sources ...ipgeo.Source) error {
+	if util.AddrIP(h.Address) == nil {
+		return nil
+	}
+
+	var (
+		merged *ipgeo.IPGeoData
+		errs   []error
+	)
+	for i, src := range sources {
+		lookup := *h
+		lookup.Geo = nil
+		cfg := c
+		cfg.IPGeoSource = src
+		// 主机名只需在第一个数据源查询时解析一次；清空后 fetchIPData 才会重新解析
+		cfg.RDNS = c.RDNS && i == 0
+		if cfg.RDNS {
+			lookup.Hostname = ""
+		}
+		err := lookup.fetchIPData(cfg)
+		if cfg.RDNS && lookup.Hostname != "" {
+			h.Hostname = lookup.Hostname
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		merged = mergeGeo(merged, lookup.Geo)
+	}
+	h.Lang = c.Lang
+	if merged != nil {
+		// 只要有数据源成功即视为成功
+		h.Geo = merged
+		return nil
+	}
+	if len(sources) > 0 {
+		h.Geo = timeoutGeo()
+	}
+	return errors.Join(errs...)
+}
+
+// EnrichResult applies EnrichHop to every responding hop of res, running up to
+// c.ParallelRequests lookups at a time; no probes are sent.
+func EnrichResult(res *Result, c Config, sources ...ipgeo.Source) error {
+	workers := c.ParallelRequests
+	if workers <= 0 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for i := range res.Hops {
+		for j := range res.Hops[i] {
+			h := &res.Hops[i][j]
+			if h.Address == nil {
+				continue
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if err := EnrichHop(h, c, sources...); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// mergeGeo 以 base 为准，用 extra 补齐其中的空字段
+func mergeGeo(base, extra *ipgeo.IPGeoData) *ipgeo.IPGeoData {
+	if extra == nil || extra.Source == timeoutGeoSource {
+		return base
+	}
+	if base == nil {
+		g := *extra
+		return &g
+	}
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	fill(&base.IP, extra.IP)
+	fill(&base.Asnumber, extra.Asnumber)
+	fill(&base.Country, extra.Country)
+	fill(&base.CountryEn, extra.CountryEn)
+	fill(&base.Prov, extra.Prov)
+	fill(&base.ProvEn, extra.ProvEn)
+	fill(&base.City, extra.City)
+	fill(&base.CityEn, extra.CityEn)
+	fill(&base.District, extra.District)
+	fill(&base.Owner, extra.Owner)
+	fill(&base.Isp, extra.Isp)
+	fill(&base.Domain, extra.Domain)
+	fill(&base.Whois, extra.Whois)
+	fill(&base.Prefix, extra.Prefix)
+	if base.Lat == 0 && base.Lng == 0 {
+		base.Lat, base.Lng = extra.Lat, extra.Lng
+	}
+	if base.Router == nil {
+		base.Router = extra.Router
+	}
+	return base
+}
@@ -26,11 +26,6 @@ type GlobalpingOptions struct {
 
 	DisableMaptrace bool
 	DataOrigin      string
-
-	TablePrint   bool
-	ClassicPrint bool
-	RawPrint     bool
-	JSONPrint    bool
 }
 
 func GlobalpingTraceroute(opts *GlobalpingOptions, config *Config) (*Result, *globalping.Measurement, error) {
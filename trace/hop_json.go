@@ -0,0 +1,168 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/util"
+)
+
+// hopAddressJSON 沿用 net.IPAddr 默认编码的字段名，保持与 tracemap 等下游的兼容
+type hopAddressJSON struct {
+	IP   string
+	Zone string
+}
+
+type hopJSON struct {
+	Success  bool
+	Address  *hopAddressJSON
+	Hostname string
+	TTL      int
+	RTT      time.Duration
+	Error    json.RawMessage
+	Geo      *ipgeo.IPGeoData
+	Lang     string
+	MPLS     []string
+}
+
+// Label is the net.Addr of an address replaced by a label, such as a hop redacted with
+// package redact; it prints as the label.
+type Label string
+
+// Labels of hops whose address and location are withheld entirely.
+const (
+	LabelHidden Label = "hidden"
+	LabelSelf   Label = "self"
+)
+
+// Network implements net.Addr.
+func (l Label) Network() string { return "ip" }
+
+func (l Label) String() string { return string(l) }
+
+// Masked reports whether l stands for a hidden hop or one of our own addresses, which have no
+// usable location.
+func (l Label) Masked() bool { return l == LabelHidden || l == LabelSelf }
+
+// MarshalJSON encodes l like a *net.IPAddr, so that a result holding labels keeps the shape
+// expected by the tracemap service.
+func (l Label) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hopAddressJSON{IP: string(l)})
+}
+
+var _ net.Addr = Label("")
+
+// knownHopErrors 让反序列化后的错误仍可用 errors.Is 判断
+var knownHopErrors = []error{errHopLimitTimeout}
+
+// MarshalJSON encodes the hop with Address as {"IP","Zone"} and Error as its message.
+func (h Hop) MarshalJSON() ([]byte, error) {
+	out := hopJSON{
+		Success:  h.Success,
+		Hostname: h.Hostname,
+		TTL:      h.TTL,
+		RTT:      h.RTT,
+		Geo:      h.Geo,
+		Lang:     h.Lang,
+		MPLS:     h.MPLS,
+	}
+	if h.Address != nil {
+		out.Address = &hopAddressJSON{}
+		if ipAddr, ok := h.Address.(*net.IPAddr); ok {
+			out.Address.IP, out.Address.Zone = ipAddr.IP.String(), ipAddr.Zone
+		} else if ip := util.AddrIP(h.Address); ip != nil {
+			out.Address.IP = ip.String()
+		} else {
+			out.Address.IP = h.Address.String()
+		}
+	}
+	if h.Error != nil {
+		msg, err := json.Marshal(h.Error.Error())
+		if err != nil {
+			return nil, err
+		}
+		out.Error = msg
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a hop written by MarshalJSON or by older releases. An address that is
+// not an IP, e.g. one replaced while redacting, is decoded as a Label.
+func (h *Hop) UnmarshalJSON(data []byte) error {
+	var in hopJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*h = Hop{
+		Success:  in.Success,
+		Hostname: in.Hostname,
+		TTL:      in.TTL,
+		RTT:      in.RTT,
+		Geo:      in.Geo,
+		Lang:     in.Lang,
+		MPLS:     in.MPLS,
+	}
+	if in.Address != nil && in.Address.IP != "" {
+		if ip := net.ParseIP(in.Address.IP); ip != nil {
+			h.Address = &net.IPAddr{IP: ip, Zone: in.Address.Zone}
+		} else {
+			h.Address = Label(in.Address.IP)
+		}
+	}
+	h.Error = decodeHopError(in.Error)
+	return nil
+}
+
+func decodeHopError(raw json.RawMessage) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+	var msg string
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		// 旧版本将 error 接口编码为 {}，其唯一来源是跳超时
+		return errHopLimitTimeout
+	}
+	return HopError(msg)
+}
+
+// LoadResult reads a Result saved with `nexttrace --json`.
+func LoadResult(path string) (*Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var res Result
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(res.Hops) == 0 {
+		return nil, fmt.Errorf("%s: no hops in result", path)
+	}
+	return &res, nil
+}
+
+// IsHopTimeout reports whether err marks a probe that got no reply within the hop timeout.
+func IsHopTimeout(err error) bool {
+	return errors.Is(err, errHopLimitTimeout)
+}
+
+// HopError converts a hop error message back into an error, returning the package's
+// sentinel errors for known messages so that IsHopTimeout keeps working.
+func HopError(msg string) error {
+	if msg == "" {
+		return nil
+	}
+	for _, known := range knownHopErrors {
+		if known.Error() == msg {
+			return known
+		}
+	}
+	return errors.New(msg)
+}
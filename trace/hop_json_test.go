@@ -0,0 +1,69 @@
+package trace
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/ipgeo"
+)
+
+func TestHopJSONRoundTrip(t *testing.T) {
+	res := &Result{
+		Hops: [][]Hop{
+			{{TTL: 1, Error: errHopLimitTimeout}},
+			{
+				{Success: true, TTL: 2, Address: &net.IPAddr{IP: net.ParseIP("fe80::1"), Zone: "eth0"}, Hostname: "gw", RTT: 1500 * time.Microsecond, MPLS: []string{"Lbl 16"}},
+				{Success: true, TTL: 2, Address: &net.TCPAddr{IP: net.ParseIP("1.1.1.1"), Port: 443}, RTT: 3 * time.Millisecond, Geo: &ipgeo.IPGeoData{Asnumber: "13335", Country: "US"}, Lang: "en"},
+			},
+		},
+		TraceMapUrl: "https://example.com/map",
+	}
+
+	data, err := json.Marshal(res)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Address":{"IP":"fe80::1","Zone":"eth0"}`)
+	assert.Contains(t, string(data), `"Error":"hop timeout"`)
+
+	path := filepath.Join(t.TempDir(), "result.json")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	loaded, err := LoadResult(path)
+	require.NoError(t, err)
+
+	require.Len(t, loaded.Hops, 2)
+	assert.True(t, errors.Is(loaded.Hops[0][0].Error, errHopLimitTimeout))
+	assert.Nil(t, loaded.Hops[0][0].Address)
+	assert.Equal(t, &net.IPAddr{IP: net.ParseIP("fe80::1"), Zone: "eth0"}, loaded.Hops[1][0].Address)
+	assert.Equal(t, "1.1.1.1", loaded.Hops[1][1].Address.String())
+	assert.Equal(t, 3*time.Millisecond, loaded.Hops[1][1].RTT)
+	assert.Equal(t, "13335", loaded.Hops[1][1].Geo.Asnumber)
+	assert.Equal(t, []string{"Lbl 16"}, loaded.Hops[1][0].MPLS)
+	assert.Equal(t, res.TraceMapUrl, loaded.TraceMapUrl)
+
+	again, err := json.Marshal(loaded)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(data), string(again))
+}
+
+func TestHopUnmarshalLegacy(t *testing.T) {
+	var h Hop
+	require.NoError(t, json.Unmarshal([]byte(`{"Success":false,"Address":null,"TTL":3,"RTT":0,"Error":{},"Geo":null}`), &h))
+	assert.ErrorIs(t, h.Error, errHopLimitTimeout)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"Success":true,"Address":{"IP":"8.8.8.8","Zone":""},"TTL":4,"RTT":1000000,"Error":null}`), &h))
+	assert.NoError(t, h.Error)
+	assert.Equal(t, "8.8.8.8", h.Address.String())
+
+	require.NoError(t, json.Unmarshal([]byte(`{"Address":{"IP":"anon-805a6668","Zone":""}}`), &h))
+	assert.Equal(t, Label("anon-805a6668"), h.Address, "redacted addresses become labels")
+	out, err := json.Marshal(h)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"Address":{"IP":"anon-805a6668","Zone":""}`)
+}
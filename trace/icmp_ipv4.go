@@ -186,6 +186,8 @@ func (t *ICMPTracer) addHopWithIndex(peer net.Addr, ttl, i int, rtt time.Duratio
 		}
 	}
 
+	probesReceived.Inc(string(ICMPTrace))
+
 	h := Hop{
 		Success: true,
 		Address: peer,
@@ -242,7 +244,7 @@ func (t *ICMPTracer) matchWorker(ctx context.Context) {
 	}
 }
 
-func (t *ICMPTracer) Execute() (res *Result, err error) {
+func (t *ICMPTracer) Execute(ctx context.Context) (res *Result, err error) {
 	// 初始化 Echo.ID
 	t.initEchoID()
 
@@ -284,7 +286,7 @@ func (t *ICMPTracer) Execute() (res *Result, err error) {
 	s.InitICMP()
 	defer s.Close()
 
-	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	ctx, cancel := context.WithCancelCause(sigCtx)
 	t.final.Store(-1)
 
@@ -445,6 +447,7 @@ func (t *ICMPTracer) send(ctx context.Context, s *internal.ICMPSpec, ttl, i int)
 		_ = t.clearPending(seq)
 		return err
 	}
+	probesSent.Inc(string(ICMPTrace))
 	t.storeSent(seq, start)
 	return nil
 }
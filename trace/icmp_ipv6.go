@@ -186,6 +186,8 @@ func (t *ICMPTracerv6) addHopWithIndex(peer net.Addr, ttl, i int, rtt time.Durat
 		}
 	}
 
+	probesReceived.Inc(string(ICMPTrace))
+
 	h := Hop{
 		Success: true,
 		Address: peer,
@@ -242,7 +244,7 @@ func (t *ICMPTracerv6) matchWorker(ctx context.Context) {
 	}
 }
 
-func (t *ICMPTracerv6) Execute() (res *Result, err error) {
+func (t *ICMPTracerv6) Execute(ctx context.Context) (res *Result, err error) {
 	// 初始化 Echo.ID
 	t.initEchoID()
 
@@ -284,7 +286,7 @@ func (t *ICMPTracerv6) Execute() (res *Result, err error) {
 	s.InitICMP()
 	defer s.Close()
 
-	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	ctx, cancel := context.WithCancelCause(sigCtx)
 	t.final.Store(-1)
 
@@ -448,6 +450,7 @@ func (t *ICMPTracerv6) send(ctx context.Context, s *internal.ICMPSpec, ttl, i in
 		_ = t.clearPending(seq)
 		return err
 	}
+	probesSent.Inc(string(ICMPTrace))
 	t.storeSent(seq, start)
 	return nil
 }
@@ -0,0 +1,30 @@
+package trace
+
+import (
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/metrics"
+)
+
+var (
+	probesSent       = metrics.NewCounterVec("nexttrace_probes_sent_total", "Probe packets sent, by trace method.", "method")
+	probesReceived   = metrics.NewCounterVec("nexttrace_probes_received_total", "Probe replies matched to a sent probe, by trace method.", "method")
+	geoLookups       = metrics.NewCounterVec("nexttrace_geo_lookups_total", "IP geolocation lookups sent to a provider.", "provider")
+	geoLookupErrors  = metrics.NewCounterVec("nexttrace_geo_lookup_errors_total", "IP geolocation lookups that failed after all retries.", "provider")
+	geoCacheHits     = metrics.NewCounterVec("nexttrace_geo_cache_hits_total", "IP geolocation lookups served from geoCache.", "provider")
+	geoCacheMisses   = metrics.NewCounterVec("nexttrace_geo_cache_misses_total", "IP geolocation lookups not found in geoCache.", "provider")
+	geoCacheHitRatio = metrics.NewGaugeVec("nexttrace_geo_cache_hit_ratio", "Ratio of geoCache hits to all cache lookups.", "provider")
+)
+
+func recordGeoCache(src ipgeo.Source, hit bool) {
+	provider := ipgeo.SourceName(src)
+	if hit {
+		geoCacheHits.Inc(provider)
+	} else {
+		geoCacheMisses.Inc(provider)
+	}
+	hits := geoCacheHits.Value(provider)
+	total := hits + geoCacheMisses.Value(provider)
+	if total > 0 {
+		geoCacheHitRatio.Set(hits/total, provider)
+	}
+}
@@ -174,6 +174,8 @@ func (t *TCPTracerIPv6) addHopWithIndex(peer net.Addr, ttl, i int, rtt time.Dura
 		}
 	}
 
+	probesReceived.Inc(string(TCPTrace))
+
 	h := Hop{
 		Success: true,
 		Address: peer,
@@ -234,7 +236,7 @@ func (t *TCPTracerIPv6) matchWorker(ctx context.Context) {
 	}
 }
 
-func (t *TCPTracerIPv6) Execute() (res *Result, err error) {
+func (t *TCPTracerIPv6) Execute(ctx context.Context) (res *Result, err error) {
 	// 初始化 pending、sentAt 和 matchQ
 	t.pending = make(map[int]struct{})
 	t.sentAt = make(map[int]sentInfo)
@@ -276,7 +278,7 @@ func (t *TCPTracerIPv6) Execute() (res *Result, err error) {
 	s.InitTCP()
 	defer s.Close()
 
-	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	ctx, cancel := context.WithCancelCause(sigCtx)
 	t.final.Store(-1)
 
@@ -484,6 +486,7 @@ func (t *TCPTracerIPv6) send(ctx context.Context, s *internal.TCPSpec, ttl, i in
 		_ = t.clearPending(seq)
 		return err
 	}
+	probesSent.Inc(string(TCPTrace))
 	t.storeSent(seq, SrcPort, start)
 	return nil
 }
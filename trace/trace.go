@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -90,10 +91,17 @@ type matchTask struct {
 }
 
 type Tracer interface {
-	Execute() (*Result, error)
+	// Execute runs the trace until it completes or ctx is done.
+	Execute(ctx context.Context) (*Result, error)
 }
 
 func Traceroute(method Method, config Config) (*Result, error) {
+	return TracerouteContext(context.Background(), method, config)
+}
+
+// TracerouteContext runs a trace that is aborted when ctx is done.
+// On cancellation the partial result is returned together with the context error.
+func TracerouteContext(ctx context.Context, method Method, config Config) (*Result, error) {
 	var tracer Tracer
 
 	if config.MaxHops == 0 {
@@ -161,7 +169,7 @@ func Traceroute(method Method, config Config) (*Result, error) {
 		return &Result{}, errInvalidMethod
 	}
 
-	result, err := tracer.Execute()
+	result, err := tracer.Execute(ctx)
 	if err != nil && errors.Is(err, syscall.EPERM) {
 		err = fmt.Errorf("%w, please run as root", err)
 	}
@@ -175,6 +183,8 @@ func Traceroute(method Method, config Config) (*Result, error) {
 		select {
 		case <-done:
 			// 正常完成
+		case <-ctx.Done():
+			// 调用方已取消，不再等待
 		case <-time.After(30 * time.Second):
 			// 超时，不再等待，直接返回当前结果
 		}
@@ -291,6 +301,18 @@ func (s *Result) setGeoWait(numMeasurements int) {
 	s.geoWait = geoWaitForMeasurements(numMeasurements)
 }
 
+// Snapshot returns a copy of the hops recorded so far; it is safe to call while the trace is running.
+func (s *Result) Snapshot() [][]Hop {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	hops := make([][]Hop, len(s.Hops))
+	for i, attempts := range s.Hops {
+		hops[i] = append([]Hop(nil), attempts...)
+	}
+	return hops
+}
+
 func (s *Result) reduce(final int) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -465,6 +487,11 @@ func (s *Result) addWithGeoAsync(hop Hop, attemptIdx, numMeasurements, maxAttemp
 	}(hop.TTL, idx, hop)
 }
 
+// geoCacheKey 按数据源与语言区分缓存，避免不同数据源的结果互相覆盖
+func geoCacheKey(c Config, key string) string {
+	return fmt.Sprintf("%x|%s|%s", reflect.ValueOf(c.IPGeoSource).Pointer(), c.Lang, key)
+}
+
 func (h *Hop) fetchIPData(c Config) error {
 	ipStr := h.Address.String()
 	// DN42
@@ -485,13 +512,16 @@ func (h *Hop) fetchIPData(c Config) error {
 		}
 
 		if c.IPGeoSource != nil {
+			cacheKey := geoCacheKey(c, combined)
 			// 如果缓存中已有结果，直接使用
-			if cacheVal, ok := geoCache.Load(combined); ok {
+			if cacheVal, ok := geoCache.Load(cacheKey); ok {
 				if g, ok := cacheVal.(*ipgeo.IPGeoData); ok && g != nil {
+					recordGeoCache(c.IPGeoSource, true)
 					h.Geo = g
 					return nil
 				}
 			}
+			recordGeoCache(c.IPGeoSource, false)
 			// singleflight 合并相同 key 的并发查询
 			maxRetries := c.NumMeasurements - 1
 			if maxRetries < 0 {
@@ -509,7 +539,8 @@ func (h *Hop) fetchIPData(c Config) error {
 					timeout = 6 * time.Second
 				}
 
-				v, err, _ := ipGeoSF.Do(combined, func() (any, error) {
+				v, err, _ := ipGeoSF.Do(cacheKey, func() (any, error) {
+					geoLookups.Inc(ipgeo.SourceName(c.IPGeoSource))
 					return c.IPGeoSource(combined, timeout, c.Lang, c.Maptrace)
 				})
 				if err != nil {
@@ -525,13 +556,14 @@ func (h *Hop) fetchIPData(c Config) error {
 
 				// 成功：写入结果与缓存，结束
 				h.Geo = geo
-				geoCache.Store(combined, h.Geo)
+				geoCache.Store(cacheKey, h.Geo)
 				return nil
 			}
 			// 所有尝试均失败
 			if lastErr == nil {
 				lastErr = errors.New("ipgeo: lookup failed without specific error (DN42)")
 			}
+			geoLookupErrors.Inc(ipgeo.SourceName(c.IPGeoSource))
 			h.Geo = timeoutGeo()
 			return lastErr
 		}
@@ -552,13 +584,16 @@ func (h *Hop) fetchIPData(c Config) error {
 			return
 		}
 		// (2) 如果缓存中已有结果，直接使用
-		if cacheVal, ok := geoCache.Load(ipStr); ok {
+		cacheKey := geoCacheKey(c, ipStr)
+		if cacheVal, ok := geoCache.Load(cacheKey); ok {
 			if g, ok := cacheVal.(*ipgeo.IPGeoData); ok && g != nil {
+				recordGeoCache(c.IPGeoSource, true)
 				h.Geo = g
 				ipGeoCh <- nil
 				return
 			}
 		}
+		recordGeoCache(c.IPGeoSource, false)
 		// (3) singleflight 去重
 		maxRetries := c.NumMeasurements - 1
 		if maxRetries < 0 {
@@ -576,7 +611,8 @@ func (h *Hop) fetchIPData(c Config) error {
 				timeout = 6 * time.Second
 			}
 
-			v, err, _ := ipGeoSF.Do(ipStr, func() (any, error) {
+			v, err, _ := ipGeoSF.Do(cacheKey, func() (any, error) {
+				geoLookups.Inc(ipgeo.SourceName(c.IPGeoSource))
 				return c.IPGeoSource(ipStr, timeout, c.Lang, c.Maptrace)
 			})
 			if err != nil {
@@ -592,7 +628,7 @@ func (h *Hop) fetchIPData(c Config) error {
 
 			// 成功：写入结果与缓存，结束
 			h.Geo = geo
-			geoCache.Store(ipStr, h.Geo)
+			geoCache.Store(cacheKey, h.Geo)
 			ipGeoCh <- nil
 			return
 		}
@@ -600,6 +636,7 @@ func (h *Hop) fetchIPData(c Config) error {
 		if lastErr == nil {
 			lastErr = errors.New("ipgeo: lookup failed without specific error")
 		}
+		geoLookupErrors.Inc(ipgeo.SourceName(c.IPGeoSource))
 		ipGeoCh <- lastErr
 	}()
 
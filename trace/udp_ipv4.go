@@ -218,6 +218,8 @@ func (t *UDPTracer) addHopWithIndex(peer net.Addr, ttl, i int, rtt time.Duration
 		}
 	}
 
+	probesReceived.Inc(string(UDPTrace))
+
 	h := Hop{
 		Success: true,
 		Address: peer,
@@ -280,7 +282,7 @@ func (t *UDPTracer) matchWorker(ctx context.Context) {
 	}
 }
 
-func (t *UDPTracer) Execute() (res *Result, err error) {
+func (t *UDPTracer) Execute(ctx context.Context) (res *Result, err error) {
 	// 初始化 ttlQueues、pending、sentAt 和 matchQ
 	t.ttlQueues = make(map[int][]attemptPort)
 	t.pending = make(map[attemptKey]struct{})
@@ -323,7 +325,7 @@ func (t *UDPTracer) Execute() (res *Result, err error) {
 	s.InitUDP()
 	defer s.Close()
 
-	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	ctx, cancel := context.WithCancelCause(sigCtx)
 	t.final.Store(-1)
 
@@ -537,6 +539,7 @@ func (t *UDPTracer) send(ctx context.Context, s *internal.UDPSpec, ttl, i int) e
 		_ = t.clearPending(ttl, i)
 		return err
 	}
+	probesSent.Inc(string(UDPTrace))
 
 	if t.OSType != 1 {
 		t.storeSent(seq, 0, 0, SrcPort, start)
@@ -174,6 +174,8 @@ func (t *UDPTracerIPv6) addHopWithIndex(peer net.Addr, ttl, i int, rtt time.Dura
 		}
 	}
 
+	probesReceived.Inc(string(UDPTrace))
+
 	h := Hop{
 		Success: true,
 		Address: peer,
@@ -234,7 +236,7 @@ func (t *UDPTracerIPv6) matchWorker(ctx context.Context) {
 	}
 }
 
-func (t *UDPTracerIPv6) Execute() (res *Result, err error) {
+func (t *UDPTracerIPv6) Execute(ctx context.Context) (res *Result, err error) {
 	// 初始化 pending、sentAt 和 matchQ
 	t.pending = make(map[int]struct{})
 	t.sentAt = make(map[int]sentInfo)
@@ -275,7 +277,7 @@ func (t *UDPTracerIPv6) Execute() (res *Result, err error) {
 	s.InitUDP()
 	defer s.Close()
 
-	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	ctx, cancel := context.WithCancelCause(sigCtx)
 	t.final.Store(-1)
 
@@ -468,6 +470,7 @@ func (t *UDPTracerIPv6) send(ctx context.Context, s *internal.UDPSpec, ttl, i in
 		_ = t.clearPending(seq)
 		return err
 	}
+	probesSent.Inc(string(UDPTrace))
 	t.storeSent(seq, SrcPort, start)
 	return nil
 }
@@ -0,0 +1,179 @@
+package tracediff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nxtrace/NTrace-core/schema"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+// consoleResult 对应 Web 控制台 /api/trace 的响应
+type consoleResult struct {
+	Hops []struct {
+		TTL      int `json:"ttl"`
+		Attempts []struct {
+			Success  bool     `json:"success"`
+			IP       string   `json:"ip"`
+			Hostname string   `json:"hostname"`
+			RTT      float64  `json:"rtt_ms"`
+			Geo      *geoJSON `json:"geo"`
+		} `json:"attempts"`
+	} `json:"hops"`
+}
+
+// mtrResult 对应 MTR 快照
+type mtrResult struct {
+	Stats []struct {
+		TTL      int      `json:"ttl"`
+		Host     string   `json:"host"`
+		IP       string   `json:"ip"`
+		Sent     int      `json:"sent"`
+		Received int      `json:"received"`
+		Avg      float64  `json:"avg_ms"`
+		Geo      *geoJSON `json:"geo"`
+	} `json:"stats"`
+}
+
+type geoJSON struct {
+	Asnumber string `json:"asnumber"`
+}
+
+func (g *geoJSON) asn() string {
+	if g == nil {
+		return ""
+	}
+	return g.Asnumber
+}
+
+// envelope 用于识别输入格式
+type envelope struct {
+	LegacyHops json.RawMessage `json:"Hops"`
+	Hops       json.RawMessage `json:"hops"`
+	Stats      json.RawMessage `json:"stats"`
+	Kind       string          `json:"kind"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// LoadFile reads a Path from a JSON file; see Parse for the accepted formats.
+func LoadFile(path string) (Path, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Path{}, err
+	}
+	p, err := Parse(filepath.Base(path), data)
+	if err != nil {
+		return Path{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse decodes a Path from `nexttrace --json` output, a web console trace response,
+// an MTR snapshot, a history record wrapping either of them, or a result of another tool
+// accepted by schema.Import.
+func Parse(label string, data []byte) (Path, error) {
+	if doc, ok, err := schema.Import(data); ok {
+		if err != nil {
+			return Path{}, err
+		}
+		res, err := doc.Result()
+		if err != nil {
+			return Path{}, err
+		}
+		return FromResult(label, res), nil
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return Path{}, fmt.Errorf("decode trace: %w", err)
+	}
+	// "Hops" 为命令行 JSON，"hops" 为控制台响应；两者都可能出现时再按元素形状区分
+	switch {
+	case env.Kind != "" && len(env.Data) > 0:
+		return Parse(label, env.Data)
+	case len(env.Stats) > 0:
+		return parseMTR(label, data)
+	case len(env.LegacyHops) > 0 || len(env.Hops) > 0:
+		if p, err := parseConsole(label, data); err == nil {
+			return p, nil
+		}
+		return parseLegacy(label, data)
+	}
+	return Path{}, errors.New("unrecognised trace format")
+}
+
+func parseLegacy(label string, data []byte) (Path, error) {
+	var res trace.Result
+	if err := json.Unmarshal(data, &res); err != nil {
+		return Path{}, fmt.Errorf("decode trace: %w", err)
+	}
+	return FromResult(label, &res), nil
+}
+
+func parseConsole(label string, data []byte) (Path, error) {
+	var res consoleResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return Path{}, err
+	}
+	p := Path{Label: label}
+	for idx, h := range res.Hops {
+		if h.TTL == 0 && len(h.Attempts) == 0 {
+			return Path{}, errors.New("not a console trace response")
+		}
+		hop := Hop{TTL: h.TTL, Sent: len(h.Attempts)}
+		if hop.TTL == 0 {
+			hop.TTL = idx + 1
+		}
+		var samples []sample
+		for _, a := range h.Attempts {
+			if a.IP == "" {
+				continue
+			}
+			samples = append(samples, sample{ip: a.IP, hostname: a.Hostname, asn: a.Geo.asn(), rtt: a.RTT})
+		}
+		fillHop(&hop, samples)
+		p.Hops = append(p.Hops, hop)
+	}
+	return p, nil
+}
+
+// parseMTR 以每个 TTL 中接收最多的地址作为该跳
+func parseMTR(label string, data []byte) (Path, error) {
+	var res mtrResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return Path{}, fmt.Errorf("decode mtr snapshot: %w", err)
+	}
+	p := Path{Label: label}
+	byTTL := make(map[int]*Hop)
+	best := make(map[int]int)
+	maxTTL := 0
+	for _, row := range res.Stats {
+		hop, ok := byTTL[row.TTL]
+		if !ok {
+			hop = &Hop{TTL: row.TTL}
+			byTTL[row.TTL] = hop
+		}
+		hop.Sent += row.Sent
+		hop.Received += row.Received
+		if row.IP != "" && row.Received > 0 {
+			hop.IPs = append(hop.IPs, row.IP)
+			if row.Received > best[row.TTL] {
+				best[row.TTL] = row.Received
+				hop.IP, hop.Hostname, hop.ASN, hop.RTT = row.IP, row.Host, row.Geo.asn(), row.Avg
+			}
+		}
+		if row.TTL > maxTTL {
+			maxTTL = row.TTL
+		}
+	}
+	for ttl := 1; ttl <= maxTTL; ttl++ {
+		if hop, ok := byTTL[ttl]; ok {
+			p.Hops = append(p.Hops, *hop)
+		} else {
+			p.Hops = append(p.Hops, Hop{TTL: ttl})
+		}
+	}
+	return p, nil
+}
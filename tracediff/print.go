@@ -0,0 +1,83 @@
+package tracediff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var changeColors = map[string]*color.Color{
+	ChangeSame:    color.New(color.FgHiBlack),
+	ChangeIP:      color.New(color.FgYellow, color.Bold),
+	ChangeASN:     color.New(color.FgMagenta, color.Bold),
+	ChangeTimeout: color.New(color.FgYellow),
+	ChangeAdded:   color.New(color.FgGreen, color.Bold),
+	ChangeRemoved: color.New(color.FgRed, color.Bold),
+}
+
+// Print writes a colored, hop-aligned rendering of the report to w.
+func (r *Report) Print(w io.Writer) {
+	header := color.New(color.FgWhite, color.Bold)
+	fmt.Fprintf(w, "%s %s %s %s\n", header.Sprint("Comparing"), color.New(color.FgRed).Sprint("A="+r.A), header.Sprint("->"), color.New(color.FgGreen).Sprint("B="+r.B))
+	fmt.Fprintf(w, "%-4s %-40s %-40s %10s  %s\n", "TTL", "A", "B", "ΔRTT", "CHANGE")
+
+	for _, d := range r.Hops {
+		// 先补齐宽度再着色，颜色控制符不计入列宽
+		delta := fmt.Sprintf("%10s", "")
+		if d.RTTDelta != nil {
+			delta = fmt.Sprintf("%10s", fmt.Sprintf("%+.2fms", *d.RTTDelta))
+			if *d.RTTDelta >= 10 {
+				delta = color.New(color.FgRed).Sprint(delta)
+			} else if *d.RTTDelta <= -10 {
+				delta = color.New(color.FgGreen).Sprint(delta)
+			}
+		}
+		c := changeColors[d.Change]
+		fmt.Fprintf(w, "%-4d %-40s %-40s %s  %s\n", d.TTL, hopLabel(d.A), hopLabel(d.B), delta, c.Sprint(d.Change))
+	}
+
+	switch {
+	case r.Identical:
+		fmt.Fprintln(w, color.New(color.FgGreen, color.Bold).Sprint("Paths are identical"))
+	case r.Diverge != nil:
+		if r.Diverge.IP == "" {
+			fmt.Fprint(w, "Paths differ from the first responding hop")
+		} else {
+			fmt.Fprintf(w, "Paths diverge after %s (TTL %d/%d)", color.New(color.FgCyan, color.Bold).Sprint(r.Diverge.IP), r.Diverge.TTLA, r.Diverge.TTLB)
+		}
+		if r.Reconverge != nil {
+			fmt.Fprintf(w, " and reconverge at %s (TTL %d/%d)\n", color.New(color.FgCyan, color.Bold).Sprint(r.Reconverge.IP), r.Reconverge.TTLA, r.Reconverge.TTLB)
+		} else {
+			fmt.Fprintln(w, " and do not reconverge")
+		}
+	default:
+		fmt.Fprintln(w, "Responding hops are identical; differences are limited to timeouts")
+	}
+
+	var parts []string
+	for _, kind := range []string{ChangeSame, ChangeIP, ChangeASN, ChangeTimeout, ChangeAdded, ChangeRemoved} {
+		if n := r.Summary[kind]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", kind, n))
+		}
+	}
+	fmt.Fprintf(w, "Summary: %s\n", strings.Join(parts, " "))
+}
+
+func hopLabel(h *Hop) string {
+	if h == nil {
+		return "-"
+	}
+	if !h.Responded() {
+		return "*"
+	}
+	label := h.IP
+	if h.ASN != "" {
+		label += " AS" + h.ASN
+	}
+	if h.RTT > 0 {
+		label += fmt.Sprintf(" %.2fms", h.RTT)
+	}
+	return label
+}
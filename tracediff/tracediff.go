@@ -0,0 +1,294 @@
+// Package tracediff compares two traceroute results hop by hop.
+package tracediff
+
+import (
+	"math"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+// Change kinds of a TTL in the diff.
+const (
+	ChangeSame    = "same"
+	ChangeIP      = "ip_changed"
+	ChangeASN     = "asn_changed"
+	ChangeTimeout = "timeout_changed"
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+)
+
+// Hop summarises all attempts of one TTL.
+type Hop struct {
+	TTL int `json:"ttl"`
+	// IP 为出现次数最多的响应地址，全部超时时为空
+	IP       string   `json:"ip,omitempty"`
+	IPs      []string `json:"ips,omitempty"`
+	Hostname string   `json:"hostname,omitempty"`
+	ASN      string   `json:"asn,omitempty"`
+	// RTT 为成功响应的平均往返时延（毫秒）
+	RTT      float64 `json:"rtt_ms,omitempty"`
+	Sent     int     `json:"sent"`
+	Received int     `json:"received"`
+}
+
+// Responded reports whether any attempt of the hop got a reply.
+func (h *Hop) Responded() bool {
+	return h != nil && h.IP != ""
+}
+
+// Path is one side of a comparison.
+type Path struct {
+	Label string `json:"label"`
+	Hops  []Hop  `json:"hops"`
+}
+
+// HopDiff is the comparison of a single TTL.
+type HopDiff struct {
+	TTL      int      `json:"ttl"`
+	Change   string   `json:"change"`
+	A        *Hop     `json:"a,omitempty"`
+	B        *Hop     `json:"b,omitempty"`
+	RTTDelta *float64 `json:"rtt_delta_ms,omitempty"`
+}
+
+// Point locates an address in both paths.
+type Point struct {
+	IP   string `json:"ip"`
+	TTLA int    `json:"ttl_a"`
+	TTLB int    `json:"ttl_b"`
+}
+
+// Report is the result of Compare.
+type Report struct {
+	A         string    `json:"a"`
+	B         string    `json:"b"`
+	Identical bool      `json:"identical"`
+	Hops      []HopDiff `json:"hops"`
+	// Diverge 为两条路径分叉前最后一个共同地址（IP 为空表示首跳即不同），
+	// Reconverge 为分叉后首次重新出现的共同地址
+	Diverge    *Point         `json:"diverge,omitempty"`
+	Reconverge *Point         `json:"reconverge,omitempty"`
+	Summary    map[string]int `json:"summary"`
+}
+
+// FromResult summarises a trace result into a Path.
+func FromResult(label string, res *trace.Result) Path {
+	p := Path{Label: label}
+	if res == nil {
+		return p
+	}
+	for idx, attempts := range res.Hops {
+		hop := Hop{TTL: idx + 1, Sent: len(attempts)}
+		var samples []sample
+		for _, attempt := range attempts {
+			if attempt.TTL > 0 {
+				hop.TTL = attempt.TTL
+			}
+			if attempt.Address == nil {
+				continue
+			}
+			s := sample{ip: addrIP(attempt.Address), hostname: attempt.Hostname}
+			if attempt.Success || attempt.RTT > 0 {
+				s.rtt = float64(attempt.RTT) / float64(time.Millisecond)
+			}
+			if attempt.Geo != nil {
+				s.asn = attempt.Geo.Asnumber
+			}
+			samples = append(samples, s)
+		}
+		fillHop(&hop, samples)
+		p.Hops = append(p.Hops, hop)
+	}
+	return p
+}
+
+type sample struct {
+	ip       string
+	hostname string
+	asn      string
+	rtt      float64
+}
+
+func addrIP(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP.String()
+	case *net.TCPAddr:
+		return a.IP.String()
+	case *net.UDPAddr:
+		return a.IP.String()
+	}
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		return host
+	}
+	return addr.String()
+}
+
+// fillHop 根据响应样本计算主地址、ASN 与平均 RTT
+func fillHop(hop *Hop, samples []sample) {
+	hop.Received = len(samples)
+	if len(samples) == 0 {
+		return
+	}
+	counts := make(map[string]int)
+	for _, s := range samples {
+		if counts[s.ip] == 0 {
+			hop.IPs = append(hop.IPs, s.ip)
+		}
+		counts[s.ip]++
+	}
+	primary := hop.IPs[0]
+	for _, ip := range hop.IPs {
+		if counts[ip] > counts[primary] {
+			primary = ip
+		}
+	}
+	hop.IP = primary
+
+	var sum float64
+	var n int
+	for _, s := range samples {
+		if s.rtt > 0 {
+			sum += s.rtt
+			n++
+		}
+		if s.ip == primary {
+			if hop.Hostname == "" {
+				hop.Hostname = s.hostname
+			}
+			if hop.ASN == "" {
+				hop.ASN = s.asn
+			}
+		}
+	}
+	if n > 0 {
+		hop.RTT = math.Round(sum/float64(n)*1000) / 1000
+	}
+}
+
+// Compare aligns a and b by TTL and reports per-hop changes plus where the paths diverge and reconverge.
+func Compare(a, b Path) Report {
+	r := Report{A: a.Label, B: b.Label, Summary: make(map[string]int)}
+
+	// 按 TTL 配对：导入的结果可能缺少部分 TTL，切片下标不一定对应同一跳
+	hopsA, hopsB := byTTL(a), byTTL(b)
+	ttls := make([]int, 0, len(hopsA)+len(hopsB))
+	for ttl := range hopsA {
+		ttls = append(ttls, ttl)
+	}
+	for ttl := range hopsB {
+		if _, ok := hopsA[ttl]; !ok {
+			ttls = append(ttls, ttl)
+		}
+	}
+	sort.Ints(ttls)
+	for _, ttl := range ttls {
+		d := HopDiff{TTL: ttl, A: hopsA[ttl], B: hopsB[ttl]}
+		d.Change = classify(d.A, d.B)
+		if d.A.Responded() && d.B.Responded() && d.A.RTT > 0 && d.B.RTT > 0 {
+			delta := math.Round((d.B.RTT-d.A.RTT)*1000) / 1000
+			d.RTTDelta = &delta
+		}
+		r.Summary[d.Change]++
+		r.Hops = append(r.Hops, d)
+	}
+	r.Identical = r.Summary[ChangeSame] == len(r.Hops)
+	r.Diverge, r.Reconverge = divergence(a, b)
+	return r
+}
+
+// byTTL 以 TTL 索引路径中的跳，TTL 重复时保留第一个
+func byTTL(p Path) map[int]*Hop {
+	hops := make(map[int]*Hop, len(p.Hops))
+	for i := range p.Hops {
+		hop := p.Hops[i]
+		if _, ok := hops[hop.TTL]; !ok {
+			hops[hop.TTL] = &hop
+		}
+	}
+	return hops
+}
+
+func classify(a, b *Hop) string {
+	switch {
+	case a == nil:
+		return ChangeAdded
+	case b == nil:
+		return ChangeRemoved
+	case !a.Responded() && !b.Responded():
+		return ChangeSame
+	case a.Responded() != b.Responded():
+		return ChangeTimeout
+	case sharesIP(a, b):
+		return ChangeSame
+	case a.ASN != "" && b.ASN != "" && a.ASN != b.ASN:
+		return ChangeASN
+	default:
+		return ChangeIP
+	}
+}
+
+func sharesIP(a, b *Hop) bool {
+	for _, x := range a.IPs {
+		for _, y := range b.IPs {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return a.IP == b.IP
+}
+
+// divergence 以响应地址序列比较两条路径：最后一个共同前缀地址为分叉点，分叉后首个双方都经过的地址为汇合点
+func divergence(a, b Path) (*Point, *Point) {
+	seqA := respondedHops(a)
+	seqB := respondedHops(b)
+
+	i := 0
+	for i < len(seqA) && i < len(seqB) && seqA[i].IP == seqB[i].IP {
+		i++
+	}
+	if i == len(seqA) && i == len(seqB) {
+		return nil, nil
+	}
+
+	var diverge *Point
+	if i > 0 {
+		diverge = &Point{IP: seqA[i-1].IP, TTLA: seqA[i-1].TTL, TTLB: seqB[i-1].TTL}
+	} else {
+		diverge = &Point{}
+	}
+
+	posB := make(map[string]int)
+	for j := i; j < len(seqB); j++ {
+		if _, ok := posB[seqB[j].IP]; !ok {
+			posB[seqB[j].IP] = j
+		}
+	}
+	var candidates []Point
+	for j := i; j < len(seqA); j++ {
+		if k, ok := posB[seqA[j].IP]; ok {
+			candidates = append(candidates, Point{IP: seqA[j].IP, TTLA: seqA[j].TTL, TTLB: seqB[k].TTL})
+		}
+	}
+	if len(candidates) == 0 {
+		return diverge, nil
+	}
+	sort.SliceStable(candidates, func(x, y int) bool {
+		return candidates[x].TTLA+candidates[x].TTLB < candidates[y].TTLA+candidates[y].TTLB
+	})
+	return diverge, &candidates[0]
+}
+
+func respondedHops(p Path) []Hop {
+	var hops []Hop
+	for _, h := range p.Hops {
+		if h.IP != "" {
+			hops = append(hops, h)
+		}
+	}
+	return hops
+}
@@ -0,0 +1,144 @@
+package tracediff
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nxtrace/NTrace-core/ipgeo"
+	"github.com/nxtrace/NTrace-core/trace"
+)
+
+func hop(ttl int, ip, asn string, rtt float64) Hop {
+	h := Hop{TTL: ttl, Sent: 1}
+	if ip != "" {
+		h.IP, h.IPs, h.ASN, h.RTT, h.Received = ip, []string{ip}, asn, rtt, 1
+	}
+	return h
+}
+
+func TestCompare(t *testing.T) {
+	a := Path{Label: "a", Hops: []Hop{
+		hop(1, "192.168.1.1", "", 1),
+		hop(2, "10.0.0.1", "4134", 5),
+		hop(3, "10.0.0.2", "4134", 10),
+		hop(4, "", "", 0),
+		hop(5, "1.1.1.1", "13335", 30),
+	}}
+	b := Path{Label: "b", Hops: []Hop{
+		hop(1, "192.168.1.1", "", 1.5),
+		hop(2, "10.1.0.1", "4134", 6),
+		hop(3, "20.0.0.2", "4809", 20),
+		hop(4, "20.0.0.3", "4809", 25),
+		hop(5, "1.1.1.1", "13335", 40),
+		hop(6, "1.1.1.1", "13335", 41),
+	}}
+
+	r := Compare(a, b)
+	require.Len(t, r.Hops, 6)
+	assert.False(t, r.Identical)
+
+	changes := make([]string, 0, len(r.Hops))
+	for _, d := range r.Hops {
+		changes = append(changes, d.Change)
+	}
+	assert.Equal(t, []string{ChangeSame, ChangeIP, ChangeASN, ChangeTimeout, ChangeSame, ChangeAdded}, changes)
+	require.NotNil(t, r.Hops[4].RTTDelta)
+	assert.InDelta(t, 10, *r.Hops[4].RTTDelta, 1e-9)
+	assert.Nil(t, r.Hops[3].RTTDelta)
+
+	assert.Equal(t, &Point{IP: "192.168.1.1", TTLA: 1, TTLB: 1}, r.Diverge)
+	assert.Equal(t, &Point{IP: "1.1.1.1", TTLA: 5, TTLB: 5}, r.Reconverge)
+	assert.Equal(t, 2, r.Summary[ChangeSame])
+
+	same := Compare(a, a)
+	assert.True(t, same.Identical)
+	assert.Nil(t, same.Diverge)
+}
+
+func TestCompareDivergesFromFirstHop(t *testing.T) {
+	a := Path{Hops: []Hop{hop(1, "10.0.0.1", "", 1), hop(2, "8.8.8.8", "", 2)}}
+	b := Path{Hops: []Hop{hop(1, "10.0.0.2", "", 1)}}
+
+	r := Compare(a, b)
+	assert.Equal(t, ChangeRemoved, r.Hops[1].Change)
+	require.NotNil(t, r.Diverge)
+	assert.Empty(t, r.Diverge.IP)
+	assert.Nil(t, r.Reconverge)
+}
+
+func TestCompareAlignsByTTL(t *testing.T) {
+	// 导入的结果可能缺少中间的 TTL
+	a := Path{Hops: []Hop{hop(1, "10.0.0.1", "", 1), hop(3, "10.0.0.3", "", 3)}}
+	b := Path{Hops: []Hop{hop(1, "10.0.0.1", "", 1), hop(2, "10.0.0.2", "", 2), hop(3, "10.0.0.3", "", 25)}}
+
+	r := Compare(a, b)
+	require.Len(t, r.Hops, 3)
+	assert.Equal(t, []int{1, 2, 3}, []int{r.Hops[0].TTL, r.Hops[1].TTL, r.Hops[2].TTL})
+	assert.Equal(t, ChangeAdded, r.Hops[1].Change)
+	assert.Equal(t, ChangeSame, r.Hops[2].Change)
+	assert.Equal(t, 3, r.Hops[2].A.TTL)
+	require.NotNil(t, r.Hops[2].RTTDelta)
+	assert.InDelta(t, 22, *r.Hops[2].RTTDelta, 1e-9)
+
+	var buf bytes.Buffer
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	r.Print(&buf)
+	assert.Contains(t, buf.String(), color.New(color.FgRed).Sprint("  +22.00ms")+"  ")
+}
+
+func TestFromResult(t *testing.T) {
+	res := &trace.Result{Hops: [][]trace.Hop{
+		{{TTL: 1}, {TTL: 1}},
+		{
+			{Success: true, TTL: 2, Address: &net.IPAddr{IP: net.ParseIP("10.0.0.9")}, RTT: 4 * time.Millisecond},
+			{Success: true, TTL: 2, Address: &net.IPAddr{IP: net.ParseIP("10.0.0.1")}, RTT: 2 * time.Millisecond, Geo: &ipgeo.IPGeoData{Asnumber: "4134"}},
+			{Success: true, TTL: 2, Address: &net.IPAddr{IP: net.ParseIP("10.0.0.1")}, RTT: 6 * time.Millisecond},
+		},
+	}}
+
+	p := FromResult("live", res)
+	require.Len(t, p.Hops, 2)
+	assert.False(t, p.Hops[0].Responded())
+	assert.Equal(t, 2, p.Hops[0].Sent)
+	assert.Equal(t, "10.0.0.1", p.Hops[1].IP)
+	assert.Equal(t, []string{"10.0.0.9", "10.0.0.1"}, p.Hops[1].IPs)
+	assert.Equal(t, "4134", p.Hops[1].ASN)
+	assert.InDelta(t, 4, p.Hops[1].RTT, 1e-9)
+}
+
+func TestParseFormats(t *testing.T) {
+	legacy := `{"Hops":[[{"Success":true,"Address":{"IP":"1.1.1.1","Zone":""},"Hostname":"one.one","TTL":1,"RTT":2500000,"Geo":{"asnumber":"13335"}}]],"TraceMapUrl":""}`
+	console := `{"target":"1.1.1.1","hops":[{"ttl":1,"attempts":[{"success":true,"ip":"1.1.1.1","rtt_ms":2.5,"geo":{"asnumber":"13335"}}]}]}`
+	record := `{"id":"x","kind":"trace","data":` + console + `}`
+	mtr := `{"iteration":3,"stats":[{"ttl":1,"ip":"1.1.1.1","sent":3,"received":3,"avg_ms":2.5,"geo":{"asnumber":"13335"}},{"ttl":1,"ip":"1.0.0.1","sent":3,"received":1}]}`
+	mtrJSON := `{"report":{"mtr":{"dst":"1.1.1.1","tests":2},"hubs":[{"count":1,"host":"1.1.1.1","ASN":"AS13335","Loss%":0,"Snt":2,"Last":3,"Avg":2.5,"Best":2,"Wrst":3}]}}`
+
+	for name, doc := range map[string]string{"legacy": legacy, "console": console, "record": record, "mtr": mtr, "mtr-json": mtrJSON} {
+		t.Run(name, func(t *testing.T) {
+			p, err := Parse(name, []byte(doc))
+			require.NoError(t, err)
+			require.Len(t, p.Hops, 1)
+			assert.Equal(t, "1.1.1.1", p.Hops[0].IP)
+			assert.Equal(t, "13335", p.Hops[0].ASN)
+			assert.InDelta(t, 2.5, p.Hops[0].RTT, 1e-9)
+		})
+	}
+
+	atlas := `[{"type":"traceroute","dst_addr":"1.1.1.1","result":[{"hop":1,"result":[{"from":"1.1.1.1","rtt":2.5},{"x":"*"}]}]}]`
+	p, err := Parse("atlas", []byte(atlas))
+	require.NoError(t, err)
+	require.Len(t, p.Hops, 1)
+	assert.Equal(t, "1.1.1.1", p.Hops[0].IP)
+	assert.Equal(t, 2, p.Hops[0].Sent)
+
+	_, err = Parse("bad", []byte(`{"foo":1}`))
+	assert.Error(t, err)
+}
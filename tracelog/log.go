@@ -2,31 +2,28 @@ package tracelog
 
 import (
 	"fmt"
-	"io"
-	"log"
 	"net"
-	"os"
 	"strconv"
 	"strings"
 
 	"github.com/nxtrace/NTrace-core/trace"
 )
 
-func RealtimePrinter(res *trace.Result, ttl int) {
-	f, err := os.OpenFile("/tmp/trace.log", os.O_CREATE|os.O_APPEND|os.O_RDWR, os.ModePerm)
-	if err != nil {
-		return
-	}
-	defer func(f *os.File) {
-		err := f.Close()
-		if err != nil {
-			log.Fatal(err)
+// FormatHop returns TTL ttl of res as text lines, one block per responding address.
+func FormatHop(res *trace.Result, ttl int) string {
+	var b strings.Builder
+	for _, line := range hopLines(res, ttl) {
+		b.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			b.WriteByte('\n')
 		}
-	}(f)
+	}
+	return b.String()
+}
 
-	multiWriter := io.MultiWriter(os.Stdout, f)
-	log.SetOutput(multiWriter)
-	log.SetFlags(0)
+// hopLines 返回一跳的输出，每个响应地址一段
+func hopLines(res *trace.Result, ttl int) []string {
+	var lines []string
 	var resStr string
 	resStr += fmt.Sprintf("%-2d  ", ttl+1)
 
@@ -57,8 +54,7 @@ func RealtimePrinter(res *trace.Result, ttl int) {
 
 	if latestIP == "" {
 		resStr += fmt.Sprintf("%s\n", "*")
-		log.Print(resStr)
-		return
+		return append(lines, resStr)
 	}
 
 	var blockDisplay = false
@@ -110,7 +106,9 @@ func RealtimePrinter(res *trace.Result, ttl int) {
 				resStr += fmt.Sprintf("/ %s", v[j])
 			}
 		}
-		log.Print(resStr)
+		lines = append(lines, resStr)
+		resStr = ""
 		blockDisplay = true
 	}
+	return lines
 }
@@ -0,0 +1,281 @@
+package tracelog
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log formats.
+const (
+	FormatText  = "text"
+	FormatJSONL = "jsonl"
+)
+
+// Formats lists the accepted values of Options.Format.
+var Formats = []string{FormatText, FormatJSONL}
+
+// DefaultPath is used when no log path is given. {user} keeps the logs of different users apart.
+var DefaultPath = filepath.Join(os.TempDir(), "nexttrace-{user}.log")
+
+// Options configures a trace log.
+type Options struct {
+	// Path is the log file; see ExpandPath for the placeholders it may contain.
+	Path string
+	// Format is FormatText (the default) or FormatJSONL.
+	Format string
+	// MaxSize rotates the log before a write would make it larger than MaxSize bytes; 0 disables rotation.
+	MaxSize int64
+	// MaxAge rotates the log once it is older than MaxAge and removes rotated logs older than
+	// MaxAge; 0 keeps them regardless of age.
+	MaxAge time.Duration
+	// MaxBackups keeps at most this many rotated logs; 0 keeps all of them.
+	MaxBackups int
+	// Opened, when set, is called with the absolute path of every log file Open opens.
+	Opened func(path string)
+}
+
+// Fields are the values substituted into a log path.
+type Fields struct {
+	Target string
+	IP     string
+	Time   time.Time
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ExpandPath substitutes the placeholders of a log path: {target} and {ip} of the trace,
+// {date} (2006-01-02) and {time} (20060102-150405) of its start, {user} and {pid}. A leading
+// ~/ is the home directory. Placeholders whose field is empty are left as they are.
+func ExpandPath(pattern string, f Fields) string {
+	if rest, ok := strings.CutPrefix(pattern, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			pattern = filepath.Join(home, rest)
+		}
+	}
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	// 用户名、目标与地址可能含有 \、/ 或 :，替换为文件名中安全的字符
+	pairs := []string{"{user}", unsafeChars.ReplaceAllString(name, "_"), "{pid}", strconv.Itoa(os.Getpid())}
+	if f.Target != "" {
+		pairs = append(pairs, "{target}", unsafeChars.ReplaceAllString(f.Target, "_"))
+	}
+	if f.IP != "" {
+		pairs = append(pairs, "{ip}", unsafeChars.ReplaceAllString(f.IP, "_"))
+	}
+	if !f.Time.IsZero() {
+		pairs = append(pairs, "{date}", f.Time.Format("2006-01-02"), "{time}", f.Time.Format("20060102-150405"))
+	}
+	return strings.NewReplacer(pairs...).Replace(filepath.Clean(pattern))
+}
+
+// pathLocks 为同一进程内写同一文件的 Writer 共享的锁，写入与轮转互斥
+var (
+	pathLocksMu sync.Mutex
+	pathLocks   = make(map[string]*sync.Mutex)
+)
+
+func pathLock(path string) *sync.Mutex {
+	pathLocksMu.Lock()
+	defer pathLocksMu.Unlock()
+	mu, ok := pathLocks[path]
+	if !ok {
+		mu = new(sync.Mutex)
+		pathLocks[path] = mu
+	}
+	return mu
+}
+
+// Writer appends to a log file and rotates it by size and age. Every Write is a single append, so
+// records of traces running in parallel, in this process or in others, do not interleave.
+// When another process has rotated the file, the Writer reopens the path before writing.
+type Writer struct {
+	path string
+	opts Options
+	mu   *sync.Mutex
+	f    *os.File
+}
+
+// Open opens the log file at path, creating it and its directory when missing. The file is
+// readable by its owner only.
+func Open(path string, opts Options) (*Writer, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(abs), 0o700); err != nil {
+		return nil, err
+	}
+	w := &Writer{path: abs, opts: opts, mu: pathLock(abs)}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	if opts.Opened != nil {
+		opts.Opened(abs)
+	}
+	return w, nil
+}
+
+// Path returns the absolute path of the log file.
+func (w *Writer) Path() string { return w.path }
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	w.f = f
+	return nil
+}
+
+// Write appends p to the log, rotating it first when it would grow past MaxSize or is older
+// than MaxAge.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+	info, err := w.current()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	if size > 0 && (w.opts.MaxSize > 0 && size+int64(len(p)) > w.opts.MaxSize ||
+		w.opts.MaxAge > 0 && time.Since(w.started(info)) > w.opts.MaxAge) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	return w.f.Write(p)
+}
+
+// current 返回日志文件的信息；文件已被其他进程轮转或删除时重新打开该路径
+func (w *Writer) current() (os.FileInfo, error) {
+	opened, err := w.f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	onDisk, err := os.Stat(w.path)
+	if err == nil && os.SameFile(opened, onDisk) {
+		return opened, nil
+	}
+	_ = w.f.Close()
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w.f.Stat()
+}
+
+// started 返回当前日志开始写入的时间，即最近一次轮转的时间；从未轮转过的日志没有可移植的
+// 创建时间，退而使用最后修改时间
+func (w *Writer) started(info os.FileInfo) time.Time {
+	if backups, err := listBackups(w.path); err == nil && len(backups) > 0 {
+		return backups[0].t
+	}
+	return info.ModTime()
+}
+
+// rotate 将当前日志重命名为带时间戳的备份并新建日志，然后按数量与时间清理旧备份
+func (w *Writer) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(w.path, backupName(w.path, time.Now())); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	return w.prune(time.Now())
+}
+
+const backupLayout = "20060102-150405.000"
+
+// backupName 在扩展名前插入时间戳，例如 trace.log -> trace-20250102-150405.000.log
+func backupName(path string, t time.Time) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(path, ext), t.Format(backupLayout), ext)
+}
+
+type backup struct {
+	name string
+	t    time.Time
+}
+
+// Backups returns the rotated logs of the log file at path, newest first.
+func Backups(path string) ([]string, error) {
+	backups, err := listBackups(path)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(backups))
+	for i, b := range backups {
+		names[i] = b.name
+	}
+	return names, nil
+}
+
+func listBackups(path string) ([]backup, error) {
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(filepath.Base(path), ext) + "-"
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	var backups []backup
+	for _, e := range entries {
+		stamp, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok || e.IsDir() || !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		t, err := time.ParseInLocation(backupLayout, strings.TrimSuffix(stamp, ext), time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{filepath.Join(filepath.Dir(path), e.Name()), t})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].t.After(backups[j].t) })
+	return backups, nil
+}
+
+func (w *Writer) prune(now time.Time) error {
+	if w.opts.MaxBackups <= 0 && w.opts.MaxAge <= 0 {
+		return nil
+	}
+	backups, err := listBackups(w.path)
+	if err != nil {
+		return err
+	}
+	for i, b := range backups {
+		if (w.opts.MaxBackups > 0 && i >= w.opts.MaxBackups) || (w.opts.MaxAge > 0 && now.Sub(b.t) > w.opts.MaxAge) {
+			if err := os.Remove(b.name); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close closes the log file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
@@ -0,0 +1,165 @@
+package tracelog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandPath(t *testing.T) {
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.Local)
+	path := ExpandPath("/var/log/{target}/{ip}-{date}-{time}.log", Fields{Target: "https://example.com/x", IP: "2001:db8::1", Time: started})
+	assert.Equal(t, "/var/log/https_example.com_x/2001_db8_1-2025-01-02-20250102-030405.log", path)
+
+	// 未提供的字段保持原样
+	assert.Equal(t, "/tmp/{target}.log", ExpandPath("/tmp/{target}.log", Fields{}))
+	assert.NotContains(t, ExpandPath(DefaultPath, Fields{}), "{user}")
+
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "trace.log"), ExpandPath("~/trace.log", Fields{}))
+}
+
+func TestWriterRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "trace.log")
+	w, err := Open(path, Options{MaxSize: 10, MaxBackups: 2})
+	require.NoError(t, err)
+	defer w.Close()
+
+	for _, record := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		_, err := w.Write([]byte(record))
+		require.NoError(t, err)
+		// 备份文件名精确到毫秒
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "dddddd\n", string(data))
+
+	backups, err := Backups(path)
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	newest, err := os.ReadFile(backups[0])
+	require.NoError(t, err)
+	assert.Equal(t, "cccccc\n", string(newest))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestWriterPrunesOldBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "trace.log")
+	old := backupName(path, time.Now().Add(-48*time.Hour))
+	require.NoError(t, os.WriteFile(old, []byte("old\n"), 0o600))
+	// 不符合备份命名的文件不受影响
+	other := filepath.Join(dir, "trace-2025-01-02.log")
+	require.NoError(t, os.WriteFile(other, []byte("other\n"), 0o600))
+
+	w, err := Open(path, Options{MaxSize: 4, MaxAge: 24 * time.Hour})
+	require.NoError(t, err)
+	defer w.Close()
+	for range 2 {
+		_, err := w.Write([]byte("new\n"))
+		require.NoError(t, err)
+	}
+
+	backups, err := Backups(path)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.NotEqual(t, old, backups[0])
+	assert.FileExists(t, other)
+}
+
+func TestWriterRotatesByAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "trace.log")
+	require.NoError(t, os.WriteFile(path, []byte("idle\n"), 0o600))
+	// 从未轮转过的日志按最后修改时间计算
+	past := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(path, past, past))
+
+	var opened []string
+	w, err := Open(path, Options{MaxAge: 24 * time.Hour, Opened: func(p string) { opened = append(opened, p) }})
+	require.NoError(t, err)
+	defer w.Close()
+	assert.Equal(t, []string{path}, opened)
+	_, err = w.Write([]byte("fresh\n"))
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fresh\n", string(data))
+	backups, err := Backups(path)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+
+	// 持续写入的日志从上次轮转时开始计算
+	old := backupName(path, past)
+	require.NoError(t, os.Rename(backups[0], old))
+	_, err = w.Write([]byte("later\n"))
+	require.NoError(t, err)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "later\n", string(data))
+	backups, err = Backups(path)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.NotEqual(t, old, backups[0])
+}
+
+func TestWriterReopensRotatedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.log")
+	w, err := Open(path, Options{})
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("first\n"))
+	require.NoError(t, err)
+	// 模拟另一个进程轮转了日志
+	require.NoError(t, os.Rename(path, path+".1"))
+	_, err = w.Write([]byte("second\n"))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", string(data))
+}
+
+func TestWriterConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.log")
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w, err := Open(path, Options{})
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer w.Close()
+			line := strings.Repeat(string(rune('a'+i)), 64) + "\n"
+			for range 50 {
+				_, err := w.Write([]byte(line))
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 400)
+	for _, line := range lines {
+		require.Len(t, line, 64)
+		assert.Equal(t, strings.Repeat(line[:1], 64), line)
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/fatih/color"
 
+	"github.com/nxtrace/NTrace-core/i18n"
 	"github.com/nxtrace/NTrace-core/util"
 )
 
@@ -71,7 +72,7 @@ func GetMapUrl(r string) (string, error) {
 
 func PrintMapUrl(r string) {
 	_, err := fmt.Fprintf(color.Output, "%s %s\n",
-		color.New(color.FgWhite, color.Bold).Sprintf("%s", "MapTrace URL:"),
+		color.New(color.FgWhite, color.Bold).Sprintf("%s", i18n.T(i18n.MapTraceURL)),
 		color.New(color.FgBlue, color.Bold).Sprintf("%s", r),
 	)
 	if err != nil {
@@ -19,6 +19,10 @@ var (
 	EnvHostPort     = GetEnvDefault("NEXTTRACE_HOSTPORT", "api.nxtrace.org")
 	EnvPowProvider  = GetEnvDefault("NEXTTRACE_POWPROVIDER", "api.nxtrace.org")
 	EnvDeployAddr   = GetEnvDefault("NEXTTRACE_DEPLOY_ADDR", "")
+	EnvDeployTokens = GetEnvDefault("NEXTTRACE_DEPLOY_TOKENS", "")
+	EnvDeployConfig = GetEnvDefault("NEXTTRACE_DEPLOY_CONFIG", "")
+	EnvHistory      = GetEnvBool("NEXTTRACE_HISTORY", false)
+	EnvRedactKey    = GetEnvDefault("NEXTTRACE_REDACT_KEY", "")
 	EnvMaxAttempts  = GetEnvInt("NEXTTRACE_MAXATTEMPTS", 0)
 	EnvICMPMode     = GetEnvInt("NEXTTRACE_ICMPMODE", 0)
 	GlobalpingToken = GetEnvDefault("GLOBALPING_TOKEN", "")
@@ -13,6 +13,8 @@ import (
 	"time"
 
 	"github.com/fatih/color"
+
+	"github.com/nxtrace/NTrace-core/i18n"
 )
 
 type ResponseInfo struct {
@@ -45,7 +47,7 @@ func GetFastIP(domain string, port string, enableOutput bool) string {
 	}
 
 	if err != nil {
-		log.Println("DNS resolution failed, please check your system DNS Settings")
+		log.Println(i18n.T(i18n.DNSFailed))
 	}
 
 	if len(ips) == 0 {
@@ -67,16 +69,17 @@ func GetFastIP(domain string, port string, enableOutput bool) string {
 	case result = <-results:
 		// 正常返回结果
 	case <-time.After(timeout):
-		log.Println("IP connection has been timeout(5s), please check your network")
+		log.Println(i18n.T(i18n.APITimeout))
 	case <-sigChan: // 响应中断信号
-		log.Println("Program interrupted by user")
+		log.Println(i18n.T(i18n.Interrupted))
 		os.Exit(0)
 	}
 
 	//if len(ips) > 0 {
 	if enableOutput {
-		_, _ = fmt.Fprintf(color.Output, "%s preferred API IP - %s - %s - %s",
+		_, _ = fmt.Fprintf(color.Output, "%s %s - %s - %s - %s",
 			color.New(color.FgWhite, color.Bold).Sprintf("[NextTrace API]"),
+			i18n.T(i18n.PreferredAPI),
 			color.New(color.FgGreen, color.Bold).Sprintf("%s", result.IP),
 			color.New(color.FgCyan, color.Bold).Sprintf("%sms", result.Latency),
 			color.New(color.FgGreen, color.Bold).Sprintf("%s", result.Content),
@@ -15,11 +15,11 @@ import (
 	"github.com/fatih/color"
 
 	"github.com/nxtrace/NTrace-core/config"
+	"github.com/nxtrace/NTrace-core/i18n"
 )
 
 var SrcDev string
 var SrcPort int
-var DstIP string
 var PowProviderParam = ""
 var rDNSCache sync.Map
 var UserAgent = fmt.Sprintf("NextTrace %s/%s/%s", config.Version, runtime.GOOS, runtime.GOARCH)
@@ -289,7 +289,7 @@ func DomainLookUp(host string, ipVersion string, dotServer string, disableOutput
 		return ips[0], nil
 	}
 
-	fmt.Println("Please Choose the IP You Want To TraceRoute")
+	fmt.Println(i18n.T(i18n.ChooseIP))
 	for i, ip := range ips {
 		_, _ = fmt.Fprintf(color.Output, "%s %s\n",
 			color.New(color.FgHiYellow, color.Bold).Sprintf("%d.", i),
@@ -297,13 +297,13 @@ func DomainLookUp(host string, ipVersion string, dotServer string, disableOutput
 		)
 	}
 	var index int
-	fmt.Printf("Your Option: ")
+	fmt.Print(i18n.T(i18n.ChooseOption))
 	_, err = fmt.Scanln(&index)
 	if err != nil {
 		index = 0
 	}
 	if index >= len(ips) || index < 0 {
-		fmt.Println("Your Option is invalid")
+		fmt.Println(i18n.T(i18n.InvalidOption))
 		return nil, fmt.Errorf("invalid selection: %d", index)
 	}
 	return ips[index], nil
@@ -341,7 +341,7 @@ func GetProxy() *url.URL {
 	}
 	proxyURL, err := url.Parse(EnvProxyURL)
 	if err != nil {
-		log.Println("Failed to parse proxy URL:", err)
+		log.Println(i18n.T(i18n.ProxyParseFailed, err))
 		return nil
 	}
 	return proxyURL